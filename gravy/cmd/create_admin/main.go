@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
@@ -38,6 +39,9 @@ func main() {
 		return
 	}
 
+	// The server looks users up by lowercased address.
+	*email = strings.ToLower(strings.TrimSpace(*email))
+
 	if err := godotenv.Load(); err != nil {
 		log.Println("No .env file found")
 	}
@@ -106,6 +110,7 @@ func main() {
 		PhoneNumber:   "",
 		IsBanned:      false,
 		SetupComplete: true,
+		EmailVerified: true,
 	}
 
 	_, insertErr := userCollection.InsertOne(ctx, newUser)
@@ -116,4 +121,7 @@ func main() {
 	fmt.Printf("✅ Successfully created admin user!\n")
 	fmt.Printf("   Email: %s\n", newUser.Email)
 	fmt.Printf("   Name:  %s\n", newUser.Name)
+	if os.Getenv("REQUIRE_ADMIN_2FA") == "true" {
+		fmt.Printf("   Sign in and set up two-factor authentication before using admin features.\n")
+	}
 }
@@ -0,0 +1,326 @@
+// Command oidc_stub is a minimal OpenID Connect provider for trying out and
+// testing OIDC login locally. It signs in whoever fills in its login form,
+// so never expose it.
+//
+//	go run ./cmd/oidc_stub -addr :9000
+//
+// and run the server with
+//
+//	OIDC_ISSUER=http://localhost:9000
+//	OIDC_CLIENT_ID=wikinitt
+//	OIDC_REDIRECT_URL=http://localhost:3000/auth/oidc/callback
+//	OIDC_ALLOWED_DOMAINS=nitt.edu
+//
+// It supports the authorization code flow with PKCE (S256 only), which is
+// all the server uses.
+package main
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"html/template"
+	"log"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	keyID   = "stub-1"
+	codeTTL = time.Minute
+)
+
+type pendingCode struct {
+	clientID      string
+	redirectURI   string
+	codeChallenge string
+	nonce         string
+	email         string
+	name          string
+	emailVerified bool
+	expiresAt     time.Time
+}
+
+type stub struct {
+	issuer       string
+	clientID     string
+	clientSecret string
+	// email and name are what the login form starts with.
+	email string
+	name  string
+	key   *rsa.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]*pendingCode
+}
+
+var loginForm = template.Must(template.New("login").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head><meta charset="utf-8"><title>Stub OIDC login</title></head>
+<body style="font-family: sans-serif; max-width: 28em; margin: 3em auto">
+<h1>Stub OIDC login</h1>
+<p>Signing in to <code>{{.ClientID}}</code>. Anyone can sign in as anyone here.</p>
+<form method="post" action="/authorize">
+{{range $k, $v := .Params}}<input type="hidden" name="{{$k}}" value="{{$v}}">
+{{end}}<p><label>Email<br><input name="email" value="{{.Email}}" size="32"></label></p>
+<p><label>Name<br><input name="name" value="{{.Name}}" size="32"></label></p>
+<p><label><input type="checkbox" name="email_verified" value="true" checked> Email verified</label></p>
+<p><button type="submit">Sign in</button></p>
+</form>
+</body>
+</html>
+`))
+
+func main() {
+	addr := flag.String("addr", ":9000", "Address to listen on")
+	issuer := flag.String("issuer", "http://localhost:9000", "Issuer URL the server is configured with")
+	clientID := flag.String("client-id", "wikinitt", "Client ID to accept")
+	clientSecret := flag.String("client-secret", "", "Client secret to require at the token endpoint (optional)")
+	email := flag.String("email", "student@nitt.edu", "Email address the login form starts with")
+	name := flag.String("name", "Test Student", "Name the login form starts with")
+	flag.Parse()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		log.Fatalf("Failed to generate signing key: %v", err)
+	}
+	s := &stub{
+		issuer:       strings.TrimRight(*issuer, "/"),
+		clientID:     *clientID,
+		clientSecret: *clientSecret,
+		email:        *email,
+		name:         *name,
+		key:          key,
+		codes:        make(map[string]*pendingCode),
+	}
+
+	log.Printf("Stub OIDC provider for client %q listening on %s (issuer %s)", s.clientID, *addr, s.issuer)
+	log.Fatal(http.ListenAndServe(*addr, s.handler()))
+}
+
+func (s *stub) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", s.discovery)
+	mux.HandleFunc("/jwks", s.jwks)
+	mux.HandleFunc("/authorize", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			s.approve(w, r)
+			return
+		}
+		s.authorize(w, r)
+	})
+	mux.HandleFunc("/token", s.token)
+	return mux
+}
+
+func (s *stub) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                s.issuer,
+		"authorization_endpoint":                s.issuer + "/authorize",
+		"token_endpoint":                        s.issuer + "/token",
+		"jwks_uri":                              s.issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+		"scopes_supported":                      []string{"openid", "email", "profile"},
+	})
+}
+
+func (s *stub) jwks(w http.ResponseWriter, r *http.Request) {
+	enc := base64.RawURLEncoding
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": keyID,
+			"alg": "RS256",
+			"use": "sig",
+			"n":   enc.EncodeToString(s.key.N.Bytes()),
+			"e":   enc.EncodeToString(big.NewInt(int64(s.key.E)).Bytes()),
+		}},
+	})
+}
+
+// authorize checks the authorization request and shows the login form,
+// which carries the request's parameters on to approve.
+func (s *stub) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if msg := s.checkAuthRequest(q); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+	params := make(map[string]string)
+	for _, k := range []string{"client_id", "redirect_uri", "state", "nonce", "code_challenge"} {
+		params[k] = q.Get(k)
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	loginForm.Execute(w, map[string]interface{}{
+		"ClientID": s.clientID,
+		"Params":   params,
+		"Email":    s.email,
+		"Name":     s.name,
+	})
+}
+
+func (s *stub) checkAuthRequest(q url.Values) string {
+	switch {
+	case q.Get("response_type") != "" && q.Get("response_type") != "code":
+		return "only response_type=code is supported"
+	case q.Get("client_id") != s.clientID:
+		return "unknown client_id"
+	case q.Get("redirect_uri") == "":
+		return "redirect_uri is required"
+	case q.Get("code_challenge") == "":
+		return "code_challenge is required"
+	case q.Get("code_challenge_method") != "" && q.Get("code_challenge_method") != "S256":
+		return "only the S256 code challenge method is supported"
+	}
+	return ""
+}
+
+// approve issues a code for the submitted login and sends the user back.
+func (s *stub) approve(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if msg := s.checkAuthRequest(r.PostForm); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	code := randomString()
+	s.mu.Lock()
+	s.codes[code] = &pendingCode{
+		clientID:      r.PostForm.Get("client_id"),
+		redirectURI:   r.PostForm.Get("redirect_uri"),
+		codeChallenge: r.PostForm.Get("code_challenge"),
+		nonce:         r.PostForm.Get("nonce"),
+		email:         strings.TrimSpace(r.PostForm.Get("email")),
+		name:          strings.TrimSpace(r.PostForm.Get("name")),
+		emailVerified: r.PostForm.Get("email_verified") == "true",
+		expiresAt:     time.Now().Add(codeTTL),
+	}
+	s.mu.Unlock()
+
+	target, err := url.Parse(r.PostForm.Get("redirect_uri"))
+	if err != nil {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+	q := target.Query()
+	q.Set("code", code)
+	q.Set("state", r.PostForm.Get("state"))
+	target.RawQuery = q.Encode()
+	http.Redirect(w, r, target.String(), http.StatusFound)
+}
+
+func (s *stub) token(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		tokenError(w, "invalid_request", err.Error())
+		return
+	}
+	form := r.PostForm
+
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok {
+		clientID, clientSecret = form.Get("client_id"), form.Get("client_secret")
+	}
+	if clientID != s.clientID ||
+		(s.clientSecret != "" && subtle.ConstantTimeCompare([]byte(clientSecret), []byte(s.clientSecret)) != 1) {
+		tokenError(w, "invalid_client", "unknown client or wrong secret")
+		return
+	}
+	if form.Get("grant_type") != "authorization_code" {
+		tokenError(w, "unsupported_grant_type", "only authorization_code is supported")
+		return
+	}
+
+	s.mu.Lock()
+	pending := s.codes[form.Get("code")]
+	delete(s.codes, form.Get("code"))
+	s.mu.Unlock()
+
+	if pending == nil || time.Now().After(pending.expiresAt) || pending.clientID != clientID {
+		tokenError(w, "invalid_grant", "unknown or expired code")
+		return
+	}
+	if form.Get("redirect_uri") != pending.redirectURI {
+		tokenError(w, "invalid_grant", "redirect_uri does not match the authorization request")
+		return
+	}
+	sum := sha256.Sum256([]byte(form.Get("code_verifier")))
+	if base64.RawURLEncoding.EncodeToString(sum[:]) != pending.codeChallenge {
+		tokenError(w, "invalid_grant", "code_verifier does not match the code challenge")
+		return
+	}
+
+	// The subject is derived from the address so signing in again with the
+	// same address is the same account.
+	subject := sha256.Sum256([]byte(strings.ToLower(pending.email)))
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":            s.issuer,
+		"sub":            "stub-" + hex.EncodeToString(subject[:8]),
+		"aud":            clientID,
+		"iat":            now.Unix(),
+		"exp":            now.Add(time.Hour).Unix(),
+		"email":          pending.email,
+		"email_verified": pending.emailVerified,
+		"name":           pending.name,
+	}
+	if pending.nonce != "" {
+		claims["nonce"] = pending.nonce
+	}
+	idToken := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	idToken.Header["kid"] = keyID
+	signed, err := idToken.SignedString(s.key)
+	if err != nil {
+		tokenError(w, "server_error", err.Error())
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": randomString(),
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     signed,
+	})
+}
+
+func tokenError(w http.ResponseWriter, code, description string) {
+	writeJSON(w, http.StatusBadRequest, map[string]string{
+		"error":             code,
+		"error_description": description,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func randomString() string {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
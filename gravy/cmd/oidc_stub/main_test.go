@@ -0,0 +1,477 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pranava-mohan/wikinitt/gravy/internal/oidc"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+const redirectURL = "http://frontend.test/auth/oidc/callback"
+
+// memRepo keeps pending logins and identities in memory, with the
+// semantics of the MongoDB repository.
+type memRepo struct {
+	mu         sync.Mutex
+	pending    map[string]oidc.PendingLogin
+	identities []*oidc.Identity
+}
+
+func (m *memRepo) SavePending(ctx context.Context, login oidc.PendingLogin) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pending[login.StateHash] = login
+	return nil
+}
+
+func (m *memRepo) TakePending(ctx context.Context, stateHash, browserHash string) (*oidc.PendingLogin, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	login, ok := m.pending[stateHash]
+	if !ok || login.BrowserHash != browserHash || !login.ExpiresAt.After(time.Now()) {
+		return nil, mongo.ErrNoDocuments
+	}
+	delete(m.pending, stateHash)
+	return &login, nil
+}
+
+func (m *memRepo) GetIdentity(ctx context.Context, issuer, subject string) (*oidc.Identity, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, i := range m.identities {
+		if i.Issuer == issuer && i.Subject == subject {
+			copied := *i
+			return &copied, nil
+		}
+	}
+	return nil, mongo.ErrNoDocuments
+}
+
+func (m *memRepo) LinkIdentity(ctx context.Context, identity *oidc.Identity) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	identity.ID = fmt.Sprint(len(m.identities) + 1)
+	copied := *identity
+	m.identities = append(m.identities, &copied)
+	return nil
+}
+
+func (m *memRepo) TouchIdentity(ctx context.Context, id, email string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, i := range m.identities {
+		if i.ID == id {
+			i.Email = email
+			i.LastLoginAt = time.Now()
+			return nil
+		}
+	}
+	return mongo.ErrNoDocuments
+}
+
+func (m *memRepo) ListIdentities(ctx context.Context, userID string) ([]*oidc.Identity, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var list []*oidc.Identity
+	for _, i := range m.identities {
+		if i.UserID == userID {
+			copied := *i
+			list = append(list, &copied)
+		}
+	}
+	return list, nil
+}
+
+func (m *memRepo) EnsureIndexes(ctx context.Context) error {
+	return nil
+}
+
+type testEnv struct {
+	stub *stub
+	srv  *httptest.Server
+	svc  *oidc.Service
+}
+
+// newTestEnv starts the stub provider and a service configured to use it.
+func newTestEnv(t *testing.T, allowedDomains ...string) *testEnv {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &stub{
+		clientID: "wikinitt",
+		email:    "student@nitt.edu",
+		name:     "Test Student",
+		key:      key,
+		codes:    make(map[string]*pendingCode),
+	}
+	srv := httptest.NewServer(s.handler())
+	t.Cleanup(srv.Close)
+	s.issuer = srv.URL
+
+	cfg := oidc.Config{
+		Issuer:         srv.URL,
+		ClientID:       "wikinitt",
+		RedirectURL:    redirectURL,
+		Scopes:         []string{"openid", "email", "profile"},
+		AllowedDomains: allowedDomains,
+	}
+	repo := &memRepo{pending: make(map[string]oidc.PendingLogin)}
+	return &testEnv{stub: s, srv: srv, svc: oidc.NewService(oidc.NewProvider(cfg), repo)}
+}
+
+// browser holds the cookies the server set, like the user's browser would.
+type browser struct {
+	cookies map[string]*http.Cookie
+}
+
+func newBrowser() *browser {
+	return &browser{cookies: make(map[string]*http.Cookie)}
+}
+
+// call runs fn as part of a request to the GraphQL endpoint sent from the
+// browser.
+func (b *browser) call(fn func(ctx context.Context)) {
+	req := httptest.NewRequest(http.MethodPost, "/query", nil)
+	for _, c := range b.cookies {
+		req.AddCookie(c)
+	}
+	rec := httptest.NewRecorder()
+	oidc.Middleware(false)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fn(r.Context())
+	})).ServeHTTP(rec, req)
+
+	for _, c := range rec.Result().Cookies() {
+		if c.MaxAge < 0 {
+			delete(b.cookies, c.Name)
+		} else {
+			b.cookies[c.Name] = c
+		}
+	}
+}
+
+func (e *testEnv) begin(t *testing.T, b *browser) string {
+	t.Helper()
+	var authURL string
+	var err error
+	b.call(func(ctx context.Context) {
+		authURL, _, err = e.svc.Begin(ctx)
+	})
+	if err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	return authURL
+}
+
+func (e *testEnv) complete(b *browser, code, state string) (*oidc.Claims, error) {
+	var claims *oidc.Claims
+	var err error
+	b.call(func(ctx context.Context) {
+		claims, err = e.svc.Complete(ctx, code, state)
+	})
+	return claims, err
+}
+
+// signIn fills in the stub's login form for the authorization URL and
+// returns the code and state it redirects back with. edit may change the
+// submitted form first.
+func (e *testEnv) signIn(t *testing.T, authURL, email string, edit func(url.Values)) (string, string) {
+	t.Helper()
+	resp, err := http.Get(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("authorization request: %s", resp.Status)
+	}
+
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	form := url.Values{
+		"email":          {email},
+		"name":           {"Test Student"},
+		"email_verified": {"true"},
+	}
+	for _, k := range []string{"client_id", "redirect_uri", "state", "nonce", "code_challenge"} {
+		form.Set(k, u.Query().Get(k))
+	}
+	if edit != nil {
+		edit(form)
+	}
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err = client.PostForm(e.srv.URL+"/authorize", form)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("login form: %s", resp.Status)
+	}
+	back, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return back.Query().Get("code"), back.Query().Get("state")
+}
+
+// login runs a whole login in a new browser.
+func (e *testEnv) login(t *testing.T, email string) *oidc.Claims {
+	t.Helper()
+	b := newBrowser()
+	code, state := e.signIn(t, e.begin(t, b), email, nil)
+	claims, err := e.complete(b, code, state)
+	if err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	return claims
+}
+
+func TestLogin(t *testing.T) {
+	e := newTestEnv(t)
+	b := newBrowser()
+	authURL := e.begin(t, b)
+
+	cookie := b.cookies["oidc_login"]
+	if cookie == nil || !cookie.HttpOnly {
+		t.Fatalf("Begin set login cookie %+v, want an HttpOnly cookie", cookie)
+	}
+	u, _ := url.Parse(authURL)
+	if got := u.Query().Get("code_challenge_method"); got != "S256" {
+		t.Errorf("code_challenge_method = %q, want S256", got)
+	}
+
+	code, state := e.signIn(t, authURL, "student@nitt.edu", nil)
+	claims, err := e.complete(b, code, state)
+	if err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	if claims.Issuer != e.srv.URL || claims.Subject == "" || claims.Email != "student@nitt.edu" || !claims.EmailVerified {
+		t.Errorf("claims = %+v", claims)
+	}
+	if _, ok := b.cookies["oidc_login"]; ok {
+		t.Error("Complete did not clear the login cookie")
+	}
+}
+
+func TestLoginState(t *testing.T) {
+	tests := []struct {
+		name string
+		// run completes a login whose code and state were issued to the
+		// browser that started it.
+		run func(t *testing.T, e *testEnv, started *browser, code, state string) error
+	}{
+		{
+			name: "unknown state",
+			run: func(t *testing.T, e *testEnv, started *browser, code, state string) error {
+				_, err := e.complete(started, code, "forged")
+				return err
+			},
+		},
+		{
+			name: "missing code",
+			run: func(t *testing.T, e *testEnv, started *browser, code, state string) error {
+				_, err := e.complete(started, "", state)
+				return err
+			},
+		},
+		{
+			name: "state used twice",
+			run: func(t *testing.T, e *testEnv, started *browser, code, state string) error {
+				cookie := started.cookies["oidc_login"]
+				if _, err := e.complete(started, code, state); err != nil {
+					return fmt.Errorf("first use failed: %w", err)
+				}
+				started.cookies["oidc_login"] = cookie
+				_, err := e.complete(started, code, state)
+				return err
+			},
+		},
+		{
+			name: "browser without the login cookie",
+			run: func(t *testing.T, e *testEnv, started *browser, code, state string) error {
+				_, err := e.complete(newBrowser(), code, state)
+				return err
+			},
+		},
+		{
+			// A login CSRF: the attacker's callback link opened in the
+			// victim's browser, which has a login of its own in progress.
+			name: "login cookie of another browser",
+			run: func(t *testing.T, e *testEnv, started *browser, code, state string) error {
+				victim := newBrowser()
+				e.begin(t, victim)
+				_, err := e.complete(victim, code, state)
+				if err == nil {
+					return nil
+				}
+				// The state is still the attacker's to complete.
+				if _, err := e.complete(started, code, state); err != nil {
+					return fmt.Errorf("the browser that started the login could not complete it: %w", err)
+				}
+				return oidc.ErrInvalidState
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEnv(t)
+			b := newBrowser()
+			code, state := e.signIn(t, e.begin(t, b), "student@nitt.edu", nil)
+			if err := tt.run(t, e, b, code, state); !errors.Is(err, oidc.ErrInvalidState) {
+				t.Errorf("err = %v, want %v", err, oidc.ErrInvalidState)
+			}
+		})
+	}
+}
+
+func TestLoginProviderChecks(t *testing.T) {
+	tests := []struct {
+		name    string
+		edit    func(url.Values)
+		wantErr string
+	}{
+		{
+			name:    "code challenge replaced",
+			edit:    func(f url.Values) { f.Set("code_challenge", "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM") },
+			wantErr: "code_verifier does not match",
+		},
+		{
+			name:    "nonce replaced",
+			edit:    func(f url.Values) { f.Set("nonce", "attacker") },
+			wantErr: "nonce mismatch",
+		},
+		{
+			name:    "nonce dropped",
+			edit:    func(f url.Values) { f.Del("nonce") },
+			wantErr: "nonce mismatch",
+		},
+		{
+			name:    "redirect changed",
+			edit:    func(f url.Values) { f.Set("redirect_uri", "http://attacker.test/callback") },
+			wantErr: "redirect_uri does not match",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEnv(t)
+			b := newBrowser()
+			code, state := e.signIn(t, e.begin(t, b), "student@nitt.edu", tt.edit)
+			_, err := e.complete(b, code, state)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoginBadSignature(t *testing.T) {
+	e := newTestEnv(t)
+	// The first login makes the service fetch and cache the stub's key.
+	e.login(t, "student@nitt.edu")
+
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.stub.key = other
+
+	b := newBrowser()
+	code, state := e.signIn(t, e.begin(t, b), "student@nitt.edu", nil)
+	_, err = e.complete(b, code, state)
+	if err == nil || !strings.Contains(err.Error(), "invalid ID token") {
+		t.Errorf("err = %v, want an invalid ID token", err)
+	}
+}
+
+func TestIdentityLinking(t *testing.T) {
+	e := newTestEnv(t)
+	ctx := context.Background()
+
+	claims := e.login(t, "student@nitt.edu")
+	identity, err := e.svc.Identity(ctx, claims)
+	if err != nil || identity != nil {
+		t.Fatalf("Identity before linking = %+v, %v; want none", identity, err)
+	}
+	if err := e.svc.Link(ctx, "user-1", claims); err != nil {
+		t.Fatalf("Link: %v", err)
+	}
+
+	// The stub keeps the subject when the address changes case, and the
+	// identity follows the address the provider now reports.
+	again := e.login(t, "Student@nitt.edu")
+	if again.Subject != claims.Subject {
+		t.Fatalf("subject changed from %q to %q", claims.Subject, again.Subject)
+	}
+	identity, err = e.svc.Identity(ctx, again)
+	if err != nil || identity == nil || identity.UserID != "user-1" {
+		t.Fatalf("Identity after linking = %+v, %v; want user-1", identity, err)
+	}
+	list, err := e.svc.Identities(ctx, "user-1")
+	if err != nil || len(list) != 1 || list[0].Email != "Student@nitt.edu" {
+		t.Errorf("Identities = %+v, %v", list, err)
+	}
+
+	someoneElse := e.login(t, "other@nitt.edu")
+	if identity, err := e.svc.Identity(ctx, someoneElse); err != nil || identity != nil {
+		t.Errorf("Identity of another account = %+v, %v; want none", identity, err)
+	}
+}
+
+func TestDomainRestriction(t *testing.T) {
+	e := newTestEnv(t, "nitt.edu")
+	u, err := url.Parse(e.begin(t, newBrowser()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := u.Query().Get("hd"); got != "nitt.edu" {
+		t.Errorf("hd = %q, want nitt.edu", got)
+	}
+
+	tests := []struct {
+		email   string
+		domains []string
+		want    bool
+	}{
+		{"student@nitt.edu", []string{"nitt.edu"}, true},
+		{"Student@NITT.EDU", []string{"nitt.edu"}, true},
+		{"staff@alumni.nitt.edu", []string{"nitt.edu", "alumni.nitt.edu"}, true},
+		{"someone@gmail.com", []string{"nitt.edu"}, false},
+		{"someone@evilnitt.edu", []string{"nitt.edu"}, false},
+		{"student@nitt.edu@gmail.com", []string{"nitt.edu"}, false},
+		{"no-at-sign", []string{"nitt.edu"}, false},
+		{"someone@gmail.com", nil, true},
+	}
+	for _, tt := range tests {
+		cfg := oidc.Config{AllowedDomains: tt.domains}
+		if got := cfg.DomainAllowed(tt.email); got != tt.want {
+			t.Errorf("DomainAllowed(%q) with %q = %v, want %v", tt.email, tt.domains, got, tt.want)
+		}
+	}
+}
+
+func TestBeginNeedsBrowserRequest(t *testing.T) {
+	e := newTestEnv(t)
+	if _, _, err := e.svc.Begin(context.Background()); err == nil {
+		t.Error("Begin without the middleware succeeded, want an error")
+	}
+}
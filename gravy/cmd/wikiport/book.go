@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/pranava-mohan/wikinitt/gravy/internal/export"
+)
+
+func runBook(ctx context.Context, d *deps, args []string) error {
+	fs := flag.NewFlagSet("book", flag.ExitOnError)
+	category := fs.String("category", "", "Export the published articles in this category slug")
+	ids := fs.String("ids", "", "Comma-separated article IDs to export, in order")
+	format := fs.String("format", "epub", "Output format: epub or html")
+	out := fs.String("out", "", "File to write (default: derived from the title)")
+	title := fs.String("title", "", "Book title (default: the category name)")
+	fs.Parse(args)
+
+	source := export.Source{CategorySlug: *category}
+	for _, id := range strings.Split(*ids, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			source.ArticleIDs = append(source.ArticleIDs, id)
+		}
+	}
+	if err := source.Validate(); err != nil {
+		fs.Usage()
+		return err
+	}
+
+	f := export.Format(strings.ToUpper(*format))
+	if f != export.FormatEPUB && f != export.FormatHTML {
+		return fmt.Errorf("unknown format %q", *format)
+	}
+
+	book, err := export.NewBuilder(d.articles, d.categories).Collect(ctx, source, *title)
+	if err != nil {
+		return err
+	}
+
+	path := *out
+	if path == "" {
+		path = book.FileName(f)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := export.Write(file, book, f); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	log.Printf("Wrote %d articles and %d images to %s", len(book.Chapters), len(book.Images), path)
+	return nil
+}
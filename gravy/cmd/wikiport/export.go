@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pranava-mohan/wikinitt/gravy/internal/articles"
+)
+
+const exportPageSize = 100
+
+func runExport(ctx context.Context, d *deps, args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	dir := fs.String("dir", "", "Directory to write .md files to (required)")
+	status := fs.String("status", "", "Only export articles in this status, e.g. PUBLISHED")
+	fs.Parse(args)
+
+	if *dir == "" {
+		fs.Usage()
+		return fmt.Errorf("-dir is required")
+	}
+	if err := os.MkdirAll(*dir, 0o755); err != nil {
+		return err
+	}
+
+	filter := articles.ListFilter{}
+	if *status != "" {
+		s := articles.Status(strings.ToUpper(*status))
+		filter.Status = &s
+	}
+
+	authors := make(map[string]string)
+	exported := 0
+	for offset := 0; ; offset += exportPageSize {
+		limit, skip := exportPageSize, offset
+		page, err := d.articles.List(ctx, filter, &limit, &skip)
+		if err != nil {
+			return fmt.Errorf("failed to list articles: %w", err)
+		}
+
+		for _, a := range page {
+			doc := Document{
+				FrontMatter: FrontMatter{
+					Title:     a.Title,
+					Slug:      a.Slug,
+					Category:  a.Category,
+					Tags:      a.Tags,
+					Featured:  a.Featured,
+					Thumbnail: a.Thumbnail,
+					Author:    authorUsername(ctx, d, authors, a.AuthorID),
+					Status:    string(a.CurrentStatus()),
+					Version:   a.Version,
+				},
+				Content: a.Content,
+			}
+			data, err := doc.Marshal()
+			if err != nil {
+				return fmt.Errorf("failed to encode %s: %w", a.Slug, err)
+			}
+			if err := os.WriteFile(filepath.Join(*dir, a.Slug+".md"), data, 0o644); err != nil {
+				return err
+			}
+			exported++
+		}
+
+		if len(page) < exportPageSize {
+			break
+		}
+	}
+
+	log.Printf("Exported %d articles to %s", exported, *dir)
+	return nil
+}
+
+func authorUsername(ctx context.Context, d *deps, cache map[string]string, id string) string {
+	if username, ok := cache[id]; ok {
+		return username
+	}
+	username := ""
+	if u, err := d.users.GetByID(ctx, id); err == nil {
+		username = u.Username
+	}
+	cache[id] = username
+	return username
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+)
+
+const frontMatterDelimiter = "---"
+
+// FrontMatter is the YAML header of an exported article. Version is the
+// article's version at export time; importing a file whose version no
+// longer matches is reported as a conflict instead of overwriting newer
+// edits. Hand-written files may leave it out for new articles; updating an
+// existing article without it needs the import's -force flag.
+type FrontMatter struct {
+	Title     string   `yaml:"title"`
+	Slug      string   `yaml:"slug"`
+	Category  string   `yaml:"category"`
+	Tags      []string `yaml:"tags,omitempty"`
+	Featured  bool     `yaml:"featured"`
+	Thumbnail string   `yaml:"thumbnail,omitempty"`
+	Author    string   `yaml:"author,omitempty"`
+	Status    string   `yaml:"status,omitempty"`
+	Version   int      `yaml:"version,omitempty"`
+}
+
+// Document is an article as stored on disk: front matter followed by the
+// Markdown body.
+type Document struct {
+	FrontMatter
+	Content string
+}
+
+func (d *Document) Marshal() ([]byte, error) {
+	header, err := yaml.Marshal(d.FrontMatter)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(frontMatterDelimiter + "\n")
+	buf.Write(header)
+	buf.WriteString(frontMatterDelimiter + "\n\n")
+	buf.WriteString(d.Content)
+	return buf.Bytes(), nil
+}
+
+func ParseDocument(data []byte) (*Document, error) {
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+	if !strings.HasPrefix(text, frontMatterDelimiter+"\n") {
+		return nil, errors.New("missing front matter")
+	}
+	rest := text[len(frontMatterDelimiter)+1:]
+
+	// Prefixing a newline lets an empty header match like any other.
+	rest = "\n" + rest
+	end := strings.Index(rest, "\n"+frontMatterDelimiter+"\n")
+	if end < 0 {
+		return nil, errors.New("unterminated front matter")
+	}
+
+	var doc Document
+	if err := yaml.Unmarshal([]byte(rest[:end]), &doc.FrontMatter); err != nil {
+		return nil, fmt.Errorf("invalid front matter: %w", err)
+	}
+	// Marshal separates the header from the body with a blank line.
+	doc.Content = strings.TrimPrefix(rest[end+len(frontMatterDelimiter)+2:], "\n")
+	return &doc, nil
+}
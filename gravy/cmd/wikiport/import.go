@@ -0,0 +1,409 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pranava-mohan/wikinitt/gravy/internal/articles"
+	"github.com/pranava-mohan/wikinitt/gravy/internal/categories"
+	"github.com/pranava-mohan/wikinitt/gravy/internal/rag"
+	"github.com/pranava-mohan/wikinitt/gravy/internal/sanitization"
+	"github.com/pranava-mohan/wikinitt/gravy/internal/versioning"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+type action string
+
+const (
+	actionCreate    action = "create"
+	actionUpdate    action = "update"
+	actionUnchanged action = "unchanged"
+	actionConflict  action = "conflict"
+	actionInvalid   action = "invalid"
+)
+
+// planned is what importing one file would do.
+type planned struct {
+	file     string
+	action   action
+	reason   string
+	doc      *Document
+	existing *articles.Article
+	authorID string
+	updates  bson.M
+}
+
+type importer struct {
+	deps     *deps
+	importer string
+	authors  map[string]string
+	// Categories this run creates, or would create in a dry run, and
+	// whether they have been created yet.
+	newCategories map[string]bool
+	// Files already planned, by slug.
+	slugs map[string]string
+	// force lets files without a version overwrite existing articles.
+	force bool
+}
+
+func runImport(ctx context.Context, d *deps, args []string) error {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	dir := fs.String("dir", "", "Directory of .md files to import (required)")
+	as := fs.String("as", "", "Username the import is recorded as in revisions, and the author of new articles without a known author (required unless -dry-run)")
+	dryRun := fs.Bool("dry-run", false, "Report what would be created, updated or in conflict without writing anything")
+	summary := fs.String("summary", "Imported from Markdown", "Revision summary for imported changes")
+	force := fs.Bool("force", false, "Let files without a version overwrite existing articles")
+	fs.Parse(args)
+
+	if *dir == "" {
+		fs.Usage()
+		return fmt.Errorf("-dir is required")
+	}
+
+	imp := &importer{
+		deps:          d,
+		authors:       make(map[string]string),
+		newCategories: make(map[string]bool),
+		slugs:         make(map[string]string),
+		force:         *force,
+	}
+	if *as != "" {
+		u, err := d.users.GetByUsername(ctx, *as)
+		if err != nil {
+			return fmt.Errorf("unknown user %q: %w", *as, err)
+		}
+		imp.importer = u.ID
+	} else if !*dryRun {
+		fs.Usage()
+		return fmt.Errorf("-as is required unless -dry-run is set")
+	}
+
+	files, err := filepath.Glob(filepath.Join(*dir, "*.md"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(files)
+
+	counts := make(map[action]int)
+	for _, file := range files {
+		p := imp.plan(ctx, file)
+		if !*dryRun && (p.action == actionCreate || p.action == actionUpdate) {
+			if err := imp.apply(ctx, p, *summary); err != nil {
+				var conflict *versioning.ConflictError
+				if errors.As(err, &conflict) {
+					p.action, p.reason = actionConflict, err.Error()
+				} else {
+					p.action, p.reason = actionInvalid, err.Error()
+				}
+			}
+		}
+
+		counts[p.action]++
+		line := fmt.Sprintf("%-9s %s", p.action, filepath.Base(file))
+		if p.reason != "" {
+			line += ": " + p.reason
+		}
+		fmt.Println(line)
+	}
+
+	names := make([]string, 0, len(imp.newCategories))
+	for name := range imp.newCategories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Printf("%-9s category %q\n", actionCreate, name)
+	}
+	prefix := ""
+	if *dryRun {
+		prefix = "dry run: "
+	}
+	fmt.Printf("%s%d created, %d updated, %d unchanged, %d conflicts, %d invalid\n", prefix,
+		counts[actionCreate], counts[actionUpdate], counts[actionUnchanged], counts[actionConflict], counts[actionInvalid])
+	return nil
+}
+
+// plan works out what importing file would do without changing anything.
+func (imp *importer) plan(ctx context.Context, file string) *planned {
+	p := &planned{file: file}
+	invalid := func(format string, args ...interface{}) *planned {
+		p.action, p.reason = actionInvalid, fmt.Sprintf(format, args...)
+		return p
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return invalid("%v", err)
+	}
+	doc, err := ParseDocument(data)
+	if err != nil {
+		return invalid("%v", err)
+	}
+	p.doc = doc
+
+	doc.Title = strings.TrimSpace(doc.Title)
+	if doc.Title == "" {
+		return invalid("title is required")
+	}
+	if doc.Category == "" {
+		return invalid("category is required")
+	}
+	if doc.Slug == "" {
+		if doc.Slug, err = articles.GenerateSlug(doc.Title, 50); err != nil {
+			return invalid("%v", err)
+		}
+	}
+	if doc.Tags, err = articles.NormalizeTags(doc.Tags); err != nil {
+		return invalid("%v", err)
+	}
+	doc.Content = sanitization.SanitizeContent(doc.Content)
+	doc.Thumbnail = sanitization.SanitizeString(doc.Thumbnail)
+
+	if other, ok := imp.slugs[doc.Slug]; ok {
+		p.action, p.reason = actionConflict, fmt.Sprintf("slug %q is also used by %s", doc.Slug, filepath.Base(other))
+		return p
+	}
+	imp.slugs[doc.Slug] = file
+
+	existing, err := imp.deps.articles.GetBySlug(ctx, doc.Slug)
+	if err == mongo.ErrNoDocuments {
+		// A slug that now redirects belongs to a renamed or merged article;
+		// creating a new one would shadow the redirect.
+		if target, err := imp.deps.articles.ResolveRedirect(ctx, doc.Slug); err == nil {
+			p.action = actionConflict
+			p.reason = fmt.Sprintf("slug now redirects to %q; update the file's slug", target.Slug)
+			return p
+		}
+		return imp.planCreate(ctx, p)
+	}
+	if err != nil {
+		return invalid("%v", err)
+	}
+	p.existing = existing
+
+	if doc.Version == 0 && !imp.force {
+		p.action = actionConflict
+		p.reason = fmt.Sprintf("file has no version and the article exists (current version %d); add the version or pass -force", existing.Version)
+		return p
+	}
+	if doc.Version != 0 && doc.Version != existing.Version {
+		p.action = actionConflict
+		p.reason = fmt.Sprintf("article changed since export (file version %d, current version %d)", doc.Version, existing.Version)
+		return p
+	}
+
+	p.updates = changedFields(existing, doc)
+	if len(p.updates) == 0 {
+		p.action = actionUnchanged
+		return p
+	}
+	if err := imp.planCategory(ctx, doc.Category); err != nil {
+		return invalid("%v", err)
+	}
+	fields := make([]string, 0, len(p.updates))
+	for k := range p.updates {
+		fields = append(fields, k)
+	}
+	sort.Strings(fields)
+	p.action, p.reason = actionUpdate, strings.Join(fields, ", ")
+	return p
+}
+
+func (imp *importer) planCreate(ctx context.Context, p *planned) *planned {
+	doc := p.doc
+	status := articles.StatusPublished
+	if doc.Status != "" {
+		status = articles.Status(strings.ToUpper(doc.Status))
+	}
+	switch status {
+	case articles.StatusDraft, articles.StatusInReview, articles.StatusPublished, articles.StatusArchived:
+	default:
+		p.action, p.reason = actionInvalid, fmt.Sprintf("status %q cannot be imported", doc.Status)
+		return p
+	}
+	doc.Status = string(status)
+
+	p.authorID = imp.authorID(ctx, doc.Author)
+	if p.authorID == "" {
+		p.authorID = imp.importer
+	}
+	if p.authorID == "" && doc.Author != "" {
+		p.action, p.reason = actionConflict, fmt.Sprintf("unknown author %q; pass -as to attribute it to someone else", doc.Author)
+		return p
+	}
+
+	if err := imp.planCategory(ctx, doc.Category); err != nil {
+		p.action, p.reason = actionInvalid, err.Error()
+		return p
+	}
+	p.action = actionCreate
+	return p
+}
+
+// planCategory notes categories that do not exist yet so the import can
+// create them.
+func (imp *importer) planCategory(ctx context.Context, name string) error {
+	if _, ok := imp.newCategories[name]; ok {
+		return nil
+	}
+	existing, err := imp.deps.categories.GetByName(ctx, name)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		imp.newCategories[name] = false
+	}
+	return nil
+}
+
+func (imp *importer) authorID(ctx context.Context, username string) string {
+	if username == "" {
+		return ""
+	}
+	if id, ok := imp.authors[username]; ok {
+		return id
+	}
+	id := ""
+	if u, err := imp.deps.users.GetByUsername(ctx, username); err == nil {
+		id = u.ID
+	}
+	imp.authors[username] = id
+	return id
+}
+
+// changedFields returns the updates needed to make existing match doc.
+// Status and author are left alone on updates: publishing goes through the
+// review workflow, and authorship does not change.
+func changedFields(existing *articles.Article, doc *Document) bson.M {
+	updates := bson.M{}
+	if existing.Title != doc.Title {
+		updates["title"] = doc.Title
+	}
+	if existing.Content != doc.Content {
+		updates["content"] = doc.Content
+	}
+	if existing.Category != doc.Category {
+		updates["category"] = doc.Category
+	}
+	if !sameTags(existing.Tags, doc.Tags) {
+		updates["tags"] = doc.Tags
+	}
+	if existing.Featured != doc.Featured {
+		updates["featured"] = doc.Featured
+	}
+	if existing.Thumbnail != doc.Thumbnail {
+		updates["thumbnail"] = doc.Thumbnail
+	}
+	return updates
+}
+
+func sameTags(a, b []string) bool {
+	if len(a) == 0 && len(b) == 0 {
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func (imp *importer) apply(ctx context.Context, p *planned, summary string) error {
+	if created, ok := imp.newCategories[p.doc.Category]; ok && !created {
+		if err := imp.createCategory(ctx, p.doc.Category); err != nil {
+			return err
+		}
+	}
+
+	switch p.action {
+	case actionCreate:
+		return imp.create(ctx, p, summary)
+	case actionUpdate:
+		return imp.update(ctx, p, summary)
+	}
+	return nil
+}
+
+func (imp *importer) createCategory(ctx context.Context, name string) error {
+	slug, err := articles.GenerateSlug(name, 50)
+	if err != nil {
+		return err
+	}
+	if _, err := imp.deps.categories.Create(ctx, categories.Category{Name: name, Slug: slug}); err != nil {
+		return fmt.Errorf("failed to create category %q: %w", name, err)
+	}
+	imp.newCategories[name] = true
+	return nil
+}
+
+func (imp *importer) create(ctx context.Context, p *planned, summary string) error {
+	doc := p.doc
+	article := articles.Article{
+		Title:     doc.Title,
+		Content:   doc.Content,
+		Slug:      doc.Slug,
+		Category:  doc.Category,
+		Tags:      doc.Tags,
+		Thumbnail: doc.Thumbnail,
+		Featured:  doc.Featured,
+		AuthorID:  p.authorID,
+		Status:    articles.Status(doc.Status),
+	}
+	if article.Status == articles.StatusPublished {
+		now := time.Now()
+		article.PublishedAt = &now
+	}
+
+	created, err := imp.deps.articles.Create(ctx, article, summary)
+	if err != nil {
+		return err
+	}
+	if created.IsPublished() {
+		if _, err := imp.deps.backlinks.Enqueue(ctx, created); err != nil {
+			log.Printf("Failed to enqueue backlink job for %s: %v", created.Slug, err)
+		}
+		imp.pushEvent(ctx, rag.EventTypeCreate, created)
+	}
+	return nil
+}
+
+func (imp *importer) update(ctx context.Context, p *planned, summary string) error {
+	meta := articles.RevisionMeta{AuthorID: imp.importer, Summary: summary}
+
+	// Files without a version (imported with -force) are still checked
+	// against the version seen when planning, so edits made since are kept.
+	version := p.doc.Version
+	if version == 0 {
+		version = p.existing.Version
+	}
+	updated, err := imp.deps.articles.UpdateVersion(ctx, p.existing.ID, version, p.updates, meta)
+	if err != nil {
+		return err
+	}
+	if updated.IsPublished() {
+		_, retitled := p.updates["title"]
+		_, rewritten := p.updates["content"]
+		if retitled || rewritten {
+			if _, err := imp.deps.backlinks.Enqueue(ctx, updated); err != nil {
+				log.Printf("Failed to enqueue backlink job for %s: %v", updated.Slug, err)
+			}
+		}
+		imp.pushEvent(ctx, rag.EventTypeUpdate, updated)
+	}
+	return nil
+}
+
+func (imp *importer) pushEvent(ctx context.Context, eventType rag.EventType, a *articles.Article) {
+	if imp.deps.rag == nil {
+		return
+	}
+	if err := imp.deps.rag.PushEvent(ctx, rag.ArticleToEvent(eventType, a)); err != nil {
+		log.Printf("Failed to push RAG %s event for %s: %v", eventType, a.Slug, err)
+	}
+}
@@ -0,0 +1,106 @@
+// Command wikiport exports wiki articles to a directory of Markdown files
+// with YAML front matter, and imports such a directory back. It also
+// imports MediaWiki XML exports, converting wikitext to Markdown, and
+// bundles articles into an EPUB or a single printable HTML file, and seeds
+// placeholder articles for local development.
+//
+//	wikiport export -dir ./wiki
+//	wikiport import -dir ./wiki -as admin -dry-run
+//	wikiport import -dir ./handwritten -as admin -force
+//	wikiport mediawiki -file dump.xml -as admin -category-map 'Hostels=Campus Life'
+//	wikiport book -category hostels -format epub -out hostels.epub
+//	wikiport seed -as admin -n 50
+//
+// Imports go through articles.Repository, so revisions, links and search
+// indexing happen as they do for edits made in the app. Published articles
+// are also pushed to the RAG queue and queued for backlinking.
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/joho/godotenv"
+	"github.com/pranava-mohan/wikinitt/gravy/internal/articles"
+	"github.com/pranava-mohan/wikinitt/gravy/internal/backlinks"
+	"github.com/pranava-mohan/wikinitt/gravy/internal/categories"
+	"github.com/pranava-mohan/wikinitt/gravy/internal/db"
+	"github.com/pranava-mohan/wikinitt/gravy/internal/rag"
+	"github.com/pranava-mohan/wikinitt/gravy/internal/search"
+	"github.com/pranava-mohan/wikinitt/gravy/internal/users"
+)
+
+type deps struct {
+	articles   articles.Repository
+	categories categories.Repository
+	users      users.Repository
+	backlinks  *backlinks.Runner
+	rag        rag.Client
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: wikiport <export|import|mediawiki|book|seed> [flags]")
+	fmt.Fprintln(os.Stderr, "run 'wikiport <command> -h' for the flags of a command")
+	os.Exit(2)
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found")
+	}
+
+	mongoURI := os.Getenv("MONGODB_URI")
+	if mongoURI == "" {
+		log.Fatal("MONGODB_URI environment variable is required")
+	}
+	client, err := db.Connect(mongoURI)
+	if err != nil {
+		log.Fatalf("Failed to connect to MongoDB: %v", err)
+	}
+	defer client.Disconnect(context.Background())
+	database := client.Database("wikinitt")
+
+	meiliHost := os.Getenv("MEILI_HOST")
+	if meiliHost == "" {
+		meiliHost = "http://localhost:7700"
+	}
+	searchClient := search.NewClient(meiliHost, os.Getenv("MEILI_MASTER_KEY"))
+
+	articleRepo := articles.NewRepository(database, searchClient)
+	d := &deps{
+		articles:   articleRepo,
+		categories: categories.NewRepository(database),
+		users:      users.NewRepository(database),
+		backlinks:  backlinks.NewRunner(backlinks.NewRepository(database), articleRepo, 0),
+	}
+	if redisHost, redisPort := os.Getenv("REDIS_HOST"), os.Getenv("REDIS_PORT"); redisHost != "" && redisPort != "" {
+		d.rag = rag.NewRedisClient(fmt.Sprintf("%s:%s", redisHost, redisPort), "")
+	} else {
+		log.Println("REDIS_HOST or REDIS_PORT not set, RAG sync disabled")
+	}
+
+	ctx := context.Background()
+	switch os.Args[1] {
+	case "export":
+		err = runExport(ctx, d, os.Args[2:])
+	case "import":
+		err = runImport(ctx, d, os.Args[2:])
+	case "mediawiki":
+		err = runMediaWiki(ctx, d, os.Args[2:])
+	case "book":
+		err = runBook(ctx, d, os.Args[2:])
+	case "seed":
+		err = runSeed(ctx, d, os.Args[2:])
+	default:
+		usage()
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,344 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pranava-mohan/wikinitt/gravy/internal/articles"
+	"github.com/pranava-mohan/wikinitt/gravy/internal/mediawiki"
+	"github.com/pranava-mohan/wikinitt/gravy/internal/rag"
+	"github.com/pranava-mohan/wikinitt/gravy/internal/sanitization"
+)
+
+const actionSkip action = "skip"
+
+// maxRedirectHops bounds how far links are followed through redirect pages,
+// so redirect loops in a dump cannot hang the import.
+const maxRedirectHops = 5
+
+type mediaWikiImport struct {
+	*importer
+	dryRun          bool
+	history         bool
+	namespace       int
+	summary         string
+	defaultCategory string
+	categoryMap     map[string]string
+
+	// Slugs by normalized title, for articles already on the wiki and for
+	// pages in the dump, which get their slugs before any is imported so
+	// links between them resolve whatever order they appear in.
+	titleSlugs map[string]string
+	existing   map[string]bool
+	// Redirect pages in the dump, by normalized title, to their targets.
+	redirects map[string]string
+}
+
+func runMediaWiki(ctx context.Context, d *deps, args []string) error {
+	fs := flag.NewFlagSet("mediawiki", flag.ExitOnError)
+	file := fs.String("file", "", "MediaWiki XML export to import (required)")
+	as := fs.String("as", "", "Username the import is recorded as, and the author of pages whose editors have no account here (required unless -dry-run)")
+	dryRun := fs.Bool("dry-run", false, "Report what would be imported and what cannot be converted without writing anything")
+	namespace := fs.Int("namespace", 0, "MediaWiki namespace to import; 0 is the main namespace")
+	defaultCategory := fs.String("default-category", "General", "Category for pages without MediaWiki categories")
+	categoryMap := fs.String("category-map", "", "Comma-separated MediaWiki=Wiki category renames, e.g. 'Hostels=Campus Life'")
+	history := fs.Bool("history", true, "Import each page's MediaWiki history as revisions")
+	summary := fs.String("summary", "Imported from MediaWiki", "Revision summary for imported articles")
+	fs.Parse(args)
+
+	if *file == "" {
+		fs.Usage()
+		return fmt.Errorf("-file is required")
+	}
+
+	imp := &mediaWikiImport{
+		importer: &importer{
+			deps:          d,
+			authors:       make(map[string]string),
+			newCategories: make(map[string]bool),
+		},
+		dryRun:          *dryRun,
+		history:         *history,
+		namespace:       *namespace,
+		summary:         *summary,
+		defaultCategory: *defaultCategory,
+		categoryMap:     make(map[string]string),
+		titleSlugs:      make(map[string]string),
+		existing:        make(map[string]bool),
+		redirects:       make(map[string]string),
+	}
+	if *as != "" {
+		u, err := d.users.GetByUsername(ctx, *as)
+		if err != nil {
+			return fmt.Errorf("unknown user %q: %w", *as, err)
+		}
+		imp.importer.importer = u.ID
+	} else if !*dryRun {
+		fs.Usage()
+		return fmt.Errorf("-as is required unless -dry-run is set")
+	}
+	if *categoryMap != "" {
+		for _, pair := range strings.Split(*categoryMap, ",") {
+			from, to, ok := strings.Cut(pair, "=")
+			if !ok || strings.TrimSpace(from) == "" || strings.TrimSpace(to) == "" {
+				return fmt.Errorf("invalid -category-map entry %q", pair)
+			}
+			imp.categoryMap[strings.TrimSpace(from)] = strings.TrimSpace(to)
+		}
+	}
+
+	targets, err := d.articles.ListLinkTargets(ctx)
+	if err != nil {
+		return err
+	}
+	for _, t := range targets {
+		key := articles.NormalizeTitle(t.Title)
+		imp.titleSlugs[key] = t.Slug
+		imp.existing[key] = true
+	}
+	if err := imp.scanTitles(*file); err != nil {
+		return err
+	}
+
+	f, err := os.Open(*file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	counts := make(map[action]int)
+	otherNamespaces := 0
+	reader := mediawiki.NewReader(f)
+	for {
+		page, err := reader.Next()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return fmt.Errorf("failed to read dump: %w", err)
+		}
+		if page.Namespace != imp.namespace {
+			otherNamespaces++
+			continue
+		}
+
+		act, reason, issues := imp.importPage(ctx, page)
+		counts[act]++
+		line := fmt.Sprintf("%-9s %s", act, page.Title)
+		if reason != "" {
+			line += ": " + reason
+		}
+		fmt.Println(line)
+		for _, issue := range issues {
+			fmt.Printf("%-9s   - %s\n", "", issue)
+		}
+	}
+
+	names := make([]string, 0, len(imp.newCategories))
+	for name := range imp.newCategories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Printf("%-9s category %q\n", actionCreate, name)
+	}
+	prefix := ""
+	if imp.dryRun {
+		prefix = "dry run: "
+	}
+	fmt.Printf("%s%d created, %d skipped, %d invalid; %d pages in other namespaces ignored\n", prefix,
+		counts[actionCreate], counts[actionSkip], counts[actionInvalid], otherNamespaces)
+	return nil
+}
+
+// scanTitles reads the dump once to assign every page a slug and record
+// redirects, without keeping page text.
+func (imp *mediaWikiImport) scanTitles(file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	reader := mediawiki.NewReader(f)
+	for {
+		page, err := reader.Next()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("failed to read dump: %w", err)
+		}
+		if page.Namespace != imp.namespace {
+			continue
+		}
+		key := pageKey(page.Title)
+		if page.Redirect != nil {
+			imp.redirects[key] = pageKey(page.Redirect.Title)
+			continue
+		}
+		if _, ok := imp.titleSlugs[key]; ok {
+			continue
+		}
+		slug, err := articles.GenerateSlug(page.Title, 50)
+		if err != nil {
+			return err
+		}
+		imp.titleSlugs[key] = slug
+	}
+}
+
+// pageKey normalizes a MediaWiki title, where underscores and spaces are
+// the same.
+func pageKey(title string) string {
+	return articles.NormalizeTitle(strings.ReplaceAll(title, "_", " "))
+}
+
+// linkTarget resolves an internal link, following redirect pages.
+func (imp *mediaWikiImport) linkTarget(title string) string {
+	key := pageKey(title)
+	for i := 0; i < maxRedirectHops; i++ {
+		target, ok := imp.redirects[key]
+		if !ok {
+			break
+		}
+		key = target
+	}
+	if slug, ok := imp.titleSlugs[key]; ok {
+		return "/articles/" + slug
+	}
+	return ""
+}
+
+// importPage imports one page, returning what was done and the constructs
+// in its current text that could not be converted.
+func (imp *mediaWikiImport) importPage(ctx context.Context, page *mediawiki.Page) (action, string, []string) {
+	key := pageKey(page.Title)
+	switch {
+	case page.Redirect != nil:
+		return actionSkip, fmt.Sprintf("redirect to %q; links to it point at the target", page.Redirect.Title), nil
+	case imp.existing[key]:
+		return actionSkip, "an article with this title already exists", nil
+	case page.Latest() == nil:
+		return actionInvalid, "page has no revisions", nil
+	}
+	imp.existing[key] = true
+
+	converter := &mediawiki.Converter{LinkTarget: imp.linkTarget}
+	latest := converter.Convert(page.Latest().Text)
+	category, tags, tagIssues := imp.mapCategories(latest.Categories)
+	issues := append(latest.Issues, tagIssues...)
+
+	if err := imp.planCategory(ctx, category); err != nil {
+		return actionInvalid, err.Error(), issues
+	}
+	if imp.dryRun {
+		return actionCreate, fmt.Sprintf("category %q, %d revisions", category, len(page.Revisions)), issues
+	}
+	if created, ok := imp.newCategories[category]; ok && !created {
+		if err := imp.createCategory(ctx, category); err != nil {
+			return actionInvalid, err.Error(), issues
+		}
+	}
+
+	now := time.Now()
+	article := articles.Article{
+		Title:       strings.TrimSpace(page.Title),
+		Content:     sanitization.SanitizeContent(latest.Markdown),
+		Slug:        imp.titleSlugs[key],
+		Category:    category,
+		Tags:        tags,
+		AuthorID:    imp.contributorID(ctx, page.Revisions[0].Contributor),
+		Status:      articles.StatusPublished,
+		PublishedAt: &now,
+	}
+	created, err := imp.deps.articles.Create(ctx, article, imp.summary)
+	if err != nil {
+		return actionInvalid, err.Error(), issues
+	}
+
+	if imp.history {
+		if err := imp.deps.articles.ImportRevisions(ctx, created.ID, imp.revisions(ctx, page, converter, category)); err != nil {
+			log.Printf("Failed to import history of %s: %v", created.Slug, err)
+		}
+	}
+	if _, err := imp.deps.backlinks.Enqueue(ctx, created); err != nil {
+		log.Printf("Failed to enqueue backlink job for %s: %v", created.Slug, err)
+	}
+	imp.pushEvent(ctx, rag.EventTypeCreate, created)
+	return actionCreate, created.Slug, issues
+}
+
+// mapCategories picks the article's category from the page's MediaWiki
+// categories, after renames, and keeps the rest as tags.
+func (imp *mediaWikiImport) mapCategories(names []string) (string, []string, []string) {
+	category := ""
+	tags := []string{}
+	var issues []string
+	seen := make(map[string]bool)
+	for _, name := range names {
+		if mapped, ok := imp.categoryMap[name]; ok {
+			name = mapped
+		}
+		if category == "" {
+			category = name
+			continue
+		}
+		tag := articles.NormalizeTag(name)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if len(tags) == articles.MaxTags {
+			issues = append(issues, fmt.Sprintf("category %q dropped: an article can have at most %d tags", name, articles.MaxTags))
+			continue
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+	if category == "" {
+		category = imp.defaultCategory
+	}
+	return category, tags, issues
+}
+
+// revisions converts a page's history. Editors without an account here are
+// attributed to the importer, with their MediaWiki name in the summary.
+func (imp *mediaWikiImport) revisions(ctx context.Context, page *mediawiki.Page, converter *mediawiki.Converter, category string) []articles.Revision {
+	revisions := make([]articles.Revision, 0, len(page.Revisions))
+	for _, rev := range page.Revisions {
+		summary := rev.Comment
+		authorID := imp.authorID(ctx, rev.Contributor.Username)
+		if authorID == "" {
+			authorID = imp.importer.importer
+			name := rev.Contributor.Username
+			if name == "" {
+				name = rev.Contributor.IP
+			}
+			summary = strings.TrimSpace(fmt.Sprintf("[MediaWiki: %s] %s", name, summary))
+		}
+		revisions = append(revisions, articles.Revision{
+			Title:     strings.TrimSpace(page.Title),
+			Content:   sanitization.SanitizeContent(converter.Convert(rev.Text).Markdown),
+			Category:  category,
+			AuthorID:  authorID,
+			Summary:   summary,
+			CreatedAt: rev.Timestamp,
+		})
+	}
+	return revisions
+}
+
+func (imp *mediaWikiImport) contributorID(ctx context.Context, c mediawiki.Contributor) string {
+	if id := imp.authorID(ctx, c.Username); id != "" {
+		return id
+	}
+	return imp.importer.importer
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"math/rand"
+
+	"github.com/pranava-mohan/wikinitt/gravy/internal/articles"
+)
+
+var (
+	seedCategories = []string{"Campus Life", "Academics", "Hostels", "Events", "Clubs", "Departments"}
+	seedTitles     = []string{
+		"History of Festember", "Guide to Garnet Hostel", "Life at Octagon",
+		"Department of CSE", "Nittfest Highlights", "Pragyan Events",
+		"Sportsfete 2024", "First Year Guide", "Mess Food Reviews",
+		"Library Rules", "Hospital Timings", "Bus Schedule",
+	}
+	seedThumbnails = []string{
+		"https://images.unsplash.com/photo-1541339907198-e08756dedf3f",
+		"https://images.unsplash.com/photo-1523050854058-8df90110c9f1",
+		"https://images.unsplash.com/photo-1562774053-701939374585",
+		"https://images.unsplash.com/photo-1592280771800-bcf291d02e0c",
+	}
+)
+
+// runSeed creates published placeholder articles for local development.
+// They are created the way imports are, so they get revisions, links and
+// search entries like real articles.
+func runSeed(ctx context.Context, d *deps, args []string) error {
+	fs := flag.NewFlagSet("seed", flag.ExitOnError)
+	as := fs.String("as", "", "Username of the author of the seeded articles (required)")
+	n := fs.Int("n", 50, "Number of articles to create")
+	fs.Parse(args)
+
+	if *as == "" {
+		fs.Usage()
+		return fmt.Errorf("-as is required")
+	}
+	u, err := d.users.GetByUsername(ctx, *as)
+	if err != nil {
+		return fmt.Errorf("unknown user %q: %w", *as, err)
+	}
+
+	imp := &importer{
+		deps:          d,
+		importer:      u.ID,
+		authors:       make(map[string]string),
+		newCategories: make(map[string]bool),
+		slugs:         make(map[string]string),
+	}
+	for i := 0; i < *n; i++ {
+		title := fmt.Sprintf("%s %d", seedTitles[rand.Intn(len(seedTitles))], i)
+		slug, err := articles.GenerateSlug(title, 50)
+		if err != nil {
+			return err
+		}
+		category := seedCategories[rand.Intn(len(seedCategories))]
+		if err := imp.planCategory(ctx, category); err != nil {
+			return err
+		}
+
+		p := &planned{
+			action:   actionCreate,
+			authorID: u.ID,
+			doc: &Document{
+				FrontMatter: FrontMatter{
+					Title:     title,
+					Slug:      slug,
+					Category:  category,
+					Thumbnail: seedThumbnails[rand.Intn(len(seedThumbnails))],
+					Featured:  rand.Intn(2) == 1,
+					Status:    string(articles.StatusPublished),
+				},
+				Content: fmt.Sprintf("# %s\n\nLorem ipsum dolor sit amet, consectetur adipiscing elit.\n\n## History\n\nThis is a dummy article about %s. It contains **markdown** content.\n", title, title),
+			},
+		}
+		if err := imp.apply(ctx, p, "Seeded article"); err != nil {
+			return fmt.Errorf("failed to seed %q: %w", title, err)
+		}
+	}
+
+	fmt.Printf("Seeded %d articles as %s\n", *n, *as)
+	return nil
+}
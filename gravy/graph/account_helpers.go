@@ -0,0 +1,119 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/mail"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pranava-mohan/wikinitt/gravy/internal/mailer"
+	"github.com/pranava-mohan/wikinitt/gravy/internal/users"
+)
+
+const (
+	passwordResetTTL = time.Hour
+	verifyEmailTTL   = 48 * time.Hour
+)
+
+// normalizeEmail lowercases and trims an address and checks that it is a
+// bare address, not "Name <address>".
+func normalizeEmail(email string) (string, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email {
+		return "", fmt.Errorf("invalid email address")
+	}
+	return email, nil
+}
+
+// checkSignUpDomain applies OIDC_ALLOWED_DOMAINS to the address of a new
+// account.
+func (r *Resolver) checkSignUpDomain(email string) error {
+	if !r.SignUps.DomainAllowed(email) {
+		return fmt.Errorf("sign-ups are limited to @%s addresses", strings.Join(r.SignUps.AllowedDomains, ", @"))
+	}
+	return nil
+}
+
+// sendLinkEmail issues a token for the user and emails them a link to the
+// frontend page that uses it. The email is sent in the background so the
+// request does not wait on the mail server; failures are only logged.
+func (r *Resolver) sendLinkEmail(ctx context.Context, user *users.User, purpose users.TokenPurpose) error {
+	var template, path string
+	var ttl time.Duration
+	switch purpose {
+	case users.TokenPasswordReset:
+		template, path, ttl = mailer.TemplatePasswordReset, "/reset-password", passwordResetTTL
+	case users.TokenVerifyEmail:
+		template, path, ttl = mailer.TemplateVerifyEmail, "/verify-email", verifyEmailTTL
+	default:
+		return fmt.Errorf("unknown token purpose %q", purpose)
+	}
+
+	token, err := r.UserTokenRepo.Issue(ctx, user.ID, user.Email, purpose, ttl)
+	if err != nil {
+		return err
+	}
+
+	msg, err := mailer.Render(template, user.Email, mailer.LinkEmail{
+		Name:     user.DisplayName,
+		Link:     frontendURL() + path + "?token=" + url.QueryEscape(token),
+		ValidFor: formatValidity(ttl),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to render email: %w", err)
+	}
+
+	r.sendInBackground(user, template, msg)
+	return nil
+}
+
+// sendAccountExistsEmail tells the owner of an address that someone tried
+// to sign up with it, so register does not have to reveal that the
+// address has an account.
+func (r *Resolver) sendAccountExistsEmail(user *users.User) error {
+	msg, err := mailer.Render(mailer.TemplateAccountExists, user.Email, mailer.LinkEmail{
+		Name: user.DisplayName,
+		Link: frontendURL() + "/login",
+	})
+	if err != nil {
+		return fmt.Errorf("failed to render email: %w", err)
+	}
+	r.sendInBackground(user, mailer.TemplateAccountExists, msg)
+	return nil
+}
+
+// sendInBackground sends msg without making the request wait on the mail
+// server; failures are only logged.
+func (r *Resolver) sendInBackground(user *users.User, template string, msg mailer.Message) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		if err := r.Mailer.Send(ctx, msg); err != nil {
+			log.Printf("Failed to send %s email to user %s: %v", template, user.ID, err)
+		}
+	}()
+}
+
+func frontendURL() string {
+	u := os.Getenv("FRONTEND_URL")
+	if u == "" {
+		u = "https://wikinitt.netlify.app"
+	}
+	return strings.TrimRight(u, "/")
+}
+
+func formatValidity(d time.Duration) string {
+	switch hours := int(d / time.Hour); {
+	case hours == 1:
+		return "1 hour"
+	case hours%24 == 0 && hours >= 48:
+		return fmt.Sprintf("%d days", hours/24)
+	default:
+		return fmt.Sprintf("%d hours", hours)
+	}
+}
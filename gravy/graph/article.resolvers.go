@@ -7,40 +7,150 @@ package graph
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"strings"
+	"time"
 
 	"github.com/99designs/gqlgen/graphql"
 	"github.com/pranava-mohan/wikinitt/gravy/graph/model"
 	"github.com/pranava-mohan/wikinitt/gravy/internal/articles"
 	"github.com/pranava-mohan/wikinitt/gravy/internal/auth"
+	"github.com/pranava-mohan/wikinitt/gravy/internal/backlinks"
 	"github.com/pranava-mohan/wikinitt/gravy/internal/rag"
 	"github.com/pranava-mohan/wikinitt/gravy/internal/sanitization"
 	"github.com/pranava-mohan/wikinitt/gravy/internal/users"
+	"github.com/pranava-mohan/wikinitt/gravy/internal/versioning"
+	"github.com/pranava-mohan/wikinitt/gravy/internal/views"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
 )
 
-// CreateArticle is the resolver for the createArticle field.
-func (r *mutationResolver) CreateArticle(ctx context.Context, input model.NewArticle) (*model.Article, error) {
-	slug, err := articles.GenerateSlug(input.Title, 50)
+// HTML is the resolver for the html field.
+func (r *articleResolver) HTML(ctx context.Context, obj *model.Article) (string, error) {
+	rendered, err := r.renderArticle(ctx, obj)
+	if err != nil {
+		return "", err
+	}
+	return rendered.HTML, nil
+}
+
+// Toc is the resolver for the toc field.
+func (r *articleResolver) Toc(ctx context.Context, obj *model.Article) ([]*model.TocEntry, error) {
+	rendered, err := r.renderArticle(ctx, obj)
+	if err != nil {
+		return nil, err
+	}
+	return mapTocToModel(rendered.TOC), nil
+}
+
+// ReadingTimeMinutes is the resolver for the readingTimeMinutes field.
+func (r *articleResolver) ReadingTimeMinutes(ctx context.Context, obj *model.Article) (int32, error) {
+	rendered, err := r.renderArticle(ctx, obj)
+	if err != nil {
+		return 0, err
+	}
+	return int32(rendered.ReadingTimeMinutes), nil
+}
+
+// Revisions is the resolver for the revisions field.
+func (r *articleResolver) Revisions(ctx context.Context, obj *model.Article, limit *int32, offset *int32) ([]*model.ArticleRevision, error) {
+	l, o := pageArgs(limit, offset)
+
+	revisions, err := r.ArticleRepo.ListRevisions(ctx, obj.ID, l, o)
+	if err != nil {
+		return nil, err
+	}
+
+	var modelRevisions []*model.ArticleRevision
+	for _, rev := range revisions {
+		author, _ := r.UserRepo.GetByID(ctx, rev.AuthorID)
+		modelRevisions = append(modelRevisions, mapRevisionToModel(rev, mapUserToPublic(author)))
+	}
+	return modelRevisions, nil
+}
+
+// LinkedContent is the resolver for the linkedContent field.
+func (r *articleResolver) LinkedContent(ctx context.Context, obj *model.Article) (string, error) {
+	return r.linkedContent(ctx, obj)
+}
+
+// Backlinks is the resolver for the backlinks field.
+func (r *articleResolver) Backlinks(ctx context.Context, obj *model.Article) ([]*model.ArticleLink, error) {
+	links, err := r.ArticleRepo.ListBacklinks(ctx, obj.ID)
+	if err != nil {
+		return nil, err
+	}
+	return r.linkedArticles(ctx, links, func(l *articles.Link) string { return l.SourceID })
+}
+
+// OutgoingLinks is the resolver for the outgoingLinks field.
+func (r *articleResolver) OutgoingLinks(ctx context.Context, obj *model.Article) ([]*model.ArticleLink, error) {
+	links, err := r.ArticleRepo.ListOutgoingLinks(ctx, obj.ID)
 	if err != nil {
 		return nil, err
 	}
+	return r.linkedArticles(ctx, links, func(l *articles.Link) string { return l.TargetID })
+}
+
+// Related is the resolver for the related field.
+func (r *articleResolver) Related(ctx context.Context, obj *model.Article, limit *int32) ([]*model.Article, error) {
+	l := 5
+	if limit != nil && *limit > 0 && *limit <= 20 {
+		l = int(*limit)
+	}
+
+	related, err := r.ArticleRepo.Related(ctx, obj.ID, l)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*model.Article, 0, len(related))
+	for _, a := range related {
+		r.loadArticleAuthor(ctx, a)
+		result = append(result, mapArticleToModel(a))
+	}
+	return result, nil
+}
+
+// Article is the resolver for the article field.
+func (r *editSuggestionResolver) Article(ctx context.Context, obj *model.EditSuggestion) (*model.Article, error) {
+	article, err := r.ArticleRepo.GetByID(ctx, obj.ArticleID)
+	if err != nil || !r.canViewArticle(ctx, article) {
+		return nil, nil
+	}
+	r.loadArticleAuthor(ctx, article)
+	return mapArticleToModel(article), nil
+}
 
-	sanitizedContent := sanitization.SanitizeContent(input.Content)
+// Diff is the resolver for the diff field.
+func (r *editSuggestionResolver) Diff(ctx context.Context, obj *model.EditSuggestion, mode *model.DiffMode) (*model.ContentDiff, error) {
+	current := ""
+	if article, err := r.ArticleRepo.GetByID(ctx, obj.ArticleID); err == nil {
+		current = article.Content
+	}
+	diff, diffMode := diffContent(current, obj.Content, mode)
+	return mapContentDiffToModel(diff, diffMode), nil
+}
 
-	autoLinkedContent, err := articles.AutoLinkContent(ctx, sanitizedContent, r.ArticleRepo, "")
+// CreateArticle is the resolver for the createArticle field.
+func (r *mutationResolver) CreateArticle(ctx context.Context, input model.NewArticle) (*model.Article, error) {
+	slug, err := articles.GenerateSlug(input.Title, 50)
 	if err != nil {
 		return nil, err
 	}
 
 	article := articles.Article{
 		Title:     input.Title,
-		Content:   autoLinkedContent,
+		Content:   sanitization.SanitizeContent(input.Content),
 		Slug:      slug,
 		Category:  input.Category,
 		Thumbnail: sanitization.SanitizeString(input.Thumbnail),
 		Featured:  input.Featured,
 	}
+	if article.Tags, err = articles.NormalizeTags(input.Tags); err != nil {
+		return nil, err
+	}
 
 	user := auth.ForContext(ctx)
 	if user == nil {
@@ -48,26 +158,33 @@ func (r *mutationResolver) CreateArticle(ctx context.Context, input model.NewArt
 	}
 	article.AuthorID = user.ID
 
-	created, err := r.ArticleRepo.Create(ctx, article)
-	if err != nil {
+	summary := ""
+	if input.Summary != nil {
+		summary = sanitization.SanitizeString(*input.Summary)
+	}
+
+	if input.PublishAt != nil {
+		if article.PublishAt, err = parseScheduleTime("publishAt", *input.PublishAt); err != nil {
+			return nil, err
+		}
+	}
+	if input.ExpireAt != nil {
+		if article.ExpireAt, err = parseScheduleTime("expireAt", *input.ExpireAt); err != nil {
+			return nil, err
+		}
+		if err := validateExpiry(article.ExpireAt, time.Now()); err != nil {
+			return nil, err
+		}
+	}
+	if err := validateSchedule(article.PublishAt, article.ExpireAt); err != nil {
 		return nil, err
 	}
 
-	articles.StartBacklinkWorkers(
-		context.Background(),
-		r.ArticleRepo,
-		created.Title,
-		created.Slug,
-		created.ID,
-	)
+	article.Status = articles.StatusDraft
 
-	if r.RagClient != nil {
-		go func(a *articles.Article) {
-			event := rag.ArticleToEvent(rag.EventTypeCreate, a)
-			if err := r.RagClient.PushEvent(context.Background(), event); err != nil {
-				log.Printf("Failed to push RAG create event for article %s: %v", a.ID, err)
-			}
-		}(created)
+	created, err := r.ArticleRepo.Create(ctx, article, summary)
+	if err != nil {
+		return nil, err
 	}
 
 	created.Author = &users.PublicUser{
@@ -92,18 +209,18 @@ func (r *mutationResolver) UpdateArticle(ctx context.Context, input model.Update
 		updates["title"] = *input.Title
 	}
 	if input.Content != nil {
-		sanitizedContent := sanitization.SanitizeContent(*input.Content)
-
-		autoLinkedContent, err := articles.AutoLinkContent(ctx, sanitizedContent, r.ArticleRepo, existing.Slug)
-		if err != nil {
-			return nil, err
-		}
-
-		updates["content"] = autoLinkedContent
+		updates["content"] = sanitization.SanitizeContent(*input.Content)
 	}
 	if input.Category != nil {
 		updates["category"] = *input.Category
 	}
+	if input.Tags != nil {
+		tags, err := articles.NormalizeTags(input.Tags)
+		if err != nil {
+			return nil, err
+		}
+		updates["tags"] = tags
+	}
 	if input.Thumbnail != nil {
 		updates["thumbnail"] = sanitization.SanitizeString(*input.Thumbnail)
 	}
@@ -111,18 +228,42 @@ func (r *mutationResolver) UpdateArticle(ctx context.Context, input model.Update
 		updates["featured"] = *input.Featured
 	}
 
-	updated, err := r.ArticleRepo.Update(ctx, input.ID, updates)
+	publishAt, expireAt := existing.PublishAt, existing.ExpireAt
+	if input.PublishAt != nil {
+		if publishAt, err = parseScheduleTime("publishAt", *input.PublishAt); err != nil {
+			return nil, err
+		}
+		updates["publishAt"] = publishAt
+	}
+	if input.ExpireAt != nil {
+		if expireAt, err = parseScheduleTime("expireAt", *input.ExpireAt); err != nil {
+			return nil, err
+		}
+		if err := validateExpiry(expireAt, time.Now()); err != nil {
+			return nil, err
+		}
+		updates["expireAt"] = expireAt
+	}
+	if err := validateSchedule(publishAt, expireAt); err != nil {
+		return nil, err
+	}
+
+	meta := articles.RevisionMeta{}
+	if user := auth.ForContext(ctx); user != nil {
+		meta.AuthorID = user.ID
+	}
+	if input.Summary != nil {
+		meta.Summary = sanitization.SanitizeString(*input.Summary)
+	}
+	requireReview(existing, updates, meta.AuthorID)
+
+	updated, err := r.ArticleRepo.UpdateVersion(ctx, input.ID, int(input.Version), updates, meta)
 	if err != nil {
 		return nil, err
 	}
 
-	if r.RagClient != nil {
-		go func(a *articles.Article) {
-			event := rag.ArticleToEvent(rag.EventTypeUpdate, a)
-			if err := r.RagClient.PushEvent(context.Background(), event); err != nil {
-				log.Printf("Failed to push RAG update event for article %s: %v", a.ID, err)
-			}
-		}(updated)
+	if updated.IsPublished() {
+		r.pushArticleEvent(rag.EventTypeUpdate, updated)
 	}
 
 	author, err := r.UserRepo.GetByID(ctx, updated.AuthorID)
@@ -145,18 +286,323 @@ func (r *mutationResolver) DeleteArticle(ctx context.Context, id string) (bool,
 		return false, err
 	}
 
-	if r.RagClient != nil {
-		go func(articleID string) {
-			event := rag.RagEvent{
-				Type:      rag.EventTypeDelete,
-				ArticleID: articleID,
-			}
-			if err := r.RagClient.PushEvent(context.Background(), event); err != nil {
-				log.Printf("Failed to push RAG delete event for article %s: %v", articleID, err)
+	r.pushArticleEvent(rag.EventTypeDelete, &articles.Article{ID: id})
+	return true, nil
+}
+
+// RevertArticle is the resolver for the revertArticle field.
+func (r *mutationResolver) RevertArticle(ctx context.Context, id string, version int32, revisionID string, summary *string) (*model.Article, error) {
+	user := auth.ForContext(ctx)
+	if user == nil {
+		return nil, fmt.Errorf("unauthorized")
+	}
+
+	revision, err := r.ArticleRepo.GetRevision(ctx, revisionID)
+	if err != nil {
+		return nil, err
+	}
+	if revision.ArticleID != id {
+		return nil, fmt.Errorf("revision does not belong to this article")
+	}
+
+	meta := articles.RevisionMeta{
+		AuthorID: user.ID,
+		Summary:  fmt.Sprintf("Reverted to revision %s", revision.ID),
+	}
+	if summary != nil && *summary != "" {
+		meta.Summary = sanitization.SanitizeString(*summary)
+	}
+
+	updated, err := r.ArticleRepo.UpdateVersion(ctx, id, int(version), revision.RevertUpdates(), meta)
+	if err != nil {
+		return nil, err
+	}
+
+	if updated.IsPublished() {
+		r.pushArticleEvent(rag.EventTypeUpdate, updated)
+	}
+
+	author, err := r.UserRepo.GetByID(ctx, updated.AuthorID)
+	if err == nil {
+		updated.Author = &users.PublicUser{
+			ID:     author.ID,
+			Name:   author.Name,
+			Gender: author.Gender,
+			Avatar: author.Avatar,
+		}
+	}
+
+	return mapArticleToModel(updated), nil
+}
+
+// RenameArticle is the resolver for the renameArticle field.
+func (r *mutationResolver) RenameArticle(ctx context.Context, id string, newTitle string, regenerateSlug *bool) (*model.Article, error) {
+	user := auth.ForContext(ctx)
+	if user == nil {
+		return nil, fmt.Errorf("unauthorized")
+	}
+
+	newTitle = strings.TrimSpace(newTitle)
+	if newTitle == "" {
+		return nil, fmt.Errorf("title cannot be empty")
+	}
+
+	newSlug := ""
+	if regenerateSlug == nil || *regenerateSlug {
+		slug, err := articles.GenerateSlug(newTitle, 50)
+		if err != nil {
+			return nil, err
+		}
+		newSlug = slug
+	}
+
+	meta := articles.RevisionMeta{AuthorID: user.ID, Summary: "Renamed to " + newTitle}
+	renamed, err := r.ArticleRepo.Rename(ctx, id, newTitle, newSlug, meta)
+	if err != nil {
+		return nil, err
+	}
+
+	if renamed.IsPublished() {
+		// Pages mentioning the new title should now link here too.
+		if _, err := r.BacklinkRunner.Enqueue(ctx, renamed); err != nil {
+			log.Printf("Failed to enqueue backlink job for article %s: %v", renamed.ID, err)
+		}
+		r.pushArticleEvent(rag.EventTypeUpdate, renamed)
+	}
+
+	r.loadArticleAuthor(ctx, renamed)
+	return mapArticleToModel(renamed), nil
+}
+
+// MergeArticles is the resolver for the mergeArticles field.
+func (r *mutationResolver) MergeArticles(ctx context.Context, sourceID string, targetID string) (*model.Article, error) {
+	user := auth.ForContext(ctx)
+	if user == nil {
+		return nil, fmt.Errorf("unauthorized")
+	}
+
+	source, err := r.ArticleRepo.GetByID(ctx, sourceID)
+	if err != nil {
+		return nil, err
+	}
+
+	meta := articles.RevisionMeta{AuthorID: user.ID, Summary: "Merged " + source.Title}
+	merged, err := r.ArticleRepo.Merge(ctx, sourceID, targetID, meta)
+	if err != nil {
+		return nil, err
+	}
+
+	r.pushArticleEvent(rag.EventTypeDelete, source)
+	if merged.IsPublished() {
+		r.pushArticleEvent(rag.EventTypeUpdate, merged)
+	}
+
+	r.loadArticleAuthor(ctx, merged)
+	return mapArticleToModel(merged), nil
+}
+
+// SubmitArticleForReview is the resolver for the submitArticleForReview field.
+func (r *mutationResolver) SubmitArticleForReview(ctx context.Context, id string) (*model.Article, error) {
+	user := auth.ForContext(ctx)
+	if user == nil {
+		return nil, fmt.Errorf("unauthorized")
+	}
+
+	article, err := r.ArticleRepo.Transition(ctx, id,
+		[]articles.Status{articles.StatusDraft, articles.StatusArchived},
+		articles.StatusInReview,
+		bson.M{"submittedBy": user.ID, "reviewedBy": "", "reviewNote": ""},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	r.loadArticleAuthor(ctx, article)
+	return mapArticleToModel(article), nil
+}
+
+// ApproveArticle is the resolver for the approveArticle field.
+func (r *mutationResolver) ApproveArticle(ctx context.Context, id string) (*model.Article, error) {
+	user := auth.ForContext(ctx)
+	if user == nil {
+		return nil, fmt.Errorf("unauthorized")
+	}
+
+	existing, err := r.ArticleRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if existing.CurrentStatus() != articles.StatusInReview {
+		return nil, fmt.Errorf("only articles in review can be approved")
+	}
+	if existing.SubmittedBy == user.ID || existing.AuthorID == user.ID {
+		return nil, fmt.Errorf("an article must be approved by an admin other than its author or submitter")
+	}
+
+	now := time.Now()
+	if err := validateExpiry(existing.ExpireAt, now); err != nil {
+		return nil, fmt.Errorf("%w, change it before approving", err)
+	}
+
+	fields := bson.M{"reviewedBy": user.ID, "reviewNote": ""}
+
+	// Articles with a future publishAt wait for the scheduler to publish them.
+	if existing.PublishAt != nil && existing.PublishAt.After(now) {
+		article, err := r.ArticleRepo.Transition(ctx, id, []articles.Status{articles.StatusInReview}, articles.StatusScheduled, fields)
+		if err != nil {
+			return nil, err
+		}
+		r.loadArticleAuthor(ctx, article)
+		return mapArticleToModel(article), nil
+	}
+
+	if existing.PublishedAt == nil {
+		fields["publishedAt"] = now
+	}
+
+	article, err := r.ArticleRepo.Transition(ctx, id, []articles.Status{articles.StatusInReview}, articles.StatusPublished, fields)
+	if err != nil {
+		return nil, err
+	}
+
+	r.onArticlePublished(ctx, article)
+
+	r.loadArticleAuthor(ctx, article)
+	return mapArticleToModel(article), nil
+}
+
+// RejectArticle is the resolver for the rejectArticle field.
+func (r *mutationResolver) RejectArticle(ctx context.Context, id string, reason string) (*model.Article, error) {
+	user := auth.ForContext(ctx)
+	if user == nil {
+		return nil, fmt.Errorf("unauthorized")
+	}
+
+	article, err := r.ArticleRepo.Transition(ctx, id,
+		[]articles.Status{articles.StatusInReview},
+		articles.StatusDraft,
+		bson.M{"reviewedBy": user.ID, "reviewNote": sanitization.SanitizeString(reason)},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	r.loadArticleAuthor(ctx, article)
+	return mapArticleToModel(article), nil
+}
+
+// ArchiveArticle is the resolver for the archiveArticle field.
+func (r *mutationResolver) ArchiveArticle(ctx context.Context, id string) (*model.Article, error) {
+	// An expiry belongs to the publication that is ending; clearing it
+	// keeps it from archiving the article again once it is restored.
+	article, err := r.ArticleRepo.Transition(ctx, id, []articles.Status{articles.StatusPublished, articles.StatusScheduled}, articles.StatusArchived, bson.M{"expireAt": nil})
+	if err != nil {
+		return nil, err
+	}
+
+	r.pushArticleEvent(rag.EventTypeDelete, article)
+
+	r.loadArticleAuthor(ctx, article)
+	return mapArticleToModel(article), nil
+}
+
+// SuggestArticleEdit is the resolver for the suggestArticleEdit field.
+func (r *mutationResolver) SuggestArticleEdit(ctx context.Context, articleID string, content string, summary *string) (*model.EditSuggestion, error) {
+	user := auth.ForContext(ctx)
+	if user == nil {
+		return nil, fmt.Errorf("unauthorized")
+	}
+
+	article, err := r.ArticleRepo.GetByID(ctx, articleID)
+	if err != nil || !article.IsPublished() {
+		return nil, fmt.Errorf("article not found")
+	}
+
+	sanitizedContent := sanitization.SanitizeContent(content)
+	if sanitizedContent == article.Content {
+		return nil, fmt.Errorf("suggestion does not change the article")
+	}
+
+	suggestion := articles.Suggestion{
+		ArticleID:   article.ID,
+		AuthorID:    user.ID,
+		Content:     sanitizedContent,
+		BaseVersion: article.Version,
+	}
+	if summary != nil {
+		suggestion.Summary = sanitization.SanitizeString(*summary)
+	}
+
+	created, err := r.ArticleRepo.CreateSuggestion(ctx, suggestion)
+	if err != nil {
+		return nil, err
+	}
+	return mapSuggestionToModel(created, mapUserToPublic(user)), nil
+}
+
+// ApproveEditSuggestion is the resolver for the approveEditSuggestion field.
+func (r *mutationResolver) ApproveEditSuggestion(ctx context.Context, id string) (*model.Article, error) {
+	user := auth.ForContext(ctx)
+	if user == nil {
+		return nil, fmt.Errorf("unauthorized")
+	}
+
+	suggestion, err := r.ArticleRepo.ResolveSuggestion(ctx, id, articles.SuggestionApproved, user.ID, "")
+	if err != nil {
+		return nil, err
+	}
+
+	summary := suggestion.Summary
+	if summary == "" {
+		summary = "Suggested edit"
+	}
+	meta := articles.RevisionMeta{
+		AuthorID:   suggestion.AuthorID,
+		Summary:    summary,
+		ApprovedBy: user.ID,
+	}
+	updated, err := r.ArticleRepo.UpdateVersion(ctx, suggestion.ArticleID, suggestion.BaseVersion, bson.M{"content": suggestion.Content}, meta)
+	if err != nil {
+		var conflict *versioning.ConflictError
+		if errors.As(err, &conflict) {
+			if staleErr := r.ArticleRepo.MarkSuggestionStale(ctx, id); staleErr != nil {
+				log.Printf("Failed to mark suggestion %s stale: %v", id, staleErr)
 			}
-		}(id)
+			return nil, fmt.Errorf("article changed since this suggestion was made, so it was marked stale: %w", err)
+		}
+		if reopenErr := r.ArticleRepo.ReopenSuggestion(ctx, id); reopenErr != nil {
+			log.Printf("Failed to reopen suggestion %s: %v", id, reopenErr)
+		}
+		return nil, err
 	}
-	return true, nil
+
+	if updated.IsPublished() {
+		r.pushArticleEvent(rag.EventTypeUpdate, updated)
+	}
+
+	r.loadArticleAuthor(ctx, updated)
+	return mapArticleToModel(updated), nil
+}
+
+// RejectEditSuggestion is the resolver for the rejectEditSuggestion field.
+func (r *mutationResolver) RejectEditSuggestion(ctx context.Context, id string, reason string) (*model.EditSuggestion, error) {
+	user := auth.ForContext(ctx)
+	if user == nil {
+		return nil, fmt.Errorf("unauthorized")
+	}
+
+	reason = sanitization.SanitizeString(strings.TrimSpace(reason))
+	if reason == "" {
+		return nil, fmt.Errorf("a reason is required to reject a suggestion")
+	}
+
+	suggestion, err := r.ArticleRepo.ResolveSuggestion(ctx, id, articles.SuggestionRejected, user.ID, reason)
+	if err != nil {
+		return nil, err
+	}
+
+	author, _ := r.UserRepo.GetByID(ctx, suggestion.AuthorID)
+	return mapSuggestionToModel(suggestion, mapUserToPublic(author)), nil
 }
 
 // UploadImage is the resolver for the uploadImage field.
@@ -169,7 +615,7 @@ func (r *mutationResolver) UploadImage(ctx context.Context, file graphql.Upload)
 }
 
 // Articles is the resolver for the articles field.
-func (r *queryResolver) Articles(ctx context.Context, category *string, limit *int32, offset *int32, featured *bool) ([]*model.Article, error) {
+func (r *queryResolver) Articles(ctx context.Context, category *string, limit *int32, offset *int32, featured *bool, status *model.ArticleStatus) ([]*model.Article, error) {
 	var l, o *int
 	if limit != nil {
 		val := int(*limit)
@@ -184,7 +630,22 @@ func (r *queryResolver) Articles(ctx context.Context, category *string, limit *i
 		o = &val
 	}
 
-	articles, err := r.ArticleRepo.List(ctx, category, l, o, featured)
+	filter := articles.ListFilter{
+		Category: category,
+		Featured: featured,
+	}
+	published := articles.StatusPublished
+	filter.Status = &published
+	if status != nil && *status != model.ArticleStatusPublished {
+		user := auth.ForContext(ctx)
+		if user == nil || !r.actsAsAdmin(user) {
+			return nil, fmt.Errorf("access denied: admins only")
+		}
+		requested := articles.Status(*status)
+		filter.Status = &requested
+	}
+
+	articles, err := r.ArticleRepo.List(ctx, filter, l, o)
 	if err != nil {
 		return nil, err
 	}
@@ -210,6 +671,9 @@ func (r *queryResolver) Article(ctx context.Context, id string) (*model.Article,
 	if err != nil {
 		return nil, err
 	}
+	if !r.canViewArticle(ctx, article) {
+		return nil, fmt.Errorf("article not found")
+	}
 
 	author, err := r.UserRepo.GetByID(ctx, article.AuthorID)
 	if err == nil {
@@ -226,9 +690,17 @@ func (r *queryResolver) Article(ctx context.Context, id string) (*model.Article,
 // ArticleBySlug is the resolver for the articleBySlug field.
 func (r *queryResolver) ArticleBySlug(ctx context.Context, slug string) (*model.Article, error) {
 	article, err := r.ArticleRepo.GetBySlug(ctx, slug)
+	var redirectedFrom *string
+	if err == mongo.ErrNoDocuments {
+		article, err = r.ArticleRepo.ResolveRedirect(ctx, slug)
+		redirectedFrom = &slug
+	}
 	if err != nil {
 		return nil, err
 	}
+	if !r.canViewArticle(ctx, article) {
+		return nil, fmt.Errorf("article not found")
+	}
 
 	author, err := r.UserRepo.GetByID(ctx, article.AuthorID)
 	if err == nil {
@@ -239,5 +711,191 @@ func (r *queryResolver) ArticleBySlug(ctx context.Context, slug string) (*model.
 			Avatar: author.Avatar,
 		}
 	}
-	return mapArticleToModel(article), nil
+
+	if article.IsPublished() {
+		viewerID := ""
+		if user := auth.ForContext(ctx); user != nil {
+			viewerID = user.ID
+		}
+		r.ViewRecorder.Record(article.ID, views.ViewerKey(viewerID, views.IPFromContext(ctx)))
+	}
+
+	result := mapArticleToModel(article)
+	result.RedirectedFrom = redirectedFrom
+	return result, nil
+}
+
+// Tags is the resolver for the tags field.
+func (r *queryResolver) Tags(ctx context.Context, prefix *string, limit *int32) ([]*model.Tag, error) {
+	l := 10
+	if limit != nil && *limit > 0 && *limit <= 50 {
+		l = int(*limit)
+	}
+	p := ""
+	if prefix != nil {
+		p = *prefix
+	}
+
+	tags, err := r.ArticleRepo.ListTags(ctx, p, l)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*model.Tag, 0, len(tags))
+	for _, t := range tags {
+		result = append(result, mapTagToModel(t.Name, t.Count))
+	}
+	return result, nil
+}
+
+// ArticlesByTag is the resolver for the articlesByTag field.
+func (r *queryResolver) ArticlesByTag(ctx context.Context, tag string, limit *int32, offset *int32) ([]*model.Article, error) {
+	normalized := articles.NormalizeTag(tag)
+	if normalized == "" {
+		return []*model.Article{}, nil
+	}
+	l, o := pageArgs(limit, offset)
+	published := articles.StatusPublished
+	filter := articles.ListFilter{Tag: &normalized, Status: &published}
+
+	list, err := r.ArticleRepo.List(ctx, filter, &l, &o)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*model.Article, 0, len(list))
+	for _, a := range list {
+		r.loadArticleAuthor(ctx, a)
+		result = append(result, mapArticleToModel(a))
+	}
+	return result, nil
+}
+
+// TrendingArticles is the resolver for the trendingArticles field.
+func (r *queryResolver) TrendingArticles(ctx context.Context, window *model.TrendWindow, limit *int32) ([]*model.Article, error) {
+	l := 10
+	if limit != nil && *limit > 0 && *limit <= 50 {
+		l = int(*limit)
+	}
+	w := model.TrendWindowWeek
+	if window != nil {
+		w = *window
+	}
+
+	// Over-fetch since some of the most viewed articles may no longer be
+	// published.
+	trends, err := r.ViewRepo.Trending(ctx, trendWindowStart(w, time.Now()), l*2)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(trends))
+	for _, t := range trends {
+		ids = append(ids, t.ArticleID)
+	}
+	list, err := r.ArticleRepo.GetByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*model.Article, 0, l)
+	for _, a := range list {
+		if !a.IsPublished() {
+			continue
+		}
+		r.loadArticleAuthor(ctx, a)
+		result = append(result, mapArticleToModel(a))
+		if len(result) == l {
+			break
+		}
+	}
+	return result, nil
+}
+
+// ArticleRevisionDiff is the resolver for the articleRevisionDiff field.
+func (r *queryResolver) ArticleRevisionDiff(ctx context.Context, from string, to string, mode *model.DiffMode) (*model.ArticleRevisionDiff, error) {
+	fromRev, err := r.ArticleRepo.GetRevision(ctx, from)
+	if err != nil {
+		return nil, err
+	}
+	toRev, err := r.ArticleRepo.GetRevision(ctx, to)
+	if err != nil {
+		return nil, err
+	}
+	if fromRev.ArticleID != toRev.ArticleID {
+		return nil, fmt.Errorf("revisions belong to different articles")
+	}
+
+	article, err := r.ArticleRepo.GetByID(ctx, fromRev.ArticleID)
+	if err != nil || !r.canViewArticle(ctx, article) {
+		return nil, fmt.Errorf("article not found")
+	}
+
+	diff, diffMode := diffContent(fromRev.Content, toRev.Content, mode)
+
+	fromAuthor, _ := r.UserRepo.GetByID(ctx, fromRev.AuthorID)
+	toAuthor, _ := r.UserRepo.GetByID(ctx, toRev.AuthorID)
+
+	return mapDiffToModel(diff, diffMode, mapRevisionToModel(fromRev, mapUserToPublic(fromAuthor)), mapRevisionToModel(toRev, mapUserToPublic(toAuthor))), nil
+}
+
+// PendingEditSuggestions is the resolver for the pendingEditSuggestions field.
+func (r *queryResolver) PendingEditSuggestions(ctx context.Context, articleID *string, limit *int32, offset *int32) ([]*model.EditSuggestion, error) {
+	l, o := pageArgs(limit, offset)
+
+	status := articles.SuggestionPending
+	filter := articles.SuggestionFilter{ArticleID: articleID, Status: &status}
+	suggestions, err := r.ArticleRepo.ListSuggestions(ctx, filter, l, o)
+	if err != nil {
+		return nil, err
+	}
+	return r.mapSuggestions(ctx, suggestions), nil
+}
+
+// MyEditSuggestions is the resolver for the myEditSuggestions field.
+func (r *queryResolver) MyEditSuggestions(ctx context.Context, status *model.EditSuggestionStatus, limit *int32, offset *int32) ([]*model.EditSuggestion, error) {
+	user := auth.ForContext(ctx)
+	if user == nil {
+		return nil, fmt.Errorf("unauthorized")
+	}
+	l, o := pageArgs(limit, offset)
+
+	filter := articles.SuggestionFilter{AuthorID: &user.ID}
+	if status != nil {
+		s := articles.SuggestionStatus(*status)
+		filter.Status = &s
+	}
+	suggestions, err := r.ArticleRepo.ListSuggestions(ctx, filter, l, o)
+	if err != nil {
+		return nil, err
+	}
+	return r.mapSuggestions(ctx, suggestions), nil
 }
+
+// BacklinkJobs is the resolver for the backlinkJobs field.
+func (r *queryResolver) BacklinkJobs(ctx context.Context, state *model.BacklinkJobState, limit *int32, offset *int32) ([]*model.BacklinkJob, error) {
+	l, o := pageArgs(limit, offset)
+
+	var filterState *backlinks.State
+	if state != nil {
+		s := backlinks.State(*state)
+		filterState = &s
+	}
+
+	jobs, err := r.BacklinkJobRepo.List(ctx, filterState, l, o)
+	if err != nil {
+		return nil, err
+	}
+
+	modelJobs := []*model.BacklinkJob{}
+	for _, j := range jobs {
+		modelJobs = append(modelJobs, mapBacklinkJobToModel(j))
+	}
+	return modelJobs, nil
+}
+
+// Article returns ArticleResolver implementation.
+func (r *Resolver) Article() ArticleResolver { return &articleResolver{r} }
+
+// EditSuggestion returns EditSuggestionResolver implementation.
+func (r *Resolver) EditSuggestion() EditSuggestionResolver { return &editSuggestionResolver{r} }
+
+type articleResolver struct{ *Resolver }
+type editSuggestionResolver struct{ *Resolver }
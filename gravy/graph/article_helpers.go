@@ -0,0 +1,297 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/pranava-mohan/wikinitt/gravy/graph/model"
+	"github.com/pranava-mohan/wikinitt/gravy/internal/articles"
+	"github.com/pranava-mohan/wikinitt/gravy/internal/auth"
+	"github.com/pranava-mohan/wikinitt/gravy/internal/rag"
+	"github.com/pranava-mohan/wikinitt/gravy/internal/users"
+)
+
+// canViewArticle hides drafts, articles in review and archived articles from
+// everyone but admins.
+func (r *Resolver) canViewArticle(ctx context.Context, a *articles.Article) bool {
+	if a.IsPublished() {
+		return true
+	}
+	user := auth.ForContext(ctx)
+	return user != nil && r.actsAsAdmin(user)
+}
+
+func (r *Resolver) loadArticleAuthor(ctx context.Context, a *articles.Article) {
+	author, err := r.UserRepo.GetByID(ctx, a.AuthorID)
+	if err == nil {
+		a.Author = &users.PublicUser{
+			ID:     author.ID,
+			Name:   author.Name,
+			Gender: author.Gender,
+			Avatar: author.Avatar,
+		}
+	}
+}
+
+func (r *Resolver) pushArticleEvent(eventType rag.EventType, a *articles.Article) {
+	if r.RagClient == nil {
+		return
+	}
+	go func() {
+		event := rag.RagEvent{Type: rag.EventTypeDelete, ArticleID: a.ID}
+		if eventType != rag.EventTypeDelete {
+			event = rag.ArticleToEvent(eventType, a)
+		}
+		if err := r.RagClient.PushEvent(context.Background(), event); err != nil {
+			log.Printf("Failed to push RAG %s event for article %s: %v", eventType, a.ID, err)
+		}
+	}()
+}
+
+// onArticlePublished runs the side effects that only apply to public
+// articles: backlinking other pages to it and syncing it to the RAG store.
+func (r *Resolver) onArticlePublished(ctx context.Context, a *articles.Article) {
+	if _, err := r.BacklinkRunner.Enqueue(ctx, a); err != nil {
+		log.Printf("Failed to enqueue backlink job for article %s: %v", a.ID, err)
+	}
+	r.pushArticleEvent(rag.EventTypeCreate, a)
+}
+
+// parseScheduleTime reads an RFC3339 publishAt/expireAt input. An empty
+// string clears the value, which is reported as a nil time.
+func parseScheduleTime(field string, value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be an RFC3339 timestamp", field)
+	}
+	return &t, nil
+}
+
+// validateSchedule rejects windows that would expire before they publish.
+func validateSchedule(publishAt, expireAt *time.Time) error {
+	if publishAt != nil && expireAt != nil && !expireAt.After(*publishAt) {
+		return fmt.Errorf("expireAt must be after publishAt")
+	}
+	return nil
+}
+
+// validateExpiry rejects an expireAt that has already passed, which would
+// archive the article as soon as it is published.
+func validateExpiry(expireAt *time.Time, now time.Time) error {
+	if expireAt != nil && !expireAt.After(now) {
+		return fmt.Errorf("expireAt must be in the future")
+	}
+	return nil
+}
+
+// reviewedFields are the fields a reviewer approves. Changing one of them
+// after submission invalidates the review.
+var reviewedFields = []string{"title", "content", "category", "tags", "thumbnail"}
+
+// requireReview sends an article that is in review or approved and waiting
+// to publish back to review when updates change what was reviewed. The
+// editor becomes the submitter, so they cannot approve their own change.
+func requireReview(existing *articles.Article, updates map[string]interface{}, editorID string) {
+	status := existing.CurrentStatus()
+	if status != articles.StatusInReview && status != articles.StatusScheduled {
+		return
+	}
+	for _, field := range reviewedFields {
+		if _, ok := updates[field]; ok {
+			updates["status"] = articles.StatusInReview
+			updates["submittedBy"] = editorID
+			updates["reviewedBy"] = ""
+			return
+		}
+	}
+}
+
+// linkedArticles loads the article on the far side of each link (chosen by
+// other) and drops the ones the caller is not allowed to see.
+func (r *Resolver) linkedArticles(ctx context.Context, links []*articles.Link, other func(*articles.Link) string) ([]*model.ArticleLink, error) {
+	ids := make([]string, 0, len(links))
+	for _, l := range links {
+		ids = append(ids, other(l))
+	}
+	found, err := r.ArticleRepo.GetByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]*articles.Article, len(found))
+	for _, a := range found {
+		byID[a.ID] = a
+	}
+
+	result := []*model.ArticleLink{}
+	for _, l := range links {
+		a, ok := byID[other(l)]
+		if !ok || !r.canViewArticle(ctx, a) {
+			continue
+		}
+		r.loadArticleAuthor(ctx, a)
+		result = append(result, &model.ArticleLink{
+			Article: mapArticleToModel(a),
+			Anchor:  l.Anchor,
+		})
+	}
+	return result, nil
+}
+
+// diffContent compares two versions of article content, by line unless mode
+// asks for words.
+func diffContent(from, to string, mode *model.DiffMode) (articles.Diff, model.DiffMode) {
+	diffMode := model.DiffModeLine
+	if mode != nil {
+		diffMode = *mode
+	}
+
+	switch diffMode {
+	case model.DiffModeWord:
+		return articles.DiffWords(from, to), diffMode
+	default:
+		return articles.DiffLines(from, to), diffMode
+	}
+}
+
+func (r *Resolver) mapSuggestions(ctx context.Context, suggestions []*articles.Suggestion) []*model.EditSuggestion {
+	result := []*model.EditSuggestion{}
+	for _, s := range suggestions {
+		author, _ := r.UserRepo.GetByID(ctx, s.AuthorID)
+		result = append(result, mapSuggestionToModel(s, mapUserToPublic(author)))
+	}
+	return result
+}
+
+func pageArgs(limit *int32, offset *int32) (int, int) {
+	l := 20
+	o := 0
+	if limit != nil {
+		l = int(*limit)
+		if l > 100 || l < 1 {
+			l = 20
+		}
+	}
+	if offset != nil {
+		o = int(*offset)
+	}
+	return l, o
+}
+
+// normalizeTagFilter normalizes tags given as search filters, dropping any
+// that normalize to nothing.
+func normalizeTagFilter(tags []string) []string {
+	var result []string
+	for _, t := range tags {
+		if n := articles.NormalizeTag(t); n != "" {
+			result = append(result, n)
+		}
+	}
+	return result
+}
+
+type renderCacheCtxKey struct{}
+
+// renderCache holds each article's linked content and rendering for the
+// length of one operation, so html, toc, readingTimeMinutes and
+// linkedContent load the links and render the article once between them.
+type renderCache struct {
+	mu      sync.Mutex
+	entries map[string]*renderEntry
+}
+
+type renderEntry struct {
+	linkOnce   sync.Once
+	content    string
+	err        error
+	renderOnce sync.Once
+	rendered   *articles.Rendered
+}
+
+// WithRenderCache gives the operation run with ctx its own render cache.
+func WithRenderCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, renderCacheCtxKey{}, &renderCache{entries: make(map[string]*renderEntry)})
+}
+
+// renderEntryFor returns the cache entry for obj, or a fresh one when ctx
+// carries no cache. Entries are keyed by version as well as ID, so a
+// mutation returning the article it just changed does not see the old body.
+func renderEntryFor(ctx context.Context, obj *model.Article) *renderEntry {
+	cache, ok := ctx.Value(renderCacheCtxKey{}).(*renderCache)
+	if !ok {
+		return &renderEntry{}
+	}
+	key := fmt.Sprintf("%s@%d", obj.ID, obj.Version)
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	e, ok := cache.entries[key]
+	if !ok {
+		e = &renderEntry{}
+		cache.entries[key] = e
+	}
+	return e
+}
+
+// renderArticle renders obj with its auto-links, so the HTML, table of
+// contents and reading time all come from the same content.
+func (r *Resolver) renderArticle(ctx context.Context, obj *model.Article) (*articles.Rendered, error) {
+	e := renderEntryFor(ctx, obj)
+	content, err := r.linkedContentOnce(ctx, e, obj)
+	if err != nil {
+		return nil, err
+	}
+	e.renderOnce.Do(func() {
+		e.rendered = articles.Render(content)
+	})
+	return e.rendered, nil
+}
+
+// linkedContent renders mentions of other articles in obj's content as
+// Markdown links, skipping targets that are no longer published.
+func (r *Resolver) linkedContent(ctx context.Context, obj *model.Article) (string, error) {
+	return r.linkedContentOnce(ctx, renderEntryFor(ctx, obj), obj)
+}
+
+func (r *Resolver) linkedContentOnce(ctx context.Context, e *renderEntry, obj *model.Article) (string, error) {
+	e.linkOnce.Do(func() {
+		e.content, e.err = r.loadLinkedContent(ctx, obj)
+	})
+	return e.content, e.err
+}
+
+func (r *Resolver) loadLinkedContent(ctx context.Context, obj *model.Article) (string, error) {
+	links, err := r.ArticleRepo.ListOutgoingLinks(ctx, obj.ID)
+	if err != nil {
+		return "", err
+	}
+	if len(links) == 0 {
+		return obj.Content, nil
+	}
+
+	ids := make([]string, 0, len(links))
+	for _, l := range links {
+		ids = append(ids, l.TargetID)
+	}
+	targets, err := r.ArticleRepo.GetByIDs(ctx, ids)
+	if err != nil {
+		return "", err
+	}
+	return articles.RenderLinks(obj.Content, articles.LiveLinks(links, targets)), nil
+}
+
+// trendWindowStart returns the first day counted towards window.
+func trendWindowStart(window model.TrendWindow, now time.Time) time.Time {
+	days := 7
+	switch window {
+	case model.TrendWindowDay:
+		days = 1
+	case model.TrendWindowMonth:
+		days = 30
+	}
+	return now.AddDate(0, 0, -days)
+}
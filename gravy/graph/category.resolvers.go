@@ -7,56 +7,215 @@ package graph
 
 import (
 	"context"
-	"time"
+	"fmt"
+	"log"
+	"strings"
 
 	"github.com/pranava-mohan/wikinitt/gravy/graph/model"
 	"github.com/pranava-mohan/wikinitt/gravy/internal/articles"
+	"github.com/pranava-mohan/wikinitt/gravy/internal/categories"
+	"go.mongodb.org/mongo-driver/v2/bson"
 )
 
+// Parent is the resolver for the parent field.
+func (r *categoryResolver) Parent(ctx context.Context, obj *model.Category) (*model.Category, error) {
+	if obj.ParentID == nil {
+		return nil, nil
+	}
+	parent, err := r.CategoryRepo.GetByID(ctx, *obj.ParentID)
+	if err != nil {
+		if err == categories.ErrNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return mapCategoryToModel(parent), nil
+}
+
+// Children is the resolver for the children field.
+func (r *categoryResolver) Children(ctx context.Context, obj *model.Category) ([]*model.Category, error) {
+	children, err := r.CategoryRepo.ListChildren(ctx, obj.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*model.Category, 0, len(children))
+	for _, c := range children {
+		result = append(result, mapCategoryToModel(c))
+	}
+	return result, nil
+}
+
+// Articles is the resolver for the articles field.
+func (r *categoryResolver) Articles(ctx context.Context, obj *model.Category, limit *int32, offset *int32) ([]*model.Article, error) {
+	l, o := pageArgs(limit, offset)
+	published := articles.StatusPublished
+	filter := articles.ListFilter{Category: &obj.Name, Status: &published}
+
+	list, err := r.ArticleRepo.List(ctx, filter, &l, &o)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*model.Article, 0, len(list))
+	for _, a := range list {
+		r.loadArticleAuthor(ctx, a)
+		result = append(result, mapArticleToModel(a))
+	}
+	return result, nil
+}
+
+// ArticleCount is the resolver for the articleCount field.
+func (r *categoryResolver) ArticleCount(ctx context.Context, obj *model.Category) (int32, error) {
+	published := articles.StatusPublished
+	count, err := r.ArticleRepo.Count(ctx, articles.ListFilter{Category: &obj.Name, Status: &published})
+	if err != nil {
+		return 0, err
+	}
+	return int32(count), nil
+}
+
 // CreateCategory is the resolver for the createCategory field.
-func (r *mutationResolver) CreateCategory(ctx context.Context, name string) (*model.Category, error) {
-	slug, err := articles.GenerateSlug(name, 50)
+func (r *mutationResolver) CreateCategory(ctx context.Context, name string, parentID *string, description *string, icon *string) (*model.Category, error) {
+	name, slug, err := r.categoryNameAndSlug(ctx, "", name)
+	if err != nil {
+		return nil, err
+	}
+
+	category := categories.Category{Name: name, Slug: slug}
+	if parentID != nil && *parentID != "" {
+		if _, err := r.CategoryRepo.GetByID(ctx, *parentID); err != nil {
+			return nil, fmt.Errorf("parent category: %w", err)
+		}
+		category.ParentID = parentID
+	}
+	if description != nil {
+		category.Description = strings.TrimSpace(*description)
+	}
+	if icon != nil {
+		category.Icon = strings.TrimSpace(*icon)
+	}
+
+	created, err := r.CategoryRepo.Create(ctx, category)
 	if err != nil {
 		return nil, err
 	}
+	return mapCategoryToModel(created), nil
+}
 
-	category, err := r.CategoryRepo.Create(ctx, name, slug)
+// UpdateCategory is the resolver for the updateCategory field.
+func (r *mutationResolver) UpdateCategory(ctx context.Context, id string, input model.UpdateCategoryInput) (*model.Category, error) {
+	existing, err := r.CategoryRepo.GetByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
 
-	return &model.Category{
-		ID:        category.ID,
-		Name:      category.Name,
-		Slug:      category.Slug,
-		CreatedAt: category.CreatedAt.Format(time.RFC3339),
-	}, nil
+	updates := bson.M{}
+	renamed := false
+	if input.Name != nil && strings.TrimSpace(*input.Name) != existing.Name {
+		name, slug, err := r.categoryNameAndSlug(ctx, id, *input.Name)
+		if err != nil {
+			return nil, err
+		}
+		updates["name"] = name
+		updates["slug"] = slug
+		renamed = true
+	}
+	if input.ParentID != nil {
+		if *input.ParentID == "" {
+			updates["parentId"] = nil
+		} else {
+			if err := r.CategoryRepo.CheckParent(ctx, id, *input.ParentID); err != nil {
+				return nil, fmt.Errorf("parent category: %w", err)
+			}
+			updates["parentId"] = *input.ParentID
+		}
+	}
+	if input.Description != nil {
+		updates["description"] = strings.TrimSpace(*input.Description)
+	}
+	if input.Icon != nil {
+		updates["icon"] = strings.TrimSpace(*input.Icon)
+	}
+	if len(updates) == 0 {
+		return mapCategoryToModel(existing), nil
+	}
+
+	updated, err := r.CategoryRepo.Update(ctx, id, updates)
+	if err != nil {
+		return nil, err
+	}
+
+	if renamed {
+		moved, err := r.ArticleRepo.MoveCategory(ctx, existing.Name, updated.Name)
+		if err != nil {
+			return nil, fmt.Errorf("category renamed but failed to update its articles: %w", err)
+		}
+		log.Printf("Renamed category %q to %q on %d articles", existing.Name, updated.Name, moved)
+	}
+	return mapCategoryToModel(updated), nil
 }
 
 // DeleteCategory is the resolver for the deleteCategory field.
-func (r *mutationResolver) DeleteCategory(ctx context.Context, id string) (bool, error) {
-	err := r.CategoryRepo.Delete(ctx, id)
+func (r *mutationResolver) DeleteCategory(ctx context.Context, id string, reassignTo *string) (bool, error) {
+	category, err := r.CategoryRepo.GetByID(ctx, id)
 	if err != nil {
 		return false, err
 	}
+
+	count, err := r.ArticleRepo.Count(ctx, articles.ListFilter{Category: &category.Name})
+	if err != nil {
+		return false, err
+	}
+	if count > 0 {
+		if reassignTo == nil || *reassignTo == "" {
+			return false, fmt.Errorf("category %q still has %d articles; choose a category to reassign them to", category.Name, count)
+		}
+		if *reassignTo == id {
+			return false, fmt.Errorf("cannot reassign articles to the category being deleted")
+		}
+		target, err := r.CategoryRepo.GetByID(ctx, *reassignTo)
+		if err != nil {
+			return false, fmt.Errorf("reassign category: %w", err)
+		}
+		if _, err := r.ArticleRepo.MoveCategory(ctx, category.Name, target.Name); err != nil {
+			return false, err
+		}
+	}
+
+	if err := r.CategoryRepo.Delete(ctx, id); err != nil {
+		return false, err
+	}
 	return true, nil
 }
 
 // Categories is the resolver for the categories field.
 func (r *queryResolver) Categories(ctx context.Context) ([]*model.Category, error) {
-	categories, err := r.CategoryRepo.List(ctx)
+	list, err := r.CategoryRepo.List(ctx)
 	if err != nil {
 		return nil, err
 	}
 
 	var modelCategories []*model.Category
-	for _, c := range categories {
-		modelCategories = append(modelCategories, &model.Category{
-			ID:        c.ID,
-			Name:      c.Name,
-			Slug:      c.Slug,
-			CreatedAt: c.CreatedAt.Format(time.RFC3339),
-		})
+	for _, c := range list {
+		modelCategories = append(modelCategories, mapCategoryToModel(c))
 	}
 	return modelCategories, nil
 }
+
+// Category is the resolver for the category field.
+func (r *queryResolver) Category(ctx context.Context, slug string) (*model.Category, error) {
+	category, err := r.CategoryRepo.GetBySlug(ctx, slug)
+	if err != nil {
+		if err == categories.ErrNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return mapCategoryToModel(category), nil
+}
+
+// Category returns CategoryResolver implementation.
+func (r *Resolver) Category() CategoryResolver { return &categoryResolver{r} }
+
+type categoryResolver struct{ *Resolver }
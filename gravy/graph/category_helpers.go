@@ -0,0 +1,35 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/pranava-mohan/wikinitt/gravy/internal/articles"
+)
+
+// categoryNameAndSlug validates a category name and derives its slug,
+// rejecting names and slugs already used by a category other than id.
+func (r *Resolver) categoryNameAndSlug(ctx context.Context, id string, name string) (string, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", "", fmt.Errorf("category name cannot be empty")
+	}
+
+	existing, err := r.CategoryRepo.GetByName(ctx, name)
+	if err != nil {
+		return "", "", err
+	}
+	if existing != nil && existing.ID != id {
+		return "", "", fmt.Errorf("a category named %q already exists", name)
+	}
+
+	slug, err := articles.GenerateSlug(name, 50)
+	if err != nil {
+		return "", "", err
+	}
+	if existing, err := r.CategoryRepo.GetBySlug(ctx, slug); err == nil && existing.ID != id {
+		return "", "", fmt.Errorf("category %q already uses the slug %q", existing.Name, slug)
+	}
+	return name, slug, nil
+}
@@ -693,7 +693,7 @@ func (r *mutationResolver) RemoveMember(ctx context.Context, groupID string, use
 }
 
 // UpdatePost is the resolver for the updatePost field.
-func (r *mutationResolver) UpdatePost(ctx context.Context, postID string, title *string, content *string) (*model.Post, error) {
+func (r *mutationResolver) UpdatePost(ctx context.Context, postID string, version int32, title *string, content *string) (*model.Post, error) {
 	user := auth.ForContext(ctx)
 	if user == nil {
 		return nil, fmt.Errorf("not authenticated")
@@ -705,7 +705,7 @@ func (r *mutationResolver) UpdatePost(ctx context.Context, postID string, title
 	}
 
 	// Check if user is author or admin
-	if post.AuthorID != user.ID && !user.IsAdmin {
+	if post.AuthorID != user.ID && !r.actsAsAdmin(user) {
 		return nil, fmt.Errorf("access denied: only author or admin can edit")
 	}
 
@@ -715,7 +715,7 @@ func (r *mutationResolver) UpdatePost(ctx context.Context, postID string, title
 		sanitizedContent = &c
 	}
 
-	updatedPost, err := r.CommunityRepo.UpdatePost(ctx, postID, title, sanitizedContent)
+	updatedPost, err := r.CommunityRepo.UpdatePost(ctx, postID, int(version), title, sanitizedContent)
 	if err != nil {
 		return nil, err
 	}
@@ -757,7 +757,7 @@ func (r *mutationResolver) DeletePost(ctx context.Context, postID string) (bool,
 	}
 
 	// Check if user is author or admin
-	if post.AuthorID != user.ID && !user.IsAdmin {
+	if post.AuthorID != user.ID && !r.actsAsAdmin(user) {
 		return false, fmt.Errorf("access denied: only author or admin can delete")
 	}
 
@@ -782,7 +782,7 @@ func (r *mutationResolver) UpdateComment(ctx context.Context, commentID string,
 	}
 
 	// Check if user is author or admin
-	if comment.AuthorID != user.ID && !user.IsAdmin {
+	if comment.AuthorID != user.ID && !r.actsAsAdmin(user) {
 		return nil, fmt.Errorf("access denied: only author or admin can edit")
 	}
 
@@ -840,7 +840,7 @@ func (r *mutationResolver) DeleteComment(ctx context.Context, commentID string)
 	}
 
 	// Check if user is author or admin
-	if comment.AuthorID != user.ID && !user.IsAdmin {
+	if comment.AuthorID != user.ID && !r.actsAsAdmin(user) {
 		return false, fmt.Errorf("access denied: only author or admin can delete")
 	}
 
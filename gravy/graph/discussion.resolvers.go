@@ -7,7 +7,9 @@ package graph
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"log"
 	"time"
 
 	"github.com/pranava-mohan/wikinitt/gravy/graph/model"
@@ -148,6 +150,16 @@ func (r *mutationResolver) SendMessage(ctx context.Context, input model.NewMessa
 		return nil, err
 	}
 
+	if r.Broker != nil {
+		payload, err := json.Marshal(message)
+		if err == nil {
+			err = r.Broker.Publish(ctx, messageTopic(message.ChannelID), payload)
+		}
+		if err != nil {
+			log.Printf("Failed to publish message %s: %v", message.ID, err)
+		}
+	}
+
 	sender := &users.PublicUser{
 		ID:          user.ID,
 		Name:        user.Name,
@@ -224,7 +236,102 @@ func (r *queryResolver) Channel(ctx context.Context, id string) (*model.Channel,
 
 // MessageAdded is the resolver for the messageAdded field.
 func (r *subscriptionResolver) MessageAdded(ctx context.Context, channelID string) (<-chan *model.Message, error) {
-	return nil, fmt.Errorf("subscription not implemented yet")
+	user := auth.ForContext(ctx)
+	if user == nil {
+		return nil, fmt.Errorf("not authenticated")
+	}
+	if r.Broker == nil {
+		return nil, fmt.Errorf("subscriptions are not available")
+	}
+
+	channel, err := r.CommunityRepo.GetChannel(ctx, channelID)
+	if err != nil {
+		return nil, fmt.Errorf("channel not found")
+	}
+
+	discussion, err := r.CommunityRepo.GetDiscussion(ctx, channel.DiscussionID)
+	if err != nil {
+		return nil, err
+	}
+	if discussion == nil {
+		return nil, fmt.Errorf("discussion not found")
+	}
+
+	isMember, err := r.CommunityRepo.IsMember(ctx, discussion.GroupID, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !isMember {
+		return nil, fmt.Errorf("access denied: only group members can subscribe to messages")
+	}
+
+	payloads, err := r.Broker.Subscribe(ctx, messageTopic(channelID))
+	if err != nil {
+		return nil, err
+	}
+
+	modelChannel := &model.Channel{
+		ID:   channel.ID,
+		Name: channel.Name,
+		Type: model.ChannelType(channel.Type),
+	}
+
+	out := make(chan *model.Message, 1)
+	go func() {
+		defer close(out)
+
+		senders := make(map[string]*model.PublicUser)
+		// Members can leave or be removed while subscribed, and a quiet
+		// channel must not keep them listening.
+		memberCheck := time.NewTicker(memberRecheckInterval)
+		defer memberCheck.Stop()
+
+		for {
+			var payload []byte
+			select {
+			case <-ctx.Done():
+				return
+			case <-memberCheck.C:
+				isMember, err := r.CommunityRepo.IsMember(ctx, discussion.GroupID, user.ID)
+				if err == nil && !isMember {
+					return
+				}
+				continue
+			case p, ok := <-payloads:
+				if !ok {
+					return
+				}
+				payload = p
+			}
+
+			var message community.Message
+			if err := json.Unmarshal(payload, &message); err != nil {
+				log.Printf("Failed to decode message on channel %s: %v", channelID, err)
+				continue
+			}
+
+			sender, ok := senders[message.SenderID]
+			if !ok {
+				u, _ := r.UserRepo.GetByID(ctx, message.SenderID)
+				sender = mapPublicUserToModel(mapUserToPublic(u))
+				senders[message.SenderID] = sender
+			}
+
+			select {
+			case out <- &model.Message{
+				ID:        message.ID,
+				Content:   message.Content,
+				Sender:    sender,
+				Channel:   modelChannel,
+				CreatedAt: message.CreatedAt.Format("2006-01-02 15:04:05"),
+			}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out, nil
 }
 
 // Channel returns ChannelResolver implementation.
@@ -0,0 +1,27 @@
+package graph
+
+import (
+	"context"
+	"errors"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/pranava-mohan/wikinitt/gravy/internal/versioning"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// ErrorPresenter adds machine-readable extensions to errors clients are
+// expected to handle. Version conflicts carry code VERSION_CONFLICT and the
+// server's current version so editors can offer to merge.
+func ErrorPresenter(ctx context.Context, err error) *gqlerror.Error {
+	gqlErr := graphql.DefaultErrorPresenter(ctx, err)
+
+	var conflict *versioning.ConflictError
+	if errors.As(err, &conflict) {
+		if gqlErr.Extensions == nil {
+			gqlErr.Extensions = map[string]interface{}{}
+		}
+		gqlErr.Extensions["code"] = "VERSION_CONFLICT"
+		gqlErr.Extensions["currentVersion"] = conflict.CurrentVersion
+	}
+	return gqlErr
+}
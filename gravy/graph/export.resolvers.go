@@ -0,0 +1,76 @@
+package graph
+
+// This file will be automatically regenerated based on the schema, any resolver
+// implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.86
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/pranava-mohan/wikinitt/gravy/graph/model"
+	"github.com/pranava-mohan/wikinitt/gravy/internal/auth"
+	"github.com/pranava-mohan/wikinitt/gravy/internal/export"
+	"github.com/pranava-mohan/wikinitt/gravy/internal/sanitization"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// CreateExport is the resolver for the createExport field.
+func (r *mutationResolver) CreateExport(ctx context.Context, input model.ExportInput) (*model.ExportJob, error) {
+	user := auth.ForContext(ctx)
+	if user == nil {
+		return nil, fmt.Errorf("access denied")
+	}
+
+	job := export.Job{
+		Format:      export.Format(input.Format),
+		ArticleIDs:  input.ArticleIds,
+		RequestedBy: user.ID,
+	}
+	if input.CategorySlug != nil {
+		job.CategorySlug = strings.TrimSpace(*input.CategorySlug)
+	}
+	if input.Title != nil {
+		job.Title = sanitization.SanitizeString(strings.TrimSpace(*input.Title))
+	}
+	if job.CategorySlug != "" {
+		if _, err := r.CategoryRepo.GetBySlug(ctx, job.CategorySlug); err != nil {
+			return nil, fmt.Errorf("category not found")
+		}
+	}
+
+	created, err := r.ExportRunner.Enqueue(ctx, job)
+	if err != nil {
+		return nil, err
+	}
+	return mapExportJobToModel(created), nil
+}
+
+// ExportJobs is the resolver for the exportJobs field.
+func (r *queryResolver) ExportJobs(ctx context.Context, limit *int32, offset *int32) ([]*model.ExportJob, error) {
+	l, o := pageArgs(limit, offset)
+	jobs, err := r.ExportRepo.List(ctx, l, o)
+	if err != nil {
+		return nil, err
+	}
+
+	modelJobs := []*model.ExportJob{}
+	for _, j := range jobs {
+		modelJobs = append(modelJobs, mapExportJobToModel(j))
+	}
+	return modelJobs, nil
+}
+
+// ExportJob is the resolver for the exportJob field.
+func (r *queryResolver) ExportJob(ctx context.Context, id string) (*model.ExportJob, error) {
+	job, err := r.ExportRepo.Get(ctx, id)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return mapExportJobToModel(job), nil
+}
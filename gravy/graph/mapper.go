@@ -1,9 +1,19 @@
 package graph
 
 import (
+	"time"
+
 	"github.com/pranava-mohan/wikinitt/gravy/graph/model"
 	"github.com/pranava-mohan/wikinitt/gravy/internal/articles"
+	"github.com/pranava-mohan/wikinitt/gravy/internal/auth"
+	"github.com/pranava-mohan/wikinitt/gravy/internal/backlinks"
+	"github.com/pranava-mohan/wikinitt/gravy/internal/categories"
 	"github.com/pranava-mohan/wikinitt/gravy/internal/community"
+	"github.com/pranava-mohan/wikinitt/gravy/internal/export"
+	"github.com/pranava-mohan/wikinitt/gravy/internal/oidc"
+	"github.com/pranava-mohan/wikinitt/gravy/internal/preview"
+	"github.com/pranava-mohan/wikinitt/gravy/internal/sessions"
+	"github.com/pranava-mohan/wikinitt/gravy/internal/twofactor"
 	"github.com/pranava-mohan/wikinitt/gravy/internal/users"
 )
 
@@ -11,10 +21,27 @@ func mapArticleToModel(a *articles.Article) *model.Article {
 	if a == nil {
 		return nil
 	}
-	description := a.Content
-	runes := []rune(description)
-	if len(runes) > 50 {
-		description = string(runes[:50]) + "..."
+	var publishedAt *string
+	if a.PublishedAt != nil {
+		formatted := a.PublishedAt.Format("2006-01-02 15:04:05")
+		publishedAt = &formatted
+	}
+	var publishAt, expireAt *string
+	if a.PublishAt != nil {
+		formatted := a.PublishAt.Format(time.RFC3339)
+		publishAt = &formatted
+	}
+	if a.ExpireAt != nil {
+		formatted := a.ExpireAt.Format(time.RFC3339)
+		expireAt = &formatted
+	}
+	var reviewNote *string
+	if a.ReviewNote != "" {
+		reviewNote = &a.ReviewNote
+	}
+	tags := a.Tags
+	if tags == nil {
+		tags = []string{}
 	}
 
 	return &model.Article{
@@ -23,12 +50,93 @@ func mapArticleToModel(a *articles.Article) *model.Article {
 		Content:     a.Content,
 		Slug:        a.Slug,
 		Category:    a.Category,
+		Tags:        tags,
 		Thumbnail:   a.Thumbnail,
 		Featured:    a.Featured,
-		Description: description,
+		Description: a.Excerpt,
+		ViewCount:   int32(a.ViewCount),
 		CreatedAt:   a.CreatedAt.Format("2006-01-02 15:04:05"),
 		UpdatedAt:   a.UpdatedAt.Format("2006-01-02 15:04:05"),
 		Author:      mapPublicUserToModel(a.Author),
+		Status:      model.ArticleStatus(a.CurrentStatus()),
+		Version:     int32(a.Version),
+		PublishedAt: publishedAt,
+		PublishAt:   publishAt,
+		ExpireAt:    expireAt,
+		ReviewNote:  reviewNote,
+	}
+}
+
+func mapRevisionToModel(rev *articles.Revision, author *users.PublicUser) *model.ArticleRevision {
+	if rev == nil {
+		return nil
+	}
+	return &model.ArticleRevision{
+		ID:        rev.ID,
+		ArticleID: rev.ArticleID,
+		Title:     rev.Title,
+		Content:   rev.Content,
+		Category:  rev.Category,
+		Summary:   rev.Summary,
+		Author:    mapPublicUserToModel(author),
+		CreatedAt: rev.CreatedAt.Format("2006-01-02 15:04:05"),
+	}
+}
+
+func mapDiffChunksToModel(d articles.Diff) []*model.DiffChunk {
+	chunks := make([]*model.DiffChunk, 0, len(d.Chunks))
+	for _, c := range d.Chunks {
+		chunks = append(chunks, &model.DiffChunk{
+			Op:   model.DiffOp(c.Op),
+			Text: c.Text,
+		})
+	}
+	return chunks
+}
+
+func mapDiffToModel(d articles.Diff, mode model.DiffMode, from, to *model.ArticleRevision) *model.ArticleRevisionDiff {
+	return &model.ArticleRevisionDiff{
+		From:      from,
+		To:        to,
+		Mode:      mode,
+		Chunks:    mapDiffChunksToModel(d),
+		Additions: int32(d.Additions),
+		Deletions: int32(d.Deletions),
+	}
+}
+
+func mapContentDiffToModel(d articles.Diff, mode model.DiffMode) *model.ContentDiff {
+	return &model.ContentDiff{
+		Mode:      mode,
+		Chunks:    mapDiffChunksToModel(d),
+		Additions: int32(d.Additions),
+		Deletions: int32(d.Deletions),
+	}
+}
+
+func mapSuggestionToModel(s *articles.Suggestion, author *users.PublicUser) *model.EditSuggestion {
+	if s == nil {
+		return nil
+	}
+	var rejectReason *string
+	if s.RejectReason != "" {
+		rejectReason = &s.RejectReason
+	}
+	var reviewedAt *string
+	if s.ReviewedAt != nil {
+		formatted := s.ReviewedAt.Format("2006-01-02 15:04:05")
+		reviewedAt = &formatted
+	}
+	return &model.EditSuggestion{
+		ID:           s.ID,
+		ArticleID:    s.ArticleID,
+		Author:       mapPublicUserToModel(author),
+		Content:      s.Content,
+		Summary:      s.Summary,
+		Status:       model.EditSuggestionStatus(s.Status),
+		RejectReason: rejectReason,
+		CreatedAt:    s.CreatedAt.Format("2006-01-02 15:04:05"),
+		ReviewedAt:   reviewedAt,
 	}
 }
 
@@ -51,18 +159,52 @@ func mapUserToModel(u *users.User) *model.User {
 		return nil
 	}
 	return &model.User{
-		ID:            u.ID,
-		Name:          u.Name,
-		Username:      u.Username,
-		DisplayName:   u.DisplayName,
-		Email:         u.Email,
-		Gender:        u.Gender,
-		Avatar:        u.Avatar,
-		PhoneNumber:   u.PhoneNumber,
-		SetupComplete: u.SetupComplete,
-		IsAdmin:       u.IsAdmin,
-		IsBanned:      u.IsBanned,
-		CreatedAt:     u.CreatedAt.Format("2006-01-02 15:04:05"),
+		ID:               u.ID,
+		Name:             u.Name,
+		Username:         u.Username,
+		DisplayName:      u.DisplayName,
+		Email:            u.Email,
+		EmailVerified:    u.EmailVerified,
+		TwoFactorEnabled: u.TwoFactorEnabled,
+		Gender:           u.Gender,
+		Avatar:           u.Avatar,
+		PhoneNumber:      u.PhoneNumber,
+		SetupComplete:    u.SetupComplete,
+		IsAdmin:          u.IsAdmin,
+		IsBanned:         u.IsBanned,
+		CreatedAt:        u.CreatedAt.Format("2006-01-02 15:04:05"),
+	}
+}
+
+func mapSessionToModel(s *sessions.Session, currentID string) *model.Session {
+	return &model.Session{
+		ID:         s.ID,
+		Device:     sessions.Device(s.UserAgent),
+		UserAgent:  s.UserAgent,
+		IP:         s.IP,
+		CreatedAt:  s.CreatedAt.Format("2006-01-02 15:04:05"),
+		LastSeenAt: s.LastSeenAt.Format("2006-01-02 15:04:05"),
+		ExpiresAt:  s.ExpiresAt.Format("2006-01-02 15:04:05"),
+		Current:    s.ID == currentID,
+	}
+}
+
+func mapTokensToModel(t *auth.Tokens) *model.AuthPayload {
+	return &model.AuthPayload{
+		AccessToken:          t.AccessToken,
+		AccessTokenExpiresAt: t.AccessExpiresAt.UTC().Format(time.RFC3339),
+		RefreshToken:         t.RefreshToken,
+		Session:              mapSessionToModel(t.Session, t.Session.ID),
+	}
+}
+
+func mapIdentityToModel(i *oidc.Identity) *model.Identity {
+	return &model.Identity{
+		ID:          i.ID,
+		Issuer:      i.Issuer,
+		Email:       i.Email,
+		CreatedAt:   i.CreatedAt.Format("2006-01-02 15:04:05"),
+		LastLoginAt: i.LastLoginAt.Format("2006-01-02 15:04:05"),
 	}
 }
 
@@ -97,6 +239,7 @@ func mapPostToModel(p *community.Post, author *users.PublicUser, group *communit
 		Upvotes:       int32(p.UpvotesCount),
 		Downvotes:     int32(p.DownvotesCount),
 		IsEdited:      p.IsEdited,
+		Version:       int32(p.Version),
 		CreatedAt:     p.CreatedAt.Format("2006-01-02 15:04:05"),
 	}
 }
@@ -132,3 +275,114 @@ func mapUserToPublic(u *users.User) *users.PublicUser {
 		Avatar:      u.Avatar,
 	}
 }
+
+func mapBacklinkJobToModel(j *backlinks.Job) *model.BacklinkJob {
+	var jobError *string
+	if j.Error != "" {
+		jobError = &j.Error
+	}
+	var finishedAt *string
+	if j.FinishedAt != nil {
+		formatted := j.FinishedAt.Format("2006-01-02 15:04:05")
+		finishedAt = &formatted
+	}
+
+	return &model.BacklinkJob{
+		ID:         j.ID,
+		TargetID:   j.TargetID,
+		Title:      j.Title,
+		State:      model.BacklinkJobState(j.State),
+		Processed:  int32(j.Processed),
+		Linked:     int32(j.Linked),
+		Attempts:   int32(j.Attempts),
+		Error:      jobError,
+		CreatedAt:  j.CreatedAt.Format("2006-01-02 15:04:05"),
+		UpdatedAt:  j.UpdatedAt.Format("2006-01-02 15:04:05"),
+		FinishedAt: finishedAt,
+	}
+}
+
+func mapCategoryToModel(c *categories.Category) *model.Category {
+	return &model.Category{
+		ID:          c.ID,
+		Name:        c.Name,
+		Slug:        c.Slug,
+		Description: c.Description,
+		Icon:        c.Icon,
+		ParentID:    c.ParentID,
+		CreatedAt:   c.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+func mapTagToModel(name string, count int64) *model.Tag {
+	return &model.Tag{Name: name, Count: int32(count)}
+}
+
+func mapTocToModel(toc []articles.TocEntry) []*model.TocEntry {
+	result := make([]*model.TocEntry, 0, len(toc))
+	for _, e := range toc {
+		result = append(result, &model.TocEntry{ID: e.ID, Text: e.Text, Level: int32(e.Level)})
+	}
+	return result
+}
+
+func mapPreviewToModel(p *preview.Preview) *model.LinkPreview {
+	result := &model.LinkPreview{
+		Type:        model.LinkPreviewType(p.Type),
+		Title:       p.Title,
+		Description: p.Description,
+		URL:         p.URL,
+	}
+	if p.Image != "" {
+		result.Image = &p.Image
+	}
+	return result
+}
+
+func mapExportJobToModel(j *export.Job) *model.ExportJob {
+	result := &model.ExportJob{
+		ID:          j.ID,
+		Title:       j.Title,
+		Format:      model.ExportFormat(j.Format),
+		ArticleIds:  j.ArticleIDs,
+		State:       model.ExportJobState(j.State),
+		Chapters:    int32(j.Chapters),
+		Size:        int32(j.Size),
+		RequestedBy: j.RequestedBy,
+		CreatedAt:   j.CreatedAt.Format("2006-01-02 15:04:05"),
+		UpdatedAt:   j.UpdatedAt.Format("2006-01-02 15:04:05"),
+	}
+	if result.ArticleIds == nil {
+		result.ArticleIds = []string{}
+	}
+	if j.CategorySlug != "" {
+		result.CategorySlug = &j.CategorySlug
+	}
+	if j.Error != "" {
+		result.Error = &j.Error
+	}
+	if j.State == export.StateDone {
+		fileName := j.FileName
+		downloadURL := export.DownloadPath(j)
+		result.FileName = &fileName
+		result.DownloadURL = &downloadURL
+	}
+	if j.FinishedAt != nil {
+		formatted := j.FinishedAt.Format("2006-01-02 15:04:05")
+		result.FinishedAt = &formatted
+	}
+	return result
+}
+
+func mapTwoFactorStatusToModel(s *twofactor.Status, required bool) *model.TwoFactorStatus {
+	status := &model.TwoFactorStatus{
+		Enabled:                s.Enabled,
+		RecoveryCodesRemaining: int32(s.RecoveryCodesRemaining),
+		Required:               required,
+	}
+	if s.EnabledAt != nil {
+		enabledAt := s.EnabledAt.Format("2006-01-02 15:04:05")
+		status.EnabledAt = &enabledAt
+	}
+	return status
+}
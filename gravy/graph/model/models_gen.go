@@ -14,24 +14,94 @@ type CommunityResult interface {
 }
 
 type Article struct {
-	ID          string      `json:"id"`
-	Title       string      `json:"title"`
-	Content     string      `json:"content"`
-	Slug        string      `json:"slug"`
-	Category    string      `json:"category"`
-	Thumbnail   string      `json:"thumbnail"`
-	Featured    bool        `json:"featured"`
-	Description string      `json:"description"`
-	Author      *PublicUser `json:"author"`
-	CreatedAt   string      `json:"createdAt"`
-	UpdatedAt   string      `json:"updatedAt"`
+	ID                 string             `json:"id"`
+	Title              string             `json:"title"`
+	Content            string             `json:"content"`
+	Slug               string             `json:"slug"`
+	Category           string             `json:"category"`
+	Tags               []string           `json:"tags"`
+	Thumbnail          string             `json:"thumbnail"`
+	Featured           bool               `json:"featured"`
+	Description        string             `json:"description"`
+	HTML               string             `json:"html"`
+	Toc                []*TocEntry        `json:"toc"`
+	ReadingTimeMinutes int32              `json:"readingTimeMinutes"`
+	ViewCount          int32              `json:"viewCount"`
+	Author             *PublicUser        `json:"author"`
+	CreatedAt          string             `json:"createdAt"`
+	UpdatedAt          string             `json:"updatedAt"`
+	Status             ArticleStatus      `json:"status"`
+	Version            int32              `json:"version"`
+	PublishedAt        *string            `json:"publishedAt,omitempty"`
+	PublishAt          *string            `json:"publishAt,omitempty"`
+	ExpireAt           *string            `json:"expireAt,omitempty"`
+	ReviewNote         *string            `json:"reviewNote,omitempty"`
+	Revisions          []*ArticleRevision `json:"revisions"`
+	RedirectedFrom     *string            `json:"redirectedFrom,omitempty"`
+	LinkedContent      string             `json:"linkedContent"`
+	Backlinks          []*ArticleLink     `json:"backlinks"`
+	OutgoingLinks      []*ArticleLink     `json:"outgoingLinks"`
+	Related            []*Article         `json:"related"`
+}
+
+type ArticleLink struct {
+	Article *Article `json:"article"`
+	Anchor  string   `json:"anchor"`
+}
+
+type ArticleRevision struct {
+	ID        string      `json:"id"`
+	ArticleID string      `json:"articleId"`
+	Title     string      `json:"title"`
+	Content   string      `json:"content"`
+	Category  string      `json:"category"`
+	Summary   string      `json:"summary"`
+	Author    *PublicUser `json:"author,omitempty"`
+	CreatedAt string      `json:"createdAt"`
+}
+
+type ArticleRevisionDiff struct {
+	From      *ArticleRevision `json:"from"`
+	To        *ArticleRevision `json:"to"`
+	Mode      DiffMode         `json:"mode"`
+	Chunks    []*DiffChunk     `json:"chunks"`
+	Additions int32            `json:"additions"`
+	Deletions int32            `json:"deletions"`
+}
+
+type AuthPayload struct {
+	AccessToken          string   `json:"accessToken"`
+	AccessTokenExpiresAt string   `json:"accessTokenExpiresAt"`
+	RefreshToken         string   `json:"refreshToken"`
+	Session              *Session `json:"session"`
+}
+
+type BacklinkJob struct {
+	ID         string           `json:"id"`
+	TargetID   string           `json:"targetId"`
+	Title      string           `json:"title"`
+	State      BacklinkJobState `json:"state"`
+	Processed  int32            `json:"processed"`
+	Linked     int32            `json:"linked"`
+	Attempts   int32            `json:"attempts"`
+	Error      *string          `json:"error,omitempty"`
+	CreatedAt  string           `json:"createdAt"`
+	UpdatedAt  string           `json:"updatedAt"`
+	FinishedAt *string          `json:"finishedAt,omitempty"`
 }
 
 type Category struct {
-	ID        string `json:"id"`
-	Name      string `json:"name"`
-	Slug      string `json:"slug"`
-	CreatedAt string `json:"createdAt"`
+	ID           string      `json:"id"`
+	Name         string      `json:"name"`
+	Slug         string      `json:"slug"`
+	Description  string      `json:"description"`
+	Icon         string      `json:"icon"`
+	ParentID     *string     `json:"parentId,omitempty"`
+	Parent       *Category   `json:"parent,omitempty"`
+	Children     []*Category `json:"children"`
+	Articles     []*Article  `json:"articles"`
+	ArticleCount int32       `json:"articleCount"`
+	CreatedAt    string      `json:"createdAt"`
 }
 
 type Channel struct {
@@ -64,12 +134,63 @@ type CompleteSetupInput struct {
 	DisplayName string `json:"displayName"`
 }
 
+type ContentDiff struct {
+	Mode      DiffMode     `json:"mode"`
+	Chunks    []*DiffChunk `json:"chunks"`
+	Additions int32        `json:"additions"`
+	Deletions int32        `json:"deletions"`
+}
+
+type DiffChunk struct {
+	Op   DiffOp `json:"op"`
+	Text string `json:"text"`
+}
+
 type Discussion struct {
 	ID       string     `json:"id"`
 	Group    *Group     `json:"group"`
 	Channels []*Channel `json:"channels"`
 }
 
+type EditSuggestion struct {
+	ID           string               `json:"id"`
+	ArticleID    string               `json:"articleId"`
+	Article      *Article             `json:"article,omitempty"`
+	Author       *PublicUser          `json:"author,omitempty"`
+	Content      string               `json:"content"`
+	Summary      string               `json:"summary"`
+	Status       EditSuggestionStatus `json:"status"`
+	RejectReason *string              `json:"rejectReason,omitempty"`
+	CreatedAt    string               `json:"createdAt"`
+	ReviewedAt   *string              `json:"reviewedAt,omitempty"`
+	Diff         *ContentDiff         `json:"diff"`
+}
+
+type ExportInput struct {
+	Format       ExportFormat `json:"format"`
+	CategorySlug *string      `json:"categorySlug,omitempty"`
+	ArticleIds   []string     `json:"articleIds,omitempty"`
+	Title        *string      `json:"title,omitempty"`
+}
+
+type ExportJob struct {
+	ID           string         `json:"id"`
+	Title        string         `json:"title"`
+	Format       ExportFormat   `json:"format"`
+	CategorySlug *string        `json:"categorySlug,omitempty"`
+	ArticleIds   []string       `json:"articleIds"`
+	State        ExportJobState `json:"state"`
+	Chapters     int32          `json:"chapters"`
+	Size         int32          `json:"size"`
+	FileName     *string        `json:"fileName,omitempty"`
+	DownloadURL  *string        `json:"downloadUrl,omitempty"`
+	Error        *string        `json:"error,omitempty"`
+	RequestedBy  string         `json:"requestedBy"`
+	CreatedAt    string         `json:"createdAt"`
+	UpdatedAt    string         `json:"updatedAt"`
+	FinishedAt   *string        `json:"finishedAt,omitempty"`
+}
+
 type Group struct {
 	ID                string        `json:"id"`
 	Name              string        `json:"name"`
@@ -90,11 +211,32 @@ type Group struct {
 
 func (Group) IsCommunityResult() {}
 
+type Identity struct {
+	ID          string `json:"id"`
+	Issuer      string `json:"issuer"`
+	Email       string `json:"email"`
+	CreatedAt   string `json:"createdAt"`
+	LastLoginAt string `json:"lastLoginAt"`
+}
+
+type LinkPreview struct {
+	Type        LinkPreviewType `json:"type"`
+	Title       string          `json:"title"`
+	Description string          `json:"description"`
+	Image       *string         `json:"image,omitempty"`
+	URL         string          `json:"url"`
+}
+
 type LoginInput struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
 }
 
+type LoginResult struct {
+	Auth      *AuthPayload        `json:"auth,omitempty"`
+	Challenge *TwoFactorChallenge `json:"challenge,omitempty"`
+}
+
 type MapLocation struct {
 	ID          string      `json:"id"`
 	Name        string      `json:"name"`
@@ -134,11 +276,15 @@ type Mutation struct {
 }
 
 type NewArticle struct {
-	Title     string `json:"title"`
-	Content   string `json:"content"`
-	Category  string `json:"category"`
-	Thumbnail string `json:"thumbnail"`
-	Featured  bool   `json:"featured"`
+	Title     string   `json:"title"`
+	Content   string   `json:"content"`
+	Category  string   `json:"category"`
+	Tags      []string `json:"tags,omitempty"`
+	Thumbnail string   `json:"thumbnail"`
+	Featured  bool     `json:"featured"`
+	Summary   *string  `json:"summary,omitempty"`
+	PublishAt *string  `json:"publishAt,omitempty"`
+	ExpireAt  *string  `json:"expireAt,omitempty"`
 }
 
 type NewChannel struct {
@@ -179,6 +325,11 @@ type NewUser struct {
 	MachineToken string `json:"machineToken"`
 }
 
+type OidcLogin struct {
+	AuthorizationURL string `json:"authorizationUrl"`
+	State            string `json:"state"`
+}
+
 type Post struct {
 	ID            string      `json:"id"`
 	Title         string      `json:"title"`
@@ -191,6 +342,7 @@ type Post struct {
 	UserVote      VoteType    `json:"userVote"`
 	Comments      []*Comment  `json:"comments"`
 	IsEdited      bool        `json:"isEdited"`
+	Version       int32       `json:"version"`
 	CreatedAt     string      `json:"createdAt"`
 }
 
@@ -210,16 +362,73 @@ type PublicUser struct {
 type Query struct {
 }
 
+type RegisterInput struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+	Name     string `json:"name"`
+}
+
+type Session struct {
+	ID         string `json:"id"`
+	Device     string `json:"device"`
+	UserAgent  string `json:"userAgent"`
+	IP         string `json:"ip"`
+	CreatedAt  string `json:"createdAt"`
+	LastSeenAt string `json:"lastSeenAt"`
+	ExpiresAt  string `json:"expiresAt"`
+	Current    bool   `json:"current"`
+}
+
 type Subscription struct {
 }
 
+type Tag struct {
+	Name  string `json:"name"`
+	Count int32  `json:"count"`
+}
+
+type TocEntry struct {
+	ID    string `json:"id"`
+	Text  string `json:"text"`
+	Level int32  `json:"level"`
+}
+
+type TwoFactorChallenge struct {
+	Token     string `json:"token"`
+	ExpiresAt string `json:"expiresAt"`
+}
+
+type TwoFactorEnrollment struct {
+	Secret     string `json:"secret"`
+	OtpauthURI string `json:"otpauthUri"`
+}
+
+type TwoFactorStatus struct {
+	Enabled                bool    `json:"enabled"`
+	EnabledAt              *string `json:"enabledAt,omitempty"`
+	RecoveryCodesRemaining int32   `json:"recoveryCodesRemaining"`
+	Required               bool    `json:"required"`
+}
+
 type UpdateArticle struct {
-	ID        string  `json:"id"`
-	Title     *string `json:"title,omitempty"`
-	Content   *string `json:"content,omitempty"`
-	Category  *string `json:"category,omitempty"`
-	Thumbnail *string `json:"thumbnail,omitempty"`
-	Featured  *bool   `json:"featured,omitempty"`
+	ID        string   `json:"id"`
+	Version   int32    `json:"version"`
+	Title     *string  `json:"title,omitempty"`
+	Content   *string  `json:"content,omitempty"`
+	Category  *string  `json:"category,omitempty"`
+	Tags      []string `json:"tags,omitempty"`
+	Thumbnail *string  `json:"thumbnail,omitempty"`
+	Featured  *bool    `json:"featured,omitempty"`
+	Summary   *string  `json:"summary,omitempty"`
+	PublishAt *string  `json:"publishAt,omitempty"`
+	ExpireAt  *string  `json:"expireAt,omitempty"`
+}
+
+type UpdateCategoryInput struct {
+	Name        *string `json:"name,omitempty"`
+	ParentID    *string `json:"parentId,omitempty"`
+	Description *string `json:"description,omitempty"`
+	Icon        *string `json:"icon,omitempty"`
 }
 
 type UpdateUserInput struct {
@@ -229,18 +438,140 @@ type UpdateUserInput struct {
 }
 
 type User struct {
-	ID            string `json:"id"`
-	Name          string `json:"name"`
-	Username      string `json:"username"`
-	DisplayName   string `json:"displayName"`
-	Email         string `json:"email"`
-	Gender        string `json:"gender"`
-	Avatar        string `json:"avatar"`
-	PhoneNumber   string `json:"phoneNumber"`
-	SetupComplete bool   `json:"setupComplete"`
-	IsAdmin       bool   `json:"isAdmin"`
-	IsBanned      bool   `json:"isBanned"`
-	CreatedAt     string `json:"createdAt"`
+	ID               string `json:"id"`
+	Name             string `json:"name"`
+	Username         string `json:"username"`
+	DisplayName      string `json:"displayName"`
+	Email            string `json:"email"`
+	EmailVerified    bool   `json:"emailVerified"`
+	TwoFactorEnabled bool   `json:"twoFactorEnabled"`
+	Gender           string `json:"gender"`
+	Avatar           string `json:"avatar"`
+	PhoneNumber      string `json:"phoneNumber"`
+	SetupComplete    bool   `json:"setupComplete"`
+	IsAdmin          bool   `json:"isAdmin"`
+	IsBanned         bool   `json:"isBanned"`
+	CreatedAt        string `json:"createdAt"`
+}
+
+type ArticleStatus string
+
+const (
+	ArticleStatusDraft     ArticleStatus = "DRAFT"
+	ArticleStatusInReview  ArticleStatus = "IN_REVIEW"
+	ArticleStatusScheduled ArticleStatus = "SCHEDULED"
+	ArticleStatusPublished ArticleStatus = "PUBLISHED"
+	ArticleStatusArchived  ArticleStatus = "ARCHIVED"
+)
+
+var AllArticleStatus = []ArticleStatus{
+	ArticleStatusDraft,
+	ArticleStatusInReview,
+	ArticleStatusScheduled,
+	ArticleStatusPublished,
+	ArticleStatusArchived,
+}
+
+func (e ArticleStatus) IsValid() bool {
+	switch e {
+	case ArticleStatusDraft, ArticleStatusInReview, ArticleStatusScheduled, ArticleStatusPublished, ArticleStatusArchived:
+		return true
+	}
+	return false
+}
+
+func (e ArticleStatus) String() string {
+	return string(e)
+}
+
+func (e *ArticleStatus) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = ArticleStatus(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid ArticleStatus", str)
+	}
+	return nil
+}
+
+func (e ArticleStatus) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *ArticleStatus) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e ArticleStatus) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
+type BacklinkJobState string
+
+const (
+	BacklinkJobStatePending BacklinkJobState = "PENDING"
+	BacklinkJobStateRunning BacklinkJobState = "RUNNING"
+	BacklinkJobStateDone    BacklinkJobState = "DONE"
+	BacklinkJobStateFailed  BacklinkJobState = "FAILED"
+)
+
+var AllBacklinkJobState = []BacklinkJobState{
+	BacklinkJobStatePending,
+	BacklinkJobStateRunning,
+	BacklinkJobStateDone,
+	BacklinkJobStateFailed,
+}
+
+func (e BacklinkJobState) IsValid() bool {
+	switch e {
+	case BacklinkJobStatePending, BacklinkJobStateRunning, BacklinkJobStateDone, BacklinkJobStateFailed:
+		return true
+	}
+	return false
+}
+
+func (e BacklinkJobState) String() string {
+	return string(e)
+}
+
+func (e *BacklinkJobState) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = BacklinkJobState(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid BacklinkJobState", str)
+	}
+	return nil
+}
+
+func (e BacklinkJobState) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *BacklinkJobState) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e BacklinkJobState) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
 }
 
 type ChannelType string
@@ -296,6 +627,291 @@ func (e ChannelType) MarshalJSON() ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
+type DiffMode string
+
+const (
+	DiffModeLine DiffMode = "LINE"
+	DiffModeWord DiffMode = "WORD"
+)
+
+var AllDiffMode = []DiffMode{
+	DiffModeLine,
+	DiffModeWord,
+}
+
+func (e DiffMode) IsValid() bool {
+	switch e {
+	case DiffModeLine, DiffModeWord:
+		return true
+	}
+	return false
+}
+
+func (e DiffMode) String() string {
+	return string(e)
+}
+
+func (e *DiffMode) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = DiffMode(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid DiffMode", str)
+	}
+	return nil
+}
+
+func (e DiffMode) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *DiffMode) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e DiffMode) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
+type DiffOp string
+
+const (
+	DiffOpEqual  DiffOp = "EQUAL"
+	DiffOpInsert DiffOp = "INSERT"
+	DiffOpDelete DiffOp = "DELETE"
+)
+
+var AllDiffOp = []DiffOp{
+	DiffOpEqual,
+	DiffOpInsert,
+	DiffOpDelete,
+}
+
+func (e DiffOp) IsValid() bool {
+	switch e {
+	case DiffOpEqual, DiffOpInsert, DiffOpDelete:
+		return true
+	}
+	return false
+}
+
+func (e DiffOp) String() string {
+	return string(e)
+}
+
+func (e *DiffOp) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = DiffOp(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid DiffOp", str)
+	}
+	return nil
+}
+
+func (e DiffOp) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *DiffOp) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e DiffOp) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
+type EditSuggestionStatus string
+
+const (
+	EditSuggestionStatusPending  EditSuggestionStatus = "PENDING"
+	EditSuggestionStatusApproved EditSuggestionStatus = "APPROVED"
+	EditSuggestionStatusRejected EditSuggestionStatus = "REJECTED"
+	EditSuggestionStatusStale    EditSuggestionStatus = "STALE"
+)
+
+var AllEditSuggestionStatus = []EditSuggestionStatus{
+	EditSuggestionStatusPending,
+	EditSuggestionStatusApproved,
+	EditSuggestionStatusRejected,
+	EditSuggestionStatusStale,
+}
+
+func (e EditSuggestionStatus) IsValid() bool {
+	switch e {
+	case EditSuggestionStatusPending, EditSuggestionStatusApproved, EditSuggestionStatusRejected, EditSuggestionStatusStale:
+		return true
+	}
+	return false
+}
+
+func (e EditSuggestionStatus) String() string {
+	return string(e)
+}
+
+func (e *EditSuggestionStatus) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = EditSuggestionStatus(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid EditSuggestionStatus", str)
+	}
+	return nil
+}
+
+func (e EditSuggestionStatus) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *EditSuggestionStatus) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e EditSuggestionStatus) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
+type ExportFormat string
+
+const (
+	ExportFormatEpub ExportFormat = "EPUB"
+	ExportFormatHTML ExportFormat = "HTML"
+)
+
+var AllExportFormat = []ExportFormat{
+	ExportFormatEpub,
+	ExportFormatHTML,
+}
+
+func (e ExportFormat) IsValid() bool {
+	switch e {
+	case ExportFormatEpub, ExportFormatHTML:
+		return true
+	}
+	return false
+}
+
+func (e ExportFormat) String() string {
+	return string(e)
+}
+
+func (e *ExportFormat) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = ExportFormat(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid ExportFormat", str)
+	}
+	return nil
+}
+
+func (e ExportFormat) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *ExportFormat) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e ExportFormat) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
+type ExportJobState string
+
+const (
+	ExportJobStatePending ExportJobState = "PENDING"
+	ExportJobStateRunning ExportJobState = "RUNNING"
+	ExportJobStateDone    ExportJobState = "DONE"
+	ExportJobStateFailed  ExportJobState = "FAILED"
+)
+
+var AllExportJobState = []ExportJobState{
+	ExportJobStatePending,
+	ExportJobStateRunning,
+	ExportJobStateDone,
+	ExportJobStateFailed,
+}
+
+func (e ExportJobState) IsValid() bool {
+	switch e {
+	case ExportJobStatePending, ExportJobStateRunning, ExportJobStateDone, ExportJobStateFailed:
+		return true
+	}
+	return false
+}
+
+func (e ExportJobState) String() string {
+	return string(e)
+}
+
+func (e *ExportJobState) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = ExportJobState(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid ExportJobState", str)
+	}
+	return nil
+}
+
+func (e ExportJobState) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *ExportJobState) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e ExportJobState) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
 type GroupType string
 
 const (
@@ -351,6 +967,63 @@ func (e GroupType) MarshalJSON() ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
+type LinkPreviewType string
+
+const (
+	LinkPreviewTypeArticle LinkPreviewType = "ARTICLE"
+	LinkPreviewTypePost    LinkPreviewType = "POST"
+	LinkPreviewTypeGroup   LinkPreviewType = "GROUP"
+)
+
+var AllLinkPreviewType = []LinkPreviewType{
+	LinkPreviewTypeArticle,
+	LinkPreviewTypePost,
+	LinkPreviewTypeGroup,
+}
+
+func (e LinkPreviewType) IsValid() bool {
+	switch e {
+	case LinkPreviewTypeArticle, LinkPreviewTypePost, LinkPreviewTypeGroup:
+		return true
+	}
+	return false
+}
+
+func (e LinkPreviewType) String() string {
+	return string(e)
+}
+
+func (e *LinkPreviewType) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = LinkPreviewType(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid LinkPreviewType", str)
+	}
+	return nil
+}
+
+func (e LinkPreviewType) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *LinkPreviewType) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e LinkPreviewType) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
 type Role string
 
 const (
@@ -406,6 +1079,63 @@ func (e Role) MarshalJSON() ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
+type TrendWindow string
+
+const (
+	TrendWindowDay   TrendWindow = "DAY"
+	TrendWindowWeek  TrendWindow = "WEEK"
+	TrendWindowMonth TrendWindow = "MONTH"
+)
+
+var AllTrendWindow = []TrendWindow{
+	TrendWindowDay,
+	TrendWindowWeek,
+	TrendWindowMonth,
+}
+
+func (e TrendWindow) IsValid() bool {
+	switch e {
+	case TrendWindowDay, TrendWindowWeek, TrendWindowMonth:
+		return true
+	}
+	return false
+}
+
+func (e TrendWindow) String() string {
+	return string(e)
+}
+
+func (e *TrendWindow) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = TrendWindow(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid TrendWindow", str)
+	}
+	return nil
+}
+
+func (e TrendWindow) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *TrendWindow) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e TrendWindow) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
 type VoteType string
 
 const (
@@ -0,0 +1,69 @@
+package graph
+
+// This file will be automatically regenerated based on the schema, any resolver
+// implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.86
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pranava-mohan/wikinitt/gravy/graph/model"
+	"github.com/pranava-mohan/wikinitt/gravy/internal/auth"
+	"github.com/pranava-mohan/wikinitt/gravy/internal/oidc"
+)
+
+// BeginOidcLogin is the resolver for the beginOidcLogin field.
+func (r *mutationResolver) BeginOidcLogin(ctx context.Context) (*model.OidcLogin, error) {
+	if r.OIDC == nil {
+		return nil, oidc.ErrNotConfigured
+	}
+	authURL, state, err := r.OIDC.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &model.OidcLogin{AuthorizationURL: authURL, State: state}, nil
+}
+
+// CompleteOidcLogin is the resolver for the completeOidcLogin field.
+func (r *mutationResolver) CompleteOidcLogin(ctx context.Context, code string, state string) (*model.LoginResult, error) {
+	if r.OIDC == nil {
+		return nil, oidc.ErrNotConfigured
+	}
+	claims, err := r.OIDC.Complete(ctx, code, state)
+	if err != nil {
+		return nil, err
+	}
+	user, err := r.userForClaims(ctx, claims)
+	if err != nil {
+		return nil, err
+	}
+	return r.finishLogin(ctx, user)
+}
+
+// OidcEnabled is the resolver for the oidcEnabled field.
+func (r *queryResolver) OidcEnabled(ctx context.Context) (bool, error) {
+	return r.OIDC != nil, nil
+}
+
+// MyIdentities is the resolver for the myIdentities field.
+func (r *queryResolver) MyIdentities(ctx context.Context) ([]*model.Identity, error) {
+	user := auth.ForContext(ctx)
+	if user == nil {
+		return nil, fmt.Errorf("not authenticated")
+	}
+	if r.OIDC == nil {
+		return []*model.Identity{}, nil
+	}
+
+	list, err := r.OIDC.Identities(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	modelIdentities := []*model.Identity{}
+	for _, i := range list {
+		modelIdentities = append(modelIdentities, mapIdentityToModel(i))
+	}
+	return modelIdentities, nil
+}
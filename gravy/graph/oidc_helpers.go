@@ -0,0 +1,108 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pranava-mohan/wikinitt/gravy/internal/auth"
+	"github.com/pranava-mohan/wikinitt/gravy/internal/oidc"
+	"github.com/pranava-mohan/wikinitt/gravy/internal/users"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// userForClaims finds the user an OpenID Connect identity belongs to,
+// linking it to the user with the same verified address or signing up a
+// new user the first time it is seen.
+func (r *Resolver) userForClaims(ctx context.Context, claims *oidc.Claims) (*users.User, error) {
+	identity, err := r.OIDC.Identity(ctx, claims)
+	if err != nil {
+		return nil, err
+	}
+	if identity != nil {
+		return r.UserRepo.GetByID(ctx, identity.UserID)
+	}
+
+	email, err := normalizeEmail(claims.Email)
+	if err != nil || !claims.EmailVerified {
+		return nil, fmt.Errorf("your account has no verified email address")
+	}
+
+	user, err := r.UserRepo.GetByEmail(ctx, email)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return nil, err
+	}
+	if user == nil {
+		if err := r.checkSignUpDomain(email); err != nil {
+			return nil, err
+		}
+		name := claims.Name
+		if name == "" {
+			name = strings.Split(email, "@")[0]
+		}
+		user, err = r.registerUser(ctx, users.User{
+			OAuthID:       claims.Subject,
+			Name:          name,
+			Email:         email,
+			EmailVerified: true,
+			Gender:        "unknown",
+		}, claims.Subject)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if !user.EmailVerified {
+		updates := map[string]interface{}{"emailVerified": true}
+		// Anyone could have registered the address with a password before
+		// its owner signed in, so that password and its sessions go.
+		if user.PasswordHash != "" {
+			updates["passwordHash"] = ""
+			if _, err := r.Sessions.RevokeAll(ctx, user.ID, "email verified by another sign-in"); err != nil {
+				return nil, err
+			}
+		}
+		if _, err := r.UserRepo.Update(ctx, user.ID, updates); err != nil {
+			return nil, err
+		}
+		user.EmailVerified = true
+	}
+
+	if err := r.OIDC.Link(ctx, user.ID, claims); err != nil {
+		return nil, fmt.Errorf("failed to link account: %w", err)
+	}
+	return user, nil
+}
+
+// registerUser creates a user with a generated avatar and a placeholder
+// username, both derived from seed; the user picks a username in
+// completeSetup.
+func (r *Resolver) registerUser(ctx context.Context, user users.User, seed string) (*users.User, error) {
+	avatarURL, err := auth.AvatarGenerationAndCleanup(seed, r.Uploader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate avatar: %w", err)
+	}
+
+	defaultUsername := fmt.Sprintf("user_%s", seed)
+	// Sanitize username
+	defaultUsername = regexp.MustCompile(`[^a-zA-Z0-9_.-]`).ReplaceAllString(defaultUsername, "")
+	// Simple random suffix
+	defaultUsername = fmt.Sprintf("%s_%d", defaultUsername, time.Now().UnixNano())
+
+	user.Username = defaultUsername
+	if user.DisplayName == "" {
+		user.DisplayName = user.Name
+	}
+	user.Avatar = avatarURL
+	user.CreatedAt = time.Now()
+	user.IsAdmin = false
+	user.IsBanned = false
+	user.SetupComplete = false
+
+	if err := r.UserRepo.Create(ctx, &user); err != nil {
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+	return &user, nil
+}
@@ -0,0 +1,31 @@
+package graph
+
+// This file will be automatically regenerated based on the schema, any resolver
+// implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.86
+
+import (
+	"context"
+	"errors"
+
+	"github.com/pranava-mohan/wikinitt/gravy/graph/model"
+	"github.com/pranava-mohan/wikinitt/gravy/internal/auth"
+	"github.com/pranava-mohan/wikinitt/gravy/internal/preview"
+)
+
+// LinkPreview is the resolver for the linkPreview field.
+func (r *queryResolver) LinkPreview(ctx context.Context, url string) (*model.LinkPreview, error) {
+	viewerID := ""
+	if user := auth.ForContext(ctx); user != nil {
+		viewerID = user.ID
+	}
+	p, err := r.Previews.ForURL(ctx, url, viewerID)
+	if errors.Is(err, preview.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return mapPreviewToModel(p), nil
+}
@@ -2,22 +2,46 @@ package graph
 
 import (
 	"github.com/pranava-mohan/wikinitt/gravy/internal/articles"
+	"github.com/pranava-mohan/wikinitt/gravy/internal/auth"
+	"github.com/pranava-mohan/wikinitt/gravy/internal/backlinks"
 	"github.com/pranava-mohan/wikinitt/gravy/internal/categories"
 	"github.com/pranava-mohan/wikinitt/gravy/internal/community"
+	"github.com/pranava-mohan/wikinitt/gravy/internal/export"
+	"github.com/pranava-mohan/wikinitt/gravy/internal/mailer"
 	"github.com/pranava-mohan/wikinitt/gravy/internal/maplocation"
+	"github.com/pranava-mohan/wikinitt/gravy/internal/oidc"
+	"github.com/pranava-mohan/wikinitt/gravy/internal/preview"
+	"github.com/pranava-mohan/wikinitt/gravy/internal/pubsub"
 	"github.com/pranava-mohan/wikinitt/gravy/internal/rag"
 	"github.com/pranava-mohan/wikinitt/gravy/internal/search"
+	"github.com/pranava-mohan/wikinitt/gravy/internal/twofactor"
 	"github.com/pranava-mohan/wikinitt/gravy/internal/uploader"
 	"github.com/pranava-mohan/wikinitt/gravy/internal/users"
+	"github.com/pranava-mohan/wikinitt/gravy/internal/views"
 )
 
 type Resolver struct {
 	UserRepo        users.Repository
+	UserTokenRepo   users.TokenRepository
+	Mailer          mailer.Mailer
+	Sessions        *auth.Sessions
+	OIDC            *oidc.Service // nil unless OIDC_ISSUER is set
+	SignUps         oidc.Config   // its AllowedDomains limit password and OIDC sign-ups alike
+	TwoFactor       *twofactor.Service
+	RequireAdmin2FA bool // admins need a second factor (REQUIRE_ADMIN_2FA)
 	ArticleRepo     articles.Repository
+	BacklinkJobRepo backlinks.Repository
+	BacklinkRunner  *backlinks.Runner
 	CategoryRepo    categories.Repository
 	CommunityRepo   community.Repository
 	Uploader        uploader.Uploader
 	SearchClient    *search.Client
 	MapLocationRepo maplocation.Repository
 	RagClient       rag.Client
+	Broker          pubsub.Broker
+	ViewRepo        views.Repository
+	ViewRecorder    *views.Recorder
+	Previews        *preview.Service
+	ExportRepo      export.Repository
+	ExportRunner    *export.Runner
 }
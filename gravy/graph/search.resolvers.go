@@ -8,6 +8,7 @@ package graph
 import (
 	"context"
 	"fmt"
+	"sort"
 
 	"github.com/pranava-mohan/wikinitt/gravy/graph/model"
 	"github.com/pranava-mohan/wikinitt/gravy/internal/community"
@@ -16,7 +17,7 @@ import (
 )
 
 // SearchArticles is the resolver for the searchArticles field.
-func (r *queryResolver) SearchArticles(ctx context.Context, query string, limit *int32, offset *int32) ([]*model.Article, error) {
+func (r *queryResolver) SearchArticles(ctx context.Context, query string, tags []string, limit *int32, offset *int32) ([]*model.Article, error) {
 	l := 10
 	if limit != nil {
 		l = int(*limit)
@@ -26,7 +27,7 @@ func (r *queryResolver) SearchArticles(ctx context.Context, query string, limit
 		o = int(*offset)
 	}
 
-	ids, err := r.SearchClient.SearchArticles(ctx, query, l, o)
+	ids, err := r.SearchClient.SearchArticles(ctx, query, normalizeTagFilter(tags), l, o)
 	if err != nil {
 		return nil, fmt.Errorf("search failed: %w", err)
 	}
@@ -38,6 +39,9 @@ func (r *queryResolver) SearchArticles(ctx context.Context, query string, limit
 
 	var result []*model.Article
 	for _, a := range articles {
+		if !a.IsPublished() {
+			continue
+		}
 		author, err := r.UserRepo.GetByID(ctx, a.AuthorID)
 		if err == nil {
 			a.Author = &users.PublicUser{
@@ -52,6 +56,26 @@ func (r *queryResolver) SearchArticles(ctx context.Context, query string, limit
 	return result, nil
 }
 
+// SearchArticleTags is the resolver for the searchArticleTags field.
+func (r *queryResolver) SearchArticleTags(ctx context.Context, query string, tags []string) ([]*model.Tag, error) {
+	facets, err := r.SearchClient.ArticleTagFacets(ctx, query, normalizeTagFilter(tags))
+	if err != nil {
+		return nil, fmt.Errorf("search failed: %w", err)
+	}
+
+	result := make([]*model.Tag, 0, len(facets))
+	for name, count := range facets {
+		result = append(result, mapTagToModel(name, count))
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result, nil
+}
+
 // SearchPosts is the resolver for the searchPosts field.
 func (r *queryResolver) SearchPosts(ctx context.Context, query string, limit *int32, offset *int32) ([]*model.Post, error) {
 	l := 10
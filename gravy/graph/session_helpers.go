@@ -0,0 +1,61 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pranava-mohan/wikinitt/gravy/graph/model"
+	"github.com/pranava-mohan/wikinitt/gravy/internal/auth"
+	"github.com/pranava-mohan/wikinitt/gravy/internal/users"
+)
+
+// startSession signs the user in on the requesting device.
+func (r *Resolver) startSession(ctx context.Context, user *users.User) (*model.AuthPayload, error) {
+	if user.IsBanned {
+		return nil, fmt.Errorf("account is banned")
+	}
+	tokens, err := r.Sessions.Start(ctx, user.ID, auth.ClientFromContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to start session: %w", err)
+	}
+	return mapTokensToModel(tokens), nil
+}
+
+// finishLogin signs the user in once their first factor is accepted, or
+// returns a challenge if they have a second factor to enter.
+func (r *Resolver) finishLogin(ctx context.Context, user *users.User) (*model.LoginResult, error) {
+	if user.IsBanned {
+		return nil, fmt.Errorf("account is banned")
+	}
+	if !user.TwoFactorEnabled {
+		payload, err := r.startSession(ctx, user)
+		if err != nil {
+			return nil, err
+		}
+		return &model.LoginResult{Auth: payload}, nil
+	}
+
+	token, expiresAt, err := r.TwoFactor.StartChallenge(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start two-factor challenge: %w", err)
+	}
+	return &model.LoginResult{Challenge: &model.TwoFactorChallenge{
+		Token:     token,
+		ExpiresAt: expiresAt.Format("2006-01-02 15:04:05"),
+	}}, nil
+}
+
+// legacySignIn starts a session for signIn, which has no way to ask for a
+// second factor, so users who have one must use the other logins.
+func (r *Resolver) legacySignIn(ctx context.Context, user *users.User) (*model.AuthPayload, error) {
+	if user.TwoFactorEnabled {
+		return nil, fmt.Errorf("this account uses two-factor authentication, sign in with login or completeOidcLogin")
+	}
+	return r.startSession(ctx, user)
+}
+
+// actsAsAdmin reports whether the user may use admin powers outside
+// @auth(requires: ADMIN) fields, which the directive checks the same way.
+func (r *Resolver) actsAsAdmin(user *users.User) bool {
+	return user.IsAdmin && (!r.RequireAdmin2FA || user.TwoFactorEnabled)
+}
@@ -0,0 +1,11 @@
+package graph
+
+import "time"
+
+// memberRecheckInterval is how often an open subscription re-verifies that
+// the subscriber is still a member of the group.
+const memberRecheckInterval = time.Minute
+
+func messageTopic(channelID string) string {
+	return "channel:" + channelID + ":messages"
+}
@@ -0,0 +1,127 @@
+package graph
+
+// This file will be automatically regenerated based on the schema, any resolver
+// implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.86
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/pranava-mohan/wikinitt/gravy/graph/model"
+	"github.com/pranava-mohan/wikinitt/gravy/internal/auth"
+	"github.com/pranava-mohan/wikinitt/gravy/internal/twofactor"
+)
+
+// BeginTwoFactorEnrollment is the resolver for the beginTwoFactorEnrollment field.
+func (r *mutationResolver) BeginTwoFactorEnrollment(ctx context.Context) (*model.TwoFactorEnrollment, error) {
+	user := auth.ForContext(ctx)
+	if user == nil {
+		return nil, fmt.Errorf("not authenticated")
+	}
+
+	enrollment, err := r.TwoFactor.BeginEnrollment(ctx, user.ID, user.Email)
+	if err != nil {
+		return nil, err
+	}
+	return &model.TwoFactorEnrollment{
+		Secret:     enrollment.Secret,
+		OtpauthURI: enrollment.URI,
+	}, nil
+}
+
+// ConfirmTwoFactorEnrollment is the resolver for the confirmTwoFactorEnrollment field.
+func (r *mutationResolver) ConfirmTwoFactorEnrollment(ctx context.Context, code string) ([]string, error) {
+	user := auth.ForContext(ctx)
+	if user == nil {
+		return nil, fmt.Errorf("not authenticated")
+	}
+
+	codes, err := r.TwoFactor.ConfirmEnrollment(ctx, user.ID, code)
+	if err != nil {
+		return nil, err
+	}
+	// The user's flag decides whether logins ask for a code, so the factor
+	// is removed again if the flag cannot be set.
+	if _, err := r.UserRepo.Update(ctx, user.ID, map[string]interface{}{"twoFactorEnabled": true}); err != nil {
+		if err := r.TwoFactor.Disable(ctx, user.ID); err != nil {
+			log.Printf("Failed to undo two-factor enrollment of user %s: %v", user.ID, err)
+		}
+		return nil, err
+	}
+	// Sessions started with only a password end here. A failure must not
+	// cost the user the recovery codes, which are only shown now.
+	if _, err := r.Sessions.RevokeOthers(ctx, user.ID, auth.SessionIDFromContext(ctx), "two-factor authentication enabled"); err != nil {
+		log.Printf("Failed to end other sessions of user %s after enabling two-factor authentication: %v", user.ID, err)
+	}
+	return codes, nil
+}
+
+// DisableTwoFactor is the resolver for the disableTwoFactor field.
+func (r *mutationResolver) DisableTwoFactor(ctx context.Context, code string) (bool, error) {
+	user := auth.ForContext(ctx)
+	if user == nil {
+		return false, fmt.Errorf("not authenticated")
+	}
+	if r.RequireAdmin2FA && user.IsAdmin {
+		return false, fmt.Errorf("admins must keep two-factor authentication on")
+	}
+
+	if err := r.TwoFactor.VerifySignedIn(ctx, user.ID, code); err != nil {
+		return false, err
+	}
+	// Clearing the flag first means logins never ask for a factor that is
+	// already gone; if removing the factor fails, the flag is set again.
+	if _, err := r.UserRepo.Update(ctx, user.ID, map[string]interface{}{"twoFactorEnabled": false}); err != nil {
+		return false, err
+	}
+	if err := r.TwoFactor.Disable(ctx, user.ID); err != nil {
+		if _, err := r.UserRepo.Update(ctx, user.ID, map[string]interface{}{"twoFactorEnabled": true}); err != nil {
+			log.Printf("Failed to restore two-factor flag of user %s: %v", user.ID, err)
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// RegenerateRecoveryCodes is the resolver for the regenerateRecoveryCodes field.
+func (r *mutationResolver) RegenerateRecoveryCodes(ctx context.Context, code string) ([]string, error) {
+	user := auth.ForContext(ctx)
+	if user == nil {
+		return nil, fmt.Errorf("not authenticated")
+	}
+
+	if err := r.TwoFactor.VerifySignedIn(ctx, user.ID, code); err != nil {
+		return nil, err
+	}
+	return r.TwoFactor.RegenerateRecoveryCodes(ctx, user.ID)
+}
+
+// CompleteTwoFactorLogin is the resolver for the completeTwoFactorLogin field.
+func (r *mutationResolver) CompleteTwoFactorLogin(ctx context.Context, challenge string, code string) (*model.AuthPayload, error) {
+	userID, err := r.TwoFactor.CompleteChallenge(ctx, challenge, code)
+	if err != nil {
+		return nil, err
+	}
+	user, err := r.UserRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, twofactor.ErrInvalidChallenge
+	}
+	return r.startSession(ctx, user)
+}
+
+// TwoFactorStatus is the resolver for the twoFactorStatus field.
+func (r *queryResolver) TwoFactorStatus(ctx context.Context) (*model.TwoFactorStatus, error) {
+	user := auth.ForContext(ctx)
+	if user == nil {
+		return nil, fmt.Errorf("not authenticated")
+	}
+
+	status, err := r.TwoFactor.Status(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	return mapTwoFactorStatusToModel(status, r.RequireAdmin2FA && user.IsAdmin), nil
+}
@@ -8,93 +8,295 @@ package graph
 import (
 	"context"
 	"fmt"
+	"log"
 	"os"
 	"regexp"
-	"time"
+	"strings"
 
 	"github.com/99designs/gqlgen/graphql"
 	"github.com/pranava-mohan/wikinitt/gravy/graph/model"
 	"github.com/pranava-mohan/wikinitt/gravy/internal/auth"
 	"github.com/pranava-mohan/wikinitt/gravy/internal/sanitization"
 	"github.com/pranava-mohan/wikinitt/gravy/internal/users"
+	"go.mongodb.org/mongo-driver/v2/mongo"
 	"golang.org/x/crypto/bcrypt"
 )
 
 // SignIn is the resolver for the signIn field.
-func (r *mutationResolver) SignIn(ctx context.Context, input model.NewUser) (string, error) {
-	if input.MachineToken != os.Getenv("MACHINE_TOKEN") {
-		return "", fmt.Errorf("invalid machine token")
+func (r *mutationResolver) SignIn(ctx context.Context, input model.NewUser) (*model.AuthPayload, error) {
+	machineToken := os.Getenv("MACHINE_TOKEN")
+	if machineToken == "" || input.MachineToken != machineToken {
+		return nil, fmt.Errorf("invalid machine token")
+	}
+	// Once OIDC is set up the server verifies identities itself and no
+	// longer takes the frontend's word for them.
+	if r.OIDC != nil {
+		return nil, fmt.Errorf("signIn is disabled, use beginOidcLogin and completeOidcLogin")
 	}
 
 	// 1. Try to find by OAuth ID (Unified)
 	existingUser, err := r.UserRepo.GetByOAuthID(ctx, input.ID)
 	if err == nil && existingUser != nil {
-		return auth.GenerateToken(existingUser.ID)
+		return r.legacySignIn(ctx, existingUser)
+	}
+
+	email, err := normalizeEmail(input.Email)
+	if err != nil {
+		return nil, err
 	}
 
 	// 2. Try to find by Email
-	existingUser, err = r.UserRepo.GetByEmail(ctx, input.Email)
+	existingUser, err = r.UserRepo.GetByEmail(ctx, email)
 	if err == nil && existingUser != nil {
 		// Link OAuth ID if not present
 		if existingUser.OAuthID == "" {
 			_, _ = r.UserRepo.Update(ctx, existingUser.ID, map[string]interface{}{"oauthId": input.ID})
 		}
-		return auth.GenerateToken(existingUser.ID)
+		return r.legacySignIn(ctx, existingUser)
 	}
 
 	// 3. Create New User
-	avatarURL, err := auth.AvatarGenerationAndCleanup(input.ID, r.Uploader)
+	if err := r.checkSignUpDomain(email); err != nil {
+		return nil, err
+	}
+	// The address comes from the caller, so it is only verified once its
+	// owner follows the link.
+	user, err := r.registerUser(ctx, users.User{
+		OAuthID:     input.ID,
+		Name:        input.Name,
+		Email:       email,
+		Gender:      input.Gender,
+		PhoneNumber: input.PhoneNumber,
+	}, input.ID)
 	if err != nil {
-		return "", fmt.Errorf("failed to generate avatar: %w", err)
+		return nil, err
+	}
+	if err := r.sendLinkEmail(ctx, user, users.TokenVerifyEmail); err != nil {
+		log.Printf("Failed to send verification email to user %s: %v", user.ID, err)
+	}
+
+	return r.startSession(ctx, user)
+}
 
+// Login is the resolver for the login field.
+func (r *mutationResolver) Login(ctx context.Context, input model.LoginInput) (*model.LoginResult, error) {
+	email, err := normalizeEmail(input.Email)
+	if err != nil {
+		return nil, fmt.Errorf("invalid credentials")
+	}
+	user, err := r.UserRepo.GetByEmail(ctx, email)
+	if err != nil {
+		return nil, fmt.Errorf("invalid credentials")
 	}
 
-	defaultUsername := fmt.Sprintf("user_%s", input.ID)
-	// Sanitize username
-	defaultUsername = regexp.MustCompile(`[^a-zA-Z0-9_.-]`).ReplaceAllString(defaultUsername, "")
-	// Simple random suffix
-	defaultUsername = fmt.Sprintf("%s_%d", defaultUsername, time.Now().UnixNano())
+	err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(input.Password))
+	if err != nil {
+		return nil, fmt.Errorf("invalid credentials")
+	}
 
-	user := users.User{
-		OAuthID:       input.ID,
-		Name:          input.Name,
-		Username:      defaultUsername,
-		DisplayName:   input.Name,
-		Email:         input.Email,
-		Gender:        input.Gender,
-		PhoneNumber:   input.PhoneNumber,
-		CreatedAt:     time.Now(),
-		IsAdmin:       false,
-		IsBanned:      false,
-		SetupComplete: false,
-		Avatar:        avatarURL,
+	// Until the address is verified, the account may belong to someone who
+	// merely typed in another person's address.
+	if !user.EmailVerified {
+		err := r.sendLinkEmail(ctx, user, users.TokenVerifyEmail)
+		if err != nil && err != users.ErrTokenRecentlyIssued {
+			log.Printf("Failed to send verification email to user %s: %v", user.ID, err)
+		}
+		return nil, fmt.Errorf("verify your email address before signing in, we sent you a link")
 	}
 
-	if err := r.UserRepo.Create(ctx, &user); err != nil {
-		return "", fmt.Errorf("failed to create user: %w", err)
+	return r.finishLogin(ctx, user)
+}
+
+// Register is the resolver for the register field.
+func (r *mutationResolver) Register(ctx context.Context, input model.RegisterInput) (bool, error) {
+	email, err := normalizeEmail(input.Email)
+	if err != nil {
+		return false, err
+	}
+	if err := r.checkSignUpDomain(email); err != nil {
+		return false, err
+	}
+	name := strings.TrimSpace(input.Name)
+	if name == "" || len(name) > 100 {
+		return false, fmt.Errorf("name must be between 1 and 100 characters")
+	}
+	if err := auth.CheckPasswordStrength(input.Password, name, email); err != nil {
+		return false, err
 	}
 
-	token, err := auth.GenerateToken(user.ID)
+	// Like requestPasswordReset, the result does not reveal whether the
+	// address has an account; its owner hears about the attempt instead.
+	if existing, err := r.UserRepo.GetByEmail(ctx, email); err == nil && existing != nil {
+		if err := r.sendAccountExistsEmail(existing); err != nil {
+			log.Printf("Failed to send account exists email to user %s: %v", existing.ID, err)
+		}
+		return true, nil
+	} else if err != nil && err != mongo.ErrNoDocuments {
+		return false, err
+	}
+
+	hash, err := auth.HashPassword(input.Password)
 	if err != nil {
-		return "", fmt.Errorf("failed to generate token: %w", err)
+		return false, fmt.Errorf("failed to hash password: %w", err)
+	}
+	user, err := r.registerUser(ctx, users.User{
+		Name:         name,
+		Email:        email,
+		Gender:       "unknown",
+		PasswordHash: hash,
+	}, randString(12))
+	if err != nil {
+		return false, err
 	}
 
-	return token, nil
+	if err := r.sendLinkEmail(ctx, user, users.TokenVerifyEmail); err != nil {
+		log.Printf("Failed to send verification email to user %s: %v", user.ID, err)
+	}
+	return true, nil
 }
 
-// Login is the resolver for the login field.
-func (r *mutationResolver) Login(ctx context.Context, input model.LoginInput) (string, error) {
-	user, err := r.UserRepo.GetByEmail(ctx, input.Email)
+// RequestPasswordReset is the resolver for the requestPasswordReset field.
+func (r *mutationResolver) RequestPasswordReset(ctx context.Context, email string) (bool, error) {
+	email, err := normalizeEmail(email)
+	if err != nil {
+		return true, nil
+	}
+	user, err := r.UserRepo.GetByEmail(ctx, email)
+	if err == mongo.ErrNoDocuments {
+		return true, nil
+	}
 	if err != nil {
-		return "", fmt.Errorf("invalid credentials")
+		return false, err
+	}
+	// Users who sign in through a provider have no password to reset.
+	if user.PasswordHash == "" || user.IsBanned {
+		return true, nil
 	}
 
-	err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(input.Password))
+	err = r.sendLinkEmail(ctx, user, users.TokenPasswordReset)
+	if err != nil && err != users.ErrTokenRecentlyIssued {
+		return false, err
+	}
+	return true, nil
+}
+
+// ResetPassword is the resolver for the resetPassword field.
+func (r *mutationResolver) ResetPassword(ctx context.Context, token string, newPassword string) (bool, error) {
+	// The token is only used up once the new password is accepted, so a
+	// weak password can be corrected without asking for another email.
+	pending, err := r.UserTokenRepo.Peek(ctx, token, users.TokenPasswordReset)
+	if err != nil {
+		return false, err
+	}
+	user, err := r.UserRepo.GetByID(ctx, pending.UserID)
+	if err != nil {
+		return false, users.ErrTokenInvalid
+	}
+	if err := auth.CheckPasswordStrength(newPassword, user.Name, user.DisplayName, user.Email); err != nil {
+		return false, err
+	}
+	hash, err := auth.HashPassword(newPassword)
+	if err != nil {
+		return false, fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	if _, err := r.UserTokenRepo.Consume(ctx, token, users.TokenPasswordReset); err != nil {
+		return false, err
+	}
+	updates := map[string]interface{}{"passwordHash": hash}
+	// Following the link proves the user reads mail at the address.
+	if pending.Email == user.Email {
+		updates["emailVerified"] = true
+	}
+	if _, err := r.UserRepo.Update(ctx, user.ID, updates); err != nil {
+		return false, err
+	}
+
+	if _, err := r.Sessions.RevokeAll(ctx, user.ID, "password reset"); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// VerifyEmail is the resolver for the verifyEmail field.
+func (r *mutationResolver) VerifyEmail(ctx context.Context, token string) (bool, error) {
+	verified, err := r.UserTokenRepo.Consume(ctx, token, users.TokenVerifyEmail)
+	if err != nil {
+		return false, err
+	}
+	user, err := r.UserRepo.GetByID(ctx, verified.UserID)
 	if err != nil {
-		return "", fmt.Errorf("invalid credentials")
+		return false, users.ErrTokenInvalid
+	}
+	// The address changed after the email was sent.
+	if user.Email != verified.Email {
+		return false, users.ErrTokenInvalid
+	}
+	if _, err := r.UserRepo.Update(ctx, user.ID, map[string]interface{}{"emailVerified": true}); err != nil {
+		return false, err
 	}
+	return true, nil
+}
 
-	return auth.GenerateToken(user.ID)
+// ResendVerificationEmail is the resolver for the resendVerificationEmail field.
+func (r *mutationResolver) ResendVerificationEmail(ctx context.Context) (bool, error) {
+	user := auth.ForContext(ctx)
+	if user == nil {
+		return false, fmt.Errorf("not authenticated")
+	}
+	if user.EmailVerified {
+		return false, fmt.Errorf("your email address is already verified")
+	}
+	if err := r.sendLinkEmail(ctx, user, users.TokenVerifyEmail); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// RefreshToken is the resolver for the refreshToken field.
+func (r *mutationResolver) RefreshToken(ctx context.Context, token string) (*model.AuthPayload, error) {
+	tokens, err := r.Sessions.Refresh(ctx, token, auth.ClientFromContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := r.UserRepo.GetByID(ctx, tokens.Session.UserID)
+	if err != nil || user.IsBanned {
+		if _, err := r.Sessions.Revoke(ctx, tokens.Session.UserID, tokens.Session.ID, "user unavailable"); err != nil {
+			return nil, err
+		}
+		return nil, auth.ErrSessionRevoked
+	}
+
+	return mapTokensToModel(tokens), nil
+}
+
+// Logout is the resolver for the logout field.
+func (r *mutationResolver) Logout(ctx context.Context, sessionID *string) (bool, error) {
+	user := auth.ForContext(ctx)
+	if user == nil {
+		return false, fmt.Errorf("not authenticated")
+	}
+
+	id := auth.SessionIDFromContext(ctx)
+	if sessionID != nil {
+		id = *sessionID
+	}
+	return r.Sessions.Revoke(ctx, user.ID, id, "logged out")
+}
+
+// LogoutAllSessions is the resolver for the logoutAllSessions field.
+func (r *mutationResolver) LogoutAllSessions(ctx context.Context) (int32, error) {
+	user := auth.ForContext(ctx)
+	if user == nil {
+		return 0, fmt.Errorf("not authenticated")
+	}
+
+	count, err := r.Sessions.RevokeAll(ctx, user.ID, "logged out everywhere")
+	if err != nil {
+		return 0, err
+	}
+	return int32(count), nil
 }
 
 // CompleteSetup is the resolver for the completeSetup field.
@@ -133,6 +335,9 @@ func (r *mutationResolver) BlockUser(ctx context.Context, id string) (bool, erro
 	if err != nil {
 		return false, err
 	}
+	if _, err := r.Sessions.RevokeAll(ctx, id, "user blocked"); err != nil {
+		return false, fmt.Errorf("user blocked but failed to end their sessions: %w", err)
+	}
 	return true, nil
 }
 
@@ -342,6 +547,26 @@ func (r *queryResolver) User(ctx context.Context, username string) (*model.Publi
 	return mapPublicUserToModel(mapUserToPublic(user)), nil
 }
 
+// MySessions is the resolver for the mySessions field.
+func (r *queryResolver) MySessions(ctx context.Context) ([]*model.Session, error) {
+	user := auth.ForContext(ctx)
+	if user == nil {
+		return nil, fmt.Errorf("not authenticated")
+	}
+
+	list, err := r.Sessions.List(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	current := auth.SessionIDFromContext(ctx)
+	modelSessions := []*model.Session{}
+	for _, s := range list {
+		modelSessions = append(modelSessions, mapSessionToModel(s, current))
+	}
+	return modelSessions, nil
+}
+
 // PublicUser returns PublicUserResolver implementation.
 func (r *Resolver) PublicUser() PublicUserResolver { return &publicUserResolver{r} }
 
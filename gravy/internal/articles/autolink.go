@@ -1,45 +1,193 @@
 package articles
 
 import (
-	"context"
-	"regexp"
-	"sort"
+	"strings"
+	"sync"
 )
 
-// AutoLinkContent replaces article titles found in content with "title (slug)"
-func AutoLinkContent(ctx context.Context, content string, repo Repository, currentSlug string) (string, error) {
-	// Get all titles once
-	titleToSlug, err := repo.GetAllTitles(ctx)
-	if err != nil {
-		return content, err
+// LinkTarget is the part of an article needed to link other articles to it.
+type LinkTarget struct {
+	ID    string `bson:"_id"`
+	Title string `bson:"title"`
+	Slug  string `bson:"slug"`
+}
+
+// Linker finds mentions of article titles in content. It keeps one
+// Aho-Corasick automaton for all titles, so a scan costs O(len(content))
+// however many articles exist, and is updated in place as articles are
+// published, renamed or removed. It is safe for concurrent use.
+type Linker struct {
+	mu      sync.RWMutex
+	m       *matcher
+	targets map[string]LinkTarget // by article ID
+	byKey   map[string][]string   // normalized title -> article IDs, oldest first
+}
+
+func NewLinker(targets []LinkTarget) *Linker {
+	l := &Linker{
+		m:       newMatcher(),
+		targets: make(map[string]LinkTarget, len(targets)),
+		byKey:   make(map[string][]string, len(targets)),
+	}
+	for _, t := range targets {
+		l.upsert(t)
+	}
+	return l
+}
+
+// Upsert adds a target or updates its title and slug.
+func (l *Linker) Upsert(t LinkTarget) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.upsert(t)
+}
+
+// Remove stops linking to the article with the given ID.
+func (l *Linker) Remove(id string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.remove(id)
+}
+
+func (l *Linker) upsert(t LinkTarget) {
+	key := NormalizeTitle(t.Title)
+	if old, ok := l.targets[t.ID]; ok && NormalizeTitle(old.Title) != key {
+		l.remove(t.ID)
+	}
+	if key == "" {
+		return
 	}
 
-	// Sort titles by length (longest first)
-	titles := make([]string, 0, len(titleToSlug))
-	for title := range titleToSlug {
-		titles = append(titles, title)
+	if _, ok := l.targets[t.ID]; !ok {
+		l.byKey[key] = append(l.byKey[key], t.ID)
 	}
-	sort.Slice(titles, func(i, j int) bool {
-		return len(titles[i]) > len(titles[j])
-	})
+	l.targets[t.ID] = t
+	l.m.add(key)
+}
 
-	result := content
+func (l *Linker) remove(id string) {
+	old, ok := l.targets[id]
+	if !ok {
+		return
+	}
+	delete(l.targets, id)
 
-	for _, title := range titles {
-		slug := titleToSlug[title]
+	key := NormalizeTitle(old.Title)
+	ids := l.byKey[key]
+	for i, other := range ids {
+		if other == id {
+			ids = append(ids[:i], ids[i+1:]...)
+			break
+		}
+	}
+	if len(ids) == 0 {
+		delete(l.byKey, key)
+		l.m.remove(key)
+	} else {
+		l.byKey[key] = ids
+	}
+}
 
-		if (slug == currentSlug) {
-			continue
+// scan runs the automaton over content, building it first if titles changed.
+func (l *Linker) scan(text []rune) []match {
+	for {
+		l.mu.RLock()
+		if !l.m.dirty {
+			matches := l.m.scanText(text)
+			l.mu.RUnlock()
+			return matches
+		}
+		l.mu.RUnlock()
+
+		l.mu.Lock()
+		if l.m.dirty {
+			l.m.build()
 		}
+		l.mu.Unlock()
+	}
+}
+
+// target picks the article a normalized title links to. When several
+// articles share a title the oldest one wins; sourceID never links to itself.
+func (l *Linker) target(key, sourceID string) (LinkTarget, bool) {
+	for _, id := range l.byKey[key] {
+		if id != sourceID {
+			return l.targets[id], true
+		}
+	}
+	return LinkTarget{}, false
+}
 
-		// Regex for whole-word, case-insensitive match
-		pattern := `(?i)\b` + regexp.QuoteMeta(title) + `\b`
-		re := regexp.MustCompile(pattern)
+// FindLinks returns one link from sourceID to every article mentioned in
+// content. The anchor is the first mention as written. Mentions inside
+// headings, code and existing links are ignored, and where mentions overlap
+// the longest title wins.
+func (l *Linker) FindLinks(content string, sourceID string) []Link {
+	text := []rune(content)
+	matches := l.scan(text)
 
-		result = re.ReplaceAllStringFunc(result, func(match string) string {
-			return match + " (" + slug + ")"
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	var links []Link
+	seen := make(map[string]bool)
+	for _, m := range matches {
+		t, ok := l.target(m.key, sourceID)
+		if !ok || seen[t.ID] {
+			continue
+		}
+		seen[t.ID] = true
+		links = append(links, Link{
+			SourceID:   sourceID,
+			TargetID:   t.ID,
+			TargetSlug: t.Slug,
+			Anchor:     string(text[m.start:m.end]),
 		})
 	}
+	return links
+}
+
+// FindLinks is a one-off scan of content against targets. Callers scanning
+// many documents should keep a Linker instead.
+func FindLinks(content string, targets []LinkTarget, sourceID string) []Link {
+	return NewLinker(targets).FindLinks(content, sourceID)
+}
+
+// RenderLinks turns every mention of a link's anchor into a Markdown link to
+// the target article. Stored content is never modified; this runs when the
+// article is read.
+func RenderLinks(content string, links []Link) string {
+	if len(links) == 0 {
+		return content
+	}
 
-	return result, nil
+	slugs := make(map[string]string, len(links))
+	m := newMatcher()
+	for _, l := range links {
+		key := NormalizeTitle(l.Anchor)
+		if key == "" {
+			continue
+		}
+		if _, ok := slugs[key]; !ok {
+			slugs[key] = l.TargetSlug
+			m.add(key)
+		}
+	}
+
+	text := []rune(content)
+	m.build()
+	matches := m.scanText(text)
+	if len(matches) == 0 {
+		return content
+	}
+
+	var b strings.Builder
+	pos := 0
+	for _, match := range matches {
+		b.WriteString(string(text[pos:match.start]))
+		b.WriteString("[" + string(text[match.start:match.end]) + "](/articles/" + slugs[match.key] + ")")
+		pos = match.end
+	}
+	b.WriteString(string(text[pos:]))
+	return b.String()
 }
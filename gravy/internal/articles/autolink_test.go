@@ -0,0 +1,301 @@
+package articles
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+)
+
+var testTargets = []LinkTarget{
+	{ID: "1", Title: "Octagon", Slug: "octagon"},
+	{ID: "2", Title: "Octagon Lab", Slug: "octagon-lab"},
+	{ID: "3", Title: "Golden  Jubilee Hall", Slug: "golden-jubilee-hall"},
+	{ID: "4", Title: "C++", Slug: "c-plus-plus"},
+	{ID: "5", Title: "Café", Slug: "cafe"},
+}
+
+func anchors(links []Link) map[string]string {
+	got := make(map[string]string)
+	for _, l := range links {
+		got[l.TargetID] = l.Anchor
+	}
+	return got
+}
+
+func TestFindLinks(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		source  string
+		want    map[string]string // target ID -> anchor
+	}{
+		{
+			name:    "case insensitive",
+			content: "Meet at the OCTAGON.",
+			want:    map[string]string{"1": "OCTAGON"},
+		},
+		{
+			name:    "longest title wins",
+			content: "Work in the octagon lab tonight.",
+			want:    map[string]string{"2": "octagon lab"},
+		},
+		{
+			name:    "whitespace collapsed",
+			content: "The golden\njubilee   hall is big.",
+			want:    map[string]string{"3": "golden\njubilee   hall"},
+		},
+		{
+			name:    "word boundaries",
+			content: "Octagonal shapes and octagons.",
+			want:    map[string]string{},
+		},
+		{
+			name:    "punctuation in titles",
+			content: "Learn C++ first.",
+			want:    map[string]string{"4": "C++"},
+		},
+		{
+			name:    "non-ASCII",
+			content: "Coffee at the café.",
+			want:    map[string]string{"5": "café"},
+		},
+		{
+			name:    "first mention is the anchor",
+			content: "octagon, then Octagon again",
+			want:    map[string]string{"1": "octagon"},
+		},
+		{
+			name:    "no self links",
+			content: "The Octagon is here.",
+			source:  "1",
+			want:    map[string]string{},
+		},
+		{
+			name:    "headings are skipped",
+			content: "# Octagon\n\nNothing else.",
+			want:    map[string]string{},
+		},
+		{
+			name:    "code is skipped",
+			content: "`octagon` and\n```\noctagon lab\n```\n",
+			want:    map[string]string{},
+		},
+		{
+			name:    "existing links are skipped",
+			content: "[the octagon](/x) and <a href=\"octagon\">x</a>",
+			want:    map[string]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := anchors(FindLinks(tt.content, testTargets, tt.source))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FindLinks(%q) = %v, want %v", tt.content, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLinkerUpdates(t *testing.T) {
+	l := NewLinker(testTargets[:1])
+	if got := anchors(l.FindLinks("octagon lab", "")); !reflect.DeepEqual(got, map[string]string{"1": "octagon"}) {
+		t.Fatalf("before upsert: %v", got)
+	}
+
+	l.Upsert(testTargets[1])
+	if got := anchors(l.FindLinks("octagon lab", "")); !reflect.DeepEqual(got, map[string]string{"2": "octagon lab"}) {
+		t.Fatalf("after upsert: %v", got)
+	}
+
+	l.Upsert(LinkTarget{ID: "2", Title: "Orion", Slug: "orion"})
+	if got := anchors(l.FindLinks("octagon lab near orion", "")); !reflect.DeepEqual(got, map[string]string{"1": "octagon", "2": "orion"}) {
+		t.Fatalf("after rename: %v", got)
+	}
+
+	l.Remove("1")
+	if got := anchors(l.FindLinks("octagon lab near orion", "")); !reflect.DeepEqual(got, map[string]string{"2": "orion"}) {
+		t.Fatalf("after remove: %v", got)
+	}
+}
+
+func TestLinkerSharedTitle(t *testing.T) {
+	l := NewLinker([]LinkTarget{
+		{ID: "a", Title: "Library", Slug: "library"},
+		{ID: "b", Title: "library", Slug: "library-2"},
+	})
+	if got := l.FindLinks("the library", ""); len(got) != 1 || got[0].TargetID != "a" {
+		t.Fatalf("oldest article should win: %v", got)
+	}
+	if got := l.FindLinks("the library", "a"); len(got) != 1 || got[0].TargetID != "b" {
+		t.Fatalf("article should not link to itself: %v", got)
+	}
+
+	l.Remove("a")
+	if got := l.FindLinks("the library", ""); len(got) != 1 || got[0].TargetID != "b" {
+		t.Fatalf("title should stay linked while another article has it: %v", got)
+	}
+}
+
+func TestRenderLinks(t *testing.T) {
+	links := []Link{
+		{TargetID: "1", TargetSlug: "octagon", Anchor: "Octagon"},
+		{TargetID: "2", TargetSlug: "octagon-lab", Anchor: "octagon lab"},
+	}
+	tests := []struct {
+		content string
+		want    string
+	}{
+		{"No links here.", "No links here."},
+		{"The Octagon and the octagon.", "The [Octagon](/articles/octagon) and the [octagon](/articles/octagon)."},
+		{"In the Octagon Lab.", "In the [Octagon Lab](/articles/octagon-lab)."},
+		{"# Octagon\n\n`octagon`", "# Octagon\n\n`octagon`"},
+		{"[Octagon](/elsewhere)", "[Octagon](/elsewhere)"},
+	}
+	for _, tt := range tests {
+		if got := RenderLinks(tt.content, links); got != tt.want {
+			t.Errorf("RenderLinks(%q) = %q, want %q", tt.content, got, tt.want)
+		}
+	}
+	if got := RenderLinks("Octagon", nil); got != "Octagon" {
+		t.Errorf("RenderLinks without links = %q", got)
+	}
+}
+
+func TestLiveLinks(t *testing.T) {
+	links := []*Link{
+		{TargetID: "1", TargetSlug: "old-slug", Anchor: "Octagon"},
+		{TargetID: "2", TargetSlug: "octagon-lab", Anchor: "Octagon Lab"},
+		{TargetID: "3", TargetSlug: "archived", Anchor: "Archived"},
+		{TargetID: "4", TargetSlug: "gone", Anchor: "Gone"},
+	}
+	targets := []*Article{
+		{ID: "1", Slug: "octagon", Status: StatusPublished},
+		{ID: "2", Slug: "octagon-lab", Status: StatusDraft},
+		{ID: "3", Slug: "archived", Status: StatusArchived},
+	}
+
+	got := LiveLinks(links, targets)
+	want := []Link{{TargetID: "1", TargetSlug: "octagon", Anchor: "Octagon"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("LiveLinks = %v, want %v", got, want)
+	}
+	if links[0].TargetSlug != "old-slug" {
+		t.Error("LiveLinks modified the stored link")
+	}
+
+	rendered := RenderLinks("The Octagon Lab is in the Octagon. Archived. Gone.", got)
+	if rendered != "The [Octagon](/articles/octagon) Lab is in the [Octagon](/articles/octagon). Archived. Gone." {
+		t.Errorf("RenderLinks with live links = %q", rendered)
+	}
+}
+
+var benchWords = []string{
+	"hostel", "mess", "library", "octagon", "festember", "pragyan", "campus",
+	"department", "lab", "guide", "events", "sports", "bus", "hospital",
+	"orion", "barn", "lecture", "hall", "club", "garnet", "agate", "opal",
+}
+
+// legacyAutoLink is the implementation Linker replaced: one regex compiled
+// and applied per title, longest titles first.
+func legacyAutoLink(content string, titleToSlug map[string]string) string {
+	titles := make([]string, 0, len(titleToSlug))
+	for title := range titleToSlug {
+		titles = append(titles, title)
+	}
+	sort.Slice(titles, func(i, j int) bool {
+		return len(titles[i]) > len(titles[j])
+	})
+
+	result := content
+	for _, title := range titles {
+		slug := titleToSlug[title]
+		re := regexp.MustCompile(`(?i)\b` + regexp.QuoteMeta(title) + `\b`)
+		result = re.ReplaceAllStringFunc(result, func(match string) string {
+			return match + " (" + slug + ")"
+		})
+	}
+	return result
+}
+
+func benchTargets(rng *rand.Rand, n int) []LinkTarget {
+	targets := make([]LinkTarget, n)
+	for i := range targets {
+		title := fmt.Sprintf("%s %s %d", benchWords[rng.Intn(len(benchWords))], benchWords[rng.Intn(len(benchWords))], i)
+		targets[i] = LinkTarget{
+			ID:    fmt.Sprintf("%024x", i),
+			Title: title,
+			Slug:  strings.ReplaceAll(title, " ", "-"),
+		}
+	}
+	return targets
+}
+
+// benchContent writes roughly size bytes of prose that mentions some titles.
+func benchContent(rng *rand.Rand, targets []LinkTarget, size int) string {
+	var b strings.Builder
+	for b.Len() < size {
+		if rng.Intn(20) == 0 {
+			b.WriteString(targets[rng.Intn(len(targets))].Title)
+		} else {
+			b.WriteString(benchWords[rng.Intn(len(benchWords))])
+		}
+		b.WriteString(" ")
+		if rng.Intn(15) == 0 {
+			b.WriteString("\n\n")
+		}
+	}
+	return b.String()
+}
+
+// runAutoLinkBenchmarks runs fn for each combination of title count and
+// content size, e.g. BenchmarkLinkerCached/titles=1000/content=16KB.
+func runAutoLinkBenchmarks(b *testing.B, fn func(b *testing.B, targets []LinkTarget, content string)) {
+	for _, n := range []int{100, 1000, 5000} {
+		for _, size := range []int{2 << 10, 16 << 10} {
+			rng := rand.New(rand.NewSource(1))
+			targets := benchTargets(rng, n)
+			content := benchContent(rng, targets, size)
+			b.Run(fmt.Sprintf("titles=%d/content=%dKB", n, size>>10), func(b *testing.B) {
+				b.ReportAllocs()
+				fn(b, targets, content)
+			})
+		}
+	}
+}
+
+func BenchmarkLegacyRegexAutoLink(b *testing.B) {
+	runAutoLinkBenchmarks(b, func(b *testing.B, targets []LinkTarget, content string) {
+		titleToSlug := make(map[string]string, len(targets))
+		for _, t := range targets {
+			titleToSlug[strings.ToLower(t.Title)] = t.Slug
+		}
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			legacyAutoLink(content, titleToSlug)
+		}
+	})
+}
+
+func BenchmarkLinkerCached(b *testing.B) {
+	runAutoLinkBenchmarks(b, func(b *testing.B, targets []LinkTarget, content string) {
+		linker := NewLinker(targets)
+		linker.FindLinks("", "")
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			linker.FindLinks(content, "")
+		}
+	})
+}
+
+func BenchmarkLinkerBuildAndScan(b *testing.B) {
+	runAutoLinkBenchmarks(b, func(b *testing.B, targets []LinkTarget, content string) {
+		for i := 0; i < b.N; i++ {
+			NewLinker(targets).FindLinks(content, "")
+		}
+	})
+}
@@ -0,0 +1,90 @@
+package articles
+
+import (
+	"context"
+	"log"
+
+	"github.com/pranava-mohan/wikinitt/gravy/internal/versioning"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+func (r *repository) Count(ctx context.Context, filter ListFilter) (int64, error) {
+	return r.coll.CountDocuments(ctx, filter.query())
+}
+
+// MoveCategory moves every article in category from to category to. It is
+// used both to cascade a category rename and to reassign articles before a
+// category is deleted. The moved articles are marked unindexed so that the
+// background indexer picks them up if re-indexing here fails.
+func (r *repository) MoveCategory(ctx context.Context, from string, to string) (int64, error) {
+	if from == to {
+		return 0, nil
+	}
+
+	var moved []Article
+	cursor, err := r.coll.Find(ctx, bson.M{"category": from}, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return 0, err
+	}
+	if err := cursor.All(ctx, &moved); err != nil {
+		return 0, err
+	}
+	if len(moved) == 0 {
+		return 0, nil
+	}
+
+	ids := make([]bson.ObjectID, 0, len(moved))
+	for _, a := range moved {
+		if oid, err := bson.ObjectIDFromHex(a.ID); err == nil {
+			ids = append(ids, oid)
+		}
+	}
+	res, err := r.coll.UpdateMany(ctx,
+		bson.M{"_id": bson.M{"$in": ids}},
+		bson.M{
+			"$set": bson.M{"category": to, "indexed": false},
+			"$inc": bson.M{versioning.Field: 1},
+		},
+	)
+	if err != nil {
+		return 0, err
+	}
+	r.invalidateRelated()
+
+	r.reindex(ctx, ids)
+	return res.ModifiedCount, nil
+}
+
+// reindex pushes the published articles among ids to Meilisearch in one
+// batch.
+func (r *repository) reindex(ctx context.Context, ids []bson.ObjectID) {
+	filter := bson.M{"_id": bson.M{"$in": ids}, "status": statusFilter(StatusPublished)}
+	cursor, err := r.coll.Find(ctx, filter)
+	if err != nil {
+		log.Printf("Failed to load articles for re-indexing: %v", err)
+		return
+	}
+	var articles []*Article
+	if err := cursor.All(ctx, &articles); err != nil {
+		log.Printf("Failed to load articles for re-indexing: %v", err)
+		return
+	}
+	if len(articles) == 0 {
+		return
+	}
+
+	docs := make([]map[string]interface{}, 0, len(articles))
+	indexed := make([]bson.ObjectID, 0, len(articles))
+	for _, a := range articles {
+		docs = append(docs, SearchDocument(a))
+		if oid, err := bson.ObjectIDFromHex(a.ID); err == nil {
+			indexed = append(indexed, oid)
+		}
+	}
+	if err := r.searchClient.IndexArticles(ctx, docs); err != nil {
+		log.Printf("Failed to re-index articles: %v", err)
+		return
+	}
+	_, _ = r.coll.UpdateMany(ctx, bson.M{"_id": bson.M{"$in": indexed}}, bson.M{"$set": bson.M{"indexed": true}})
+}
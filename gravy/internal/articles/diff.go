@@ -0,0 +1,211 @@
+package articles
+
+import (
+	"strings"
+	"unicode"
+)
+
+type DiffOp string
+
+const (
+	DiffEqual  DiffOp = "EQUAL"
+	DiffInsert DiffOp = "INSERT"
+	DiffDelete DiffOp = "DELETE"
+)
+
+type DiffChunk struct {
+	Op   DiffOp
+	Text string
+}
+
+type Diff struct {
+	Chunks    []DiffChunk
+	Additions int
+	Deletions int
+}
+
+// maxDiffEdits bounds the Myers search. Past it the changed region is reported
+// as a single delete + insert instead of eating memory on unrelated texts.
+const maxDiffEdits = 2000
+
+// DiffLines compares two texts line by line.
+func DiffLines(from, to string) Diff {
+	return diffTokens(splitLines(from), splitLines(to))
+}
+
+// DiffWords compares two texts word by word, keeping whitespace as its own token.
+func DiffWords(from, to string) Diff {
+	return diffTokens(splitWords(from), splitWords(to))
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+func splitWords(s string) []string {
+	var tokens []string
+	start := 0
+	prevSpace := false
+	for i, r := range s {
+		space := unicode.IsSpace(r)
+		if i > 0 && space != prevSpace {
+			tokens = append(tokens, s[start:i])
+			start = i
+		}
+		prevSpace = space
+	}
+	if start < len(s) {
+		tokens = append(tokens, s[start:])
+	}
+	return tokens
+}
+
+func diffTokens(a, b []string) Diff {
+	var d Diff
+
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	d.add(DiffEqual, a[:prefix])
+
+	midA, midB := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	ops, ok := myers(midA, midB)
+	if !ok {
+		d.add(DiffDelete, midA)
+		d.add(DiffInsert, midB)
+	} else {
+		for _, op := range ops {
+			switch op.op {
+			case DiffEqual, DiffDelete:
+				d.add(op.op, midA[op.index:op.index+1])
+			case DiffInsert:
+				d.add(op.op, midB[op.index:op.index+1])
+			}
+		}
+	}
+
+	d.add(DiffEqual, a[len(a)-suffix:])
+	return d
+}
+
+func (d *Diff) add(op DiffOp, tokens []string) {
+	if len(tokens) == 0 {
+		return
+	}
+	switch op {
+	case DiffInsert:
+		d.Additions += len(tokens)
+	case DiffDelete:
+		d.Deletions += len(tokens)
+	}
+
+	text := strings.Join(tokens, "")
+	if n := len(d.Chunks); n > 0 && d.Chunks[n-1].Op == op {
+		d.Chunks[n-1].Text += text
+		return
+	}
+	d.Chunks = append(d.Chunks, DiffChunk{Op: op, Text: text})
+}
+
+type editOp struct {
+	op    DiffOp
+	index int // into a for EQUAL/DELETE, into b for INSERT
+}
+
+// myers returns the shortest edit script turning a into b, or false when
+// more than maxDiffEdits edits would be needed.
+func myers(a, b []string) ([]editOp, bool) {
+	n, m := len(a), len(b)
+	if n == 0 && m == 0 {
+		return nil, true
+	}
+
+	limit := n + m
+	if limit > maxDiffEdits {
+		limit = maxDiffEdits
+	}
+	offset := limit + 1
+	v := make([]int, 2*limit+3)
+
+	// trace[d] holds v[-d..d] as it was before step d.
+	var trace [][]int
+	for d := 0; d <= limit; d++ {
+		snapshot := make([]int, 2*d+1)
+		copy(snapshot, v[offset-d:offset+d+1])
+		trace = append(trace, snapshot)
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrack(trace, n, m), true
+			}
+		}
+	}
+	return nil, false
+}
+
+func backtrack(trace [][]int, n, m int) []editOp {
+	var ops []editOp
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		at := func(k int) int { return v[k+d] }
+
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+
+		prevX := 0
+		if d > 0 {
+			prevX = at(prevK)
+		}
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			x--
+			y--
+			ops = append(ops, editOp{op: DiffEqual, index: x})
+		}
+		if d > 0 {
+			if x == prevX {
+				ops = append(ops, editOp{op: DiffInsert, index: y - 1})
+			} else {
+				ops = append(ops, editOp{op: DiffDelete, index: x - 1})
+			}
+		}
+		x, y = prevX, prevY
+	}
+
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
@@ -0,0 +1,183 @@
+package articles
+
+import (
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// sides rebuilds the two texts a diff was computed from.
+func sides(d Diff) (from, to string) {
+	var a, b strings.Builder
+	for _, c := range d.Chunks {
+		if c.Op != DiffInsert {
+			a.WriteString(c.Text)
+		}
+		if c.Op != DiffDelete {
+			b.WriteString(c.Text)
+		}
+	}
+	return a.String(), b.String()
+}
+
+func TestDiffLines(t *testing.T) {
+	tests := []struct {
+		name       string
+		from, to   string
+		want       []DiffChunk
+		adds, dels int
+	}{
+		{
+			name: "both empty",
+		},
+		{
+			name: "identical",
+			from: "a\nb\n",
+			to:   "a\nb\n",
+			want: []DiffChunk{{DiffEqual, "a\nb\n"}},
+		},
+		{
+			name: "from empty",
+			to:   "a\nb\n",
+			want: []DiffChunk{{DiffInsert, "a\nb\n"}},
+			adds: 2,
+		},
+		{
+			name: "to empty",
+			from: "a\nb\n",
+			want: []DiffChunk{{DiffDelete, "a\nb\n"}},
+			dels: 2,
+		},
+		{
+			name: "line changed in the middle",
+			from: "a\nb\nc\n",
+			to:   "a\nx\nc\n",
+			want: []DiffChunk{{DiffEqual, "a\n"}, {DiffDelete, "b\n"}, {DiffInsert, "x\n"}, {DiffEqual, "c\n"}},
+			adds: 1,
+			dels: 1,
+		},
+		{
+			name: "line inserted",
+			from: "a\nc\n",
+			to:   "a\nb\nc\n",
+			want: []DiffChunk{{DiffEqual, "a\n"}, {DiffInsert, "b\n"}, {DiffEqual, "c\n"}},
+			adds: 1,
+		},
+		{
+			name: "missing final newline",
+			from: "a\nb",
+			to:   "a\nb\n",
+			want: []DiffChunk{{DiffEqual, "a\n"}, {DiffDelete, "b"}, {DiffInsert, "b\n"}},
+			adds: 1,
+			dels: 1,
+		},
+		{
+			// The classic example from Myers' paper: ABCABBA -> CBABAC
+			// takes five edits.
+			name: "shortest edit script",
+			from: "A\nB\nC\nA\nB\nB\nA\n",
+			to:   "C\nB\nA\nB\nA\nC\n",
+			adds: 2,
+			dels: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := DiffLines(tt.from, tt.to)
+			if tt.want != nil && !reflect.DeepEqual(d.Chunks, tt.want) {
+				t.Errorf("chunks = %q, want %q", d.Chunks, tt.want)
+			}
+			if d.Additions != tt.adds || d.Deletions != tt.dels {
+				t.Errorf("+%d -%d, want +%d -%d", d.Additions, d.Deletions, tt.adds, tt.dels)
+			}
+			if from, to := sides(d); from != tt.from || to != tt.to {
+				t.Errorf("diff does not rebuild its inputs: %q, %q", from, to)
+			}
+		})
+	}
+}
+
+func TestDiffWords(t *testing.T) {
+	d := DiffWords("the quick brown fox", "the slow brown  fox")
+	want := []DiffChunk{
+		{DiffEqual, "the "},
+		{DiffDelete, "quick"},
+		{DiffInsert, "slow"},
+		{DiffEqual, " brown"},
+		{DiffDelete, " "},
+		{DiffInsert, "  "},
+		{DiffEqual, "fox"},
+	}
+	if !reflect.DeepEqual(d.Chunks, want) {
+		t.Errorf("chunks = %q, want %q", d.Chunks, want)
+	}
+}
+
+func TestDiffRandom(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	randomText := func() string {
+		var b strings.Builder
+		for i := rng.Intn(40); i > 0; i-- {
+			b.WriteString(string(rune('a' + rng.Intn(4))))
+			b.WriteString("\n")
+		}
+		return b.String()
+	}
+	for i := 0; i < 500; i++ {
+		from, to := randomText(), randomText()
+		d := DiffLines(from, to)
+		if gotFrom, gotTo := sides(d); gotFrom != from || gotTo != to {
+			t.Fatalf("diff of %q and %q rebuilds %q and %q", from, to, gotFrom, gotTo)
+		}
+		if lcs := lcsLength(splitLines(from), splitLines(to)); d.Deletions != len(splitLines(from))-lcs || d.Additions != len(splitLines(to))-lcs {
+			t.Fatalf("diff of %q and %q is not minimal: +%d -%d", from, to, d.Additions, d.Deletions)
+		}
+	}
+}
+
+// lcsLength is the textbook dynamic programme, to check the edit script
+// myers finds is a shortest one.
+func lcsLength(a, b []string) int {
+	prev := make([]int, len(b)+1)
+	for i := range a {
+		cur := make([]int, len(b)+1)
+		for j := range b {
+			switch {
+			case a[i] == b[j]:
+				cur[j+1] = prev[j] + 1
+			case prev[j+1] > cur[j]:
+				cur[j+1] = prev[j+1]
+			default:
+				cur[j+1] = cur[j]
+			}
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+func TestDiffEditLimit(t *testing.T) {
+	var a, b strings.Builder
+	for i := 0; i < maxDiffEdits; i++ {
+		a.WriteString("a\n")
+		b.WriteString("b\n")
+	}
+	from := "same\n" + a.String() + "end\n"
+	to := "same\n" + b.String() + "end\n"
+
+	d := DiffLines(from, to)
+	want := []DiffChunk{
+		{DiffEqual, "same\n"},
+		{DiffDelete, a.String()},
+		{DiffInsert, b.String()},
+		{DiffEqual, "end\n"},
+	}
+	if !reflect.DeepEqual(d.Chunks, want) {
+		t.Errorf("unrelated texts past the edit limit should become one delete and one insert, got %d chunks", len(d.Chunks))
+	}
+	if d.Additions != maxDiffEdits || d.Deletions != maxDiffEdits {
+		t.Errorf("+%d -%d, want +%d -%d", d.Additions, d.Deletions, maxDiffEdits, maxDiffEdits)
+	}
+}
@@ -0,0 +1,178 @@
+package articles
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// Link records that the source article mentions the target article's title.
+// Links live in their own collection so article content stays exactly as
+// the author wrote it.
+type Link struct {
+	ID         string    `bson:"_id,omitempty"`
+	SourceID   string    `bson:"sourceId"`
+	TargetID   string    `bson:"targetId"`
+	TargetSlug string    `bson:"targetSlug"`
+	Anchor     string    `bson:"anchor"`
+	CreatedAt  time.Time `bson:"createdAt"`
+}
+
+// ListLinkTargets returns the title and slug of every published article.
+func (r *repository) ListLinkTargets(ctx context.Context) ([]LinkTarget, error) {
+	projection := bson.M{"title": 1, "slug": 1}
+	filter := bson.M{"status": statusFilter(StatusPublished)}
+
+	cursor, err := r.coll.Find(ctx, filter, options.Find().SetProjection(projection))
+	if err != nil {
+		return nil, err
+	}
+
+	var targets []LinkTarget
+	if err := cursor.All(ctx, &targets); err != nil {
+		return nil, err
+	}
+	return targets, nil
+}
+
+// linkerRefreshInterval bounds how stale the cached Linker can get when
+// other server replicas publish or rename articles.
+const linkerRefreshInterval = 10 * time.Minute
+
+// getLinker returns the cached Linker, loading it from the database on first
+// use and periodically afterwards.
+func (r *repository) getLinker(ctx context.Context) (*Linker, error) {
+	r.linkerMu.Lock()
+	defer r.linkerMu.Unlock()
+
+	if r.linker == nil || time.Since(r.linkerLoadedAt) > linkerRefreshInterval {
+		targets, err := r.ListLinkTargets(ctx)
+		if err != nil {
+			return nil, err
+		}
+		r.linker = NewLinker(targets)
+		r.linkerLoadedAt = time.Now()
+	}
+	return r.linker, nil
+}
+
+// updateLinker keeps the cached Linker in step with a saved article: only
+// published articles are link targets.
+func (r *repository) updateLinker(a *Article) {
+	r.linkerMu.Lock()
+	defer r.linkerMu.Unlock()
+
+	if r.linker == nil {
+		return
+	}
+	if a.IsPublished() {
+		r.linker.Upsert(LinkTarget{ID: a.ID, Title: a.Title, Slug: a.Slug})
+	} else {
+		r.linker.Remove(a.ID)
+	}
+}
+
+func (r *repository) removeFromLinker(id string) {
+	r.linkerMu.Lock()
+	defer r.linkerMu.Unlock()
+
+	if r.linker != nil {
+		r.linker.Remove(id)
+	}
+}
+
+// syncLinks re-extracts the outgoing links of an article after a save and
+// replaces whatever was stored for it before.
+func (r *repository) syncLinks(ctx context.Context, a *Article) error {
+	linker, err := r.getLinker(ctx)
+	if err != nil {
+		return err
+	}
+
+	if _, err := r.links.DeleteMany(ctx, bson.M{"sourceId": a.ID}); err != nil {
+		return err
+	}
+	return r.AddLinks(ctx, linker.FindLinks(a.Content, a.ID))
+}
+
+// AddLinks stores links, keeping a single row per source/target pair.
+func (r *repository) AddLinks(ctx context.Context, links []Link) error {
+	if len(links) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	models := make([]mongo.WriteModel, 0, len(links))
+	for _, l := range links {
+		models = append(models, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"sourceId": l.SourceID, "targetId": l.TargetID}).
+			SetUpdate(bson.M{
+				"$set":         bson.M{"targetSlug": l.TargetSlug, "anchor": l.Anchor},
+				"$setOnInsert": bson.M{"createdAt": now},
+			}).
+			SetUpsert(true))
+	}
+
+	if _, err := r.links.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false)); err != nil {
+		return err
+	}
+	r.invalidateRelated()
+	return nil
+}
+
+// ListBacklinks returns the links pointing at an article ("what links here").
+func (r *repository) ListBacklinks(ctx context.Context, articleID string) ([]*Link, error) {
+	return r.findLinks(ctx, bson.M{"targetId": articleID})
+}
+
+func (r *repository) ListOutgoingLinks(ctx context.Context, articleID string) ([]*Link, error) {
+	return r.findLinks(ctx, bson.M{"sourceId": articleID})
+}
+
+func (r *repository) findLinks(ctx context.Context, filter bson.M) ([]*Link, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}})
+	cursor, err := r.links.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var links []*Link
+	if err := cursor.All(ctx, &links); err != nil {
+		return nil, err
+	}
+	return links, nil
+}
+
+func (r *repository) ensureLinkIndexes(ctx context.Context) error {
+	_, err := r.links.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "sourceId", Value: 1}, {Key: "targetId", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{Keys: bson.D{{Key: "targetId", Value: 1}}},
+	})
+	return err
+}
+
+// LiveLinks keeps the links whose target is among targets and still
+// published, pointing each at the target's current slug.
+func LiveLinks(links []*Link, targets []*Article) []Link {
+	slugs := make(map[string]string, len(targets))
+	for _, t := range targets {
+		if t.IsPublished() {
+			slugs[t.ID] = t.Slug
+		}
+	}
+	live := make([]Link, 0, len(links))
+	for _, l := range links {
+		if slug, ok := slugs[l.TargetID]; ok {
+			link := *l
+			link.TargetSlug = slug
+			live = append(live, link)
+		}
+	}
+	return live
+}
@@ -0,0 +1,397 @@
+package articles
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// matcher is an Aho-Corasick automaton over normalized (lower-cased,
+// whitespace-collapsed) titles. Titles can be added and removed without
+// rebuilding the trie; the failure links are recomputed lazily on the next
+// search after a change, which is a single pass over the trie.
+type matcher struct {
+	nodes []acNode
+	keys  map[string]int32 // normalized title -> terminal node
+	live  map[int32]bool   // terminal nodes whose title is currently wanted
+	dirty bool
+}
+
+type acNode struct {
+	children map[rune]int32
+	fail     int32
+	depth    int     // length of the path to this node, in runes
+	key      string  // normalized title ending here, if any
+	outputs  []int32 // live terminal nodes reachable via fail links, longest first
+}
+
+// match is a hit in rune offsets, [start, end).
+type match struct {
+	start, end int
+	key        string
+}
+
+func newMatcher() *matcher {
+	return &matcher{
+		nodes: []acNode{{}},
+		keys:  make(map[string]int32),
+		live:  make(map[int32]bool),
+	}
+}
+
+// NormalizeTitle lower-cases a title and collapses its whitespace, which is
+// the form titles are matched and de-duplicated in.
+func NormalizeTitle(title string) string {
+	return strings.Join(strings.Fields(strings.ToLower(title)), " ")
+}
+
+func (m *matcher) add(key string) {
+	if n, ok := m.keys[key]; ok {
+		if !m.live[n] {
+			m.live[n] = true
+			m.dirty = true
+		}
+		return
+	}
+
+	var cur int32
+	for _, r := range key {
+		next, ok := m.nodes[cur].children[r]
+		if !ok {
+			next = int32(len(m.nodes))
+			m.nodes = append(m.nodes, acNode{depth: m.nodes[cur].depth + 1})
+			if m.nodes[cur].children == nil {
+				m.nodes[cur].children = make(map[rune]int32)
+			}
+			m.nodes[cur].children[r] = next
+		}
+		cur = next
+	}
+	m.nodes[cur].key = key
+	m.keys[key] = cur
+	m.live[cur] = true
+	m.dirty = true
+}
+
+func (m *matcher) remove(key string) {
+	if n, ok := m.keys[key]; ok && m.live[n] {
+		delete(m.live, n)
+		m.dirty = true
+	}
+}
+
+// build recomputes failure links and output lists breadth-first.
+func (m *matcher) build() {
+	queue := []int32{0}
+	m.nodes[0].fail = 0
+	m.nodes[0].outputs = nil
+
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+
+		for r, child := range m.nodes[cur].children {
+			fail := int32(0)
+			if cur != 0 {
+				f := m.nodes[cur].fail
+				for {
+					if next, ok := m.nodes[f].children[r]; ok {
+						fail = next
+						break
+					}
+					if f == 0 {
+						break
+					}
+					f = m.nodes[f].fail
+				}
+			}
+			m.nodes[child].fail = fail
+
+			var outputs []int32
+			if m.live[child] {
+				outputs = append(outputs, child)
+			}
+			m.nodes[child].outputs = append(outputs, m.nodes[fail].outputs...)
+
+			queue = append(queue, child)
+		}
+	}
+	m.dirty = false
+}
+
+// findAll returns every live title occurring in folded text on word
+// boundaries, outside protected regions.
+func (m *matcher) findAll(text []rune, protected []bool) []match {
+	var matches []match
+	var state int32
+	for i, r := range text {
+		for {
+			if next, ok := m.nodes[state].children[r]; ok {
+				state = next
+				break
+			}
+			if state == 0 {
+				break
+			}
+			state = m.nodes[state].fail
+		}
+
+		for _, out := range m.nodes[state].outputs {
+			start, end := i+1-m.nodes[out].depth, i+1
+			if !isWordBoundary(text, start, end) || isProtected(protected, start, end) {
+				continue
+			}
+			matches = append(matches, match{start: start, end: end, key: m.nodes[out].key})
+		}
+	}
+	return matches
+}
+
+// longestNonOverlapping keeps, scanning left to right, the longest match at
+// each position and drops anything overlapping an earlier pick.
+func longestNonOverlapping(matches []match) []match {
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].start != matches[j].start {
+			return matches[i].start < matches[j].start
+		}
+		return matches[i].end > matches[j].end
+	})
+
+	var kept []match
+	pos := 0
+	for _, m := range matches {
+		if m.start < pos {
+			continue
+		}
+		kept = append(kept, m)
+		pos = m.end
+	}
+	return kept
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func isWordBoundary(text []rune, start, end int) bool {
+	if start > 0 && isWordRune(text[start-1]) && isWordRune(text[start]) {
+		return false
+	}
+	if end < len(text) && isWordRune(text[end-1]) && isWordRune(text[end]) {
+		return false
+	}
+	return true
+}
+
+func isProtected(protected []bool, start, end int) bool {
+	for i := start; i < end; i++ {
+		if protected[i] {
+			return true
+		}
+	}
+	return false
+}
+
+// foldText lower-cases text and collapses whitespace runs to a single space
+// so it can be matched against normalized titles. offsets maps each folded
+// rune back to its index in text.
+func foldText(text []rune) (folded []rune, offsets []int) {
+	folded = make([]rune, 0, len(text))
+	offsets = make([]int, 0, len(text))
+	for i, r := range text {
+		if unicode.IsSpace(r) {
+			if len(folded) > 0 && folded[len(folded)-1] == ' ' {
+				continue
+			}
+			r = ' '
+		}
+		folded = append(folded, unicode.ToLower(r))
+		offsets = append(offsets, i)
+	}
+	return folded, offsets
+}
+
+// scanText finds the non-overlapping title matches in text, in text's rune
+// offsets. The matcher must already be built.
+func (m *matcher) scanText(text []rune) []match {
+	folded, offsets := foldText(text)
+	protected := protectedRegions(text)
+	foldedProtected := make([]bool, len(folded))
+	for i, off := range offsets {
+		foldedProtected[i] = protected[off]
+	}
+
+	matches := m.findAll(folded, foldedProtected)
+	for i := range matches {
+		matches[i].start = offsets[matches[i].start]
+		matches[i].end = offsets[matches[i].end-1] + 1
+	}
+	return longestNonOverlapping(matches)
+}
+
+// protectedRegions marks runes that must never be turned into links:
+// headings, fenced code blocks, inline code spans, existing Markdown links
+// and images, and HTML tags.
+func protectedRegions(text []rune) []bool {
+	mask := make([]bool, len(text))
+	mark := func(from, to int) {
+		for i := from; i < to; i++ {
+			mask[i] = true
+		}
+	}
+	lineEnd := func(from int) int {
+		for i := from; i < len(text); i++ {
+			if text[i] == '\n' {
+				return i
+			}
+		}
+		return len(text)
+	}
+
+	inFence := false
+	lineStart := true
+	i := 0
+	for i < len(text) {
+		if lineStart {
+			lineStart = false
+			j := i
+			for j < len(text) && (text[j] == ' ' || text[j] == '\t') {
+				j++
+			}
+			end := lineEnd(i)
+			fence := hasRunePrefix(text[j:], "```") || hasRunePrefix(text[j:], "~~~")
+			if fence || inFence || (j < len(text) && text[j] == '#') {
+				if fence {
+					inFence = !inFence
+				}
+				mark(i, end)
+				i = end
+				continue
+			}
+		}
+
+		switch text[i] {
+		case '\n':
+			lineStart = true
+			i++
+		case '`':
+			n := runLength(text, i, '`')
+			if end := closingBackticks(text, i+n, n); end > 0 {
+				mark(i, end)
+				i = end
+			} else {
+				i += n
+			}
+		case '[':
+			if end := markdownLinkEnd(text, i); end > 0 {
+				mark(i, end)
+				i = end
+			} else {
+				i++
+			}
+		case '<':
+			if end := htmlTagEnd(text, i); end > 0 {
+				mark(i, end)
+				i = end
+			} else {
+				i++
+			}
+		default:
+			i++
+		}
+	}
+	return mask
+}
+
+func hasRunePrefix(text []rune, prefix string) bool {
+	i := 0
+	for _, r := range prefix {
+		if i >= len(text) || text[i] != r {
+			return false
+		}
+		i++
+	}
+	return true
+}
+
+func runLength(text []rune, from int, r rune) int {
+	n := 0
+	for from+n < len(text) && text[from+n] == r {
+		n++
+	}
+	return n
+}
+
+// closingBackticks finds the end of a code span opened by n backticks.
+func closingBackticks(text []rune, from, n int) int {
+	for i := from; i < len(text); {
+		if text[i] != '`' {
+			i++
+			continue
+		}
+		run := runLength(text, i, '`')
+		if run == n {
+			return i + run
+		}
+		i += run
+	}
+	return -1
+}
+
+// markdownLinkEnd returns the end of [text](url) or [text][ref] starting at
+// from, or -1 if the bracket does not open a link.
+func markdownLinkEnd(text []rune, from int) int {
+	close := matchingBracket(text, from, '[', ']')
+	if close < 0 || close+1 >= len(text) {
+		return -1
+	}
+	switch text[close+1] {
+	case '(':
+		if end := matchingBracket(text, close+1, '(', ')'); end > 0 {
+			return end + 1
+		}
+	case '[':
+		if end := matchingBracket(text, close+1, '[', ']'); end > 0 {
+			return end + 1
+		}
+	}
+	return -1
+}
+
+func matchingBracket(text []rune, from int, open, close rune) int {
+	depth := 0
+	for i := from; i < len(text); i++ {
+		switch text[i] {
+		case open:
+			depth++
+		case close:
+			depth--
+			if depth == 0 {
+				return i
+			}
+		case '\n':
+			if i+1 < len(text) && text[i+1] == '\n' {
+				return -1
+			}
+		}
+	}
+	return -1
+}
+
+func htmlTagEnd(text []rune, from int) int {
+	if from+1 >= len(text) {
+		return -1
+	}
+	if next := text[from+1]; next != '/' && next != '!' && !unicode.IsLetter(next) {
+		return -1
+	}
+	for i := from + 1; i < len(text); i++ {
+		switch text[i] {
+		case '>':
+			return i + 1
+		case '<', '\n':
+			return -1
+		}
+	}
+	return -1
+}
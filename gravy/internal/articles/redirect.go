@@ -0,0 +1,225 @@
+package articles
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+type RedirectReason string
+
+const (
+	RedirectRename RedirectReason = "RENAME"
+	RedirectMerge  RedirectReason = "MERGE"
+)
+
+// Redirect sends an old slug to the article that now owns it. It points at
+// the article ID rather than a slug so that chains of renames resolve in a
+// single lookup.
+type Redirect struct {
+	ID          string         `bson:"_id,omitempty"`
+	FromSlug    string         `bson:"fromSlug"`
+	ToArticleID string         `bson:"toArticleId"`
+	Reason      RedirectReason `bson:"reason"`
+	CreatedAt   time.Time      `bson:"createdAt"`
+}
+
+var (
+	ErrMergeSelf        = errors.New("an article cannot be merged into itself")
+	ErrMergeUnpublished = errors.New("only published articles can be merged into another")
+)
+
+// ResolveRedirect returns the article an old slug now points to.
+func (r *repository) ResolveRedirect(ctx context.Context, slug string) (*Article, error) {
+	var redirect Redirect
+	if err := r.redirects.FindOne(ctx, bson.M{"fromSlug": slug}).Decode(&redirect); err != nil {
+		return nil, err
+	}
+	return r.GetByID(ctx, redirect.ToArticleID)
+}
+
+// Rename changes an article's title and, if newSlug is not empty, its slug.
+// The old slug keeps working as a redirect, and links elsewhere that point
+// at it are updated.
+func (r *repository) Rename(ctx context.Context, id string, newTitle string, newSlug string, meta RevisionMeta) (*Article, error) {
+	existing, err := r.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := bson.M{"title": newTitle}
+	if newSlug != "" {
+		updates["slug"] = newSlug
+	}
+	renamed, err := r.Update(ctx, id, updates, meta)
+	if err != nil {
+		return nil, err
+	}
+
+	if renamed.Slug != existing.Slug {
+		if err := r.moveSlug(ctx, existing.Slug, renamed, RedirectRename, meta); err != nil {
+			return nil, err
+		}
+	}
+	return renamed, nil
+}
+
+// Merge folds source into target: source's content is appended to target as
+// a new section, everything that linked to source now links to target, and
+// source's slug redirects to target. Source is then deleted. Every step can
+// be repeated, so a merge that failed part of the way through can be run
+// again; the target is only written if it has not changed since it was read.
+func (r *repository) Merge(ctx context.Context, sourceID, targetID string, meta RevisionMeta) (*Article, error) {
+	if sourceID == targetID {
+		return nil, ErrMergeSelf
+	}
+	source, err := r.GetByID(ctx, sourceID)
+	if err != nil {
+		return nil, fmt.Errorf("source article: %w", err)
+	}
+	if !source.IsPublished() {
+		return nil, ErrMergeUnpublished
+	}
+	target, err := r.GetByID(ctx, targetID)
+	if err != nil {
+		return nil, fmt.Errorf("target article: %w", err)
+	}
+
+	merged := target
+	if updates, ok := mergeUpdates(source, target); ok {
+		merged, err = r.UpdateVersion(ctx, targetID, target.Version, updates, meta)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	backlinks, err := r.ListBacklinks(ctx, sourceID)
+	if err != nil {
+		return nil, err
+	}
+	var moved []Link
+	for _, l := range backlinks {
+		if l.SourceID == targetID {
+			continue
+		}
+		moved = append(moved, Link{
+			SourceID:   l.SourceID,
+			TargetID:   targetID,
+			TargetSlug: merged.Slug,
+			Anchor:     l.Anchor,
+		})
+	}
+	if err := r.AddLinks(ctx, moved); err != nil {
+		return nil, err
+	}
+
+	_, err = r.redirects.UpdateMany(ctx,
+		bson.M{"toArticleId": sourceID},
+		bson.M{"$set": bson.M{"toArticleId": targetID}},
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.moveSlug(ctx, source.Slug, merged, RedirectMerge, meta); err != nil {
+		return nil, err
+	}
+
+	if err := r.Delete(ctx, sourceID); err != nil {
+		return nil, err
+	}
+	return merged, nil
+}
+
+// mergeUpdates returns the updates appending source's content to target,
+// or false if an earlier attempt at the merge already did.
+func mergeUpdates(source, target *Article) (bson.M, bool) {
+	if slices.Contains(target.MergedFrom, source.ID) {
+		return nil, false
+	}
+	return bson.M{
+		"content":    target.Content + "\n\n## " + source.Title + "\n\n" + source.Content,
+		"mergedFrom": append(slices.Clone(target.MergedFrom), source.ID),
+	}, true
+}
+
+// moveSlug records a redirect from oldSlug to a and repoints stored links
+// that still use oldSlug.
+func (r *repository) moveSlug(ctx context.Context, oldSlug string, a *Article, reason RedirectReason, meta RevisionMeta) error {
+	// A slug that is live again must not keep redirecting elsewhere.
+	if _, err := r.redirects.DeleteMany(ctx, bson.M{"fromSlug": a.Slug}); err != nil {
+		return err
+	}
+
+	_, err := r.redirects.UpdateOne(ctx,
+		bson.M{"fromSlug": oldSlug},
+		bson.M{"$set": bson.M{
+			"toArticleId": a.ID,
+			"reason":      reason,
+			"createdAt":   time.Now(),
+		}},
+		options.UpdateOne().SetUpsert(true),
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.links.UpdateMany(ctx,
+		bson.M{"targetId": a.ID},
+		bson.M{"$set": bson.M{"targetSlug": a.Slug}},
+	)
+	if err != nil {
+		return err
+	}
+
+	return r.rewriteSlugLinks(ctx, oldSlug, a.Slug, meta)
+}
+
+// rewriteSlugLinks updates Markdown links written into article content,
+// including ones left behind by the old auto-linker, from oldSlug to newSlug.
+// Each change is saved as a revision.
+func (r *repository) rewriteSlugLinks(ctx context.Context, oldSlug, newSlug string, meta RevisionMeta) error {
+	pattern := regexp.MustCompile(`\((/articles/)?` + regexp.QuoteMeta(oldSlug) + `\)`)
+
+	filter := bson.M{"content": bson.M{"$regex": regexp.QuoteMeta(oldSlug)}}
+	cursor, err := r.coll.Find(ctx, filter, options.Find().SetProjection(bson.M{"_id": 1, "content": 1}))
+	if err != nil {
+		return err
+	}
+	var affected []Article
+	if err := cursor.All(ctx, &affected); err != nil {
+		return err
+	}
+
+	rewriteMeta := RevisionMeta{
+		AuthorID: meta.AuthorID,
+		Summary:  fmt.Sprintf("Updated links from %s to %s", oldSlug, newSlug),
+	}
+	for _, a := range affected {
+		content := pattern.ReplaceAllString(a.Content, "(${1}"+newSlug+")")
+		if content == a.Content {
+			continue
+		}
+		if _, err := r.Update(ctx, a.ID, bson.M{"content": content}, rewriteMeta); err != nil {
+			return fmt.Errorf("failed to update links in article %s: %w", a.ID, err)
+		}
+	}
+	return nil
+}
+
+func (r *repository) ensureRedirectIndexes(ctx context.Context) error {
+	_, err := r.redirects.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "fromSlug", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{Keys: bson.D{{Key: "toArticleId", Value: 1}}},
+	})
+	return err
+}
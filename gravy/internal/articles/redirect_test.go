@@ -0,0 +1,33 @@
+package articles
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestMergeUpdates(t *testing.T) {
+	source := &Article{ID: "s", Title: "Garnet Hostel", Content: "Rooms."}
+	target := &Article{ID: "t", Content: "Hostels.", MergedFrom: []string{"older"}}
+
+	updates, ok := mergeUpdates(source, target)
+	if !ok {
+		t.Fatal("first merge was skipped")
+	}
+	if want := "Hostels.\n\n## Garnet Hostel\n\nRooms."; updates["content"] != want {
+		t.Errorf("content = %q, want %q", updates["content"], want)
+	}
+	mergedFrom := updates["mergedFrom"].([]string)
+	if !slices.Equal(mergedFrom, []string{"older", "s"}) {
+		t.Errorf("mergedFrom = %v, want [older s]", mergedFrom)
+	}
+	if !slices.Equal(target.MergedFrom, []string{"older"}) {
+		t.Errorf("target.MergedFrom was modified: %v", target.MergedFrom)
+	}
+
+	// A retry reads the target as the first attempt left it.
+	target.Content = updates["content"].(string)
+	target.MergedFrom = mergedFrom
+	if _, ok := mergeUpdates(source, target); ok {
+		t.Error("retried merge appended the source again")
+	}
+}
@@ -0,0 +1,277 @@
+package articles
+
+import (
+	"context"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// Weights of the signals that make two articles related.
+const (
+	relatedSameCategory = 3.0
+	relatedSharedTag    = 2.0
+	relatedDirectLink   = 4.0
+	relatedSharedLink   = 1.0
+	relatedMaxShared    = 4.0
+	relatedTextMatch    = 3.0
+
+	relatedCandidates = 50
+	relatedKeyTerms   = 8
+	relatedCacheTTL   = time.Hour
+)
+
+type relatedEntry struct {
+	ids        []string
+	generation uint64
+	expires    time.Time
+}
+
+// Related ranks other published articles by how closely they relate to the
+// article: a shared category, shared tags, links between the two or to the
+// same articles, and text similarity from Meilisearch.
+//
+// Rankings are cached until any article changes. The cache is per process,
+// so other replicas may serve a ranking up to relatedCacheTTL old.
+func (r *repository) Related(ctx context.Context, id string, limit int) ([]*Article, error) {
+	generation := r.relatedGen.Load()
+	if entry, ok := r.relatedCache.Get(id); ok && entry.generation == generation && time.Now().Before(entry.expires) {
+		return r.relatedArticles(ctx, entry.ids, limit)
+	}
+
+	article, err := r.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	ids, err := r.rankRelated(ctx, article)
+	if err != nil {
+		return nil, err
+	}
+	r.relatedCache.Add(id, relatedEntry{
+		ids:        ids,
+		generation: generation,
+		expires:    time.Now().Add(relatedCacheTTL),
+	})
+	return r.relatedArticles(ctx, ids, limit)
+}
+
+// invalidateRelated drops every cached ranking. Any change to an article can
+// change how it ranks for others, so invalidation is not per article.
+func (r *repository) invalidateRelated() {
+	r.relatedGen.Add(1)
+}
+
+func (r *repository) relatedArticles(ctx context.Context, ids []string, limit int) ([]*Article, error) {
+	if len(ids) > limit {
+		ids = ids[:limit]
+	}
+	return r.GetByIDs(ctx, ids)
+}
+
+func (r *repository) rankRelated(ctx context.Context, a *Article) ([]string, error) {
+	scores := make(map[string]float64)
+
+	published := statusFilter(StatusPublished)
+	if a.Category != "" {
+		ids, err := r.findIDs(ctx, bson.M{"category": a.Category, "status": published})
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range ids {
+			scores[id] += relatedSameCategory
+		}
+	}
+
+	if len(a.Tags) > 0 {
+		cursor, err := r.coll.Find(ctx,
+			bson.M{"tags": bson.M{"$in": a.Tags}, "status": published},
+			options.Find().SetProjection(bson.M{"_id": 1, "tags": 1}).SetLimit(relatedCandidates),
+		)
+		if err != nil {
+			return nil, err
+		}
+		var tagged []Article
+		if err := cursor.All(ctx, &tagged); err != nil {
+			return nil, err
+		}
+		own := make(map[string]bool, len(a.Tags))
+		for _, t := range a.Tags {
+			own[t] = true
+		}
+		for _, t := range tagged {
+			for _, tag := range t.Tags {
+				if own[tag] {
+					scores[t.ID] += relatedSharedTag
+				}
+			}
+		}
+	}
+
+	if err := r.scoreLinkNeighbours(ctx, a.ID, scores); err != nil {
+		return nil, err
+	}
+
+	// Text similarity is a bonus; if Meilisearch is down the other signals
+	// still give a ranking.
+	if terms := keyTerms(a, relatedKeyTerms); len(terms) > 0 {
+		hits, err := r.searchClient.SearchSimilarArticles(ctx, terms, relatedCandidates)
+		if err != nil {
+			log.Printf("Failed to search for articles similar to %s: %v", a.ID, err)
+		}
+		for rank, id := range hits {
+			scores[id] += relatedTextMatch * (1 - float64(rank)/float64(len(hits)))
+		}
+	}
+
+	delete(scores, a.ID)
+	ids := make([]string, 0, len(scores))
+	for id := range scores {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if scores[ids[i]] != scores[ids[j]] {
+			return scores[ids[i]] > scores[ids[j]]
+		}
+		return ids[i] < ids[j]
+	})
+	if len(ids) > relatedCandidates {
+		ids = ids[:relatedCandidates]
+	}
+
+	// The link graph and search index may still mention unpublished
+	// articles; drop them before caching.
+	candidates, err := r.GetByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]string, 0, len(candidates))
+	for _, c := range candidates {
+		if c.IsPublished() {
+			result = append(result, c.ID)
+		}
+	}
+	return result, nil
+}
+
+// scoreLinkNeighbours scores articles linked to or from id, and articles
+// that link to the same articles as id does.
+func (r *repository) scoreLinkNeighbours(ctx context.Context, id string, scores map[string]float64) error {
+	outgoing, err := r.ListOutgoingLinks(ctx, id)
+	if err != nil {
+		return err
+	}
+	backlinks, err := r.ListBacklinks(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	for _, l := range outgoing {
+		scores[l.TargetID] += relatedDirectLink
+	}
+	for _, l := range backlinks {
+		scores[l.SourceID] += relatedDirectLink
+	}
+	if len(outgoing) == 0 {
+		return nil
+	}
+
+	targets := make([]string, 0, len(outgoing))
+	for _, l := range outgoing {
+		targets = append(targets, l.TargetID)
+	}
+	cursor, err := r.links.Find(ctx,
+		bson.M{"targetId": bson.M{"$in": targets}, "sourceId": bson.M{"$ne": id}},
+		options.Find().SetProjection(bson.M{"sourceId": 1}).SetLimit(relatedCandidates*4),
+	)
+	if err != nil {
+		return err
+	}
+	var shared []Link
+	if err := cursor.All(ctx, &shared); err != nil {
+		return err
+	}
+	counts := make(map[string]float64)
+	for _, l := range shared {
+		counts[l.SourceID] += relatedSharedLink
+	}
+	for source, score := range counts {
+		if score > relatedMaxShared {
+			score = relatedMaxShared
+		}
+		scores[source] += score
+	}
+	return nil
+}
+
+func (r *repository) findIDs(ctx context.Context, filter bson.M) ([]string, error) {
+	opts := options.Find().
+		SetProjection(bson.M{"_id": 1}).
+		SetSort(bson.D{{Key: "createdAt", Value: -1}}).
+		SetLimit(relatedCandidates)
+	cursor, err := r.coll.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	var found []Article
+	if err := cursor.All(ctx, &found); err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(found))
+	for _, a := range found {
+		ids = append(ids, a.ID)
+	}
+	return ids, nil
+}
+
+var termPattern = regexp.MustCompile(`\p{L}[\p{L}\p{N}]+`)
+
+var stopWords = map[string]bool{
+	"the": true, "and": true, "for": true, "are": true, "but": true, "not": true,
+	"you": true, "your": true, "all": true, "any": true, "can": true, "has": true,
+	"have": true, "had": true, "was": true, "were": true, "will": true, "with": true,
+	"this": true, "that": true, "these": true, "those": true, "from": true, "they": true,
+	"them": true, "their": true, "there": true, "then": true, "than": true, "into": true,
+	"about": true, "also": true, "been": true, "being": true, "more": true, "most": true,
+	"some": true, "such": true, "only": true, "other": true, "which": true, "what": true,
+	"when": true, "where": true, "who": true, "how": true, "its": true, "our": true,
+	"out": true, "one": true, "may": true, "should": true, "would": true, "could": true,
+	"each": true, "very": true, "here": true, "just": true, "over": true, "per": true,
+	"http": true, "https": true, "www": true, "com": true, "articles": true,
+}
+
+// keyTerms picks the words that best describe a: the most frequent
+// non-trivial words of its content, with words from the title counting
+// extra.
+func keyTerms(a *Article, n int) []string {
+	weights := make(map[string]int)
+	add := func(s string, weight int) {
+		for _, w := range termPattern.FindAllString(strings.ToLower(s), -1) {
+			if len([]rune(w)) < 3 || stopWords[w] {
+				continue
+			}
+			weights[w] += weight
+		}
+	}
+	add(a.Title, 5)
+	add(a.Content, 1)
+
+	terms := make([]string, 0, len(weights))
+	for w := range weights {
+		terms = append(terms, w)
+	}
+	sort.Slice(terms, func(i, j int) bool {
+		if weights[terms[i]] != weights[terms[j]] {
+			return weights[terms[i]] > weights[terms[j]]
+		}
+		return terms[i] < terms[j]
+	})
+	if len(terms) > n {
+		terms = terms[:n]
+	}
+	return terms
+}
@@ -0,0 +1,165 @@
+package articles
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+)
+
+const (
+	wordsPerMinute   = 200
+	excerptLength    = 160
+	renderCacheSize  = 2048
+	maxTocEntryLevel = 4
+)
+
+// TocEntry is a heading in an article's table of contents. ID is the anchor
+// the heading gets in the rendered HTML.
+type TocEntry struct {
+	ID    string
+	Text  string
+	Level int
+}
+
+// Rendered is an article body rendered to HTML along with what was learned
+// from it on the way. Rendered values are cached and shared, so they must not
+// be modified.
+type Rendered struct {
+	HTML               string
+	TOC                []TocEntry
+	ReadingTimeMinutes int
+	Excerpt            string
+}
+
+var markdown = goldmark.New(
+	goldmark.WithExtensions(extension.GFM),
+	goldmark.WithParserOptions(parser.WithAutoHeadingID()),
+	// Stored content has already been through the UGC policy and may contain
+	// the HTML it allows. The output is sanitized again below.
+	goldmark.WithRendererOptions(html.WithUnsafe()),
+)
+
+var headingIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+var renderPolicy = func() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	p.AllowAttrs("id").Matching(headingIDPattern).OnElements("h1", "h2", "h3", "h4", "h5", "h6")
+	return p
+}()
+
+var renderCache, _ = lru.New[string, *Rendered](renderCacheSize)
+
+// Render turns article content into sanitized HTML with a table of contents,
+// an estimated reading time and a plain-text excerpt. Results are cached by
+// content hash, so unchanged articles are only rendered once.
+func Render(content string) *Rendered {
+	sum := sha256.Sum256([]byte(content))
+	key := hex.EncodeToString(sum[:])
+	if cached, ok := renderCache.Get(key); ok {
+		return cached
+	}
+
+	rendered := render([]byte(content))
+	renderCache.Add(key, rendered)
+	return rendered
+}
+
+func render(source []byte) *Rendered {
+	doc := markdown.Parser().Parse(text.NewReader(source))
+
+	rendered := &Rendered{TOC: []TocEntry{}}
+	words := 0
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch node := n.(type) {
+		case *ast.Heading:
+			if node.Level > maxTocEntryLevel {
+				break
+			}
+			entry := TocEntry{Text: plainText(node, source), Level: node.Level}
+			if id, ok := node.AttributeString("id"); ok {
+				if b, ok := id.([]byte); ok {
+					entry.ID = string(b)
+				}
+			}
+			rendered.TOC = append(rendered.TOC, entry)
+		case *ast.Paragraph:
+			if rendered.Excerpt == "" && node.Parent() == doc {
+				rendered.Excerpt = excerpt(plainText(node, source))
+			}
+		case *ast.Text:
+			words += len(strings.Fields(string(node.Value(source))))
+		case *ast.String:
+			words += len(strings.Fields(string(node.Value)))
+		}
+		return ast.WalkContinue, nil
+	})
+
+	rendered.ReadingTimeMinutes = (words + wordsPerMinute - 1) / wordsPerMinute
+	if rendered.ReadingTimeMinutes < 1 {
+		rendered.ReadingTimeMinutes = 1
+	}
+
+	var buf bytes.Buffer
+	if err := markdown.Renderer().Render(&buf, source, doc); err != nil {
+		// Rendering into a buffer only fails on a broken AST; fall back to
+		// showing the source as text.
+		buf.Reset()
+		buf.WriteString("<pre>")
+		buf.WriteString(renderPolicy.Sanitize(string(source)))
+		buf.WriteString("</pre>")
+	}
+	rendered.HTML = renderPolicy.Sanitize(buf.String())
+	return rendered
+}
+
+// plainText concatenates the text inside n, dropping formatting and raw HTML.
+func plainText(n ast.Node, source []byte) string {
+	var sb strings.Builder
+	_ = ast.Walk(n, func(c ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch node := c.(type) {
+		case *ast.Text:
+			sb.Write(node.Value(source))
+			if node.SoftLineBreak() || node.HardLineBreak() {
+				sb.WriteByte(' ')
+			}
+		case *ast.String:
+			sb.Write(node.Value)
+		case *ast.RawHTML:
+			return ast.WalkSkipChildren, nil
+		}
+		return ast.WalkContinue, nil
+	})
+	return strings.Join(strings.Fields(sb.String()), " ")
+}
+
+// excerpt shortens s to at most excerptLength runes, cutting at a word
+// boundary.
+func excerpt(s string) string {
+	if utf8.RuneCountInString(s) <= excerptLength {
+		return s
+	}
+	runes := []rune(s)[:excerptLength]
+	cut := string(runes)
+	if i := strings.LastIndexByte(cut, ' '); i > 0 {
+		cut = cut[:i]
+	}
+	return strings.TrimRight(cut, " ,.;:") + "..."
+}
@@ -0,0 +1,101 @@
+package articles
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+		notWant []string
+	}{
+		{
+			name:    "markdown",
+			content: "# Title\n\nSome *text*.",
+			want:    []string{`<h1 id="title">Title</h1>`, "<em>text</em>"},
+		},
+		{
+			name:    "script tag",
+			content: "Hello <script>alert(1)</script> there.",
+			want:    []string{"Hello"},
+			notWant: []string{"<script", "alert(1)"},
+		},
+		{
+			name:    "event handler",
+			content: `<img src="/a.png" onerror="alert(1)">`,
+			want:    []string{`<img src="/a.png"`},
+			notWant: []string{"onerror"},
+		},
+		{
+			name:    "javascript link",
+			content: "[click](javascript:alert(1))",
+			notWant: []string{"javascript:"},
+		},
+		{
+			name:    "raw javascript link",
+			content: `<a href="javascript:alert(1)">click</a>`,
+			notWant: []string{"javascript:"},
+		},
+		{
+			name:    "iframe",
+			content: `<iframe src="https://example.com"></iframe>`,
+			notWant: []string{"<iframe"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			html := Render(tt.content).HTML
+			for _, w := range tt.want {
+				if !strings.Contains(html, w) {
+					t.Errorf("HTML %q does not contain %q", html, w)
+				}
+			}
+			for _, w := range tt.notWant {
+				if strings.Contains(html, w) {
+					t.Errorf("HTML %q contains %q", html, w)
+				}
+			}
+		})
+	}
+}
+
+func TestRenderTOCAndReadingTime(t *testing.T) {
+	content := "# One\n\nIntro.\n\n## Two *words*\n\n##### Too deep\n\n" + strings.Repeat("word ", 450)
+	rendered := Render(content)
+
+	wantTOC := []TocEntry{
+		{ID: "one", Text: "One", Level: 1},
+		{ID: "two-words", Text: "Two words", Level: 2},
+	}
+	if !reflect.DeepEqual(rendered.TOC, wantTOC) {
+		t.Errorf("TOC = %v, want %v", rendered.TOC, wantTOC)
+	}
+	if rendered.ReadingTimeMinutes != 3 {
+		t.Errorf("reading time = %d, want 3", rendered.ReadingTimeMinutes)
+	}
+	if rendered.Excerpt != "Intro." {
+		t.Errorf("excerpt = %q, want %q", rendered.Excerpt, "Intro.")
+	}
+	if Render("").ReadingTimeMinutes != 1 {
+		t.Error("empty article does not take a minute to read")
+	}
+}
+
+func TestRenderLinksSanitized(t *testing.T) {
+	links := []Link{{TargetID: "1", TargetSlug: "octagon", Anchor: "Octagon"}}
+	content := `Meet at the Octagon. <script>alert("Octagon")</script><img src=x onerror="alert(1)">`
+
+	html := Render(RenderLinks(content, links)).HTML
+	if !strings.Contains(html, `<a href="/articles/octagon"`) {
+		t.Errorf("HTML %q does not link the mention", html)
+	}
+	for _, bad := range []string{"<script", "onerror", "alert("} {
+		if strings.Contains(html, bad) {
+			t.Errorf("HTML %q contains %q", html, bad)
+		}
+	}
+}
@@ -2,11 +2,14 @@ package articles
 
 import (
 	"context"
+	"sync"
+	"sync/atomic"
 	"time"
-	"strings"
 
+	lru "github.com/hashicorp/golang-lru/v2"
 	"github.com/pranava-mohan/wikinitt/gravy/internal/search"
 	"github.com/pranava-mohan/wikinitt/gravy/internal/users"
+	"github.com/pranava-mohan/wikinitt/gravy/internal/versioning"
 	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo"
 	"go.mongodb.org/mongo-driver/v2/mongo/options"
@@ -18,6 +21,7 @@ type Article struct {
 	Content   string            `bson:"content"`
 	Slug      string            `bson:"slug"`
 	Category  string            `bson:"category"`
+	Tags      []string          `bson:"tags"`
 	Thumbnail string            `bson:"thumbnail"`
 	Featured  bool              `bson:"featured"`
 	AuthorID  string            `bson:"authorId"`
@@ -25,103 +29,243 @@ type Article struct {
 	UpdatedAt time.Time         `bson:"updatedAt"`
 	Indexed   bool              `bson:"indexed"`
 	Author    *users.PublicUser `bson:"-"`
+
+	Status      Status     `bson:"status"`
+	PublishedAt *time.Time `bson:"publishedAt,omitempty"`
+	SubmittedBy string     `bson:"submittedBy,omitempty"`
+	ReviewedBy  string     `bson:"reviewedBy,omitempty"`
+	ReviewNote  string     `bson:"reviewNote,omitempty"`
+	PublishAt   *time.Time `bson:"publishAt,omitempty"`
+	ExpireAt    *time.Time `bson:"expireAt,omitempty"`
+	Version     int        `bson:"version"`
+	// MergedFrom lists the articles whose content was merged into this one,
+	// so a retried merge does not append the same content twice.
+	MergedFrom []string `bson:"mergedFrom,omitempty"`
+
+	// Excerpt is the plain-text start of the content, kept up to date on
+	// every save so lists do not have to render each article.
+	Excerpt string `bson:"excerpt"`
+	// ViewCount is maintained by the views package.
+	ViewCount int64 `bson:"viewCount,omitempty"`
+	// LinksSynced is set once the article's outgoing links are stored in
+	// article_links; articles saved before that collection existed lack it.
+	LinksSynced bool `bson:"linksSynced,omitempty"`
+}
+
+// ListFilter narrows List. A nil Status lists articles in every status.
+type ListFilter struct {
+	Category *string
+	Tag      *string
+	Featured *bool
+	Status   *Status
 }
 
 type Repository interface {
-	Create(ctx context.Context, article Article) (*Article, error)
-	Update(ctx context.Context, id string, updates bson.M) (*Article, error)
+	Create(ctx context.Context, article Article, summary string) (*Article, error)
+	Update(ctx context.Context, id string, updates bson.M, meta RevisionMeta) (*Article, error)
+	UpdateVersion(ctx context.Context, id string, expectedVersion int, updates bson.M, meta RevisionMeta) (*Article, error)
 	Delete(ctx context.Context, id string) error
 	GetByID(ctx context.Context, id string) (*Article, error)
 	GetByIDs(ctx context.Context, ids []string) ([]*Article, error)
-	List(ctx context.Context, category *string, limit *int, offset *int, featured *bool) ([]*Article, error)
+	List(ctx context.Context, filter ListFilter, limit *int, offset *int) ([]*Article, error)
+	Count(ctx context.Context, filter ListFilter) (int64, error)
 	ListUnindexed(ctx context.Context, limit int) ([]*Article, error)
 	MarkIndexed(ctx context.Context, id string) error
 	GetBySlug(ctx context.Context, slug string) (*Article, error)
 	EnsureIndexes(ctx context.Context) error
-	GetAllTitles(ctx context.Context) (map[string]string, error)
-	
-	CountArticles(ctx context.Context) (int64, error)
-	GetArticlesChunk(ctx context.Context, skip int64, limit int64) ([]Article, error)
-	UpdateContent(ctx context.Context, id string, content string) error
+
+	BackfillExcerpts(ctx context.Context) (int, error)
+	BackfillLinks(ctx context.Context) (int, error)
+	ListContentAfter(ctx context.Context, afterID string, limit int) ([]Article, error)
+	ListSitemapEntries(ctx context.Context, limit, offset int) ([]SitemapEntry, error)
+
+	MoveCategory(ctx context.Context, from string, to string) (int64, error)
+	ListTags(ctx context.Context, prefix string, limit int) ([]*Tag, error)
+	Related(ctx context.Context, id string, limit int) ([]*Article, error)
+
+	ListLinkTargets(ctx context.Context) ([]LinkTarget, error)
+	AddLinks(ctx context.Context, links []Link) error
+	ListBacklinks(ctx context.Context, articleID string) ([]*Link, error)
+	ListOutgoingLinks(ctx context.Context, articleID string) ([]*Link, error)
+
+	Rename(ctx context.Context, id string, newTitle string, newSlug string, meta RevisionMeta) (*Article, error)
+	Merge(ctx context.Context, sourceID, targetID string, meta RevisionMeta) (*Article, error)
+	ResolveRedirect(ctx context.Context, slug string) (*Article, error)
+
+	CreateSuggestion(ctx context.Context, s Suggestion) (*Suggestion, error)
+	GetSuggestion(ctx context.Context, id string) (*Suggestion, error)
+	ListSuggestions(ctx context.Context, filter SuggestionFilter, limit, offset int) ([]*Suggestion, error)
+	ResolveSuggestion(ctx context.Context, id string, status SuggestionStatus, reviewerID string, reason string) (*Suggestion, error)
+	ReopenSuggestion(ctx context.Context, id string) error
+	MarkSuggestionStale(ctx context.Context, id string) error
+
+	ListRevisions(ctx context.Context, articleID string, limit, offset int) ([]*Revision, error)
+	ImportRevisions(ctx context.Context, articleID string, revisions []Revision) error
+	GetRevision(ctx context.Context, id string) (*Revision, error)
+
+	Transition(ctx context.Context, id string, from []Status, to Status, fields bson.M) (*Article, error)
+	ListScheduledDue(ctx context.Context, now time.Time, limit int) ([]*Article, error)
+	ListExpiredDue(ctx context.Context, now time.Time, limit int) ([]*Article, error)
 }
 
 type repository struct {
 	coll         *mongo.Collection
+	revisions    *mongo.Collection
+	links        *mongo.Collection
+	redirects    *mongo.Collection
+	suggestions  *mongo.Collection
+	tags         *mongo.Collection
 	searchClient *search.Client
+
+	linkerMu       sync.Mutex
+	linker         *Linker
+	linkerLoadedAt time.Time
+
+	relatedGen   atomic.Uint64
+	relatedCache *lru.Cache[string, relatedEntry]
 }
 
 func NewRepository(db *mongo.Database, searchClient *search.Client) Repository {
+	relatedCache, _ := lru.New[string, relatedEntry](1024)
 	return &repository{
 		coll:         db.Collection("articles"),
+		revisions:    db.Collection("article_revisions"),
+		links:        db.Collection("article_links"),
+		redirects:    db.Collection("article_redirects"),
+		suggestions:  db.Collection("article_suggestions"),
+		tags:         db.Collection("article_tags"),
 		searchClient: searchClient,
+		relatedCache: relatedCache,
 	}
 }
 
-func (r *repository) Create(ctx context.Context, article Article) (*Article, error) {
+func (r *repository) Create(ctx context.Context, article Article, summary string) (*Article, error) {
 	article.CreatedAt = time.Now()
-	article.UpdatedAt = time.Now()
+	article.UpdatedAt = article.CreatedAt
+	article.Version = 1
+	if article.Status == "" {
+		article.Status = StatusDraft
+	}
+	if article.Tags == nil {
+		article.Tags = []string{}
+	}
+	article.Excerpt = Render(article.Content).Excerpt
+	article.LinksSynced = true
 	res, err := r.coll.InsertOne(ctx, article)
 	if err != nil {
 		return nil, err
 	}
 	article.ID = res.InsertedID.(bson.ObjectID).Hex()
+	r.invalidateRelated()
 
-	doc := map[string]interface{}{
-		"id":        article.ID,
-		"title":     article.Title,
-		"content":   article.Content,
-		"slug":      article.Slug,
-		"category":  article.Category,
-		"thumbnail": article.Thumbnail,
-		"authorID":  article.AuthorID,
-		"createdAt": article.CreatedAt.Unix(),
+	if err := r.recordRevision(ctx, &article, RevisionMeta{AuthorID: article.AuthorID, Summary: summary}); err != nil {
+		return nil, err
+	}
+	r.updateLinker(&article)
+	if err := r.syncLinks(ctx, &article); err != nil {
+		return nil, err
 	}
-	if err := r.searchClient.IndexArticle(ctx, doc); err == nil {
-		_, _ = r.coll.UpdateOne(ctx, bson.M{"_id": res.InsertedID}, bson.M{"$set": bson.M{"indexed": true}})
-		article.Indexed = true
+
+	if article.IsPublished() {
+		r.index(ctx, &article)
+		if err := r.refreshTagCounts(ctx, article.Tags); err != nil {
+			return nil, err
+		}
 	}
 	return &article, nil
 }
 
-func (r *repository) Update(ctx context.Context, id string, updates bson.M) (*Article, error) {
+func (r *repository) Update(ctx context.Context, id string, updates bson.M, meta RevisionMeta) (*Article, error) {
+	return r.update(ctx, id, nil, updates, meta)
+}
+
+// UpdateVersion applies updates only if the article is still at
+// expectedVersion, returning a *versioning.ConflictError otherwise.
+func (r *repository) UpdateVersion(ctx context.Context, id string, expectedVersion int, updates bson.M, meta RevisionMeta) (*Article, error) {
+	return r.update(ctx, id, &expectedVersion, updates, meta)
+}
+
+func (r *repository) update(ctx context.Context, id string, expectedVersion *int, updates bson.M, meta RevisionMeta) (*Article, error) {
 	idObj, err := bson.ObjectIDFromHex(id)
 	if err != nil {
 		return nil, err
 	}
-	updates["updatedAt"] = time.Now()
-	res := r.coll.FindOneAndUpdate(ctx, bson.M{"_id": idObj}, bson.M{"$set": updates})
-	if res.Err() != nil {
-		return nil, res.Err()
+	filter := bson.M{"_id": idObj}
+	if expectedVersion != nil {
+		filter[versioning.Field] = versioning.Matches(*expectedVersion)
 	}
-	var article Article
-	if err := res.Decode(&article); err != nil {
+
+	// Tags dropped by this edit need their counts refreshed too.
+	var previousTags []string
+	if _, ok := updates["tags"]; ok {
+		if existing, err := r.GetByID(ctx, id); err == nil {
+			previousTags = existing.Tags
+		}
+	}
+
+	if content, ok := updates["content"].(string); ok {
+		updates["excerpt"] = Render(content).Excerpt
+	}
+	updates["updatedAt"] = time.Now()
+	updates["linksSynced"] = true
+	update := bson.M{"$set": updates, "$inc": bson.M{versioning.Field: 1}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var updatedArticle *Article
+	if err := r.coll.FindOneAndUpdate(ctx, filter, update, opts).Decode(&updatedArticle); err != nil {
+		if err == mongo.ErrNoDocuments && expectedVersion != nil {
+			if current, getErr := r.GetByID(ctx, id); getErr == nil {
+				return nil, &versioning.ConflictError{Resource: "article", CurrentVersion: current.Version}
+			}
+		}
 		return nil, err
 	}
+	r.invalidateRelated()
 
-	updatedArticle, err := r.GetByID(ctx, id)
-	if err != nil {
+	if err := r.recordRevision(ctx, updatedArticle, meta); err != nil {
+		return nil, err
+	}
+	r.updateLinker(updatedArticle)
+	if err := r.syncLinks(ctx, updatedArticle); err != nil {
 		return nil, err
 	}
 
-	doc := map[string]interface{}{
-		"id":        updatedArticle.ID,
-		"title":     updatedArticle.Title,
-		"content":   updatedArticle.Content,
-		"slug":      updatedArticle.Slug,
-		"category":  updatedArticle.Category,
-		"thumbnail": updatedArticle.Thumbnail,
-		"authorID":  updatedArticle.AuthorID,
-		"createdAt": updatedArticle.CreatedAt.Unix(),
+	if updatedArticle.IsPublished() {
+		r.index(ctx, updatedArticle)
 	}
-	if err := r.searchClient.IndexArticle(ctx, doc); err == nil {
-		_, _ = r.coll.UpdateOne(ctx, bson.M{"_id": idObj}, bson.M{"$set": bson.M{"indexed": true}})
-		updatedArticle.Indexed = true
+	if err := r.refreshTagCounts(ctx, previousTags, updatedArticle.Tags); err != nil {
+		return nil, err
 	}
 
 	return updatedArticle, nil
 }
 
+// SearchDocument is the Meilisearch representation of an article.
+func SearchDocument(a *Article) map[string]interface{} {
+	return map[string]interface{}{
+		"id":        a.ID,
+		"title":     a.Title,
+		"content":   a.Content,
+		"slug":      a.Slug,
+		"category":  a.Category,
+		"tags":      a.Tags,
+		"thumbnail": a.Thumbnail,
+		"authorID":  a.AuthorID,
+		"createdAt": a.CreatedAt.Unix(),
+	}
+}
+
+func (r *repository) index(ctx context.Context, a *Article) {
+	if err := r.searchClient.IndexArticle(ctx, SearchDocument(a)); err == nil {
+		_ = r.MarkIndexed(ctx, a.ID)
+		a.Indexed = true
+	}
+}
+
 func (r *repository) Delete(ctx context.Context, id string) error {
+	existing, err := r.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
 	idObj, err := bson.ObjectIDFromHex(id)
 	if err != nil {
 		return err
@@ -130,6 +274,16 @@ func (r *repository) Delete(ctx context.Context, id string) error {
 	if err != nil {
 		return err
 	}
+	r.invalidateRelated()
+	if err := r.refreshTagCounts(ctx, existing.Tags); err != nil {
+		return err
+	}
+
+	r.removeFromLinker(id)
+	linkFilter := bson.M{"$or": []bson.M{{"sourceId": id}, {"targetId": id}}}
+	if _, err := r.links.DeleteMany(ctx, linkFilter); err != nil {
+		return err
+	}
 
 	// Delete from index
 	return r.searchClient.DeleteArticle(ctx, id)
@@ -186,14 +340,25 @@ func (r *repository) GetByIDs(ctx context.Context, ids []string) ([]*Article, er
 	return finalArticles, nil
 }
 
-func (r *repository) List(ctx context.Context, category *string, limit *int, offset *int, featured *bool) ([]*Article, error) {
+func (f ListFilter) query() bson.M {
 	filter := bson.M{}
-	if category != nil {
-		filter["category"] = *category
+	if f.Category != nil {
+		filter["category"] = *f.Category
 	}
-	if featured != nil {
-		filter["featured"] = *featured
+	if f.Tag != nil {
+		filter["tags"] = *f.Tag
 	}
+	if f.Featured != nil {
+		filter["featured"] = *f.Featured
+	}
+	if f.Status != nil {
+		filter["status"] = statusFilter(*f.Status)
+	}
+	return filter
+}
+
+func (r *repository) List(ctx context.Context, listFilter ListFilter, limit *int, offset *int) ([]*Article, error) {
+	filter := listFilter.query()
 
 	opts := options.Find()
 	if limit != nil {
@@ -221,6 +386,7 @@ func (r *repository) ListUnindexed(ctx context.Context, limit int) ([]*Article,
 			{"indexed": false},
 			{"indexed": bson.M{"$exists": false}},
 		},
+		"status": statusFilter(StatusPublished),
 	}
 	opts := options.Find().SetLimit(int64(limit))
 	cursor, err := r.coll.Find(ctx, filter, opts)
@@ -259,116 +425,127 @@ func (r *repository) EnsureIndexes(ctx context.Context) error {
 			Options: options.Index().SetUnique(true),
 		},
 		{Keys: bson.D{{Key: "category", Value: 1}}},
+		{Keys: bson.D{{Key: "tags", Value: 1}, {Key: "status", Value: 1}}},
 		{Keys: bson.D{{Key: "featured", Value: 1}}},
 		{Keys: bson.D{{Key: "createdAt", Value: -1}}},
 		{Keys: bson.D{{Key: "indexed", Value: 1}}},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "createdAt", Value: -1}}},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "publishAt", Value: 1}}},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "expireAt", Value: 1}}},
 	}
 
-	_, err := r.coll.Indexes().CreateMany(ctx, indices)
-	return err
-}
+	if _, err := r.coll.Indexes().CreateMany(ctx, indices); err != nil {
+		return err
+	}
 
-func (r *repository) GetAllTitles(ctx context.Context) (map[string]string, error) {
-	// We only need title + slug, nothing else
-	projection := bson.M{
-		"title": 1,
-		"slug":  1,
+	_, err := r.revisions.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "articleId", Value: 1}, {Key: "createdAt", Value: -1}},
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := r.ensureLinkIndexes(ctx); err != nil {
+		return err
+	}
+	if err := r.ensureRedirectIndexes(ctx); err != nil {
+		return err
+	}
+	if err := r.ensureTagIndexes(ctx); err != nil {
+		return err
 	}
+	return r.ensureSuggestionIndexes(ctx)
+}
 
-	cursor, err := r.coll.Find(ctx, bson.M{}, options.Find().SetProjection(projection))
+// BackfillExcerpts stores the excerpt of articles saved before excerpts
+// were stored. It does not count as an edit, so versions are left alone.
+func (r *repository) BackfillExcerpts(ctx context.Context) (int, error) {
+	opts := options.Find().SetProjection(bson.M{"_id": 1, "content": 1})
+	cursor, err := r.coll.Find(ctx, bson.M{"excerpt": bson.M{"$exists": false}}, opts)
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
 	defer cursor.Close(ctx)
 
-	titleToSlug := make(map[string]string)
-
+	count := 0
 	for cursor.Next(ctx) {
-		var doc struct {
-			Title string `bson:"title"`
-			Slug  string `bson:"slug"`
+		var a Article
+		if err := cursor.Decode(&a); err != nil {
+			return count, err
 		}
-
-		if err := cursor.Decode(&doc); err != nil {
-			return nil, err
+		idObj, err := bson.ObjectIDFromHex(a.ID)
+		if err != nil {
+			return count, err
 		}
-
-		// Normalize title for case-insensitive matching
-		titleToSlug[strings.ToLower(doc.Title)] = doc.Slug
-	}
-
-	if err := cursor.Err(); err != nil {
-		return nil, err
+		_, err = r.coll.UpdateOne(ctx,
+			bson.M{"_id": idObj},
+			bson.M{"$set": bson.M{"excerpt": Render(a.Content).Excerpt}},
+		)
+		if err != nil {
+			return count, err
+		}
+		count++
 	}
-
-	return titleToSlug, nil
+	return count, cursor.Err()
 }
 
-
-func (r *repository) CountArticles(ctx context.Context) (int64, error) {
-	return r.coll.CountDocuments(ctx, bson.M{})
-}
-
-
-func (r *repository) GetArticlesChunk(
-	ctx context.Context,
-	skip int64,
-	limit int64,
-) ([]Article, error) {
-
-	opts := options.Find().
-		SetSkip(skip).
-		SetLimit(limit).
-		SetSort(bson.M{"_id": 1}).
-		SetProjection(bson.M{
-			"_id": 1,
-			"content": 1,
-		})
-
-	cursor, err := r.coll.Find(ctx, bson.M{}, opts)
+// BackfillLinks stores the outgoing links of articles saved before links
+// were stored, so they show up in backlinks without waiting for an edit.
+func (r *repository) BackfillLinks(ctx context.Context) (int, error) {
+	opts := options.Find().SetProjection(bson.M{"_id": 1, "content": 1})
+	cursor, err := r.coll.Find(ctx, bson.M{"linksSynced": bson.M{"$exists": false}}, opts)
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
 	defer cursor.Close(ctx)
 
-	var articles []Article
-
+	count := 0
 	for cursor.Next(ctx) {
-
-		// temp struct with correct type
-		var temp struct {
-			ID      bson.ObjectID `bson:"_id"`
-			Content string             `bson:"content"`
+		var a Article
+		if err := cursor.Decode(&a); err != nil {
+			return count, err
 		}
-
-		if err := cursor.Decode(&temp); err != nil {
-			return nil, err
+		if err := r.syncLinks(ctx, &a); err != nil {
+			return count, err
 		}
-
-		articles = append(articles, Article{
-			ID:      temp.ID.Hex(),  // convert to string here
-			Content: temp.Content,
-		})
+		idObj, err := bson.ObjectIDFromHex(a.ID)
+		if err != nil {
+			return count, err
+		}
+		if _, err := r.coll.UpdateOne(ctx, bson.M{"_id": idObj}, bson.M{"$set": bson.M{"linksSynced": true}}); err != nil {
+			return count, err
+		}
+		count++
 	}
-
-	return articles, nil
+	return count, cursor.Err()
 }
 
+// ListContentAfter pages through every article in _id order, returning only
+// IDs and content. Paging by _id rather than skip keeps long scans stable
+// while articles are being inserted.
+func (r *repository) ListContentAfter(ctx context.Context, afterID string, limit int) ([]Article, error) {
+	filter := bson.M{}
+	if afterID != "" {
+		after, err := bson.ObjectIDFromHex(afterID)
+		if err != nil {
+			return nil, err
+		}
+		filter["_id"] = bson.M{"$gt": after}
+	}
 
-func (r *repository) UpdateContent(ctx context.Context, id string, content string) error {
+	opts := options.Find().
+		SetLimit(int64(limit)).
+		SetSort(bson.D{{Key: "_id", Value: 1}}).
+		SetProjection(bson.M{"_id": 1, "content": 1})
 
-	objID, err := bson.ObjectIDFromHex(id)
+	cursor, err := r.coll.Find(ctx, filter, opts)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	_, err = r.coll.UpdateOne(
-		ctx,
-		bson.M{"_id": objID},
-		bson.M{"$set": bson.M{"content": content}},
-	)
-
-	return err
+	var articles []Article
+	if err := cursor.All(ctx, &articles); err != nil {
+		return nil, err
+	}
+	return articles, nil
 }
-
-
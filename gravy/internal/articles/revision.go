@@ -0,0 +1,129 @@
+package articles
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// Revision is an immutable snapshot of an article taken on every create/update.
+// ApprovedBy is set when the edit was suggested by AuthorID and applied by
+// an admin.
+type Revision struct {
+	ID        string `bson:"_id,omitempty"`
+	ArticleID string `bson:"articleId"`
+	Title     string `bson:"title"`
+	Content   string `bson:"content"`
+	Category  string `bson:"category"`
+	// Tags and Thumbnail are nil on revisions recorded before they were
+	// stored, and reverting to one leaves them as they are.
+	Tags       []string  `bson:"tags"`
+	Thumbnail  *string   `bson:"thumbnail,omitempty"`
+	AuthorID   string    `bson:"authorId"`
+	Summary    string    `bson:"summary"`
+	ApprovedBy string    `bson:"approvedBy,omitempty"`
+	CreatedAt  time.Time `bson:"createdAt"`
+}
+
+// RevertUpdates returns the updates that restore an article to rev.
+func (rev *Revision) RevertUpdates() bson.M {
+	updates := bson.M{
+		"title":    rev.Title,
+		"content":  rev.Content,
+		"category": rev.Category,
+	}
+	if rev.Tags != nil {
+		updates["tags"] = rev.Tags
+	}
+	if rev.Thumbnail != nil {
+		updates["thumbnail"] = *rev.Thumbnail
+	}
+	return updates
+}
+
+// RevisionMeta describes who made an edit and why.
+type RevisionMeta struct {
+	AuthorID   string
+	Summary    string
+	ApprovedBy string
+}
+
+func (r *repository) recordRevision(ctx context.Context, article *Article, meta RevisionMeta) error {
+	tags := article.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	thumbnail := article.Thumbnail
+	revision := Revision{
+		ArticleID:  article.ID,
+		Title:      article.Title,
+		Content:    article.Content,
+		Category:   article.Category,
+		Tags:       tags,
+		Thumbnail:  &thumbnail,
+		AuthorID:   meta.AuthorID,
+		Summary:    meta.Summary,
+		ApprovedBy: meta.ApprovedBy,
+		CreatedAt:  article.UpdatedAt,
+	}
+	if _, err := r.revisions.InsertOne(ctx, revision); err != nil {
+		return fmt.Errorf("failed to record revision: %w", err)
+	}
+	return nil
+}
+
+// ImportRevisions adds revisions recorded elsewhere, such as another wiki's
+// page history, to an article. They keep their own CreatedAt so they sort
+// before the article's revisions here.
+func (r *repository) ImportRevisions(ctx context.Context, articleID string, revisions []Revision) error {
+	if len(revisions) == 0 {
+		return nil
+	}
+	docs := make([]interface{}, 0, len(revisions))
+	for _, revision := range revisions {
+		revision.ID = ""
+		revision.ArticleID = articleID
+		docs = append(docs, revision)
+	}
+	if _, err := r.revisions.InsertMany(ctx, docs); err != nil {
+		return fmt.Errorf("failed to import revisions: %w", err)
+	}
+	return nil
+}
+
+func (r *repository) ListRevisions(ctx context.Context, articleID string, limit, offset int) ([]*Revision, error) {
+	opts := options.Find().
+		SetLimit(int64(limit)).
+		SetSkip(int64(offset)).
+		SetSort(bson.D{{Key: "createdAt", Value: -1}, {Key: "_id", Value: -1}})
+
+	cursor, err := r.revisions.Find(ctx, bson.M{"articleId": articleID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	var revisions []*Revision
+	if err := cursor.All(ctx, &revisions); err != nil {
+		return nil, err
+	}
+	return revisions, nil
+}
+
+func (r *repository) GetRevision(ctx context.Context, id string) (*Revision, error) {
+	idObj, err := bson.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+	var revision Revision
+	err = r.revisions.FindOne(ctx, bson.M{"_id": idObj}).Decode(&revision)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("revision not found")
+		}
+		return nil, err
+	}
+	return &revision, nil
+}
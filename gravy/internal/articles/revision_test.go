@@ -0,0 +1,69 @@
+package articles
+
+import (
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestRevertUpdates(t *testing.T) {
+	thumbnail := "https://example.com/a.png"
+	empty := ""
+	tests := []struct {
+		name string
+		rev  Revision
+		want bson.M
+	}{
+		{
+			name: "full revision",
+			rev:  Revision{Title: "T", Content: "C", Category: "X", Tags: []string{"a"}, Thumbnail: &thumbnail},
+			want: bson.M{"title": "T", "content": "C", "category": "X", "tags": []string{"a"}, "thumbnail": thumbnail},
+		},
+		{
+			name: "cleared tags and thumbnail",
+			rev:  Revision{Title: "T", Content: "C", Category: "X", Tags: []string{}, Thumbnail: &empty},
+			want: bson.M{"title": "T", "content": "C", "category": "X", "tags": []string{}, "thumbnail": ""},
+		},
+		{
+			name: "revision from before tags were stored",
+			rev:  Revision{Title: "T", Content: "C", Category: "X"},
+			want: bson.M{"title": "T", "content": "C", "category": "X"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rev.RevertUpdates(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("RevertUpdates() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRevisionTagsRoundTrip(t *testing.T) {
+	for _, tags := range [][]string{{}, {"a", "b"}} {
+		data, err := bson.Marshal(Revision{Tags: tags})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got Revision
+		if err := bson.Unmarshal(data, &got); err != nil {
+			t.Fatal(err)
+		}
+		if got.Tags == nil || len(got.Tags) != len(tags) {
+			t.Errorf("tags %v came back as %v", tags, got.Tags)
+		}
+	}
+
+	data, err := bson.Marshal(bson.M{"title": "old"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var old Revision
+	if err := bson.Unmarshal(data, &old); err != nil {
+		t.Fatal(err)
+	}
+	if old.Tags != nil || old.Thumbnail != nil {
+		t.Errorf("revision without tags decoded as %v, %v", old.Tags, old.Thumbnail)
+	}
+}
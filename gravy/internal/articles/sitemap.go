@@ -0,0 +1,35 @@
+package articles
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// SitemapEntry is what a sitemap lists for a published article.
+type SitemapEntry struct {
+	Slug      string    `bson:"slug"`
+	UpdatedAt time.Time `bson:"updatedAt"`
+}
+
+// ListSitemapEntries pages through published articles oldest first, so
+// pages stay stable as articles are added.
+func (r *repository) ListSitemapEntries(ctx context.Context, limit, offset int) ([]SitemapEntry, error) {
+	opts := options.Find().
+		SetProjection(bson.M{"slug": 1, "updatedAt": 1}).
+		SetSort(bson.D{{Key: "_id", Value: 1}}).
+		SetLimit(int64(limit)).
+		SetSkip(int64(offset))
+
+	cursor, err := r.coll.Find(ctx, bson.M{"status": statusFilter(StatusPublished)}, opts)
+	if err != nil {
+		return nil, err
+	}
+	var entries []SitemapEntry
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
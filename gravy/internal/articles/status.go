@@ -0,0 +1,134 @@
+package articles
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+type Status string
+
+const (
+	StatusDraft     Status = "DRAFT"
+	StatusInReview  Status = "IN_REVIEW"
+	StatusScheduled Status = "SCHEDULED"
+	StatusPublished Status = "PUBLISHED"
+	StatusArchived  Status = "ARCHIVED"
+)
+
+var ErrInvalidTransition = errors.New("article is not in a state that allows this change")
+
+// CurrentStatus treats articles written before the review workflow existed
+// (no status field) as published.
+func (a *Article) CurrentStatus() Status {
+	if a.Status == "" {
+		return StatusPublished
+	}
+	return a.Status
+}
+
+func (a *Article) IsPublished() bool {
+	return a.CurrentStatus() == StatusPublished
+}
+
+// statusFilter matches the given statuses, including legacy documents
+// without a status when PUBLISHED is among them.
+func statusFilter(statuses ...Status) bson.M {
+	values := make([]interface{}, 0, len(statuses)+1)
+	for _, s := range statuses {
+		values = append(values, s)
+		if s == StatusPublished {
+			values = append(values, nil)
+		}
+	}
+	return bson.M{"$in": values}
+}
+
+// Transition moves an article to status `to` if it is currently in one of
+// `from`, setting any extra fields in the same write. The search index is
+// kept in step: articles entering PUBLISHED are indexed, articles leaving it
+// are removed.
+func (r *repository) Transition(ctx context.Context, id string, from []Status, to Status, fields bson.M) (*Article, error) {
+	idObj, err := bson.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	set := bson.M{"status": to, "updatedAt": time.Now()}
+	for k, v := range fields {
+		set[k] = v
+	}
+	if to != StatusPublished {
+		set["indexed"] = false
+	}
+
+	filter := bson.M{"_id": idObj, "status": statusFilter(from...)}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var article *Article
+	err = r.coll.FindOneAndUpdate(ctx, filter, bson.M{"$set": set}, opts).Decode(&article)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrInvalidTransition
+		}
+		return nil, err
+	}
+
+	r.updateLinker(article)
+	r.invalidateRelated()
+	if err := r.refreshTagCounts(ctx, article.Tags); err != nil {
+		return nil, err
+	}
+
+	if to == StatusPublished {
+		r.index(ctx, article)
+	} else {
+		for _, s := range from {
+			if s == StatusPublished {
+				_ = r.searchClient.DeleteArticle(ctx, article.ID)
+				break
+			}
+		}
+	}
+
+	return article, nil
+}
+
+// ListScheduledDue returns approved articles whose publishAt has passed (or
+// was cleared after approval).
+func (r *repository) ListScheduledDue(ctx context.Context, now time.Time, limit int) ([]*Article, error) {
+	filter := bson.M{
+		"status": StatusScheduled,
+		"$or": []bson.M{
+			{"publishAt": bson.M{"$lte": now}},
+			{"publishAt": nil},
+		},
+	}
+	return r.findDue(ctx, filter, "publishAt", limit)
+}
+
+// ListExpiredDue returns published articles whose expireAt has passed.
+func (r *repository) ListExpiredDue(ctx context.Context, now time.Time, limit int) ([]*Article, error) {
+	filter := bson.M{
+		"status":   statusFilter(StatusPublished),
+		"expireAt": bson.M{"$lte": now},
+	}
+	return r.findDue(ctx, filter, "expireAt", limit)
+}
+
+func (r *repository) findDue(ctx context.Context, filter bson.M, sortField string, limit int) ([]*Article, error) {
+	opts := options.Find().SetLimit(int64(limit)).SetSort(bson.D{{Key: sortField, Value: 1}})
+	cursor, err := r.coll.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	var articles []*Article
+	if err := cursor.All(ctx, &articles); err != nil {
+		return nil, err
+	}
+	return articles, nil
+}
@@ -0,0 +1,173 @@
+package articles
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+type SuggestionStatus string
+
+const (
+	SuggestionPending  SuggestionStatus = "PENDING"
+	SuggestionApproved SuggestionStatus = "APPROVED"
+	SuggestionRejected SuggestionStatus = "REJECTED"
+	// SuggestionStale marks a suggestion that was approved after the article
+	// had been edited again, so applying it would have discarded those edits.
+	SuggestionStale SuggestionStatus = "STALE"
+)
+
+var ErrSuggestionResolved = errors.New("suggestion has already been reviewed")
+
+// Suggestion is an edit proposed by a user who cannot edit articles
+// directly. It is applied only once an admin approves it.
+type Suggestion struct {
+	ID           string           `bson:"_id,omitempty"`
+	ArticleID    string           `bson:"articleId"`
+	AuthorID     string           `bson:"authorId"`
+	Content      string           `bson:"content"`
+	Summary      string           `bson:"summary"`
+	BaseVersion  int              `bson:"baseVersion"`
+	Status       SuggestionStatus `bson:"status"`
+	ReviewedBy   string           `bson:"reviewedBy,omitempty"`
+	RejectReason string           `bson:"rejectReason,omitempty"`
+	CreatedAt    time.Time        `bson:"createdAt"`
+	ReviewedAt   *time.Time       `bson:"reviewedAt,omitempty"`
+}
+
+// SuggestionFilter narrows ListSuggestions. Nil fields match everything.
+type SuggestionFilter struct {
+	ArticleID *string
+	AuthorID  *string
+	Status    *SuggestionStatus
+}
+
+func (r *repository) CreateSuggestion(ctx context.Context, s Suggestion) (*Suggestion, error) {
+	s.Status = SuggestionPending
+	s.CreatedAt = time.Now()
+	res, err := r.suggestions.InsertOne(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+	s.ID = res.InsertedID.(bson.ObjectID).Hex()
+	return &s, nil
+}
+
+func (r *repository) GetSuggestion(ctx context.Context, id string) (*Suggestion, error) {
+	idObj, err := bson.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+	var s Suggestion
+	if err := r.suggestions.FindOne(ctx, bson.M{"_id": idObj}).Decode(&s); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New("suggestion not found")
+		}
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (r *repository) ListSuggestions(ctx context.Context, filter SuggestionFilter, limit, offset int) ([]*Suggestion, error) {
+	query := bson.M{}
+	if filter.ArticleID != nil {
+		query["articleId"] = *filter.ArticleID
+	}
+	if filter.AuthorID != nil {
+		query["authorId"] = *filter.AuthorID
+	}
+	if filter.Status != nil {
+		query["status"] = *filter.Status
+	}
+
+	// The moderation queue is worked oldest first; everything else shows the
+	// latest first.
+	order := -1
+	if filter.Status != nil && *filter.Status == SuggestionPending {
+		order = 1
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "createdAt", Value: order}}).
+		SetLimit(int64(limit)).
+		SetSkip(int64(offset))
+
+	cursor, err := r.suggestions.Find(ctx, query, opts)
+	if err != nil {
+		return nil, err
+	}
+	var suggestions []*Suggestion
+	if err := cursor.All(ctx, &suggestions); err != nil {
+		return nil, err
+	}
+	return suggestions, nil
+}
+
+// ResolveSuggestion moves a pending suggestion to APPROVED or REJECTED. It
+// fails with ErrSuggestionResolved if someone else reviewed it first.
+func (r *repository) ResolveSuggestion(ctx context.Context, id string, status SuggestionStatus, reviewerID string, reason string) (*Suggestion, error) {
+	idObj, err := bson.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	set := bson.M{
+		"status":     status,
+		"reviewedBy": reviewerID,
+		"reviewedAt": time.Now(),
+	}
+	if reason != "" {
+		set["rejectReason"] = reason
+	}
+	filter := bson.M{"_id": idObj, "status": SuggestionPending}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var s *Suggestion
+	if err := r.suggestions.FindOneAndUpdate(ctx, filter, bson.M{"$set": set}, opts).Decode(&s); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrSuggestionResolved
+		}
+		return nil, err
+	}
+	return s, nil
+}
+
+// ReopenSuggestion puts a suggestion back in the queue, used when applying
+// an approved suggestion fails.
+func (r *repository) ReopenSuggestion(ctx context.Context, id string) error {
+	idObj, err := bson.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	_, err = r.suggestions.UpdateOne(ctx, bson.M{"_id": idObj}, bson.M{
+		"$set":   bson.M{"status": SuggestionPending},
+		"$unset": bson.M{"reviewedBy": "", "reviewedAt": "", "rejectReason": ""},
+	})
+	return err
+}
+
+// MarkSuggestionStale closes a suggestion whose base version is no longer
+// the article's current version.
+func (r *repository) MarkSuggestionStale(ctx context.Context, id string) error {
+	idObj, err := bson.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	_, err = r.suggestions.UpdateOne(ctx, bson.M{"_id": idObj}, bson.M{"$set": bson.M{
+		"status":       SuggestionStale,
+		"rejectReason": "The article was edited after this suggestion was made.",
+	}})
+	return err
+}
+
+func (r *repository) ensureSuggestionIndexes(ctx context.Context) error {
+	_, err := r.suggestions.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "createdAt", Value: 1}}},
+		{Keys: bson.D{{Key: "authorId", Value: 1}, {Key: "createdAt", Value: -1}}},
+		{Keys: bson.D{{Key: "articleId", Value: 1}, {Key: "status", Value: 1}}},
+	})
+	return err
+}
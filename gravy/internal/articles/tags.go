@@ -0,0 +1,124 @@
+package articles
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const (
+	MaxTags      = 10
+	MaxTagLength = 32
+)
+
+// Tag is an entry in the tag collection. Count is the number of published
+// articles carrying the tag; tags nobody uses any more are kept at zero
+// rather than deleted.
+type Tag struct {
+	ID        string    `bson:"_id,omitempty"`
+	Name      string    `bson:"name"`
+	Count     int64     `bson:"count"`
+	UpdatedAt time.Time `bson:"updatedAt"`
+}
+
+var tagSeparators = regexp.MustCompile(`[\s_]+`)
+var tagInvalidChars = regexp.MustCompile(`[^\p{L}\p{N}-]+`)
+
+// NormalizeTag lower-cases a tag and turns spaces into hyphens, so "First
+// Year" and "first-year" are the same tag. It returns "" for tags with
+// nothing usable left.
+func NormalizeTag(tag string) string {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	tag = tagSeparators.ReplaceAllString(tag, "-")
+	tag = tagInvalidChars.ReplaceAllString(tag, "")
+	tag = strings.Trim(tag, "-")
+	if len(tag) > MaxTagLength {
+		tag = strings.TrimRight(tag[:MaxTagLength], "-")
+	}
+	return tag
+}
+
+// NormalizeTags normalizes and de-duplicates tags, keeping their order.
+func NormalizeTags(tags []string) ([]string, error) {
+	result := make([]string, 0, len(tags))
+	seen := make(map[string]bool)
+	for _, t := range tags {
+		n := NormalizeTag(t)
+		if n == "" || seen[n] {
+			continue
+		}
+		seen[n] = true
+		result = append(result, n)
+	}
+	if len(result) > MaxTags {
+		return nil, fmt.Errorf("an article can have at most %d tags", MaxTags)
+	}
+	return result, nil
+}
+
+// ListTags returns tags in use that start with prefix, most used first.
+func (r *repository) ListTags(ctx context.Context, prefix string, limit int) ([]*Tag, error) {
+	filter := bson.M{"count": bson.M{"$gt": 0}}
+	if p := NormalizeTag(prefix); p != "" {
+		filter["name"] = bson.M{"$regex": "^" + regexp.QuoteMeta(p)}
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "count", Value: -1}, {Key: "name", Value: 1}}).
+		SetLimit(int64(limit))
+
+	cursor, err := r.tags.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	var tags []*Tag
+	if err := cursor.All(ctx, &tags); err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// refreshTagCounts recounts the published articles carrying each of tags.
+// Recounting instead of incrementing keeps the counts right however an
+// article moved in or out of a tag.
+func (r *repository) refreshTagCounts(ctx context.Context, tags ...[]string) error {
+	seen := make(map[string]bool)
+	for _, list := range tags {
+		for _, tag := range list {
+			if seen[tag] {
+				continue
+			}
+			seen[tag] = true
+
+			count, err := r.coll.CountDocuments(ctx, bson.M{"tags": tag, "status": statusFilter(StatusPublished)})
+			if err != nil {
+				return err
+			}
+			_, err = r.tags.UpdateOne(ctx,
+				bson.M{"name": tag},
+				bson.M{"$set": bson.M{"count": count, "updatedAt": time.Now()}},
+				options.UpdateOne().SetUpsert(true),
+			)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (r *repository) ensureTagIndexes(ctx context.Context) error {
+	_, err := r.tags.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "name", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{Keys: bson.D{{Key: "count", Value: -1}, {Key: "name", Value: 1}}},
+	})
+	return err
+}
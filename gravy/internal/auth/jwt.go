@@ -1,44 +1,76 @@
 package auth
 
 import (
-	"os"
+	"crypto/rand"
+	"encoding/hex"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
-var jwtSecret []byte
+// AccessTokenTTL is how long an access token is accepted. Clients keep a
+// session going by trading their refresh token for a new pair.
+const AccessTokenTTL = 15 * time.Minute
 
-func getJwtSecret() []byte {
-	if len(jwtSecret) == 0 {
-		jwtSecret = []byte(os.Getenv("JWT_SECRET"))
-	}
-	return jwtSecret
+// Claims are what an access token says about its bearer.
+type Claims struct {
+	UserID    string
+	SessionID string
 }
 
-func GenerateToken(userID string) (string, error) {
+// GenerateToken issues an access token for a session, signed with the
+// active key, and returns it with its expiry.
+func GenerateToken(userID, sessionID string) (string, time.Time, error) {
+	ks, err := Keys()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	jti := make([]byte, 16)
+	if _, err := rand.Read(jti); err != nil {
+		return "", time.Time{}, err
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(AccessTokenTTL)
 	claims := jwt.MapClaims{
 		"user_id": userID,
-		"exp":     time.Now().Add(time.Hour * 24 * 90).Unix(),
+		"sid":     sessionID,
+		"jti":     hex.EncodeToString(jti),
+		"iat":     now.Unix(),
+		"exp":     expiresAt.Unix(),
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(getJwtSecret())
+	signed, err := ks.sign(claims)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return signed, expiresAt, nil
 }
 
-func ParseToken(tokenStr string) (string, error) {
-	token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
-		return getJwtSecret(), nil
-	})
+// ParseToken validates an access token against the key named by its kid
+// header. Tokens issued before sessions existed carry no session ID and are
+// rejected, since they could not be revoked.
+func ParseToken(tokenStr string) (*Claims, error) {
+	ks, err := Keys()
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := jwt.Parse(tokenStr, ks.keyFunc, jwt.WithValidMethods(ks.algorithms()), jwt.WithExpirationRequired())
 
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		userID := claims["user_id"].(string)
-		return userID, nil
+		userID, _ := claims["user_id"].(string)
+		sessionID, _ := claims["sid"].(string)
+		if userID == "" || sessionID == "" {
+			return nil, jwt.ErrTokenInvalidClaims
+		}
+		return &Claims{UserID: userID, SessionID: sessionID}, nil
 	}
 
-	return "", jwt.ErrTokenInvalidClaims
+	return nil, jwt.ErrTokenInvalidClaims
 }
@@ -0,0 +1,352 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Supported signing algorithms.
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+)
+
+// Key is one JWT key. Keys without signing material only verify tokens
+// signed before they were rotated out.
+type Key struct {
+	ID        string
+	Algorithm string
+	signKey   interface{}
+	verifyKey interface{}
+}
+
+func (k *Key) method() jwt.SigningMethod {
+	return jwt.GetSigningMethod(k.Algorithm)
+}
+
+// KeySet holds the key new tokens are signed with and every key tokens are
+// still accepted from.
+type KeySet struct {
+	active *Key
+	keys   map[string]*Key
+	// legacy verifies tokens issued without a kid header, which were signed
+	// with JWT_SECRET.
+	legacy *Key
+}
+
+// keyFile is the format of JWT_KEYS_FILE and JWT_KEYS:
+//
+//	{
+//	  "active": "2026-10",
+//	  "keys": [
+//	    {"kid": "2026-10", "alg": "EdDSA", "privateKeyFile": "/run/secrets/jwt-2026-10.pem"},
+//	    {"kid": "2026-04", "alg": "EdDSA", "publicKey": "-----BEGIN PUBLIC KEY-----\n..."},
+//	    {"kid": "2025-hs", "alg": "HS256", "secret": "..."}
+//	  ]
+//	}
+//
+// Private keys are PKCS#8 (or PKCS#1 for RSA) PEM, public keys PKIX PEM.
+type keyFile struct {
+	Active string         `json:"active"`
+	Keys   []keyFileEntry `json:"keys"`
+}
+
+type keyFileEntry struct {
+	ID             string `json:"kid"`
+	Algorithm      string `json:"alg"`
+	Secret         string `json:"secret"`
+	PrivateKey     string `json:"privateKey"`
+	PrivateKeyFile string `json:"privateKeyFile"`
+	PublicKey      string `json:"publicKey"`
+	PublicKeyFile  string `json:"publicKeyFile"`
+}
+
+var (
+	keysOnce   sync.Once
+	loadedKeys *KeySet
+	keysErr    error
+)
+
+// Keys returns the key set configured in the environment, loading it on
+// first use.
+func Keys() (*KeySet, error) {
+	keysOnce.Do(func() {
+		loadedKeys, keysErr = LoadKeys()
+	})
+	return loadedKeys, keysErr
+}
+
+// LoadKeys reads the key set from JWT_KEYS_FILE, or from JWT_KEYS holding
+// the same JSON. Without either, JWT_SECRET is the only key. When a key file
+// is used and JWT_SECRET is still set, it stays as a verification key so
+// switching to a key file does not sign everyone out.
+func LoadKeys() (*KeySet, error) {
+	secret := os.Getenv("JWT_SECRET")
+
+	var data []byte
+	if path := os.Getenv("JWT_KEYS_FILE"); path != "" {
+		var err error
+		data, err = os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read JWT_KEYS_FILE: %w", err)
+		}
+	} else if inline := os.Getenv("JWT_KEYS"); inline != "" {
+		data = []byte(inline)
+	}
+
+	if data == nil {
+		if secret == "" {
+			return nil, errors.New("no JWT keys configured: set JWT_SECRET, JWT_KEYS or JWT_KEYS_FILE")
+		}
+		key := secretKey(secret)
+		return &KeySet{active: key, keys: map[string]*Key{key.ID: key}, legacy: key}, nil
+	}
+
+	var file keyFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid JWT key file: %w", err)
+	}
+	ks := &KeySet{keys: make(map[string]*Key)}
+	for _, entry := range file.Keys {
+		key, err := entry.load()
+		if err != nil {
+			return nil, fmt.Errorf("JWT key %q: %w", entry.ID, err)
+		}
+		if _, dup := ks.keys[key.ID]; dup {
+			return nil, fmt.Errorf("JWT key %q is listed twice", key.ID)
+		}
+		ks.keys[key.ID] = key
+	}
+
+	ks.active = ks.keys[file.Active]
+	if ks.active == nil {
+		return nil, fmt.Errorf("active JWT key %q is not in the key list", file.Active)
+	}
+	if ks.active.signKey == nil {
+		return nil, fmt.Errorf("active JWT key %q has no private key or secret", file.Active)
+	}
+
+	if secret != "" {
+		legacy := secretKey(secret)
+		if _, ok := ks.keys[legacy.ID]; !ok {
+			legacy.signKey = nil
+			ks.keys[legacy.ID] = legacy
+		}
+		ks.legacy = ks.keys[legacy.ID]
+	}
+	return ks, nil
+}
+
+// secretKey makes an HS256 key from a shared secret. Its ID is derived from
+// the secret so it stays the same across restarts without revealing it.
+func secretKey(secret string) *Key {
+	sum := sha256.Sum256([]byte(secret))
+	return &Key{
+		ID:        "hs-" + hex.EncodeToString(sum[:4]),
+		Algorithm: AlgHS256,
+		signKey:   []byte(secret),
+		verifyKey: []byte(secret),
+	}
+}
+
+func (e keyFileEntry) load() (*Key, error) {
+	if e.ID == "" {
+		return nil, errors.New("kid is required")
+	}
+	key := &Key{ID: e.ID, Algorithm: e.Algorithm}
+
+	if e.Algorithm == AlgHS256 {
+		if len(e.Secret) < 32 {
+			return nil, errors.New("HS256 secrets must be at least 32 bytes")
+		}
+		key.signKey = []byte(e.Secret)
+		key.verifyKey = []byte(e.Secret)
+		return key, nil
+	}
+	if e.Algorithm != AlgRS256 && e.Algorithm != AlgEdDSA {
+		return nil, fmt.Errorf("unsupported algorithm %q", e.Algorithm)
+	}
+
+	privatePEM, err := pemValue(e.PrivateKey, e.PrivateKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	if privatePEM != nil {
+		private, err := parsePrivateKey(privatePEM)
+		if err != nil {
+			return nil, err
+		}
+		switch k := private.(type) {
+		case ed25519.PrivateKey:
+			key.signKey, key.verifyKey = k, k.Public()
+		case *rsa.PrivateKey:
+			key.signKey, key.verifyKey = k, &k.PublicKey
+		}
+	} else {
+		publicPEM, err := pemValue(e.PublicKey, e.PublicKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		if publicPEM == nil {
+			return nil, errors.New("a private or public key is required")
+		}
+		key.verifyKey, err = parsePublicKey(publicPEM)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	switch key.verifyKey.(type) {
+	case ed25519.PublicKey:
+		if e.Algorithm != AlgEdDSA {
+			return nil, fmt.Errorf("an Ed25519 key cannot be used with %s", e.Algorithm)
+		}
+	case *rsa.PublicKey:
+		if e.Algorithm != AlgRS256 {
+			return nil, fmt.Errorf("an RSA key cannot be used with %s", e.Algorithm)
+		}
+		if key.verifyKey.(*rsa.PublicKey).N.BitLen() < 2048 {
+			return nil, errors.New("RSA keys must be at least 2048 bits")
+		}
+	default:
+		return nil, errors.New("only Ed25519 and RSA keys are supported")
+	}
+	return key, nil
+}
+
+func pemValue(inline, path string) ([]byte, error) {
+	if inline != "" {
+		return []byte(inline), nil
+	}
+	if path == "" {
+		return nil, nil
+	}
+	return os.ReadFile(path)
+}
+
+func parsePrivateKey(data []byte) (interface{}, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("private key is not PEM encoded")
+	}
+	if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	return nil, errors.New("private key is neither PKCS#8 nor PKCS#1")
+}
+
+func parsePublicKey(data []byte) (interface{}, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("public key is not PEM encoded")
+	}
+	return x509.ParsePKIXPublicKey(block.Bytes)
+}
+
+// sign signs claims with the active key.
+func (ks *KeySet) sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(ks.active.method(), claims)
+	token.Header["kid"] = ks.active.ID
+	return token.SignedString(ks.active.signKey)
+}
+
+// keyFunc picks the verification key named by a token's kid header, and
+// refuses tokens whose algorithm is not the one the key is for.
+func (ks *KeySet) keyFunc(token *jwt.Token) (interface{}, error) {
+	key := ks.legacy
+	if kid, ok := token.Header["kid"].(string); ok {
+		key = ks.keys[kid]
+	}
+	if key == nil {
+		return nil, errors.New("unknown signing key")
+	}
+	if token.Method.Alg() != key.Algorithm {
+		return nil, errors.New("token algorithm does not match its key")
+	}
+	return key.verifyKey, nil
+}
+
+func (ks *KeySet) algorithms() []string {
+	seen := make(map[string]bool)
+	var algs []string
+	for _, k := range ks.keys {
+		if !seen[k.Algorithm] {
+			seen[k.Algorithm] = true
+			algs = append(algs, k.Algorithm)
+		}
+	}
+	return algs
+}
+
+// JWK is a public key in JSON Web Key form.
+type JWK struct {
+	KeyType   string `json:"kty"`
+	ID        string `json:"kid"`
+	Algorithm string `json:"alg"`
+	Use       string `json:"use"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+}
+
+// PublicKeys returns the asymmetric keys as JWKs, sorted by kid. Shared
+// secrets are never published, so services verifying HS256 tokens still
+// need JWT_SECRET.
+func (ks *KeySet) PublicKeys() []JWK {
+	enc := base64.RawURLEncoding
+	var list []JWK
+	for _, k := range ks.keys {
+		switch pub := k.verifyKey.(type) {
+		case ed25519.PublicKey:
+			list = append(list, JWK{KeyType: "OKP", ID: k.ID, Algorithm: k.Algorithm, Use: "sig", Curve: "Ed25519", X: enc.EncodeToString(pub)})
+		case *rsa.PublicKey:
+			list = append(list, JWK{
+				KeyType: "RSA", ID: k.ID, Algorithm: k.Algorithm, Use: "sig",
+				N: enc.EncodeToString(pub.N.Bytes()),
+				E: enc.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+			})
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list
+}
+
+// JWKSHandler serves the public keys at /.well-known/jwks.json.
+func (ks *KeySet) JWKSHandler() http.Handler {
+	body, _ := json.Marshal(struct {
+		Keys []JWK `json:"keys"`
+	}{Keys: append([]JWK{}, ks.PublicKeys()...)})
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		// Verifiers refetch on an unknown kid, so a short cache only delays
+		// picking up keys that are not used for signing yet.
+		w.Header().Set("Cache-Control", "public, max-age=300")
+		w.Write(body)
+	})
+}
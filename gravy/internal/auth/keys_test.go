@@ -0,0 +1,421 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+var (
+	testRSAOnce sync.Once
+	testRSAKey  *rsa.PrivateKey
+)
+
+// rsaKey is generated once; 2048-bit keys take a while.
+func rsaKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	testRSAOnce.Do(func() {
+		var err error
+		testRSAKey, err = rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+	return testRSAKey
+}
+
+func privatePEM(t *testing.T, key interface{}) string {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+}
+
+func publicPEM(t *testing.T, key interface{}) string {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+}
+
+func keysJSON(t *testing.T, file keyFile) string {
+	t.Helper()
+	data, err := json.Marshal(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// loadKeys runs LoadKeys with the given JWT_SECRET and JWT_KEYS.
+func loadKeys(t *testing.T, secret, keys string) (*KeySet, error) {
+	t.Helper()
+	t.Setenv("JWT_SECRET", secret)
+	t.Setenv("JWT_KEYS_FILE", "")
+	t.Setenv("JWT_KEYS", keys)
+	return LoadKeys()
+}
+
+func mustLoadKeys(t *testing.T, secret, keys string) *KeySet {
+	t.Helper()
+	ks, err := loadKeys(t, secret, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ks
+}
+
+// useKeys makes Keys return ks for the rest of the test.
+func useKeys(t *testing.T, ks *KeySet) {
+	t.Helper()
+	keysOnce.Do(func() {})
+	prev, prevErr := loadedKeys, keysErr
+	loadedKeys, keysErr = ks, nil
+	t.Cleanup(func() { loadedKeys, keysErr = prev, prevErr })
+}
+
+func TestLoadKeysErrors(t *testing.T) {
+	_, edPrivate, _ := ed25519.GenerateKey(rand.Reader)
+	edPublic := edPrivate.Public()
+	smallRSA, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		secret string
+		file   keyFile
+		raw    string
+		want   string
+	}{
+		{name: "nothing configured", want: "no JWT keys configured"},
+		{name: "invalid JSON", raw: "{", want: "invalid JWT key file"},
+		{
+			name: "missing kid",
+			file: keyFile{Active: "a", Keys: []keyFileEntry{{Algorithm: AlgHS256, Secret: testSecret}}},
+			want: "kid is required",
+		},
+		{
+			name: "short secret",
+			file: keyFile{Active: "a", Keys: []keyFileEntry{{ID: "a", Algorithm: AlgHS256, Secret: "short"}}},
+			want: "at least 32 bytes",
+		},
+		{
+			name: "unsupported algorithm",
+			file: keyFile{Active: "a", Keys: []keyFileEntry{{ID: "a", Algorithm: "none"}}},
+			want: `unsupported algorithm "none"`,
+		},
+		{
+			name: "duplicate kid",
+			file: keyFile{Active: "a", Keys: []keyFileEntry{
+				{ID: "a", Algorithm: AlgHS256, Secret: testSecret},
+				{ID: "a", Algorithm: AlgHS256, Secret: testSecret},
+			}},
+			want: "listed twice",
+		},
+		{
+			name: "unknown active key",
+			file: keyFile{Active: "b", Keys: []keyFileEntry{{ID: "a", Algorithm: AlgHS256, Secret: testSecret}}},
+			want: "not in the key list",
+		},
+		{
+			name: "active key without a private key",
+			file: keyFile{Active: "a", Keys: []keyFileEntry{{ID: "a", Algorithm: AlgEdDSA, PublicKey: publicPEM(t, edPublic)}}},
+			want: "has no private key",
+		},
+		{
+			name: "no key material",
+			file: keyFile{Active: "a", Keys: []keyFileEntry{{ID: "a", Algorithm: AlgEdDSA}}},
+			want: "a private or public key is required",
+		},
+		{
+			name: "Ed25519 key declared as RS256",
+			file: keyFile{Active: "a", Keys: []keyFileEntry{{ID: "a", Algorithm: AlgRS256, PrivateKey: privatePEM(t, edPrivate)}}},
+			want: "cannot be used with RS256",
+		},
+		{
+			name: "RSA key declared as EdDSA",
+			file: keyFile{Active: "a", Keys: []keyFileEntry{{ID: "a", Algorithm: AlgEdDSA, PrivateKey: privatePEM(t, rsaKey(t))}}},
+			want: "cannot be used with EdDSA",
+		},
+		{
+			name: "small RSA key",
+			file: keyFile{Active: "a", Keys: []keyFileEntry{{ID: "a", Algorithm: AlgRS256, PrivateKey: privatePEM(t, smallRSA)}}},
+			want: "at least 2048 bits",
+		},
+		{
+			name: "not PEM",
+			file: keyFile{Active: "a", Keys: []keyFileEntry{{ID: "a", Algorithm: AlgEdDSA, PrivateKey: "not a key"}}},
+			want: "not PEM encoded",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw := tt.raw
+			if raw == "" && tt.file.Active != "" {
+				raw = keysJSON(t, tt.file)
+			}
+			_, err := loadKeys(t, tt.secret, raw)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("LoadKeys error = %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadKeysFile(t *testing.T) {
+	_, edPrivate, _ := ed25519.GenerateKey(rand.Reader)
+	dir := t.TempDir()
+	keyPath := filepath.Join(dir, "ed.pem")
+	if err := os.WriteFile(keyPath, []byte(privatePEM(t, edPrivate)), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	filePath := filepath.Join(dir, "keys.json")
+	data := keysJSON(t, keyFile{Active: "ed", Keys: []keyFileEntry{{ID: "ed", Algorithm: AlgEdDSA, PrivateKeyFile: keyPath}}})
+	if err := os.WriteFile(filePath, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("JWT_SECRET", "")
+	t.Setenv("JWT_KEYS", "ignored when a file is set")
+	t.Setenv("JWT_KEYS_FILE", filePath)
+	ks, err := LoadKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ks.active.ID != "ed" || ks.legacy != nil {
+		t.Errorf("active = %q, legacy = %v", ks.active.ID, ks.legacy)
+	}
+}
+
+// token signs claims with key, setting kid unless it is "".
+func token(t *testing.T, method jwt.SigningMethod, key interface{}, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+	tok := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		tok.Header["kid"] = kid
+	}
+	signed, err := tok.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func validClaims() jwt.MapClaims {
+	return jwt.MapClaims{"user_id": "u1", "sid": "s1", "exp": time.Now().Add(time.Minute).Unix()}
+}
+
+func TestKeyRotation(t *testing.T) {
+	_, oldPrivate, _ := ed25519.GenerateKey(rand.Reader)
+	_, newPrivate, _ := ed25519.GenerateKey(rand.Reader)
+
+	// Before the rotation: only the old key, and JWT_SECRET from before key
+	// files were used.
+	before := mustLoadKeys(t, testSecret, keysJSON(t, keyFile{Active: "old", Keys: []keyFileEntry{
+		{ID: "old", Algorithm: AlgEdDSA, PrivateKey: privatePEM(t, oldPrivate)},
+	}}))
+	useKeys(t, before)
+	oldToken, _, err := GenerateToken("u1", "s1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	legacyToken := token(t, jwt.SigningMethodHS256, []byte(testSecret), "", validClaims())
+
+	// After: the new key signs, the old one only verifies.
+	after := mustLoadKeys(t, testSecret, keysJSON(t, keyFile{Active: "new", Keys: []keyFileEntry{
+		{ID: "new", Algorithm: AlgEdDSA, PrivateKey: privatePEM(t, newPrivate)},
+		{ID: "old", Algorithm: AlgEdDSA, PublicKey: publicPEM(t, oldPrivate.Public())},
+	}}))
+	useKeys(t, after)
+	newToken, _, err := GenerateToken("u2", "s2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, tok := range map[string]string{"old key": oldToken, "new key": newToken, "JWT_SECRET without kid": legacyToken} {
+		if _, err := ParseToken(tok); err != nil {
+			t.Errorf("token signed with the %s was rejected: %v", name, err)
+		}
+	}
+	parsed, _, _ := jwt.NewParser().ParseUnverified(newToken, jwt.MapClaims{})
+	if parsed.Header["kid"] != "new" || parsed.Header["alg"] != AlgEdDSA {
+		t.Errorf("new tokens should be signed with the active key, header = %v", parsed.Header)
+	}
+
+	// Dropping the old key from the list stops its tokens working.
+	dropped := mustLoadKeys(t, "", keysJSON(t, keyFile{Active: "new", Keys: []keyFileEntry{
+		{ID: "new", Algorithm: AlgEdDSA, PrivateKey: privatePEM(t, newPrivate)},
+	}}))
+	useKeys(t, dropped)
+	if _, err := ParseToken(oldToken); err == nil {
+		t.Error("token signed with a removed key was accepted")
+	}
+	if _, err := ParseToken(legacyToken); err == nil {
+		t.Error("token without kid was accepted after JWT_SECRET was removed")
+	}
+}
+
+func TestParseTokenRejects(t *testing.T) {
+	private := rsaKey(t)
+	ks := mustLoadKeys(t, "", keysJSON(t, keyFile{Active: "rsa", Keys: []keyFileEntry{
+		{ID: "rsa", Algorithm: AlgRS256, PrivateKey: privatePEM(t, private)},
+		{ID: "hs", Algorithm: AlgHS256, Secret: testSecret},
+	}}))
+	useKeys(t, ks)
+
+	expired := validClaims()
+	expired["exp"] = time.Now().Add(-time.Minute).Unix()
+	noExpiry := validClaims()
+	delete(noExpiry, "exp")
+	noSession := validClaims()
+	delete(noSession, "sid")
+
+	publicDER, _ := x509.MarshalPKIXPublicKey(&private.PublicKey)
+	_, otherEd, _ := ed25519.GenerateKey(rand.Reader)
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"expired", token(t, jwt.SigningMethodRS256, private, "rsa", expired)},
+		{"no expiry", token(t, jwt.SigningMethodRS256, private, "rsa", noExpiry)},
+		{"no session", token(t, jwt.SigningMethodRS256, private, "rsa", noSession)},
+		{"unknown kid", token(t, jwt.SigningMethodRS256, private, "other", validClaims())},
+		{"no kid and no JWT_SECRET", token(t, jwt.SigningMethodHS256, []byte(testSecret), "", validClaims())},
+		// HMAC with the RSA public key as the secret, the classic algorithm
+		// confusion attack.
+		{"HS256 under an RSA kid", token(t, jwt.SigningMethodHS256, publicDER, "rsa", validClaims())},
+		{"RS256 under an HS256 kid", token(t, jwt.SigningMethodRS256, private, "hs", validClaims())},
+		{"algorithm not in the key set", token(t, jwt.SigningMethodEdDSA, otherEd, "rsa", validClaims())},
+		{"alg none", token(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, "rsa", validClaims())},
+		{"tampered payload", tamper(token(t, jwt.SigningMethodRS256, private, "rsa", validClaims()))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if claims, err := ParseToken(tt.token); err == nil {
+				t.Errorf("accepted token with claims %+v", claims)
+			}
+		})
+	}
+
+	claims, err := ParseToken(token(t, jwt.SigningMethodHS256, []byte(testSecret), "hs", validClaims()))
+	if err != nil || claims.UserID != "u1" || claims.SessionID != "s1" {
+		t.Errorf("valid HS256 token: %+v, %v", claims, err)
+	}
+}
+
+// tamper swaps a token's payload for one naming another user, keeping the
+// original signature.
+func tamper(tok string) string {
+	parts := strings.Split(tok, ".")
+	parts[1] = base64.RawURLEncoding.EncodeToString([]byte(`{"user_id":"admin","sid":"s1","exp":9999999999}`))
+	return strings.Join(parts, ".")
+}
+
+func TestPublicKeys(t *testing.T) {
+	edPublic, edPrivate, _ := ed25519.GenerateKey(rand.Reader)
+	private := rsaKey(t)
+	ks := mustLoadKeys(t, testSecret, keysJSON(t, keyFile{Active: "ed", Keys: []keyFileEntry{
+		{ID: "ed", Algorithm: AlgEdDSA, PrivateKey: privatePEM(t, edPrivate)},
+		{ID: "rsa", Algorithm: AlgRS256, PublicKey: publicPEM(t, &private.PublicKey)},
+		{ID: "hs", Algorithm: AlgHS256, Secret: testSecret},
+	}}))
+
+	enc := base64.RawURLEncoding
+	want := []JWK{
+		{KeyType: "OKP", ID: "ed", Algorithm: AlgEdDSA, Use: "sig", Curve: "Ed25519", X: enc.EncodeToString(edPublic)},
+		{
+			KeyType: "RSA", ID: "rsa", Algorithm: AlgRS256, Use: "sig",
+			N: enc.EncodeToString(private.N.Bytes()),
+			E: enc.EncodeToString(big.NewInt(int64(private.E)).Bytes()),
+		},
+	}
+	got := ks.PublicKeys()
+	if len(got) != len(want) {
+		t.Fatalf("PublicKeys = %+v, want %+v (shared secrets must not be published)", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("key %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	// The published Ed25519 key verifies tokens the set signs.
+	useKeys(t, ks)
+	signed, _, err := GenerateToken("u1", "s1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	x, _ := enc.DecodeString(got[0].X)
+	if _, err := jwt.Parse(signed, func(*jwt.Token) (interface{}, error) { return ed25519.PublicKey(x), nil }); err != nil {
+		t.Errorf("token does not verify against the published key: %v", err)
+	}
+}
+
+func TestJWKSHandler(t *testing.T) {
+	_, edPrivate, _ := ed25519.GenerateKey(rand.Reader)
+	ks := mustLoadKeys(t, "", keysJSON(t, keyFile{Active: "ed", Keys: []keyFileEntry{
+		{ID: "ed", Algorithm: AlgEdDSA, PrivateKey: privatePEM(t, edPrivate)},
+	}}))
+	srv := httptest.NewServer(ks.JWKSHandler())
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "application/json" {
+		t.Fatalf("status %d, content type %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	var body struct {
+		Keys []JWK `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if len(body.Keys) != 1 || body.Keys[0].ID != "ed" {
+		t.Errorf("keys = %+v", body.Keys)
+	}
+
+	resp, err = http.Post(srv.URL, "application/json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("POST status = %d", resp.StatusCode)
+	}
+
+	// Only HS256 keys: the set is still an empty list, not null.
+	hsOnly := mustLoadKeys(t, testSecret, "")
+	rec := httptest.NewRecorder()
+	hsOnly.JWKSHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/.well-known/jwks.json", nil))
+	if got := strings.TrimSpace(rec.Body.String()); got != `{"keys":[]}` {
+		t.Errorf("body = %s", got)
+	}
+}
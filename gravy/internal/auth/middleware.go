@@ -2,25 +2,48 @@ package auth
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"net"
 	"net/http"
 	"strings"
+	"time"
 
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/pranava-mohan/wikinitt/gravy/internal/sessions"
 	"github.com/pranava-mohan/wikinitt/gravy/internal/users"
+	"github.com/pranava-mohan/wikinitt/gravy/internal/views"
 )
 
-var userCtxKey = &contextKey{"user"}
+var (
+	userCtxKey    = &contextKey{"user"}
+	sessionCtxKey = &contextKey{"session"}
+	clientCtxKey  = &contextKey{"client"}
+)
 
 type contextKey struct {
 	name string
 }
 
-func Middleware(userRepo users.Repository) func(http.Handler) http.Handler {
+// websocketRecheckInterval is how often an open websocket connection's
+// token, session and user are checked again.
+const websocketRecheckInterval = time.Minute
+
+var websocketCancelCtxKey = &contextKey{"websocketCancel"}
+
+// Middleware authenticates requests carrying an access token. Tokens whose
+// session has been revoked or has expired, and tokens of banned users, are
+// refused with 401 so clients know to refresh or sign in again.
+func Middleware(userRepo users.Repository, sessionStore *Sessions) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			client := requestClient(r)
+			ctx := context.WithValue(r.Context(), clientCtxKey, client)
 			header := r.Header.Get("Authorization")
 
 			if header == "" {
-				next.ServeHTTP(w, r)
+				next.ServeHTTP(w, r.WithContext(ctx))
 				return
 			}
 
@@ -30,27 +53,138 @@ func Middleware(userRepo users.Repository) func(http.Handler) http.Handler {
 				tokenStr = splitToken[1]
 			}
 
-			userID, err := ParseToken(tokenStr)
+			ctx, err := authenticate(ctx, userRepo, sessionStore, tokenStr, client)
 			if err != nil {
-				http.Error(w, "Invalid token", http.StatusForbidden)
+				switch {
+				case errors.Is(err, jwt.ErrTokenExpired):
+					http.Error(w, "Token expired", http.StatusUnauthorized)
+				case errors.Is(err, ErrSessionRevoked):
+					http.Error(w, "Session expired or revoked", http.StatusUnauthorized)
+				default:
+					http.Error(w, "Invalid token", http.StatusForbidden)
+				}
 				return
 			}
 
-			user, err := userRepo.GetByID(r.Context(), userID)
-			if err != nil {
-				next.ServeHTTP(w, r)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// WebsocketInitFunc authenticates subscription connections. Browsers cannot set
+// headers on websocket upgrades, so the token is read from the connection_init
+// payload instead ("Authorization" or "authToken"). Connections outlive the
+// token they were opened with, so they are closed once it expires, its
+// session is revoked or the user is banned; clients reconnect with a new
+// token.
+func WebsocketInitFunc(userRepo users.Repository, sessionStore *Sessions) transport.WebsocketInitFunc {
+	return func(ctx context.Context, initPayload transport.InitPayload) (context.Context, *transport.InitPayload, error) {
+		header := initPayload.Authorization()
+		if header == "" {
+			header = initPayload.GetString("authToken")
+		}
+		if header == "" {
+			return ctx, nil, nil
+		}
+
+		tokenStr := strings.TrimPrefix(header, "Bearer ")
+		ctx, err := authenticate(ctx, userRepo, sessionStore, tokenStr, ClientFromContext(ctx))
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid token")
+		}
+		if ForContext(ctx) == nil {
+			return ctx, nil, nil
+		}
+
+		// Cancelling the connection's context makes gqlgen close it.
+		ctx, cancel := context.WithCancel(ctx)
+		ctx = context.WithValue(ctx, websocketCancelCtxKey, cancel)
+		go watchConnection(ctx, cancel, websocketRecheckInterval, func(ctx context.Context) bool {
+			checked, err := authenticate(ctx, userRepo, sessionStore, tokenStr, ClientFromContext(ctx))
+			return err == nil && ForContext(checked) != nil
+		})
+		return ctx, nil, nil
+	}
+}
+
+// WebsocketCloseFunc stops watching a connection once it is closed.
+func WebsocketCloseFunc(ctx context.Context, closeCode int) {
+	if cancel, ok := ctx.Value(websocketCancelCtxKey).(context.CancelFunc); ok {
+		cancel()
+	}
+}
+
+// watchConnection runs valid every interval until ctx is done, and cancels
+// ctx when it reports false.
+func watchConnection(ctx context.Context, cancel context.CancelFunc, interval time.Duration, valid func(ctx context.Context) bool) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !valid(ctx) {
+				cancel()
 				return
 			}
+		}
+	}
+}
 
-			ctx := context.WithValue(r.Context(), userCtxKey, user)
+// authenticate resolves an access token to its user and session. A token
+// whose user no longer exists authenticates nobody but is not an error.
+func authenticate(ctx context.Context, userRepo users.Repository, sessionStore *Sessions, tokenStr string, client sessions.Client) (context.Context, error) {
+	claims, err := ParseToken(tokenStr)
+	if err != nil {
+		return nil, err
+	}
 
-			r = r.WithContext(ctx)
-			next.ServeHTTP(w, r)
-		})
+	session, err := sessionStore.Check(ctx, claims.SessionID, client)
+	if err != nil {
+		return nil, err
+	}
+	if session.UserID != claims.UserID {
+		return nil, jwt.ErrTokenInvalidClaims
+	}
+
+	user, err := userRepo.GetByID(ctx, claims.UserID)
+	if err != nil {
+		return ctx, nil
+	}
+	if user.IsBanned {
+		return nil, ErrSessionRevoked
 	}
+
+	ctx = context.WithValue(ctx, userCtxKey, user)
+	return context.WithValue(ctx, sessionCtxKey, session.ID), nil
+}
+
+func requestClient(r *http.Request) sessions.Client {
+	ip := views.IPFromContext(r.Context())
+	if ip == "" {
+		ip = r.RemoteAddr
+		if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+			ip = host
+		}
+	}
+	return sessions.Client{UserAgent: r.UserAgent(), IP: ip}
 }
 
 func ForContext(ctx context.Context) *users.User {
 	raw, _ := ctx.Value(userCtxKey).(*users.User)
 	return raw
 }
+
+// SessionIDFromContext returns the session the request was authenticated
+// with, or "" if it was not.
+func SessionIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(sessionCtxKey).(string)
+	return id
+}
+
+// ClientFromContext returns the device the request came from.
+func ClientFromContext(ctx context.Context) sessions.Client {
+	client, _ := ctx.Value(clientCtxKey).(sessions.Client)
+	return client
+}
@@ -0,0 +1,50 @@
+package auth
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWatchConnection(t *testing.T) {
+	t.Run("closes once a check fails", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		var checks atomic.Int32
+		done := make(chan struct{})
+		go func() {
+			watchConnection(ctx, cancel, time.Millisecond, func(context.Context) bool {
+				return checks.Add(1) < 3
+			})
+			close(done)
+		}()
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(time.Second):
+			t.Fatal("connection was not closed")
+		}
+		<-done
+		if n := checks.Load(); n != 3 {
+			t.Errorf("checked %d times, want 3", n)
+		}
+	})
+
+	t.Run("stops when the connection closes", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		ctx = context.WithValue(ctx, websocketCancelCtxKey, cancel)
+		done := make(chan struct{})
+		go func() {
+			watchConnection(ctx, cancel, time.Hour, func(context.Context) bool { return true })
+			close(done)
+		}()
+
+		WebsocketCloseFunc(ctx, 1000)
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("watcher kept running after the connection closed")
+		}
+	})
+}
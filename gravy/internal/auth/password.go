@@ -0,0 +1,109 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	MinPasswordLength = 10
+	// maxPasswordBytes is bcrypt's limit; anything longer would be silently
+	// cut off.
+	maxPasswordBytes = 72
+	// passphraseLength is the length from which a password is accepted
+	// without mixing character classes.
+	passphraseLength = 16
+	passwordCost     = 12
+)
+
+// commonPasswords are rejected however they are capitalised or padded with
+// digits and symbols.
+var commonPasswords = []string{
+	"password", "passw0rd", "qwerty", "qwertyuiop", "asdfgh", "zxcvbn", "abc123",
+	"letmein", "welcome", "iloveyou", "admin", "administrator", "monkey", "dragon",
+	"football", "cricket", "sunshine", "princess", "master", "secret", "login",
+	"wikinitt", "nitt", "trichy", "tiruchirappalli", "nittrichy",
+}
+
+// CheckPasswordStrength rejects passwords that are short, use too few kinds
+// of characters, are common, or contain personal details such as the
+// user's name or email address.
+func CheckPasswordStrength(password string, personal ...string) error {
+	if len([]rune(password)) < MinPasswordLength {
+		return fmt.Errorf("password must be at least %d characters", MinPasswordLength)
+	}
+	if len(password) > maxPasswordBytes {
+		return fmt.Errorf("password must be at most %d bytes", maxPasswordBytes)
+	}
+
+	var lower, upper, digit, other bool
+	distinct := make(map[rune]bool)
+	for _, r := range password {
+		distinct[r] = true
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			other = true
+		}
+	}
+	if len(distinct) < 5 {
+		return errors.New("password is too repetitive")
+	}
+	classes := 0
+	for _, ok := range []bool{lower, upper, digit, other} {
+		if ok {
+			classes++
+		}
+	}
+	if classes < 3 && len([]rune(password)) < passphraseLength {
+		return fmt.Errorf("password must mix at least three of lowercase letters, uppercase letters, digits and symbols, or be at least %d characters long", passphraseLength)
+	}
+
+	core := strings.ToLower(strings.TrimFunc(password, func(r rune) bool {
+		return !unicode.IsLetter(r)
+	}))
+	for _, common := range commonPasswords {
+		if core == common {
+			return errors.New("password is too common")
+		}
+	}
+
+	lowered := strings.ToLower(password)
+	for _, p := range personal {
+		for _, part := range personalParts(p) {
+			if len(part) >= 4 && strings.Contains(lowered, part) {
+				return errors.New("password must not contain your name or email address")
+			}
+		}
+	}
+	return nil
+}
+
+// personalParts splits a name or email address into the words a password
+// should not contain.
+func personalParts(s string) []string {
+	if at := strings.IndexByte(s, '@'); at >= 0 {
+		s = s[:at]
+	}
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// HashPassword hashes a password for storage.
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), passwordCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
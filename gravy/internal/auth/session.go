@@ -0,0 +1,215 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/golang-lru/v2/expirable"
+	"github.com/pranava-mohan/wikinitt/gravy/internal/sessions"
+)
+
+const (
+	// RefreshTokenTTL is how long a session lasts without being refreshed.
+	// Every refresh pushes the expiry back.
+	RefreshTokenTTL = 30 * 24 * time.Hour
+
+	// sessionCacheTTL bounds how long a revocation made by another server
+	// instance can go unnoticed.
+	sessionCacheTTL  = 30 * time.Second
+	sessionCacheSize = 10000
+
+	// touchInterval limits how often a session's last-seen time is written.
+	touchInterval = 5 * time.Minute
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrSessionRevoked      = errors.New("session has expired or been revoked")
+)
+
+// Tokens is what a client receives when a session starts or is refreshed.
+type Tokens struct {
+	AccessToken     string
+	AccessExpiresAt time.Time
+	RefreshToken    string
+	Session         *sessions.Session
+}
+
+// Sessions issues and revokes sessions and checks that the session behind
+// an access token is still active.
+type Sessions struct {
+	repo  sessions.Repository
+	cache *expirable.LRU[string, *sessions.Session]
+}
+
+func NewSessions(repo sessions.Repository) *Sessions {
+	return &Sessions{
+		repo:  repo,
+		cache: expirable.NewLRU[string, *sessions.Session](sessionCacheSize, nil, sessionCacheTTL),
+	}
+}
+
+// Start opens a new session for the user.
+func (s *Sessions) Start(ctx context.Context, userID string, client sessions.Client) (*Tokens, error) {
+	secret, hash, err := newRefreshSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	session := &sessions.Session{
+		UserID:      userID,
+		RefreshHash: hash,
+		UserAgent:   client.UserAgent,
+		IP:          client.IP,
+		CreatedAt:   now,
+		LastSeenAt:  now,
+		ExpiresAt:   now.Add(RefreshTokenTTL),
+	}
+	if err := s.repo.Create(ctx, session); err != nil {
+		return nil, err
+	}
+	s.cache.Add(session.ID, session)
+	return issueTokens(session, secret)
+}
+
+// Refresh trades a refresh token for a new access token and a new refresh
+// token; the old refresh token stops working. Presenting a refresh token
+// that was already traded in means it was copied, so the whole session is
+// revoked.
+func (s *Sessions) Refresh(ctx context.Context, refreshToken string, client sessions.Client) (*Tokens, error) {
+	id, secret, ok := strings.Cut(refreshToken, ".")
+	if !ok || id == "" || secret == "" {
+		return nil, ErrInvalidRefreshToken
+	}
+	hash := hashRefreshSecret(secret)
+
+	newSecret, newHash, err := newRefreshSecret()
+	if err != nil {
+		return nil, err
+	}
+	session, err := s.repo.Rotate(ctx, id, hash, newHash, client, time.Now().Add(RefreshTokenTTL))
+	if err == sessions.ErrNotFound {
+		existing, getErr := s.repo.Get(ctx, id)
+		if getErr == nil && existing.Active(time.Now()) && existing.PreviousHash != "" &&
+			subtle.ConstantTimeCompare([]byte(existing.PreviousHash), []byte(hash)) == 1 {
+			log.Printf("auth: refresh token of session %s was reused, revoking it", id)
+			if _, err := s.Revoke(ctx, existing.UserID, id, "refresh token reused"); err != nil {
+				log.Printf("auth: failed to revoke session %s: %v", id, err)
+			}
+		}
+		return nil, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.cache.Add(session.ID, session)
+	return issueTokens(session, newSecret)
+}
+
+// Check returns the session if it is still active, and records that it was
+// used from client.
+func (s *Sessions) Check(ctx context.Context, sessionID string, client sessions.Client) (*sessions.Session, error) {
+	session, ok := s.cache.Get(sessionID)
+	if !ok {
+		var err error
+		session, err = s.repo.Get(ctx, sessionID)
+		if err == sessions.ErrNotFound {
+			return nil, ErrSessionRevoked
+		}
+		if err != nil {
+			return nil, err
+		}
+		s.cache.Add(sessionID, session)
+	}
+
+	now := time.Now()
+	if !session.Active(now) {
+		return nil, ErrSessionRevoked
+	}
+
+	if now.Sub(session.LastSeenAt) > touchInterval {
+		// Cached sessions are shared between requests, so update a copy.
+		touched := *session
+		touched.LastSeenAt = now
+		touched.UserAgent = client.UserAgent
+		touched.IP = client.IP
+		s.cache.Add(sessionID, &touched)
+		if err := s.repo.Touch(ctx, sessionID, client); err != nil {
+			log.Printf("auth: failed to update last seen time of session %s: %v", sessionID, err)
+		}
+		session = &touched
+	}
+	return session, nil
+}
+
+// List returns the user's active sessions.
+func (s *Sessions) List(ctx context.Context, userID string) ([]*sessions.Session, error) {
+	return s.repo.ListActive(ctx, userID)
+}
+
+// Revoke ends one of the user's sessions. It reports whether there was an
+// active session to end.
+func (s *Sessions) Revoke(ctx context.Context, userID, sessionID, reason string) (bool, error) {
+	revoked, err := s.repo.Revoke(ctx, userID, sessionID, reason)
+	if err != nil {
+		return false, err
+	}
+	s.cache.Remove(sessionID)
+	return revoked, nil
+}
+
+// RevokeAll ends every session of the user and returns how many there were.
+func (s *Sessions) RevokeAll(ctx context.Context, userID, reason string) (int, error) {
+	return s.RevokeOthers(ctx, userID, "", reason)
+}
+
+// RevokeOthers ends every session of the user but keepID and returns how
+// many there were.
+func (s *Sessions) RevokeOthers(ctx context.Context, userID, keepID, reason string) (int, error) {
+	ids, err := s.repo.RevokeAll(ctx, userID, keepID, reason)
+	if err != nil {
+		return 0, err
+	}
+	for _, id := range ids {
+		s.cache.Remove(id)
+	}
+	return len(ids), nil
+}
+
+func issueTokens(session *sessions.Session, secret string) (*Tokens, error) {
+	accessToken, expiresAt, err := GenerateToken(session.UserID, session.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &Tokens{
+		AccessToken:     accessToken,
+		AccessExpiresAt: expiresAt,
+		RefreshToken:    session.ID + "." + secret,
+		Session:         session,
+	}, nil
+}
+
+// newRefreshSecret returns the secret half of a refresh token and the hash
+// that is stored in its place.
+func newRefreshSecret() (string, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	secret := base64.RawURLEncoding.EncodeToString(b)
+	return secret, hashRefreshSecret(secret), nil
+}
+
+func hashRefreshSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,286 @@
+package backlinks
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+type State string
+
+const (
+	StatePending State = "PENDING"
+	StateRunning State = "RUNNING"
+	StateDone    State = "DONE"
+	StateFailed  State = "FAILED"
+)
+
+// MaxAttempts is how many times a job is claimed before it is marked FAILED.
+const MaxAttempts = 5
+
+// retryBackoff is multiplied by the attempt count to delay a failed job's
+// next run.
+const retryBackoff = 30 * time.Second
+
+// ErrLeaseLost is returned when a worker's claim on a job has expired and
+// another worker has taken it over.
+var ErrLeaseLost = errors.New("backlink job was claimed by another worker")
+
+// Job scans every article for mentions of one newly published title and
+// records the links it finds. Cursor is the _id of the last article scanned,
+// so a job picked up again after a restart carries on where it stopped.
+type Job struct {
+	ID          string     `bson:"_id,omitempty"`
+	Key         string     `bson:"key"`
+	TargetID    string     `bson:"targetId"`
+	Title       string     `bson:"title"`
+	Slug        string     `bson:"slug"`
+	State       State      `bson:"state"`
+	Active      bool       `bson:"active,omitempty"`
+	Cursor      string     `bson:"cursor"`
+	Processed   int        `bson:"processed"`
+	Linked      int        `bson:"linked"`
+	Attempts    int        `bson:"attempts"`
+	Error       string     `bson:"error,omitempty"`
+	Owner       string     `bson:"owner,omitempty"`
+	LockedUntil *time.Time `bson:"lockedUntil,omitempty"`
+	CreatedAt   time.Time  `bson:"createdAt"`
+	UpdatedAt   time.Time  `bson:"updatedAt"`
+	FinishedAt  *time.Time `bson:"finishedAt,omitempty"`
+}
+
+type Repository interface {
+	Enqueue(ctx context.Context, job Job) (*Job, error)
+	Claim(ctx context.Context, owner string, lockFor time.Duration) (*Job, error)
+	FailAbandoned(ctx context.Context) (int64, error)
+	Checkpoint(ctx context.Context, job *Job, lockFor time.Duration) error
+	Complete(ctx context.Context, job *Job) error
+	Fail(ctx context.Context, job *Job, cause error) error
+	Release(ctx context.Context, job *Job) error
+	List(ctx context.Context, state *State, limit, offset int) ([]*Job, error)
+	EnsureIndexes(ctx context.Context) error
+}
+
+type repository struct {
+	coll *mongo.Collection
+}
+
+func NewRepository(db *mongo.Database) Repository {
+	return &repository{
+		coll: db.Collection("backlink_jobs"),
+	}
+}
+
+// Enqueue adds a job unless one for the same key is already pending or
+// running, in which case that job is returned instead.
+func (r *repository) Enqueue(ctx context.Context, job Job) (*Job, error) {
+	now := time.Now()
+	job.ID = ""
+	job.State = StatePending
+	job.Active = true
+	job.CreatedAt = now
+	job.UpdatedAt = now
+
+	filter := bson.M{"key": job.Key, "active": true}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var stored *Job
+	err := r.coll.FindOneAndUpdate(ctx, filter, bson.M{"$setOnInsert": job}, opts).Decode(&stored)
+	if mongo.IsDuplicateKeyError(err) {
+		// Lost an upsert race with another enqueue for the same key.
+		err = r.coll.FindOne(ctx, filter).Decode(&stored)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return stored, nil
+}
+
+// Claim takes the oldest pending job that is not waiting out a retry delay,
+// or a running job whose worker stopped renewing its lock, and locks it to
+// owner. Jobs that have used up their attempts are left to FailAbandoned.
+func (r *repository) Claim(ctx context.Context, owner string, lockFor time.Duration) (*Job, error) {
+	now := time.Now()
+	filter := bson.M{
+		"active":   true,
+		"attempts": bson.M{"$lt": MaxAttempts},
+		"$or": []bson.M{
+			{"lockedUntil": nil},
+			{"lockedUntil": bson.M{"$lte": now}},
+		},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"state":       StateRunning,
+			"owner":       owner,
+			"lockedUntil": now.Add(lockFor),
+			"updatedAt":   now,
+		},
+		"$inc": bson.M{"attempts": 1},
+	}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "createdAt", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	var job *Job
+	err := r.coll.FindOneAndUpdate(ctx, filter, update, opts).Decode(&job)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return job, nil
+}
+
+// FailAbandoned marks FAILED the running jobs whose worker stopped renewing
+// its lock during their last attempt. Claim no longer picks those up, so
+// without this they would stay RUNNING forever.
+func (r *repository) FailAbandoned(ctx context.Context) (int64, error) {
+	now := time.Now()
+	res, err := r.coll.UpdateMany(ctx,
+		bson.M{
+			"active":      true,
+			"state":       StateRunning,
+			"attempts":    bson.M{"$gte": MaxAttempts},
+			"lockedUntil": bson.M{"$lte": now},
+		},
+		bson.M{
+			"$set": bson.M{
+				"state":      StateFailed,
+				"error":      "the worker stopped during the last attempt",
+				"updatedAt":  now,
+				"finishedAt": now,
+			},
+			"$unset": bson.M{"active": "", "owner": "", "lockedUntil": ""},
+		},
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.ModifiedCount, nil
+}
+
+// Checkpoint saves the job's progress and renews the worker's lock.
+func (r *repository) Checkpoint(ctx context.Context, job *Job, lockFor time.Duration) error {
+	now := time.Now()
+	return r.updateOwned(ctx, job, bson.M{"$set": bson.M{
+		"cursor":      job.Cursor,
+		"processed":   job.Processed,
+		"linked":      job.Linked,
+		"lockedUntil": now.Add(lockFor),
+		"updatedAt":   now,
+	}})
+}
+
+func (r *repository) Complete(ctx context.Context, job *Job) error {
+	now := time.Now()
+	return r.updateOwned(ctx, job, bson.M{
+		"$set": bson.M{
+			"state":      StateDone,
+			"cursor":     job.Cursor,
+			"processed":  job.Processed,
+			"linked":     job.Linked,
+			"updatedAt":  now,
+			"finishedAt": now,
+		},
+		"$unset": bson.M{"active": "", "owner": "", "lockedUntil": "", "error": ""},
+	})
+}
+
+// Fail records cause and puts the job back in the queue after a delay, or
+// marks it FAILED once it has used up its attempts. Progress made so far is
+// kept.
+func (r *repository) Fail(ctx context.Context, job *Job, cause error) error {
+	now := time.Now()
+	set := bson.M{
+		"state":       StatePending,
+		"cursor":      job.Cursor,
+		"processed":   job.Processed,
+		"linked":      job.Linked,
+		"error":       cause.Error(),
+		"lockedUntil": now.Add(time.Duration(job.Attempts) * retryBackoff),
+		"updatedAt":   now,
+	}
+	unset := bson.M{"owner": ""}
+	if job.Attempts >= MaxAttempts {
+		set["state"] = StateFailed
+		set["finishedAt"] = now
+		delete(set, "lockedUntil")
+		unset["active"] = ""
+		unset["lockedUntil"] = ""
+	}
+	return r.updateOwned(ctx, job, bson.M{"$set": set, "$unset": unset})
+}
+
+// Release hands a job back to the queue without counting it as a failure,
+// e.g. when the server is shutting down.
+func (r *repository) Release(ctx context.Context, job *Job) error {
+	return r.updateOwned(ctx, job, bson.M{
+		"$set": bson.M{
+			"state":     StatePending,
+			"cursor":    job.Cursor,
+			"processed": job.Processed,
+			"linked":    job.Linked,
+			"updatedAt": time.Now(),
+		},
+		"$inc":   bson.M{"attempts": -1},
+		"$unset": bson.M{"owner": "", "lockedUntil": ""},
+	})
+}
+
+func (r *repository) updateOwned(ctx context.Context, job *Job, update bson.M) error {
+	idObj, err := bson.ObjectIDFromHex(job.ID)
+	if err != nil {
+		return err
+	}
+	res, err := r.coll.UpdateOne(ctx, bson.M{"_id": idObj, "owner": job.Owner, "state": StateRunning}, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+func (r *repository) List(ctx context.Context, state *State, limit, offset int) ([]*Job, error) {
+	filter := bson.M{}
+	if state != nil {
+		filter["state"] = *state
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "createdAt", Value: -1}}).
+		SetLimit(int64(limit)).
+		SetSkip(int64(offset))
+
+	cursor, err := r.coll.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var jobs []*Job
+	if err := cursor.All(ctx, &jobs); err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+func (r *repository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.coll.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		// At most one pending or running job per key, see jobKey.
+		{
+			Keys: bson.D{{Key: "key", Value: 1}},
+			Options: options.Index().
+				SetUnique(true).
+				SetPartialFilterExpression(bson.M{"active": true}),
+		},
+		{Keys: bson.D{{Key: "active", Value: 1}, {Key: "createdAt", Value: 1}}},
+		{Keys: bson.D{{Key: "state", Value: 1}, {Key: "createdAt", Value: -1}}},
+	})
+	return err
+}
@@ -0,0 +1,197 @@
+package backlinks
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/pranava-mohan/wikinitt/gravy/internal/articles"
+	"github.com/pranava-mohan/wikinitt/gravy/internal/lease"
+)
+
+const (
+	batchSize    = 100
+	lockDuration = 2 * time.Minute
+	pollInterval = 15 * time.Second
+)
+
+// Runner processes backlink jobs with a fixed pool of workers. Jobs live in
+// Mongo, so work queued on one replica may be picked up by another, and
+// jobs interrupted by a restart are resumed from their cursor.
+type Runner struct {
+	jobs     Repository
+	articles articles.Repository
+	workers  int
+	owner    string
+	wake     chan struct{}
+}
+
+func NewRunner(jobs Repository, articleRepo articles.Repository, workers int) *Runner {
+	return &Runner{
+		jobs:     jobs,
+		articles: articleRepo,
+		workers:  workers,
+		owner:    lease.NewOwnerID(),
+		wake:     make(chan struct{}, 1),
+	}
+}
+
+// Enqueue schedules a scan that links every article mentioning a's title to
+// a. If a scan for a under the same title is already queued or running, that
+// job is returned instead of starting another.
+func (r *Runner) Enqueue(ctx context.Context, a *articles.Article) (*Job, error) {
+	job, err := r.jobs.Enqueue(ctx, Job{
+		Key:      jobKey(a),
+		TargetID: a.ID,
+		Title:    a.Title,
+		Slug:     a.Slug,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+	return job, nil
+}
+
+// Run starts the workers and blocks until ctx is cancelled and they have
+// handed back any job they were holding.
+func (r *Runner) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for i := 0; i < r.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.work(ctx)
+		}()
+	}
+	wg.Wait()
+}
+
+func (r *Runner) work(ctx context.Context) {
+	for {
+		if n, err := r.jobs.FailAbandoned(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("backlinks: failed to reap abandoned jobs: %v", err)
+		} else if n > 0 {
+			log.Printf("backlinks: marked %d abandoned jobs FAILED", n)
+		}
+
+		job, err := r.jobs.Claim(ctx, r.owner, lockDuration)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("backlinks: failed to claim job: %v", err)
+		}
+
+		if job == nil {
+			select {
+			case <-ctx.Done():
+				return
+			case <-r.wake:
+			case <-time.After(pollInterval):
+			}
+			continue
+		}
+
+		r.process(ctx, job)
+	}
+}
+
+// jobKey identifies the scan for a under its current title. Articles that
+// share a title get a job each, and a renamed article gets a new one.
+func jobKey(a *articles.Article) string {
+	return a.ID + ":" + articles.NormalizeTitle(a.Title)
+}
+
+func (r *Runner) process(ctx context.Context, job *Job) {
+	linker, err := r.linker(ctx, job)
+	if err != nil {
+		r.fail(ctx, job, err)
+		return
+	}
+
+	for {
+		if ctx.Err() != nil {
+			r.release(job)
+			return
+		}
+
+		batch, err := r.articles.ListContentAfter(ctx, job.Cursor, batchSize)
+		if err != nil {
+			r.fail(ctx, job, err)
+			return
+		}
+		if len(batch) == 0 {
+			break
+		}
+
+		links := findLinksTo(linker, batch, job.TargetID)
+		if err := r.articles.AddLinks(ctx, links); err != nil {
+			r.fail(ctx, job, err)
+			return
+		}
+
+		job.Cursor = batch[len(batch)-1].ID
+		job.Processed += len(batch)
+		job.Linked += len(links)
+		if err := r.jobs.Checkpoint(ctx, job, lockDuration); err != nil {
+			if err != ErrLeaseLost {
+				r.fail(ctx, job, err)
+			}
+			return
+		}
+	}
+
+	if err := r.jobs.Complete(ctx, job); err != nil {
+		log.Printf("backlinks: failed to complete job %s: %v", job.ID, err)
+		return
+	}
+	log.Printf("backlinks: job %s for %q linked %d of %d articles", job.ID, job.Title, job.Linked, job.Processed)
+}
+
+// linker matches every published title, not just the job's, so a mention
+// of a longer title ("Garnet Hostel") is not taken for a shorter one
+// ("Garnet").
+func (r *Runner) linker(ctx context.Context, job *Job) (*articles.Linker, error) {
+	targets, err := r.articles.ListLinkTargets(ctx)
+	if err != nil {
+		return nil, err
+	}
+	linker := articles.NewLinker(targets)
+	linker.Upsert(articles.LinkTarget{ID: job.TargetID, Title: job.Title, Slug: job.Slug})
+	return linker, nil
+}
+
+// findLinksTo returns the links from batch to targetID found by linker.
+func findLinksTo(linker *articles.Linker, batch []articles.Article, targetID string) []articles.Link {
+	var links []articles.Link
+	for _, a := range batch {
+		for _, l := range linker.FindLinks(a.Content, a.ID) {
+			if l.TargetID == targetID {
+				links = append(links, l)
+			}
+		}
+	}
+	return links
+}
+
+// release hands the job back when the server is stopping, without using up
+// one of its attempts.
+func (r *Runner) release(job *Job) {
+	if err := r.jobs.Release(context.Background(), job); err != nil && err != ErrLeaseLost {
+		log.Printf("backlinks: failed to release job %s: %v", job.ID, err)
+	}
+}
+
+func (r *Runner) fail(ctx context.Context, job *Job, cause error) {
+	if ctx.Err() != nil {
+		r.release(job)
+		return
+	}
+	log.Printf("backlinks: job %s for %q failed (attempt %d): %v", job.ID, job.Title, job.Attempts, cause)
+	if err := r.jobs.Fail(context.Background(), job, cause); err != nil && err != ErrLeaseLost {
+		log.Printf("backlinks: failed to record failure of job %s: %v", job.ID, err)
+	}
+}
@@ -0,0 +1,48 @@
+package backlinks
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/pranava-mohan/wikinitt/gravy/internal/articles"
+)
+
+func TestFindLinksTo(t *testing.T) {
+	linker := articles.NewLinker([]articles.LinkTarget{
+		{ID: "garnet", Title: "Garnet", Slug: "garnet"},
+		{ID: "garnet-hostel", Title: "Garnet Hostel", Slug: "garnet-hostel"},
+	})
+	batch := []articles.Article{
+		{ID: "a", Content: "Rooms in Garnet Hostel are large."},
+		{ID: "b", Content: "Garnet is a gemstone."},
+		{ID: "c", Content: "Nothing to see."},
+	}
+
+	got := findLinksTo(linker, batch, "garnet")
+	want := []articles.Link{{SourceID: "b", TargetID: "garnet", TargetSlug: "garnet", Anchor: "Garnet"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("links to Garnet = %v, want %v", got, want)
+	}
+
+	got = findLinksTo(linker, batch, "garnet-hostel")
+	want = []articles.Link{{SourceID: "a", TargetID: "garnet-hostel", TargetSlug: "garnet-hostel", Anchor: "Garnet Hostel"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("links to Garnet Hostel = %v, want %v", got, want)
+	}
+}
+
+func TestJobKey(t *testing.T) {
+	first := &articles.Article{ID: "1", Title: "Octagon"}
+	sameTitle := &articles.Article{ID: "2", Title: "octagon"}
+	renamed := &articles.Article{ID: "1", Title: "Octagon Lab"}
+
+	if jobKey(first) == jobKey(sameTitle) {
+		t.Error("articles sharing a title share a job")
+	}
+	if jobKey(first) == jobKey(renamed) {
+		t.Error("a renamed article reuses the job for its old title")
+	}
+	if jobKey(first) != jobKey(&articles.Article{ID: "1", Title: " OCTAGON "}) {
+		t.Error("job key depends on how the title is written")
+	}
+}
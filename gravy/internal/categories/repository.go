@@ -2,6 +2,7 @@ package categories
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"go.mongodb.org/mongo-driver/v2/bson"
@@ -9,18 +10,37 @@ import (
 	"go.mongodb.org/mongo-driver/v2/mongo/options"
 )
 
+// Category groups articles. Articles refer to their category by name, so
+// renaming a category must be cascaded to them.
 type Category struct {
-	ID        string    `bson:"_id,omitempty"`
-	Name      string    `bson:"name"`
-	Slug      string    `bson:"slug"`
-	CreatedAt time.Time `bson:"createdAt"`
+	ID          string    `bson:"_id,omitempty"`
+	Name        string    `bson:"name"`
+	Slug        string    `bson:"slug"`
+	ParentID    *string   `bson:"parentId,omitempty"`
+	Description string    `bson:"description"`
+	Icon        string    `bson:"icon"`
+	CreatedAt   time.Time `bson:"createdAt"`
+	// PreviousSlugs are the slugs the category had before it was renamed,
+	// so links to them keep working.
+	PreviousSlugs []string `bson:"previousSlugs,omitempty"`
 }
 
+var (
+	ErrNotFound    = errors.New("category not found")
+	ErrParentCycle = errors.New("a category cannot be nested under itself or one of its descendants")
+)
+
 type Repository interface {
-	Create(ctx context.Context, name string, slug string) (*Category, error)
+	Create(ctx context.Context, category Category) (*Category, error)
+	Update(ctx context.Context, id string, updates bson.M) (*Category, error)
 	List(ctx context.Context) ([]*Category, error)
+	ListChildren(ctx context.Context, parentID string) ([]*Category, error)
 	Delete(ctx context.Context, id string) error
+	GetByID(ctx context.Context, id string) (*Category, error)
 	GetByName(ctx context.Context, name string) (*Category, error)
+	GetBySlug(ctx context.Context, slug string) (*Category, error)
+	CheckParent(ctx context.Context, id string, parentID string) error
+	EnsureIndexes(ctx context.Context) error
 }
 
 type repository struct {
@@ -33,12 +53,8 @@ func NewRepository(db *mongo.Database) Repository {
 	}
 }
 
-func (r *repository) Create(ctx context.Context, name string, slug string) (*Category, error) {
-	category := Category{
-		Name:      name,
-		Slug:      slug,
-		CreatedAt: time.Now(),
-	}
+func (r *repository) Create(ctx context.Context, category Category) (*Category, error) {
+	category.CreatedAt = time.Now()
 
 	res, err := r.coll.InsertOne(ctx, category)
 	if err != nil {
@@ -48,9 +64,54 @@ func (r *repository) Create(ctx context.Context, name string, slug string) (*Cat
 	return &category, nil
 }
 
+func (r *repository) Update(ctx context.Context, id string, updates bson.M) (*Category, error) {
+	idObj, err := bson.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	update := bson.M{"$set": updates}
+	// A nil parent moves the category to the top level.
+	if parent, ok := updates["parentId"]; ok && parent == nil {
+		delete(updates, "parentId")
+		update = bson.M{"$set": updates, "$unset": bson.M{"parentId": ""}}
+		if len(updates) == 0 {
+			delete(update, "$set")
+		}
+	}
+	// A new slug keeps the old one as an alias.
+	if slug, ok := updates["slug"]; ok {
+		existing, err := r.GetByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if existing.Slug != "" && existing.Slug != slug {
+			update["$addToSet"] = bson.M{"previousSlugs": existing.Slug}
+		}
+	}
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var category *Category
+	if err := r.coll.FindOneAndUpdate(ctx, bson.M{"_id": idObj}, update, opts).Decode(&category); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return category, nil
+}
+
 func (r *repository) List(ctx context.Context) ([]*Category, error) {
+	return r.find(ctx, bson.M{})
+}
+
+func (r *repository) ListChildren(ctx context.Context, parentID string) ([]*Category, error) {
+	return r.find(ctx, bson.M{"parentId": parentID})
+}
+
+func (r *repository) find(ctx context.Context, filter bson.M) ([]*Category, error) {
 	opts := options.Find().SetSort(bson.D{{Key: "name", Value: 1}})
-	cursor, err := r.coll.Find(ctx, bson.M{}, opts)
+	cursor, err := r.coll.Find(ctx, filter, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -61,7 +122,21 @@ func (r *repository) List(ctx context.Context) ([]*Category, error) {
 	return categories, nil
 }
 
+// Delete removes a category. Its children move up to its parent.
 func (r *repository) Delete(ctx context.Context, id string) error {
+	category, err := r.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	childUpdate := bson.M{"$unset": bson.M{"parentId": ""}}
+	if category.ParentID != nil {
+		childUpdate = bson.M{"$set": bson.M{"parentId": *category.ParentID}}
+	}
+	if _, err := r.coll.UpdateMany(ctx, bson.M{"parentId": id}, childUpdate); err != nil {
+		return err
+	}
+
 	idObj, err := bson.ObjectIDFromHex(id)
 	if err != nil {
 		return err
@@ -70,6 +145,21 @@ func (r *repository) Delete(ctx context.Context, id string) error {
 	return err
 }
 
+func (r *repository) GetByID(ctx context.Context, id string) (*Category, error) {
+	idObj, err := bson.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+	var category Category
+	if err := r.coll.FindOne(ctx, bson.M{"_id": idObj}).Decode(&category); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &category, nil
+}
+
 func (r *repository) GetByName(ctx context.Context, name string) (*Category, error) {
 	var category Category
 	err := r.coll.FindOne(ctx, bson.M{"name": name}).Decode(&category)
@@ -81,3 +171,49 @@ func (r *repository) GetByName(ctx context.Context, name string) (*Category, err
 	}
 	return &category, nil
 }
+
+// GetBySlug also finds a category by a slug it had before it was renamed.
+// The current slug wins if another category has taken the old one since.
+func (r *repository) GetBySlug(ctx context.Context, slug string) (*Category, error) {
+	var category Category
+	err := r.coll.FindOne(ctx, bson.M{"slug": slug}).Decode(&category)
+	if err == mongo.ErrNoDocuments {
+		err = r.coll.FindOne(ctx, bson.M{"previousSlugs": slug}).Decode(&category)
+	}
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &category, nil
+}
+
+// CheckParent verifies that parentID exists and that making it the parent
+// of id would not create a cycle.
+func (r *repository) CheckParent(ctx context.Context, id string, parentID string) error {
+	for current := parentID; current != ""; {
+		if current == id {
+			return ErrParentCycle
+		}
+		parent, err := r.GetByID(ctx, current)
+		if err != nil {
+			return err
+		}
+		if parent.ParentID == nil {
+			break
+		}
+		current = *parent.ParentID
+	}
+	return nil
+}
+
+func (r *repository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.coll.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "name", Value: 1}}},
+		{Keys: bson.D{{Key: "slug", Value: 1}}},
+		{Keys: bson.D{{Key: "previousSlugs", Value: 1}}},
+		{Keys: bson.D{{Key: "parentId", Value: 1}, {Key: "name", Value: 1}}},
+	})
+	return err
+}
@@ -43,6 +43,7 @@ type Post struct {
 	DownvotesCount int       `bson:"downvotesCount"`
 	Indexed        bool      `bson:"indexed"`
 	IsEdited       bool      `bson:"isEdited"`
+	Version        int       `bson:"version"`
 	CreatedAt      time.Time `bson:"createdAt"`
 }
 
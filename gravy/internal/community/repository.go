@@ -12,6 +12,7 @@ import (
 	"go.mongodb.org/mongo-driver/v2/mongo/options"
 
 	"github.com/pranava-mohan/wikinitt/gravy/internal/search"
+	"github.com/pranava-mohan/wikinitt/gravy/internal/versioning"
 )
 
 type Repository interface {
@@ -19,6 +20,7 @@ type Repository interface {
 	GetGroup(ctx context.Context, slug string) (*Group, error)
 	GetGroupByID(ctx context.Context, id string) (*Group, error)
 	ListGroups(ctx context.Context, filter GroupFilter, limit, offset int) ([]*Group, error)
+	CountGroups(ctx context.Context, filter GroupFilter) (int64, error)
 	JoinGroup(ctx context.Context, groupID, userID string) error
 	LeaveGroup(ctx context.Context, groupID, userID string) error
 	DeleteGroup(ctx context.Context, groupID string) error
@@ -36,7 +38,7 @@ type Repository interface {
 	ListPublicPosts(ctx context.Context, limit, offset int) ([]*Post, error)
 	ListPostsByAuthor(ctx context.Context, authorID string, limit, offset int) ([]*Post, error)
 	ListPublicPostsByAuthor(ctx context.Context, authorID string, limit, offset int) ([]*Post, error)
-	UpdatePost(ctx context.Context, postID string, title *string, content *string) (*Post, error)
+	UpdatePost(ctx context.Context, postID string, expectedVersion int, title *string, content *string) (*Post, error)
 	DeletePost(ctx context.Context, postID string) error
 
 	CreateComment(ctx context.Context, comment *Comment) error
@@ -142,17 +144,20 @@ func (r *repository) GetGroupByID(ctx context.Context, id string) (*Group, error
 	return &group, nil
 }
 
-func (r *repository) ListGroups(ctx context.Context, filter GroupFilter, limit, offset int) ([]*Group, error) {
+func (f GroupFilter) query() bson.M {
 	query := bson.M{}
-	if filter.OwnerID != nil {
-		query["ownerId"] = *filter.OwnerID
+	if f.OwnerID != nil {
+		query["ownerId"] = *f.OwnerID
 	}
-	if filter.Type != nil {
-		query["type"] = *filter.Type
+	if f.Type != nil {
+		query["type"] = *f.Type
 	}
+	return query
+}
 
+func (r *repository) ListGroups(ctx context.Context, filter GroupFilter, limit, offset int) ([]*Group, error) {
 	opts := options.Find().SetLimit(int64(limit)).SetSkip(int64(offset))
-	cursor, err := r.db.Collection("groups").Find(ctx, query, opts)
+	cursor, err := r.db.Collection("groups").Find(ctx, filter.query(), opts)
 	if err != nil {
 		return nil, err
 	}
@@ -163,6 +168,10 @@ func (r *repository) ListGroups(ctx context.Context, filter GroupFilter, limit,
 	return groups, nil
 }
 
+func (r *repository) CountGroups(ctx context.Context, filter GroupFilter) (int64, error) {
+	return r.db.Collection("groups").CountDocuments(ctx, filter.query())
+}
+
 func (r *repository) JoinGroup(ctx context.Context, groupID, userID string) error {
 	oid, err := bson.ObjectIDFromHex(groupID)
 	if err != nil {
@@ -389,6 +398,7 @@ func (r *repository) ListPublicGroupsByMember(ctx context.Context, userID string
 }
 
 func (r *repository) CreatePost(ctx context.Context, post *Post) error {
+	post.Version = 1
 	res, err := r.db.Collection("posts").InsertOne(ctx, post)
 	if err != nil {
 		return err
@@ -1327,7 +1337,9 @@ func generateRandomString(n int) string {
 	return base64.RawURLEncoding.EncodeToString(b)
 }
 
-func (r *repository) UpdatePost(ctx context.Context, postID string, title *string, content *string) (*Post, error) {
+// UpdatePost applies the edit only if the post is still at expectedVersion,
+// returning a *versioning.ConflictError otherwise.
+func (r *repository) UpdatePost(ctx context.Context, postID string, expectedVersion int, title *string, content *string) (*Post, error) {
 	oid, err := bson.ObjectIDFromHex(postID)
 	if err != nil {
 		return nil, err
@@ -1341,7 +1353,11 @@ func (r *repository) UpdatePost(ctx context.Context, postID string, title *strin
 		update["content"] = *content
 	}
 
-	_, err = r.db.Collection("posts").UpdateOne(ctx, bson.M{"_id": oid}, bson.M{"$set": update})
+	filter := bson.M{"_id": oid, versioning.Field: versioning.Matches(expectedVersion)}
+	res, err := r.db.Collection("posts").UpdateOne(ctx, filter, bson.M{
+		"$set": update,
+		"$inc": bson.M{versioning.Field: 1},
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -1350,6 +1366,9 @@ func (r *repository) UpdatePost(ctx context.Context, postID string, title *strin
 	if err != nil {
 		return nil, err
 	}
+	if res.MatchedCount == 0 {
+		return nil, &versioning.ConflictError{Resource: "post", CurrentVersion: post.Version}
+	}
 
 	group, err := r.GetGroupByID(ctx, post.GroupID)
 	if err == nil {
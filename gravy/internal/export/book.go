@@ -0,0 +1,254 @@
+// Package export bundles articles into an offline book, either an EPUB 3
+// file or a single self-contained HTML page, with images embedded.
+package export
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pranava-mohan/wikinitt/gravy/internal/articles"
+	"github.com/pranava-mohan/wikinitt/gravy/internal/categories"
+)
+
+type Format string
+
+const (
+	FormatEPUB Format = "EPUB"
+	FormatHTML Format = "HTML"
+)
+
+// MaxChapters bounds the size of one export.
+const MaxChapters = 500
+
+// Extension is the file extension for the format, with the dot.
+func (f Format) Extension() string {
+	if f == FormatEPUB {
+		return ".epub"
+	}
+	return ".html"
+}
+
+// ContentType is the MIME type of the format.
+func (f Format) ContentType() string {
+	if f == FormatEPUB {
+		return "application/epub+zip"
+	}
+	return "text/html; charset=utf-8"
+}
+
+// Source selects what to export: every published article in a category,
+// in title order, or the listed articles in the order given.
+type Source struct {
+	CategorySlug string
+	ArticleIDs   []string
+}
+
+// Validate checks that s selects articles one way and not both.
+func (s Source) Validate() error {
+	switch {
+	case s.CategorySlug == "" && len(s.ArticleIDs) == 0:
+		return errors.New("a category or a list of articles is required")
+	case s.CategorySlug != "" && len(s.ArticleIDs) > 0:
+		return errors.New("give a category or a list of articles, not both")
+	case len(s.ArticleIDs) > MaxChapters:
+		return fmt.Errorf("an export can have at most %d articles", MaxChapters)
+	}
+	return nil
+}
+
+// Book is a set of articles ready to be written out. Chapter bodies are
+// sanitized HTML that still refers to images and other articles by URL;
+// the writers rewrite those references for their format.
+type Book struct {
+	Title    string
+	Modified time.Time
+	Chapters []*Chapter
+	// Images fetched for the book, by the URL chapters refer to them by.
+	Images map[string]*Image
+
+	// inBook maps article slugs to chapters, to turn links between
+	// exported articles into links within the book.
+	inBook      map[string]*Chapter
+	frontendURL string
+}
+
+type Chapter struct {
+	ID    string
+	Title string
+	Slug  string
+	Body  string
+	TOC   []articles.TocEntry
+}
+
+// Builder collects the articles of a Source into a Book.
+type Builder struct {
+	articles    articles.Repository
+	categories  categories.Repository
+	client      *http.Client
+	frontendURL string
+}
+
+// NewBuilder creates a Builder. Relative links and images are resolved
+// against FRONTEND_URL.
+func NewBuilder(articleRepo articles.Repository, categoryRepo categories.Repository) *Builder {
+	frontendURL := os.Getenv("FRONTEND_URL")
+	if frontendURL == "" {
+		frontendURL = "https://wikinitt.netlify.app"
+	}
+	return &Builder{
+		articles:    articleRepo,
+		categories:  categoryRepo,
+		client:      newImageClient(publicAddr),
+		frontendURL: strings.TrimRight(frontendURL, "/"),
+	}
+}
+
+// Collect loads the source's articles, renders them and fetches their
+// images. title overrides the default title, which is the category name.
+func (b *Builder) Collect(ctx context.Context, source Source, title string) (*Book, error) {
+	if err := source.Validate(); err != nil {
+		return nil, err
+	}
+
+	list, defaultTitle, err := b.load(ctx, source)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, errors.New("there are no published articles to export")
+	}
+	if title == "" {
+		title = defaultTitle
+	}
+
+	book := &Book{
+		Title:       title,
+		Images:      make(map[string]*Image),
+		inBook:      make(map[string]*Chapter, len(list)),
+		frontendURL: b.frontendURL,
+	}
+	for i, a := range list {
+		chapter := &Chapter{ID: fmt.Sprintf("chapter-%d", i+1), Title: a.Title, Slug: a.Slug}
+		book.Chapters = append(book.Chapters, chapter)
+		book.inBook[a.Slug] = chapter
+		if a.UpdatedAt.After(book.Modified) {
+			book.Modified = a.UpdatedAt
+		}
+	}
+
+	ids := make(map[string]string, len(list))
+	for _, a := range list {
+		ids[a.ID] = a.Slug
+	}
+	fetcher := newImageFetcher(b.client)
+	for i, a := range list {
+		content, err := b.linkWithinBook(ctx, a, ids)
+		if err != nil {
+			return nil, err
+		}
+		rendered := articles.Render(content)
+		chapter := book.Chapters[i]
+		chapter.Body = rendered.HTML
+		chapter.TOC = rendered.TOC
+
+		for _, src := range imageSources(rendered.HTML) {
+			if _, ok := book.Images[src]; ok {
+				continue
+			}
+			if img := fetcher.fetch(ctx, b.absoluteURL(src)); img != nil {
+				img.ID = fmt.Sprintf("image-%d", len(book.Images)+1)
+				book.Images[src] = img
+			}
+		}
+	}
+	return book, nil
+}
+
+func (b *Builder) load(ctx context.Context, source Source) ([]*articles.Article, string, error) {
+	if source.CategorySlug != "" {
+		category, err := b.categories.GetBySlug(ctx, source.CategorySlug)
+		if err != nil {
+			return nil, "", fmt.Errorf("category %q: %w", source.CategorySlug, err)
+		}
+		published := articles.StatusPublished
+		limit := MaxChapters
+		list, err := b.articles.List(ctx, articles.ListFilter{Category: &category.Name, Status: &published}, &limit, nil)
+		if err != nil {
+			return nil, "", err
+		}
+		sort.SliceStable(list, func(i, j int) bool {
+			return strings.ToLower(list[i].Title) < strings.ToLower(list[j].Title)
+		})
+		return list, category.Name, nil
+	}
+
+	found, err := b.articles.GetByIDs(ctx, source.ArticleIDs)
+	if err != nil {
+		return nil, "", err
+	}
+	byID := make(map[string]*articles.Article, len(found))
+	for _, a := range found {
+		byID[a.ID] = a
+	}
+	list := make([]*articles.Article, 0, len(source.ArticleIDs))
+	seen := make(map[string]bool)
+	for _, id := range source.ArticleIDs {
+		a, ok := byID[id]
+		if !ok || !a.IsPublished() {
+			return nil, "", fmt.Errorf("article %s not found or not published", id)
+		}
+		if !seen[id] {
+			seen[id] = true
+			list = append(list, a)
+		}
+	}
+	return list, "WikiNITT articles", nil
+}
+
+// linkWithinBook renders the article's automatic links, keeping only those
+// to other articles in the book, which readers can follow offline.
+func (b *Builder) linkWithinBook(ctx context.Context, a *articles.Article, slugs map[string]string) (string, error) {
+	links, err := b.articles.ListOutgoingLinks(ctx, a.ID)
+	if err != nil {
+		return "", err
+	}
+	live := make([]articles.Link, 0, len(links))
+	for _, l := range links {
+		if slug, ok := slugs[l.TargetID]; ok {
+			l.TargetSlug = slug
+			live = append(live, *l)
+		}
+	}
+	return articles.RenderLinks(a.Content, live), nil
+}
+
+func (b *Builder) absoluteURL(ref string) string {
+	if strings.HasPrefix(ref, "/") && !strings.HasPrefix(ref, "//") {
+		return b.frontendURL + ref
+	}
+	return ref
+}
+
+// FileName is a download name for the book in format f.
+func (book *Book) FileName(f Format) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r + ('a' - 'A')
+		}
+		return '-'
+	}, book.Title)
+	name = strings.Trim(strings.Join(strings.FieldsFunc(name, func(r rune) bool { return r == '-' }), "-"), "-")
+	if name == "" {
+		name = "wikinitt"
+	}
+	return name + f.Extension()
+}
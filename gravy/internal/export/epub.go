@@ -0,0 +1,236 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/xml"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Write writes book to w in the given format.
+func Write(w io.Writer, book *Book, format Format) error {
+	switch format {
+	case FormatEPUB:
+		return writeEPUB(w, book)
+	case FormatHTML:
+		return writeHTML(w, book)
+	}
+	return fmt.Errorf("unknown export format %q", format)
+}
+
+const stylesheet = `body { font-family: Georgia, serif; line-height: 1.5; margin: 0 auto; max-width: 42em; padding: 0 1em; }
+h1, h2, h3, h4 { font-family: Helvetica, Arial, sans-serif; line-height: 1.2; }
+img { max-width: 100%; height: auto; }
+pre { white-space: pre-wrap; background: #f4f4f4; padding: 0.5em; }
+code { font-family: Menlo, Consolas, monospace; font-size: 0.9em; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: 0.25em 0.5em; }
+blockquote { border-left: 3px solid #ccc; margin-left: 0; padding-left: 1em; color: #555; }
+nav ol { list-style: none; padding-left: 1em; }
+.chapter { page-break-before: always; break-before: page; }
+`
+
+const containerXML = `<?xml version="1.0" encoding="UTF-8"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+  <rootfiles>
+    <rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/>
+  </rootfiles>
+</container>
+`
+
+// writeEPUB writes an EPUB 3 package: one XHTML file per chapter, a
+// navigation document built from the chapters' headings, and the images.
+func writeEPUB(w io.Writer, book *Book) error {
+	z := zip.NewWriter(w)
+
+	// The mimetype file must come first, stored uncompressed and without a
+	// data descriptor, so readers can identify the file from its first bytes.
+	mimetype := []byte("application/epub+zip")
+	mt, err := z.CreateRaw(&zip.FileHeader{
+		Name:               "mimetype",
+		Method:             zip.Store,
+		CRC32:              crc32.ChecksumIEEE(mimetype),
+		CompressedSize64:   uint64(len(mimetype)),
+		UncompressedSize64: uint64(len(mimetype)),
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := mt.Write(mimetype); err != nil {
+		return err
+	}
+
+	ref := refs{
+		chapterHref: func(ch *Chapter, anchor string) string {
+			if anchor == "" {
+				return ch.ID + ".xhtml"
+			}
+			return ch.ID + ".xhtml#" + anchor
+		},
+		imageSrc: func(img *Image) string { return "images/" + img.ID + img.Extension() },
+	}
+
+	files := []struct {
+		name string
+		data func() ([]byte, error)
+	}{
+		{"META-INF/container.xml", func() ([]byte, error) { return []byte(containerXML), nil }},
+		{"OEBPS/content.opf", func() ([]byte, error) { return packageDocument(book), nil }},
+		{"OEBPS/nav.xhtml", func() ([]byte, error) { return navDocument(book, ref), nil }},
+		{"OEBPS/style.css", func() ([]byte, error) { return []byte(stylesheet), nil }},
+	}
+	for _, ch := range book.Chapters {
+		ch := ch
+		files = append(files, struct {
+			name string
+			data func() ([]byte, error)
+		}{"OEBPS/" + ch.ID + ".xhtml", func() ([]byte, error) { return chapterDocument(book, ch, ref) }})
+	}
+
+	for _, f := range files {
+		data, err := f.data()
+		if err != nil {
+			return err
+		}
+		if err := writeZipFile(z, f.name, data); err != nil {
+			return err
+		}
+	}
+	for _, img := range book.imageList() {
+		if err := writeZipFile(z, "OEBPS/"+ref.imageSrc(img), img.Data); err != nil {
+			return err
+		}
+	}
+	return z.Close()
+}
+
+func writeZipFile(z *zip.Writer, name string, data []byte) error {
+	f, err := z.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	return err
+}
+
+func packageDocument(book *Book) []byte {
+	var b bytes.Buffer
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	b.WriteString(`<package xmlns="http://www.idpf.org/2007/opf" version="3.0" unique-identifier="book-id" xml:lang="en">` + "\n")
+	b.WriteString(`  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/">` + "\n")
+	fmt.Fprintf(&b, "    <dc:identifier id=\"book-id\">urn:uuid:%s</dc:identifier>\n", book.uuid())
+	fmt.Fprintf(&b, "    <dc:title>%s</dc:title>\n", escape(book.Title))
+	b.WriteString("    <dc:language>en</dc:language>\n")
+	b.WriteString("    <dc:publisher>WikiNITT</dc:publisher>\n")
+	fmt.Fprintf(&b, "    <meta property=\"dcterms:modified\">%s</meta>\n", book.Modified.UTC().Format(time.RFC3339))
+	b.WriteString("  </metadata>\n  <manifest>\n")
+	b.WriteString(`    <item id="nav" href="nav.xhtml" media-type="application/xhtml+xml" properties="nav"/>` + "\n")
+	b.WriteString(`    <item id="style" href="style.css" media-type="text/css"/>` + "\n")
+	for _, ch := range book.Chapters {
+		fmt.Fprintf(&b, "    <item id=\"%s\" href=\"%s.xhtml\" media-type=\"application/xhtml+xml\"/>\n", ch.ID, ch.ID)
+	}
+	for _, img := range book.imageList() {
+		fmt.Fprintf(&b, "    <item id=\"%s\" href=\"images/%s%s\" media-type=\"%s\"/>\n", img.ID, img.ID, img.Extension(), img.MediaType)
+	}
+	b.WriteString("  </manifest>\n  <spine>\n")
+	b.WriteString(`    <itemref idref="nav"/>` + "\n")
+	for _, ch := range book.Chapters {
+		fmt.Fprintf(&b, "    <itemref idref=\"%s\"/>\n", ch.ID)
+	}
+	b.WriteString("  </spine>\n</package>\n")
+	return b.Bytes()
+}
+
+func xhtmlHead(b *bytes.Buffer, title string) {
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n<!DOCTYPE html>\n")
+	b.WriteString(`<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops" lang="en" xml:lang="en">` + "\n")
+	fmt.Fprintf(b, "<head>\n<meta charset=\"utf-8\"/>\n<title>%s</title>\n", escape(title))
+	b.WriteString(`<link rel="stylesheet" type="text/css" href="style.css"/>` + "\n</head>\n")
+}
+
+func navDocument(book *Book, ref refs) []byte {
+	var b bytes.Buffer
+	xhtmlHead(&b, book.Title)
+	b.WriteString("<body>\n")
+	fmt.Fprintf(&b, "<h1>%s</h1>\n", escape(book.Title))
+	writeTOC(&b, book, ref, `<nav epub:type="toc" id="toc">`)
+	b.WriteString("</body>\n</html>\n")
+	return b.Bytes()
+}
+
+// writeTOC lists the chapters, each with its top headings.
+func writeTOC(b *bytes.Buffer, book *Book, ref refs, navTag string) {
+	b.WriteString(navTag + "\n<h2>Contents</h2>\n<ol>\n")
+	for _, ch := range book.Chapters {
+		fmt.Fprintf(b, "<li><a href=\"%s\">%s</a>", escape(ref.chapterHref(ch, "")), escape(ch.Title))
+		var headings []string
+		for _, e := range ch.TOC {
+			if e.Level <= 3 && e.ID != "" {
+				headings = append(headings, fmt.Sprintf("<li><a href=\"%s\">%s</a></li>", escape(ref.chapterHref(ch, anchorID(ch, e.ID))), escape(e.Text)))
+			}
+		}
+		if len(headings) > 0 {
+			b.WriteString("\n<ol>\n" + strings.Join(headings, "\n") + "\n</ol>\n")
+		}
+		b.WriteString("</li>\n")
+	}
+	b.WriteString("</ol>\n</nav>\n")
+}
+
+func chapterDocument(book *Book, ch *Chapter, ref refs) ([]byte, error) {
+	body, err := book.renderBody(ch, ref)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render %s: %w", ch.Slug, err)
+	}
+	var b bytes.Buffer
+	xhtmlHead(&b, ch.Title)
+	fmt.Fprintf(&b, "<body>\n<section id=\"%s\">\n<h1>%s</h1>\n", ch.ID, escape(ch.Title))
+	b.WriteString(body)
+	b.WriteString("\n</section>\n</body>\n</html>\n")
+	return b.Bytes(), nil
+}
+
+// imageList returns the book's images in the order they were fetched.
+func (book *Book) imageList() []*Image {
+	list := make([]*Image, 0, len(book.Images))
+	for _, img := range book.Images {
+		list = append(list, img)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return imageNumber(list[i]) < imageNumber(list[j])
+	})
+	return list
+}
+
+func imageNumber(img *Image) int {
+	n, _ := strconv.Atoi(strings.TrimPrefix(img.ID, "image-"))
+	return n
+}
+
+// uuid derives a stable identifier from the book's contents, so exporting
+// the same articles again gives the same identifier and readers treat it as
+// an update rather than a new book.
+func (book *Book) uuid() string {
+	h := sha256.New()
+	io.WriteString(h, book.Title)
+	for _, ch := range book.Chapters {
+		io.WriteString(h, "\x00"+ch.Slug)
+	}
+	sum := h.Sum(nil)
+	sum[6] = sum[6]&0x0f | 0x50
+	sum[8] = sum[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+}
+
+func escape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
@@ -0,0 +1,60 @@
+package export
+
+import (
+	"crypto/subtle"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// DownloadPath is where a finished job's file can be downloaded.
+func DownloadPath(job *Job) string {
+	return "/exports/" + job.ID + "?token=" + job.DownloadToken
+}
+
+// DownloadHandler serves /exports/{id}?token=... Only admins can see a
+// job's token, through the exportJobs query, so the link itself is the
+// credential and works without an Authorization header.
+func DownloadHandler(jobs Repository) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		id := strings.TrimPrefix(r.URL.Path, "/exports/")
+		job, err := jobs.Get(r.Context(), id)
+		if err != nil || job.State != StateDone {
+			http.NotFound(w, r)
+			return
+		}
+		token := r.URL.Query().Get("token")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(job.DownloadToken)) != 1 {
+			http.NotFound(w, r)
+			return
+		}
+
+		file, err := jobs.OpenFile(r.Context(), job)
+		if err != nil {
+			log.Printf("Failed to open export %s: %v", job.ID, err)
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+		defer file.Close()
+
+		w.Header().Set("Content-Type", job.Format.ContentType())
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": job.FileName}))
+		w.Header().Set("Content-Length", strconv.FormatInt(job.Size, 10))
+		w.Header().Set("Cache-Control", "private, no-store")
+		if r.Method == http.MethodHead {
+			return
+		}
+		if _, err := io.Copy(w, file); err != nil {
+			log.Printf("Failed to send export %s: %v", job.ID, err)
+		}
+	})
+}
@@ -0,0 +1,43 @@
+package export
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+)
+
+// writeHTML writes the book as one HTML page with the stylesheet inline and
+// images as data URIs, so it can be opened, printed or shared on its own.
+func writeHTML(w io.Writer, book *Book) error {
+	ref := refs{
+		chapterHref: func(ch *Chapter, anchor string) string {
+			if anchor == "" {
+				return "#" + ch.ID
+			}
+			return "#" + anchor
+		},
+		imageSrc: func(img *Image) string {
+			return "data:" + img.MediaType + ";base64," + base64.StdEncoding.EncodeToString(img.Data)
+		},
+	}
+
+	var b bytes.Buffer
+	b.WriteString("<!DOCTYPE html>\n<html lang=\"en\">\n<head>\n<meta charset=\"utf-8\">\n")
+	b.WriteString("<meta name=\"viewport\" content=\"width=device-width, initial-scale=1\">\n")
+	fmt.Fprintf(&b, "<title>%s</title>\n<style>\n%s</style>\n</head>\n<body>\n", escape(book.Title), stylesheet)
+	fmt.Fprintf(&b, "<h1>%s</h1>\n", escape(book.Title))
+	writeTOC(&b, book, ref, `<nav id="toc">`)
+
+	for _, ch := range book.Chapters {
+		body, err := book.renderBody(ch, ref)
+		if err != nil {
+			return fmt.Errorf("failed to render %s: %w", ch.Slug, err)
+		}
+		fmt.Fprintf(&b, "<section class=\"chapter\" id=\"%s\">\n<h1>%s</h1>\n%s\n</section>\n", ch.ID, escape(ch.Title), body)
+	}
+	b.WriteString("</body>\n</html>\n")
+
+	_, err := w.Write(b.Bytes())
+	return err
+}
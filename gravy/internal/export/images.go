@@ -0,0 +1,168 @@
+package export
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	imageTimeout   = 15 * time.Second
+	maxImageSize   = 10 << 20
+	maxImagesTotal = 100 << 20
+	maxRedirects   = 5
+)
+
+// reservedPrefixes are ranges that are not reachable on the public internet
+// but that netip does not flag on its own.
+var reservedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+}
+
+// extensions for the image types EPUB readers are required to support.
+var imageExtensions = map[string]string{
+	"image/jpeg":    ".jpg",
+	"image/png":     ".png",
+	"image/gif":     ".gif",
+	"image/webp":    ".webp",
+	"image/svg+xml": ".svg",
+}
+
+// Image is an image fetched to embed in a book.
+type Image struct {
+	ID        string
+	MediaType string
+	Data      []byte
+}
+
+// Extension is the file extension for the image's type, with the dot.
+func (img *Image) Extension() string {
+	return imageExtensions[img.MediaType]
+}
+
+// publicAddr reports whether an address is on the public internet. Image
+// URLs come from article content, so without this check any author could
+// have the server fetch internal services or cloud metadata into a book.
+func publicAddr(addr netip.AddrPort) bool {
+	a := addr.Addr().Unmap()
+	if !a.IsGlobalUnicast() || a.IsPrivate() {
+		return false
+	}
+	for _, p := range reservedPrefixes {
+		if p.Contains(a) {
+			return false
+		}
+	}
+	return true
+}
+
+// newImageClient returns a client that only connects to addresses allowed
+// reports true. The check runs on every connection after the name is
+// resolved, so redirects and DNS answers cannot get around it.
+func newImageClient(allowed func(netip.AddrPort) bool) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: imageTimeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			addr, err := netip.ParseAddrPort(address)
+			if err != nil || !allowed(addr) {
+				return fmt.Errorf("refusing to connect to %s", address)
+			}
+			return nil
+		},
+	}
+	return &http.Client{
+		Timeout: imageTimeout,
+		Transport: &http.Transport{
+			// No proxy: it would make the connections the check sees.
+			Proxy:               nil,
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: 10 * time.Second,
+			MaxIdleConns:        10,
+			IdleConnTimeout:     30 * time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return errors.New("too many redirects")
+			}
+			if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+				return fmt.Errorf("redirect to unsupported scheme %q", req.URL.Scheme)
+			}
+			return nil
+		},
+	}
+}
+
+type imageFetcher struct {
+	client *http.Client
+	total  int
+}
+
+func newImageFetcher(client *http.Client) *imageFetcher {
+	return &imageFetcher{client: client}
+}
+
+// fetch downloads an image, returning nil if it cannot be used. A book
+// with a missing image is more useful than no book, so failures are only
+// logged and the writers drop the image.
+func (f *imageFetcher) fetch(ctx context.Context, rawURL string) *Image {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil
+	}
+	if f.total >= maxImagesTotal {
+		log.Printf("export: skipping image %s: images exceed %d bytes", rawURL, maxImagesTotal)
+		return nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		log.Printf("export: failed to fetch image %s: %v", rawURL, err)
+		return nil
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		log.Printf("export: failed to fetch image %s: %s", rawURL, resp.Status)
+		return nil
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxImageSize+1))
+	if err != nil {
+		log.Printf("export: failed to read image %s: %v", rawURL, err)
+		return nil
+	}
+	if len(data) > maxImageSize {
+		log.Printf("export: skipping image %s: larger than %d bytes", rawURL, maxImageSize)
+		return nil
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if _, ok := imageExtensions[mediaType]; !ok {
+		mediaType, _, _ = mime.ParseMediaType(http.DetectContentType(data))
+	}
+	if _, ok := imageExtensions[mediaType]; !ok {
+		log.Printf("export: skipping image %s: unsupported type %q", rawURL, mediaType)
+		return nil
+	}
+
+	f.total += len(data)
+	return &Image{MediaType: strings.ToLower(mediaType), Data: data}
+}
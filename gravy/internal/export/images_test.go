@@ -0,0 +1,102 @@
+package export
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"testing"
+)
+
+// pngData is a 1x1 PNG.
+var pngData = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\x00\x00\x00\x01\x00\x00\x00\x01\x08\x06\x00\x00\x00\x1f\x15\xc4\x89\x00\x00\x00\rIDATx\x9cc\xf8\x0f\x00\x00\x01\x01\x00\x05\x18\xd8N\x00\x00\x00\x00IEND\xaeB`\x82")
+
+func TestPublicAddr(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"93.184.216.34:80", true},
+		{"[2606:4700::1111]:443", true},
+		{"127.0.0.1:7700", false},
+		{"[::1]:80", false},
+		{"10.1.2.3:80", false},
+		{"172.16.0.1:80", false},
+		{"192.168.1.1:80", false},
+		{"169.254.169.254:80", false},
+		{"[fe80::1]:80", false},
+		{"[fd00::1]:80", false},
+		{"0.0.0.0:80", false},
+		{"[::]:80", false},
+		{"100.64.0.1:80", false},
+		{"224.0.0.1:80", false},
+		{"[::ffff:127.0.0.1]:80", false},
+		{"[64:ff9b::a9fe:a9fe]:80", false},
+	}
+	for _, tt := range tests {
+		if got := publicAddr(netip.MustParseAddrPort(tt.addr)); got != tt.want {
+			t.Errorf("publicAddr(%s) = %v, want %v", tt.addr, got, tt.want)
+		}
+	}
+}
+
+// allowPort lets a client connect only to the test server at rawURL.
+func allowPort(t *testing.T, rawURL string) func(netip.AddrPort) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	allowed := netip.MustParseAddrPort(u.Host)
+	return func(addr netip.AddrPort) bool { return addr == allowed }
+}
+
+func TestImageFetcher(t *testing.T) {
+	internal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(pngData)
+	}))
+	defer internal.Close()
+
+	var redirector *httptest.Server
+	redirector = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/image.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write(pngData)
+		case "/same-host":
+			http.Redirect(w, r, redirector.URL+"/image.png", http.StatusFound)
+		case "/internal":
+			http.Redirect(w, r, internal.URL+"/image.png", http.StatusFound)
+		case "/loop":
+			http.Redirect(w, r, redirector.URL+"/loop", http.StatusFound)
+		}
+	}))
+	defer redirector.Close()
+
+	allowAll := func(netip.AddrPort) bool { return true }
+	tests := []struct {
+		name    string
+		allowed func(netip.AddrPort) bool
+		url     string
+		want    bool
+	}{
+		{"loopback is refused", publicAddr, internal.URL + "/image.png", false},
+		{"allowed address is fetched", allowAll, internal.URL + "/image.png", true},
+		{"redirect to an allowed address", allowPort(t, redirector.URL), redirector.URL + "/same-host", true},
+		{"redirect to a refused address", allowPort(t, redirector.URL), redirector.URL + "/internal", false},
+		{"redirect loop", allowAll, redirector.URL + "/loop", false},
+		{"unsupported scheme", allowAll, "file:///etc/passwd", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img := newImageFetcher(newImageClient(tt.allowed)).fetch(context.Background(), tt.url)
+			if got := img != nil; got != tt.want {
+				t.Errorf("fetched = %v, want %v", got, tt.want)
+			}
+			if img != nil && img.MediaType != "image/png" {
+				t.Errorf("media type = %q, want image/png", img.MediaType)
+			}
+		})
+	}
+}
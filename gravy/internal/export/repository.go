@@ -0,0 +1,273 @@
+package export
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+type State string
+
+const (
+	StatePending State = "PENDING"
+	StateRunning State = "RUNNING"
+	StateDone    State = "DONE"
+	StateFailed  State = "FAILED"
+)
+
+// MaxAttempts is how many times a job is claimed before it is marked FAILED.
+// Jobs are only claimed again if the worker running them died.
+const MaxAttempts = 3
+
+// ErrLeaseLost is returned when a worker's claim on a job has expired and
+// another worker has taken it over.
+var ErrLeaseLost = errors.New("export job was claimed by another worker")
+
+// Job is an export requested by an admin. The finished file is kept in
+// GridFS; DownloadToken is part of the download URL so the file can be
+// fetched by a plain link.
+type Job struct {
+	ID            string     `bson:"_id,omitempty"`
+	Title         string     `bson:"title"`
+	Format        Format     `bson:"format"`
+	CategorySlug  string     `bson:"categorySlug,omitempty"`
+	ArticleIDs    []string   `bson:"articleIds,omitempty"`
+	RequestedBy   string     `bson:"requestedBy"`
+	State         State      `bson:"state"`
+	Chapters      int        `bson:"chapters"`
+	Size          int64      `bson:"size"`
+	FileID        string     `bson:"fileId,omitempty"`
+	FileName      string     `bson:"fileName,omitempty"`
+	DownloadToken string     `bson:"downloadToken"`
+	Attempts      int        `bson:"attempts"`
+	Error         string     `bson:"error,omitempty"`
+	Owner         string     `bson:"owner,omitempty"`
+	LockedUntil   *time.Time `bson:"lockedUntil,omitempty"`
+	CreatedAt     time.Time  `bson:"createdAt"`
+	UpdatedAt     time.Time  `bson:"updatedAt"`
+	FinishedAt    *time.Time `bson:"finishedAt,omitempty"`
+}
+
+// Source is what the job exports.
+func (j *Job) Source() Source {
+	return Source{CategorySlug: j.CategorySlug, ArticleIDs: j.ArticleIDs}
+}
+
+type Repository interface {
+	Create(ctx context.Context, job Job) (*Job, error)
+	Get(ctx context.Context, id string) (*Job, error)
+	List(ctx context.Context, limit, offset int) ([]*Job, error)
+	Claim(ctx context.Context, owner string, lockFor time.Duration) (*Job, error)
+	FailAbandoned(ctx context.Context) (int64, error)
+	Complete(ctx context.Context, job *Job, file io.Reader) error
+	Fail(ctx context.Context, job *Job, cause error) error
+	OpenFile(ctx context.Context, job *Job) (io.ReadCloser, error)
+	EnsureIndexes(ctx context.Context) error
+}
+
+type repository struct {
+	coll  *mongo.Collection
+	files *mongo.GridFSBucket
+}
+
+func NewRepository(db *mongo.Database) Repository {
+	return &repository{
+		coll:  db.Collection("export_jobs"),
+		files: db.GridFSBucket(options.GridFSBucket().SetName("exports")),
+	}
+}
+
+func (r *repository) Create(ctx context.Context, job Job) (*Job, error) {
+	token := make([]byte, 24)
+	if _, err := rand.Read(token); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	job.ID = ""
+	job.State = StatePending
+	job.DownloadToken = hex.EncodeToString(token)
+	job.CreatedAt = now
+	job.UpdatedAt = now
+
+	res, err := r.coll.InsertOne(ctx, job)
+	if err != nil {
+		return nil, err
+	}
+	job.ID = res.InsertedID.(bson.ObjectID).Hex()
+	return &job, nil
+}
+
+func (r *repository) Get(ctx context.Context, id string) (*Job, error) {
+	idObj, err := bson.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+	var job Job
+	if err := r.coll.FindOne(ctx, bson.M{"_id": idObj}).Decode(&job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (r *repository) List(ctx context.Context, limit, offset int) ([]*Job, error) {
+	opts := options.Find().
+		SetSort(bson.D{{Key: "createdAt", Value: -1}}).
+		SetLimit(int64(limit)).
+		SetSkip(int64(offset))
+
+	cursor, err := r.coll.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	var jobs []*Job
+	if err := cursor.All(ctx, &jobs); err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+// Claim takes the oldest pending job, or a running job whose worker stopped
+// before finishing it, and locks it to owner. Jobs that have used up their
+// attempts are left to FailAbandoned.
+func (r *repository) Claim(ctx context.Context, owner string, lockFor time.Duration) (*Job, error) {
+	now := time.Now()
+	filter := bson.M{
+		"attempts": bson.M{"$lt": MaxAttempts},
+		"$or": []bson.M{
+			{"state": StatePending},
+			{"state": StateRunning, "lockedUntil": bson.M{"$lte": now}},
+		},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"state":       StateRunning,
+			"owner":       owner,
+			"lockedUntil": now.Add(lockFor),
+			"updatedAt":   now,
+		},
+		"$inc": bson.M{"attempts": 1},
+	}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "createdAt", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	var job *Job
+	err := r.coll.FindOneAndUpdate(ctx, filter, update, opts).Decode(&job)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return job, nil
+}
+
+// FailAbandoned marks FAILED the running jobs whose worker stopped during
+// their last attempt. Claim no longer picks those up, so without this they
+// would stay RUNNING forever.
+func (r *repository) FailAbandoned(ctx context.Context) (int64, error) {
+	now := time.Now()
+	res, err := r.coll.UpdateMany(ctx,
+		bson.M{
+			"state":       StateRunning,
+			"attempts":    bson.M{"$gte": MaxAttempts},
+			"lockedUntil": bson.M{"$lte": now},
+		},
+		bson.M{
+			"$set": bson.M{
+				"state":      StateFailed,
+				"error":      "the worker stopped during the last attempt",
+				"updatedAt":  now,
+				"finishedAt": now,
+			},
+			"$unset": bson.M{"owner": "", "lockedUntil": ""},
+		},
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.ModifiedCount, nil
+}
+
+// Complete stores the exported file and marks the job DONE.
+func (r *repository) Complete(ctx context.Context, job *Job, file io.Reader) error {
+	fileID, err := r.files.UploadFromStream(ctx, job.FileName, file)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	err = r.updateOwned(ctx, job, bson.M{
+		"$set": bson.M{
+			"state":      StateDone,
+			"title":      job.Title,
+			"chapters":   job.Chapters,
+			"size":       job.Size,
+			"fileId":     fileID.Hex(),
+			"fileName":   job.FileName,
+			"updatedAt":  now,
+			"finishedAt": now,
+		},
+		"$unset": bson.M{"owner": "", "lockedUntil": "", "error": ""},
+	})
+	if err != nil {
+		// Another worker owns the job now; its file will be the one kept.
+		r.files.Delete(context.Background(), fileID)
+		return err
+	}
+	job.FileID = fileID.Hex()
+	return nil
+}
+
+// Fail marks the job FAILED. Exports fail for reasons a retry would not fix,
+// such as a deleted category, so failures are not retried.
+func (r *repository) Fail(ctx context.Context, job *Job, cause error) error {
+	now := time.Now()
+	return r.updateOwned(ctx, job, bson.M{
+		"$set": bson.M{
+			"state":      StateFailed,
+			"error":      cause.Error(),
+			"updatedAt":  now,
+			"finishedAt": now,
+		},
+		"$unset": bson.M{"owner": "", "lockedUntil": ""},
+	})
+}
+
+func (r *repository) updateOwned(ctx context.Context, job *Job, update bson.M) error {
+	idObj, err := bson.ObjectIDFromHex(job.ID)
+	if err != nil {
+		return err
+	}
+	res, err := r.coll.UpdateOne(ctx, bson.M{"_id": idObj, "owner": job.Owner, "state": StateRunning}, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+func (r *repository) OpenFile(ctx context.Context, job *Job) (io.ReadCloser, error) {
+	fileID, err := bson.ObjectIDFromHex(job.FileID)
+	if err != nil {
+		return nil, err
+	}
+	return r.files.OpenDownloadStream(ctx, fileID)
+}
+
+func (r *repository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.coll.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "state", Value: 1}, {Key: "createdAt", Value: 1}}},
+		{Keys: bson.D{{Key: "createdAt", Value: -1}}},
+	})
+	return err
+}
@@ -0,0 +1,170 @@
+package export
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// refs says how a writer addresses parts of the book. chapterHref links to
+// an anchor in a chapter, or to its start if anchor is "".
+type refs struct {
+	chapterHref func(ch *Chapter, anchor string) string
+	imageSrc    func(img *Image) string
+}
+
+func parseBody(body string) ([]*html.Node, error) {
+	return html.ParseFragment(strings.NewReader(body), &html.Node{
+		Type:     html.ElementNode,
+		Data:     "body",
+		DataAtom: atom.Body,
+	})
+}
+
+// imageSources returns the src of every image in a rendered body.
+func imageSources(body string) []string {
+	nodes, err := parseBody(body)
+	if err != nil {
+		return nil
+	}
+	var sources []string
+	for _, n := range nodes {
+		walk(n, func(n *html.Node) {
+			if n.Type == html.ElementNode && n.DataAtom == atom.Img {
+				if src := attr(n, "src"); src != "" {
+					sources = append(sources, src)
+				}
+			}
+		})
+	}
+	return sources
+}
+
+// anchorID is the id a heading of ch gets in the book. Every chapter's ids
+// are prefixed with its own so they stay unique when chapters share a page.
+func anchorID(ch *Chapter, id string) string {
+	return ch.ID + "-" + id
+}
+
+// renderBody rewrites a chapter body for one format: links to exported
+// articles point into the book, other relative links point at the website,
+// and images point at their embedded copies or, if they could not be
+// fetched, are replaced by their alt text. The result is well-formed XML as
+// well as HTML, as EPUB requires.
+func (book *Book) renderBody(ch *Chapter, r refs) (string, error) {
+	nodes, err := parseBody(ch.Body)
+	if err != nil {
+		return "", err
+	}
+
+	var dropped []*html.Node
+	for _, n := range nodes {
+		walk(n, func(n *html.Node) {
+			if n.Type != html.ElementNode {
+				return
+			}
+			switch n.DataAtom {
+			case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+				if id := attr(n, "id"); id != "" {
+					setAttr(n, "id", anchorID(ch, id))
+				}
+			case atom.A:
+				if href := attr(n, "href"); href != "" {
+					setAttr(n, "href", book.rewriteHref(ch, href, r))
+				}
+			case atom.Img:
+				if img, ok := book.Images[attr(n, "src")]; ok {
+					setAttr(n, "src", r.imageSrc(img))
+				} else {
+					dropped = append(dropped, n)
+				}
+			}
+		})
+	}
+	for _, n := range dropped {
+		var replacement *html.Node
+		if alt := attr(n, "alt"); alt != "" {
+			replacement = &html.Node{Type: html.TextNode, Data: alt}
+		}
+		if n.Parent == nil {
+			// Top-level nodes of the fragment have no parent to edit.
+			nodes = replaceNode(nodes, n, replacement)
+			continue
+		}
+		if replacement != nil {
+			n.Parent.InsertBefore(replacement, n)
+		}
+		n.Parent.RemoveChild(n)
+	}
+
+	var buf bytes.Buffer
+	for _, n := range nodes {
+		if err := html.Render(&buf, n); err != nil {
+			return "", err
+		}
+	}
+	return buf.String(), nil
+}
+
+// replaceNode replaces old in nodes with replacement, or removes it if
+// replacement is nil.
+func replaceNode(nodes []*html.Node, old, replacement *html.Node) []*html.Node {
+	i := slices.Index(nodes, old)
+	if i < 0 {
+		return nodes
+	}
+	if replacement == nil {
+		return slices.Delete(nodes, i, i+1)
+	}
+	nodes[i] = replacement
+	return nodes
+}
+
+func (book *Book) rewriteHref(ch *Chapter, href string, r refs) string {
+	if anchor, ok := strings.CutPrefix(href, "#"); ok {
+		return r.chapterHref(ch, anchorID(ch, anchor))
+	}
+	if !strings.HasPrefix(href, "/") || strings.HasPrefix(href, "//") {
+		return href
+	}
+
+	if rest, ok := strings.CutPrefix(href, "/articles/"); ok {
+		slug, anchor, _ := strings.Cut(rest, "#")
+		if target, ok := book.inBook[strings.TrimSuffix(slug, "/")]; ok {
+			if anchor != "" {
+				anchor = anchorID(target, anchor)
+			}
+			return r.chapterHref(target, anchor)
+		}
+	}
+	return book.frontendURL + href
+}
+
+func walk(n *html.Node, fn func(*html.Node)) {
+	fn(n)
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		walk(c, fn)
+	}
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func setAttr(n *html.Node, key, val string) {
+	for i := range n.Attr {
+		if n.Attr[i].Key == key {
+			n.Attr[i].Val = val
+			return
+		}
+	}
+	n.Attr = append(n.Attr, html.Attribute{Key: key, Val: val})
+}
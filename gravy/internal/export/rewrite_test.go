@@ -0,0 +1,79 @@
+package export
+
+import "testing"
+
+func TestRenderBody(t *testing.T) {
+	intro := &Chapter{ID: "ch1", Slug: "intro"}
+	hostels := &Chapter{ID: "ch2", Slug: "hostels"}
+	book := &Book{
+		Chapters: []*Chapter{intro, hostels},
+		Images: map[string]*Image{
+			"https://img.example/map.png": {ID: "img1", MediaType: "image/png"},
+		},
+		inBook:      map[string]*Chapter{"intro": intro, "hostels": hostels},
+		frontendURL: "https://wiki.example",
+	}
+	r := refs{
+		chapterHref: func(ch *Chapter, anchor string) string {
+			if anchor == "" {
+				return ch.ID + ".xhtml"
+			}
+			return ch.ID + ".xhtml#" + anchor
+		},
+		imageSrc: func(img *Image) string { return "images/" + img.ID + img.Extension() },
+	}
+
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{
+			name: "top-level image that failed to fetch",
+			body: "<p>Intro</p>\n<img src=\"https://img.example/missing.png\" alt=\"pic\">\n<p>more</p>",
+			want: "<p>Intro</p>\npic\n<p>more</p>",
+		},
+		{
+			name: "top-level image without alt text",
+			body: "<img src=\"https://img.example/missing.png\"><p>after</p>",
+			want: "<p>after</p>",
+		},
+		{
+			name: "nested image that failed to fetch",
+			body: "<p>See <img src=\"https://img.example/missing.png\" alt=\"the map\"> here</p>",
+			want: "<p>See the map here</p>",
+		},
+		{
+			name: "embedded image",
+			body: "<img src=\"https://img.example/map.png\" alt=\"map\"/>",
+			want: "<img src=\"images/img1.png\" alt=\"map\"/>",
+		},
+		{
+			name: "heading ids are prefixed",
+			body: "<h2 id=\"rooms\">Rooms</h2><a href=\"#rooms\">up</a>",
+			want: "<h2 id=\"ch1-rooms\">Rooms</h2><a href=\"ch1.xhtml#ch1-rooms\">up</a>",
+		},
+		{
+			name: "links to exported articles stay in the book",
+			body: "<a href=\"/articles/hostels\">a</a> <a href=\"/articles/hostels/#garnet\">b</a>",
+			want: "<a href=\"ch2.xhtml\">a</a> <a href=\"ch2.xhtml#ch2-garnet\">b</a>",
+		},
+		{
+			name: "other links",
+			body: "<a href=\"/articles/elsewhere\">a</a> <a href=\"https://nitt.edu\">b</a> <a href=\"//cdn.example/x\">c</a>",
+			want: "<a href=\"https://wiki.example/articles/elsewhere\">a</a> <a href=\"https://nitt.edu\">b</a> <a href=\"//cdn.example/x\">c</a>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := book.renderBody(&Chapter{ID: "ch1", Body: tt.body}, r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("renderBody:\ngot  %q\nwant %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/pranava-mohan/wikinitt/gravy/internal/lease"
+)
+
+const (
+	lockDuration = 15 * time.Minute
+	pollInterval = 30 * time.Second
+)
+
+// Runner builds queued exports one at a time. Exports fetch every image
+// they embed, so running them in parallel would only compete for the same
+// bandwidth.
+type Runner struct {
+	jobs    Repository
+	builder *Builder
+	owner   string
+	wake    chan struct{}
+}
+
+func NewRunner(jobs Repository, builder *Builder) *Runner {
+	return &Runner{
+		jobs:    jobs,
+		builder: builder,
+		owner:   lease.NewOwnerID(),
+		wake:    make(chan struct{}, 1),
+	}
+}
+
+// Enqueue queues an export and wakes the runner.
+func (r *Runner) Enqueue(ctx context.Context, job Job) (*Job, error) {
+	if job.Format != FormatEPUB && job.Format != FormatHTML {
+		return nil, fmt.Errorf("unknown export format %q", job.Format)
+	}
+	if err := job.Source().Validate(); err != nil {
+		return nil, err
+	}
+	created, err := r.jobs.Create(ctx, job)
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+	return created, nil
+}
+
+// Run processes jobs until ctx is cancelled.
+func (r *Runner) Run(ctx context.Context) {
+	for {
+		if n, err := r.jobs.FailAbandoned(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("export: failed to reap abandoned jobs: %v", err)
+		} else if n > 0 {
+			log.Printf("export: marked %d abandoned jobs FAILED", n)
+		}
+
+		job, err := r.jobs.Claim(ctx, r.owner, lockDuration)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("export: failed to claim job: %v", err)
+		}
+
+		if job == nil {
+			select {
+			case <-ctx.Done():
+				return
+			case <-r.wake:
+			case <-time.After(pollInterval):
+			}
+			continue
+		}
+
+		r.process(ctx, job)
+	}
+}
+
+func (r *Runner) process(ctx context.Context, job *Job) {
+	// A panic while building one export must not take the server down or
+	// leave the job RUNNING until its lock expires.
+	defer func() {
+		if p := recover(); p != nil {
+			r.fail(ctx, job, fmt.Errorf("export panicked: %v", p))
+		}
+	}()
+
+	book, err := r.builder.Collect(ctx, job.Source(), job.Title)
+	if err != nil {
+		r.fail(ctx, job, err)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, book, job.Format); err != nil {
+		r.fail(ctx, job, err)
+		return
+	}
+
+	job.Title = book.Title
+	job.Chapters = len(book.Chapters)
+	job.Size = int64(buf.Len())
+	job.FileName = book.FileName(job.Format)
+	if err := r.jobs.Complete(ctx, job, &buf); err != nil {
+		if err != ErrLeaseLost {
+			r.fail(ctx, job, err)
+		}
+		return
+	}
+	log.Printf("export: job %s wrote %s (%d articles, %d bytes)", job.ID, job.FileName, job.Chapters, job.Size)
+}
+
+func (r *Runner) fail(ctx context.Context, job *Job, cause error) {
+	log.Printf("export: job %s failed: %v", job.ID, cause)
+	if err := r.jobs.Fail(ctx, job, cause); err != nil && err != ErrLeaseLost {
+		log.Printf("export: failed to record failure of job %s: %v", job.ID, err)
+	}
+}
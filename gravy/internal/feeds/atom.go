@@ -0,0 +1,204 @@
+package feeds
+
+import (
+	"context"
+	"encoding/xml"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pranava-mohan/wikinitt/gravy/internal/articles"
+	"github.com/pranava-mohan/wikinitt/gravy/internal/categories"
+	"github.com/pranava-mohan/wikinitt/gravy/internal/community"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+const feedEntries = 50
+
+type atomFeed struct {
+	XMLName  xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID       string      `xml:"id"`
+	Title    string      `xml:"title"`
+	Subtitle string      `xml:"subtitle,omitempty"`
+	Updated  string      `xml:"updated"`
+	Links    []atomLink  `xml:"link"`
+	Entries  []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+type atomEntry struct {
+	ID         string         `xml:"id"`
+	Title      string         `xml:"title"`
+	Updated    string         `xml:"updated"`
+	Published  string         `xml:"published,omitempty"`
+	Author     atomPerson     `xml:"author"`
+	Links      []atomLink     `xml:"link"`
+	Categories []atomCategory `xml:"category"`
+	Summary    string         `xml:"summary,omitempty"`
+	Content    atomContent    `xml:"content"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomContent struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+// ArticlesFeed serves /feeds/articles.atom, the latest published articles.
+// ?category=<slug> limits it to one category.
+func (h *Handler) ArticlesFeed(w http.ResponseWriter, r *http.Request) {
+	h.serve(w, r, "application/atom+xml; charset=utf-8", func(ctx context.Context) (interface{}, time.Time, error) {
+		published := articles.StatusPublished
+		filter := articles.ListFilter{Status: &published}
+		feed := &atomFeed{
+			ID:    "urn:wikinitt:feeds:articles",
+			Title: "WikiNITT articles",
+			Links: []atomLink{
+				{Rel: "self", Type: "application/atom+xml", Href: requestBaseURL(r) + r.URL.RequestURI()},
+				{Rel: "alternate", Type: "text/html", Href: h.frontendURL + "/articles"},
+			},
+			Entries: []atomEntry{},
+		}
+
+		if slug := r.URL.Query().Get("category"); slug != "" {
+			category, err := h.categories.GetBySlug(ctx, slug)
+			if err == categories.ErrNotFound {
+				return nil, time.Time{}, errNotFound
+			}
+			if err != nil {
+				return nil, time.Time{}, err
+			}
+			filter.Category = &category.Name
+			feed.ID += ":category:" + category.ID
+			feed.Title = "WikiNITT articles in " + category.Name
+			feed.Subtitle = category.Description
+		}
+
+		limit := feedEntries
+		list, err := h.articles.List(ctx, filter, &limit, nil)
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+
+		authors := h.authorNames(ctx)
+		var modified time.Time
+		for _, a := range list {
+			rendered := articles.Render(a.Content)
+			entry := atomEntry{
+				ID:      "urn:wikinitt:article:" + a.ID,
+				Title:   a.Title,
+				Updated: formatTime(a.UpdatedAt),
+				Author:  atomPerson{Name: authors(a.AuthorID)},
+				Links:   []atomLink{{Rel: "alternate", Type: "text/html", Href: h.frontendURL + "/articles/" + a.Slug}},
+				Summary: rendered.Excerpt,
+				Content: atomContent{Type: "html", Body: rendered.HTML},
+			}
+			if a.PublishedAt != nil {
+				entry.Published = formatTime(*a.PublishedAt)
+			}
+			if a.Category != "" {
+				entry.Categories = append(entry.Categories, atomCategory{Term: a.Category})
+			}
+			for _, tag := range a.Tags {
+				entry.Categories = append(entry.Categories, atomCategory{Term: tag})
+			}
+			feed.Entries = append(feed.Entries, entry)
+			modified = latest(modified, a.UpdatedAt)
+		}
+		feed.Updated = formatTime(modified)
+		return feed, modified, nil
+	})
+}
+
+// GroupFeed serves /feeds/groups/{slug}.atom, the latest posts in a public
+// group. Private groups get a 404, the same as groups that do not exist.
+func (h *Handler) GroupFeed(w http.ResponseWriter, r *http.Request) {
+	slug, ok := strings.CutSuffix(strings.TrimPrefix(r.URL.Path, "/feeds/groups/"), ".atom")
+	if !ok || slug == "" || strings.Contains(slug, "/") {
+		http.NotFound(w, r)
+		return
+	}
+
+	h.serve(w, r, "application/atom+xml; charset=utf-8", func(ctx context.Context) (interface{}, time.Time, error) {
+		group, err := h.community.GetGroup(ctx, slug)
+		if err == mongo.ErrNoDocuments {
+			return nil, time.Time{}, errNotFound
+		}
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+		if group.Type != community.GroupTypePublic {
+			return nil, time.Time{}, errNotFound
+		}
+
+		posts, err := h.community.ListPosts(ctx, group.ID, feedEntries, 0)
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+
+		groupURL := h.frontendURL + "/c/" + group.Slug
+		feed := &atomFeed{
+			ID:       "urn:wikinitt:feeds:group:" + group.ID,
+			Title:    group.Name,
+			Subtitle: group.Description,
+			Links: []atomLink{
+				{Rel: "self", Type: "application/atom+xml", Href: requestBaseURL(r) + r.URL.RequestURI()},
+				{Rel: "alternate", Type: "text/html", Href: groupURL},
+			},
+			Entries: []atomEntry{},
+		}
+
+		authors := h.authorNames(ctx)
+		modified := group.CreatedAt
+		for _, p := range posts {
+			feed.Entries = append(feed.Entries, atomEntry{
+				ID:        "urn:wikinitt:post:" + p.ID,
+				Title:     p.Title,
+				Updated:   formatTime(p.CreatedAt),
+				Published: formatTime(p.CreatedAt),
+				Author:    atomPerson{Name: authors(p.AuthorID)},
+				Links:     []atomLink{{Rel: "alternate", Type: "text/html", Href: groupURL + "/posts/" + p.ID}},
+				Content:   atomContent{Type: "html", Body: articles.Render(p.Content).HTML},
+			})
+			modified = latest(modified, p.CreatedAt)
+		}
+		feed.Updated = formatTime(modified)
+		return feed, modified, nil
+	})
+}
+
+// authorNames returns a lookup of display names that fetches each user
+// once.
+func (h *Handler) authorNames(ctx context.Context) func(id string) string {
+	names := make(map[string]string)
+	return func(id string) string {
+		if name, ok := names[id]; ok {
+			return name
+		}
+		name := "WikiNITT"
+		if u, err := h.users.GetByID(ctx, id); err == nil {
+			name = u.DisplayName
+			if name == "" {
+				name = u.Username
+			}
+		}
+		names[id] = name
+		return name
+	}
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
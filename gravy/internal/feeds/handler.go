@@ -0,0 +1,136 @@
+// Package feeds serves the sitemap and Atom feeds that let search engines
+// and feed readers discover public content.
+package feeds
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/golang-lru/v2/expirable"
+	"github.com/pranava-mohan/wikinitt/gravy/internal/articles"
+	"github.com/pranava-mohan/wikinitt/gravy/internal/categories"
+	"github.com/pranava-mohan/wikinitt/gravy/internal/community"
+	"github.com/pranava-mohan/wikinitt/gravy/internal/users"
+)
+
+const (
+	cacheSize = 256
+	cacheTTL  = 2 * time.Minute
+)
+
+// Handler serves /sitemap.xml, /feeds/articles.atom and
+// /feeds/groups/{slug}.atom. Responses are cached briefly and carry an
+// ETag and Last-Modified, so unchanged feeds cost clients a 304.
+type Handler struct {
+	articles    articles.Repository
+	categories  categories.Repository
+	community   community.Repository
+	users       users.Repository
+	frontendURL string
+	cache       *expirable.LRU[string, *response]
+}
+
+type response struct {
+	body        []byte
+	contentType string
+	etag        string
+	modified    time.Time
+}
+
+// errNotFound is returned by builders for feeds of things that do not
+// exist or are not public.
+var errNotFound = errors.New("not found")
+
+// NewHandler creates a Handler. Links point at FRONTEND_URL, where the
+// pages are rendered.
+func NewHandler(articleRepo articles.Repository, categoryRepo categories.Repository, communityRepo community.Repository, userRepo users.Repository) *Handler {
+	frontendURL := os.Getenv("FRONTEND_URL")
+	if frontendURL == "" {
+		frontendURL = "https://wikinitt.netlify.app"
+	}
+	return &Handler{
+		articles:    articleRepo,
+		categories:  categoryRepo,
+		community:   communityRepo,
+		users:       userRepo,
+		frontendURL: strings.TrimRight(frontendURL, "/"),
+		cache:       expirable.NewLRU[string, *response](cacheSize, nil, cacheTTL),
+	}
+}
+
+// serve writes the response build produces for the request, building it
+// only if no fresh copy is cached. build returns the document and when its
+// content last changed.
+func (h *Handler) serve(w http.ResponseWriter, r *http.Request, contentType string, build func(ctx context.Context) (interface{}, time.Time, error)) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	key := r.URL.Path + "?" + r.URL.RawQuery
+	resp, ok := h.cache.Get(key)
+	if !ok {
+		doc, modified, err := build(r.Context())
+		if err != nil {
+			if errors.Is(err, errNotFound) {
+				http.NotFound(w, r)
+				return
+			}
+			log.Printf("Failed to build %s: %v", r.URL.Path, err)
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		var buf bytes.Buffer
+		buf.WriteString(xml.Header)
+		if err := xml.NewEncoder(&buf).Encode(doc); err != nil {
+			log.Printf("Failed to encode %s: %v", r.URL.Path, err)
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+		sum := sha256.Sum256(buf.Bytes())
+		resp = &response{
+			body:        buf.Bytes(),
+			contentType: contentType,
+			etag:        `"` + hex.EncodeToString(sum[:16]) + `"`,
+			modified:    modified,
+		}
+		h.cache.Add(key, resp)
+	}
+
+	w.Header().Set("Content-Type", resp.contentType)
+	w.Header().Set("ETag", resp.etag)
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	// ServeContent answers If-None-Match and If-Modified-Since with 304.
+	http.ServeContent(w, r, "", resp.modified, bytes.NewReader(resp.body))
+}
+
+// requestBaseURL is the scheme and host the request was made to, for links
+// back to this server.
+func requestBaseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	return scheme + "://" + r.Host
+}
+
+func latest(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+	return a
+}
@@ -0,0 +1,126 @@
+package feeds
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/pranava-mohan/wikinitt/gravy/internal/articles"
+	"github.com/pranava-mohan/wikinitt/gravy/internal/community"
+)
+
+// maxSitemapURLs is the most URLs the sitemap protocol allows in one file.
+// Past it, /sitemap.xml becomes an index of numbered pages.
+const maxSitemapURLs = 50000
+
+const sitemapNamespace = "http://www.sitemaps.org/schemas/sitemap/0.9"
+
+type urlSet struct {
+	XMLName xml.Name     `xml:"urlset"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+type sitemapURL struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+type sitemapIndex struct {
+	XMLName  xml.Name         `xml:"sitemapindex"`
+	XMLNS    string           `xml:"xmlns,attr"`
+	Sitemaps []sitemapPointer `xml:"sitemap"`
+}
+
+type sitemapPointer struct {
+	Loc string `xml:"loc"`
+}
+
+// Sitemap serves /sitemap.xml: published articles followed by public
+// groups. With more than maxSitemapURLs it serves an index instead, and
+// /sitemap.xml?page=N serves each page.
+func (h *Handler) Sitemap(w http.ResponseWriter, r *http.Request) {
+	h.serve(w, r, "application/xml; charset=utf-8", func(ctx context.Context) (interface{}, time.Time, error) {
+		articleCount, groupCount, err := h.sitemapCounts(ctx)
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+		pages := int((articleCount + groupCount + maxSitemapURLs - 1) / maxSitemapURLs)
+
+		pageParam := r.URL.Query().Get("page")
+		if pageParam == "" {
+			if pages <= 1 {
+				return h.sitemapPage(ctx, 0, articleCount)
+			}
+			index := &sitemapIndex{XMLNS: sitemapNamespace}
+			base := requestBaseURL(r)
+			for i := 1; i <= pages; i++ {
+				index.Sitemaps = append(index.Sitemaps, sitemapPointer{Loc: fmt.Sprintf("%s/sitemap.xml?page=%d", base, i)})
+			}
+			// The index only changes when the page count does, which the
+			// pages' own Last-Modified already reflects.
+			return index, time.Time{}, nil
+		}
+
+		page, err := strconv.Atoi(pageParam)
+		if err != nil || page < 1 || page > max(pages, 1) {
+			return nil, time.Time{}, errNotFound
+		}
+		return h.sitemapPage(ctx, (page-1)*maxSitemapURLs, articleCount)
+	})
+}
+
+func (h *Handler) sitemapCounts(ctx context.Context) (int64, int64, error) {
+	published := articles.StatusPublished
+	articleCount, err := h.articles.Count(ctx, articles.ListFilter{Status: &published})
+	if err != nil {
+		return 0, 0, err
+	}
+	public := community.GroupTypePublic
+	groupCount, err := h.community.CountGroups(ctx, community.GroupFilter{Type: &public})
+	if err != nil {
+		return 0, 0, err
+	}
+	return articleCount, groupCount, nil
+}
+
+// sitemapPage lists up to maxSitemapURLs URLs starting at offset, counting
+// articles first and then groups.
+func (h *Handler) sitemapPage(ctx context.Context, offset int, articleCount int64) (interface{}, time.Time, error) {
+	set := &urlSet{XMLNS: sitemapNamespace, URLs: []sitemapURL{}}
+	var modified time.Time
+
+	if int64(offset) < articleCount {
+		entries, err := h.articles.ListSitemapEntries(ctx, maxSitemapURLs, offset)
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+		for _, e := range entries {
+			set.URLs = append(set.URLs, sitemapURL{
+				Loc:     h.frontendURL + "/articles/" + e.Slug,
+				LastMod: e.UpdatedAt.UTC().Format(time.RFC3339),
+			})
+			modified = latest(modified, e.UpdatedAt)
+		}
+	}
+
+	if remaining := maxSitemapURLs - len(set.URLs); remaining > 0 {
+		groupOffset := max(offset-int(articleCount), 0)
+		public := community.GroupTypePublic
+		groups, err := h.community.ListGroups(ctx, community.GroupFilter{Type: &public}, remaining, groupOffset)
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+		for _, g := range groups {
+			set.URLs = append(set.URLs, sitemapURL{
+				Loc:     h.frontendURL + "/c/" + g.Slug,
+				LastMod: g.CreatedAt.UTC().Format(time.RFC3339),
+			})
+			modified = latest(modified, g.CreatedAt)
+		}
+	}
+	return set, modified, nil
+}
@@ -0,0 +1,74 @@
+package lease
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// Lease is a named, time-limited lock stored in Mongo. Only one holder
+// (typically one server replica) owns a lease at a time; if the holder dies
+// the lease frees itself once it expires.
+type Lease struct {
+	coll  *mongo.Collection
+	name  string
+	owner string
+	ttl   time.Duration
+}
+
+func New(db *mongo.Database, name string, ttl time.Duration) *Lease {
+	return &Lease{
+		coll:  db.Collection("leases"),
+		name:  name,
+		owner: NewOwnerID(),
+		ttl:   ttl,
+	}
+}
+
+// NewOwnerID identifies this process as a lease holder.
+func NewOwnerID() string {
+	host, _ := os.Hostname()
+	b := make([]byte, 4)
+	_, _ = rand.Read(b)
+	return fmt.Sprintf("%s-%d-%s", host, os.Getpid(), hex.EncodeToString(b))
+}
+
+// Acquire takes the lease, or extends it if we already hold it. It reports
+// false without error when another owner holds an unexpired lease.
+func (l *Lease) Acquire(ctx context.Context) (bool, error) {
+	now := time.Now()
+	filter := bson.M{
+		"_id": l.name,
+		"$or": []bson.M{
+			{"owner": l.owner},
+			{"expiresAt": bson.M{"$lte": now}},
+		},
+	}
+	update := bson.M{"$set": bson.M{
+		"owner":     l.owner,
+		"expiresAt": now.Add(l.ttl),
+	}}
+
+	_, err := l.coll.UpdateOne(ctx, filter, update, options.UpdateOne().SetUpsert(true))
+	if err != nil {
+		// The upsert collides with the existing _id when someone else holds it.
+		if mongo.IsDuplicateKeyError(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// Release gives the lease up early if we hold it.
+func (l *Lease) Release(ctx context.Context) error {
+	_, err := l.coll.DeleteOne(ctx, bson.M{"_id": l.name, "owner": l.owner})
+	return err
+}
@@ -0,0 +1,70 @@
+package lease
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// testDB connects to the server in MONGO_TEST_URI and returns a database
+// dropped after the test. Without it the test is skipped, since the lease
+// is only as good as the filter Mongo runs.
+func testDB(t *testing.T) *mongo.Database {
+	uri := os.Getenv("MONGO_TEST_URI")
+	if uri == "" {
+		t.Skip("MONGO_TEST_URI not set")
+	}
+	client, err := mongo.Connect(options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := client.Database("lease_test_" + bson.NewObjectID().Hex())
+	t.Cleanup(func() {
+		_ = db.Drop(context.Background())
+		_ = client.Disconnect(context.Background())
+	})
+	return db
+}
+
+func TestLeaseTakeover(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+	ttl := time.Second
+	a := New(db, "scheduler", ttl)
+	b := New(db, "scheduler", ttl)
+
+	mustAcquire := func(l *Lease, want bool, step string) {
+		t.Helper()
+		held, err := l.Acquire(ctx)
+		if err != nil {
+			t.Fatalf("%s: %v", step, err)
+		}
+		if held != want {
+			t.Fatalf("%s: held = %v, want %v", step, held, want)
+		}
+	}
+
+	mustAcquire(a, true, "first acquire")
+	mustAcquire(b, false, "acquire while held")
+	mustAcquire(a, true, "renew by the owner")
+
+	time.Sleep(ttl + 100*time.Millisecond)
+	mustAcquire(b, true, "take over after expiry")
+	mustAcquire(a, false, "old owner after takeover")
+
+	// Releasing a lease someone else holds must not free it.
+	if err := a.Release(ctx); err != nil {
+		t.Fatal(err)
+	}
+	mustAcquire(a, false, "acquire after releasing a lease not held")
+
+	if err := b.Release(ctx); err != nil {
+		t.Fatal(err)
+	}
+	mustAcquire(a, true, "acquire after release")
+}
@@ -0,0 +1,66 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const devFrom = "WikiNITT <no-reply@localhost>"
+
+type fileSink struct {
+	dir string
+}
+
+// NewFileSink writes each message to an .eml file in dir instead of sending
+// it, for development. The files open in any mail client.
+func NewFileSink(dir string) (Mailer, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &fileSink{dir: dir}, nil
+}
+
+func (s *fileSink) Send(ctx context.Context, msg Message) error {
+	data, err := build(devFrom, msg)
+	if err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%s.eml", time.Now().Format("20060102-150405.000000"), safeName(msg.To))
+	path := filepath.Join(s.dir, name)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return err
+	}
+	log.Printf("mailer: wrote %q to %s", msg.Subject, path)
+	return nil
+}
+
+type logSink struct{}
+
+// NewLogSink writes messages to the log instead of sending them, for
+// development. Links in them, such as password reset links, can be copied
+// from there.
+func NewLogSink() Mailer {
+	return logSink{}
+}
+
+func (logSink) Send(ctx context.Context, msg Message) error {
+	if _, err := build(devFrom, msg); err != nil {
+		return err
+	}
+	log.Printf("mailer: to %s: %s\n%s", msg.To, msg.Subject, msg.Text)
+	return nil
+}
+
+func safeName(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '-' {
+			return r
+		}
+		return '_'
+	}, s)
+}
@@ -0,0 +1,78 @@
+package mediawiki
+
+import (
+	"encoding/xml"
+	"io"
+	"sort"
+	"time"
+)
+
+// Page is a page from a MediaWiki XML export, with its revisions oldest
+// first.
+type Page struct {
+	Title     string     `xml:"title"`
+	Namespace int        `xml:"ns"`
+	ID        int64      `xml:"id"`
+	Redirect  *Redirect  `xml:"redirect"`
+	Revisions []Revision `xml:"revision"`
+}
+
+type Redirect struct {
+	Title string `xml:"title,attr"`
+}
+
+type Revision struct {
+	ID          int64       `xml:"id"`
+	Timestamp   time.Time   `xml:"timestamp"`
+	Contributor Contributor `xml:"contributor"`
+	Comment     string      `xml:"comment"`
+	Text        string      `xml:"text"`
+}
+
+// Contributor is a registered user (Username) or an anonymous editor (IP).
+type Contributor struct {
+	Username string `xml:"username"`
+	IP       string `xml:"ip"`
+}
+
+// Latest returns the page's current revision, or nil if the export has
+// none.
+func (p *Page) Latest() *Revision {
+	if len(p.Revisions) == 0 {
+		return nil
+	}
+	return &p.Revisions[len(p.Revisions)-1]
+}
+
+// Reader streams pages out of an export. Only one page is held in memory at
+// a time, so dumps larger than memory can be imported.
+type Reader struct {
+	decoder *xml.Decoder
+}
+
+func NewReader(r io.Reader) *Reader {
+	return &Reader{decoder: xml.NewDecoder(r)}
+}
+
+// Next returns the next page, or io.EOF after the last one.
+func (r *Reader) Next() (*Page, error) {
+	for {
+		token, err := r.decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "page" {
+			continue
+		}
+
+		var page Page
+		if err := r.decoder.DecodeElement(&page, &start); err != nil {
+			return nil, err
+		}
+		sort.SliceStable(page.Revisions, func(i, j int) bool {
+			return page.Revisions[i].Timestamp.Before(page.Revisions[j].Timestamp)
+		})
+		return &page, nil
+	}
+}
@@ -0,0 +1,81 @@
+package mediawiki
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReader(t *testing.T) {
+	const dump = `<mediawiki>
+  <siteinfo><sitename>Old Wiki</sitename></siteinfo>
+  <page>
+    <title>Octagon</title>
+    <ns>0</ns>
+    <id>1</id>
+    <revision>
+      <id>11</id>
+      <timestamp>2020-02-01T00:00:00Z</timestamp>
+      <contributor><ip>10.0.0.1</ip></contributor>
+      <text>second</text>
+    </revision>
+    <revision>
+      <id>10</id>
+      <timestamp>2020-01-01T00:00:00Z</timestamp>
+      <contributor><username>alice</username></contributor>
+      <comment>created</comment>
+      <text>first</text>
+    </revision>
+  </page>
+  <page>
+    <title>The Octagon</title>
+    <ns>0</ns>
+    <id>2</id>
+    <redirect title="Octagon" />
+    <revision>
+      <id>20</id>
+      <timestamp>2020-01-02T00:00:00Z</timestamp>
+      <text>#REDIRECT [[Octagon]]</text>
+    </revision>
+  </page>
+  <page>
+    <title>Empty</title>
+    <ns>0</ns>
+    <id>3</id>
+  </page>
+</mediawiki>`
+
+	r := NewReader(strings.NewReader(dump))
+	page, err := r.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Title != "Octagon" || len(page.Revisions) != 2 {
+		t.Fatalf("first page = %+v", page)
+	}
+	if page.Revisions[0].ID != 10 || page.Revisions[0].Contributor.Username != "alice" {
+		t.Errorf("revisions should be oldest first, got %+v", page.Revisions)
+	}
+	if latest := page.Latest(); latest.Text != "second" || latest.Contributor.IP != "10.0.0.1" {
+		t.Errorf("latest = %+v", latest)
+	}
+
+	page, err = r.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Redirect == nil || page.Redirect.Title != "Octagon" {
+		t.Errorf("redirect = %+v", page.Redirect)
+	}
+
+	page, err = r.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Latest() != nil {
+		t.Errorf("page without revisions has latest %+v", page.Latest())
+	}
+
+	if _, err := r.Next(); err == nil {
+		t.Error("expected io.EOF after the last page")
+	}
+}
@@ -0,0 +1,497 @@
+package mediawiki
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Result is wikitext converted to Markdown. Categories are the page's
+// [[Category:...]] links in order of appearance. Issues describe constructs
+// that could not be converted and were dropped or kept as-is.
+type Result struct {
+	Markdown   string
+	Categories []string
+	Issues     []string
+}
+
+// Converter turns wikitext into Markdown. LinkTarget maps the title of an
+// internal [[link]] to the path it should point to, or to "" if the page
+// does not exist, in which case only the link text is kept.
+type Converter struct {
+	LinkTarget func(title string) string
+}
+
+type conversion struct {
+	*Converter
+	categories []string
+	issues     map[string]int
+	blocks     []string
+}
+
+var (
+	commentPattern    = regexp.MustCompile(`(?s)<!--.*?-->`)
+	nowikiPattern     = regexp.MustCompile(`(?s)<nowiki>(.*?)</nowiki>`)
+	prePattern        = regexp.MustCompile(`(?s)<pre[^>]*>(.*?)</pre>`)
+	sourcePattern     = regexp.MustCompile(`(?s)<(syntaxhighlight|source)([^>]*)>(.*?)</(?:syntaxhighlight|source)>`)
+	langAttrPattern   = regexp.MustCompile(`lang\s*=\s*"?([\w+#-]+)"?`)
+	refPattern        = regexp.MustCompile(`(?s)<ref(?:\s[^>/]*)?/>|<ref(?:\s[^>]*)?>.*?</ref>`)
+	referencesPattern = regexp.MustCompile(`<references\s*/>|(?s)<references>.*?</references>`)
+	magicWordPattern  = regexp.MustCompile(`__[A-Z]+__`)
+	headingPattern    = regexp.MustCompile(`^(={1,6})\s*(.+?)\s*(={1,6})\s*$`)
+	hrPattern         = regexp.MustCompile(`^-{4,}\s*$`)
+	boldItalicPattern = regexp.MustCompile(`'''''(.+?)'''''`)
+	boldPattern       = regexp.MustCompile(`'''(.+?)'''`)
+	italicPattern     = regexp.MustCompile(`''(.+?)''`)
+	linkPattern       = regexp.MustCompile(`\[\[([^\[\]|]+)(?:\|([^\[\]]*))?\]\]([\p{Ll}]*)`)
+	extLinkPattern    = regexp.MustCompile(`\[((?:https?|ftp)://[^\s\]]+)(?:\s+([^\]]+))?\]`)
+	placeholder       = regexp.MustCompile("\x00(\\d+)\x00")
+)
+
+// Convert converts one page of wikitext.
+func (c *Converter) Convert(text string) *Result {
+	conv := &conversion{Converter: c, issues: make(map[string]int)}
+
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	// NUL delimits placeholders, so it must not come from the page itself.
+	text = strings.ReplaceAll(text, "\x00", "")
+	text = commentPattern.ReplaceAllString(text, "")
+	text = conv.protectBlocks(text)
+	text = conv.stripTemplates(text)
+	text = conv.stripRefs(text)
+	text = magicWordPattern.ReplaceAllString(text, "")
+	text = conv.extractNamespaced(text)
+
+	markdown := conv.convertBlocks(text)
+	// Held text can itself contain placeholders, e.g. <nowiki> inside a
+	// link, and each pass resolves one level.
+	for i := 0; i <= len(conv.blocks) && placeholder.MatchString(markdown); i++ {
+		markdown = placeholder.ReplaceAllStringFunc(markdown, func(m string) string {
+			n, err := strconv.Atoi(strings.Trim(m, "\x00"))
+			if err != nil || n < 0 || n >= len(conv.blocks) {
+				return ""
+			}
+			return conv.blocks[n]
+		})
+	}
+
+	return &Result{
+		Markdown:   strings.TrimSpace(collapseBlankLines(markdown)) + "\n",
+		Categories: conv.categories,
+		Issues:     conv.issueList(),
+	}
+}
+
+func (c *conversion) issue(format string, args ...interface{}) {
+	c.issues[fmt.Sprintf(format, args...)]++
+}
+
+func (c *conversion) issueList() []string {
+	list := make([]string, 0, len(c.issues))
+	for issue, n := range c.issues {
+		if n > 1 {
+			issue = fmt.Sprintf("%s (x%d)", issue, n)
+		}
+		list = append(list, issue)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// hold stores Markdown that must come out exactly as written and returns a
+// placeholder for it.
+func (c *conversion) hold(markdown string) string {
+	c.blocks = append(c.blocks, markdown)
+	return fmt.Sprintf("\x00%d\x00", len(c.blocks)-1)
+}
+
+// protectBlocks turns code and <nowiki> sections into placeholders so the
+// rest of the conversion leaves their contents alone.
+func (c *conversion) protectBlocks(text string) string {
+	text = sourcePattern.ReplaceAllStringFunc(text, func(m string) string {
+		parts := sourcePattern.FindStringSubmatch(m)
+		lang := ""
+		if l := langAttrPattern.FindStringSubmatch(parts[2]); l != nil {
+			lang = l[1]
+		}
+		return "\n" + c.hold(fence(lang, parts[3])) + "\n"
+	})
+	text = prePattern.ReplaceAllStringFunc(text, func(m string) string {
+		return "\n" + c.hold(fence("", prePattern.FindStringSubmatch(m)[1])) + "\n"
+	})
+	return nowikiPattern.ReplaceAllStringFunc(text, func(m string) string {
+		return c.hold(escapeMarkdown(nowikiPattern.FindStringSubmatch(m)[1]))
+	})
+}
+
+func fence(lang, code string) string {
+	return "```" + lang + "\n" + strings.Trim(code, "\n") + "\n```"
+}
+
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`, "`", "\\`", "#", `\#`,
+)
+
+func escapeMarkdown(s string) string {
+	return markdownEscaper.Replace(s)
+}
+
+// stripTemplates removes {{templates}} and {{#parser functions}}, which
+// have no Markdown equivalent, reporting each by name.
+func (c *conversion) stripTemplates(text string) string {
+	var sb strings.Builder
+	for {
+		start := strings.Index(text, "{{")
+		if start < 0 {
+			sb.WriteString(text)
+			return sb.String()
+		}
+		end := matchingClose(text, start, "{{", "}}")
+		if end < 0 {
+			sb.WriteString(text)
+			return sb.String()
+		}
+
+		sb.WriteString(text[:start])
+		body := text[start+2 : end-2]
+		name := body
+		if i := strings.IndexAny(name, "|:"); i >= 0 {
+			name = name[:i]
+		}
+		c.issue("template {{%s}} removed", strings.TrimSpace(name))
+		text = text[end:]
+	}
+}
+
+// matchingClose returns the index just past the close that balances the
+// open at start, or -1.
+func matchingClose(text string, start int, open, close string) int {
+	depth := 0
+	for i := start; i < len(text); {
+		switch {
+		case strings.HasPrefix(text[i:], open):
+			depth++
+			i += len(open)
+		case strings.HasPrefix(text[i:], close):
+			depth--
+			i += len(close)
+			if depth == 0 {
+				return i
+			}
+		default:
+			i++
+		}
+	}
+	return -1
+}
+
+func (c *conversion) stripRefs(text string) string {
+	if n := len(refPattern.FindAllString(text, -1)); n > 0 {
+		c.issues["footnote <ref> removed"] += n
+		text = refPattern.ReplaceAllString(text, "")
+	}
+	return referencesPattern.ReplaceAllString(text, "")
+}
+
+// extractNamespaced removes [[Category:...]] links, recording the
+// categories, and [[File:...]] embeds, which would need the file uploaded.
+func (c *conversion) extractNamespaced(text string) string {
+	var sb strings.Builder
+	for {
+		start := strings.Index(text, "[[")
+		if start < 0 {
+			sb.WriteString(text)
+			return sb.String()
+		}
+		end := matchingClose(text, start, "[[", "]]")
+		if end < 0 {
+			sb.WriteString(text)
+			return sb.String()
+		}
+
+		inner := text[start+2 : end-2]
+		namespace, rest, found := strings.Cut(inner, ":")
+		switch ns := strings.ToLower(strings.TrimSpace(namespace)); {
+		case found && ns == "category":
+			sb.WriteString(text[:start])
+			name, _, _ := strings.Cut(rest, "|")
+			c.categories = append(c.categories, strings.TrimSpace(name))
+		case found && (ns == "file" || ns == "image" || ns == "media"):
+			sb.WriteString(text[:start])
+			name, _, _ := strings.Cut(rest, "|")
+			c.issue("file %s not imported", strings.TrimSpace(name))
+		default:
+			sb.WriteString(text[:end])
+		}
+		text = text[end:]
+	}
+}
+
+func (c *conversion) convertBlocks(text string) string {
+	lines := strings.Split(text, "\n")
+	var out []string
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case strings.HasPrefix(trimmed, "{|"):
+			end := tableEnd(lines, i)
+			out = append(out, "", c.convertTable(lines[i:end+1]), "")
+			i = end
+		case headingPattern.MatchString(trimmed):
+			m := headingPattern.FindStringSubmatch(trimmed)
+			level := min(len(m[1]), len(m[3]))
+			out = append(out, "", strings.Repeat("#", level)+" "+c.convertInline(m[2]), "")
+		case hrPattern.MatchString(trimmed):
+			out = append(out, "", "---", "")
+		case len(line) > 0 && strings.ContainsRune("*#:;", rune(line[0])):
+			out = append(out, c.convertListItem(line))
+		case strings.HasPrefix(line, " ") && trimmed != "":
+			// A leading space makes a preformatted line.
+			var code []string
+			for ; i < len(lines) && strings.HasPrefix(lines[i], " "); i++ {
+				code = append(code, lines[i][1:])
+			}
+			i--
+			out = append(out, "", fence("", strings.Join(code, "\n")), "")
+		default:
+			out = append(out, c.convertInline(line))
+		}
+	}
+	return strings.Join(out, "\n")
+}
+
+func (c *conversion) convertListItem(line string) string {
+	prefixLen := 0
+	for prefixLen < len(line) && strings.ContainsRune("*#:;", rune(line[prefixLen])) {
+		prefixLen++
+	}
+	prefix := line[:prefixLen]
+	content := c.convertInline(strings.TrimSpace(line[prefixLen:]))
+
+	indent := ""
+	for _, ch := range prefix[:prefixLen-1] {
+		if ch == '#' {
+			indent += "   "
+		} else {
+			indent += "  "
+		}
+	}
+
+	switch prefix[prefixLen-1] {
+	case '*':
+		return indent + "- " + content
+	case '#':
+		return indent + "1. " + content
+	case ';':
+		term, definition, found := strings.Cut(content, " : ")
+		if found {
+			return indent + "**" + strings.TrimSpace(term) + "**: " + strings.TrimSpace(definition)
+		}
+		return indent + "**" + content + "**"
+	default:
+		// ":" indents a line; the closest Markdown has is a quote.
+		return indent + strings.Repeat("> ", strings.Count(prefix, ":")) + content
+	}
+}
+
+func tableEnd(lines []string, start int) int {
+	depth := 0
+	for i := start; i < len(lines); i++ {
+		trimmed := strings.TrimSpace(lines[i])
+		if strings.HasPrefix(trimmed, "{|") {
+			depth++
+		} else if strings.HasPrefix(trimmed, "|}") {
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return len(lines) - 1
+}
+
+// convertTable converts a {| ... |} table to a GFM table. GFM tables have
+// exactly one header row and no spanning cells: later header cells become
+// ordinary cells, and spans and nested tables are flattened and reported.
+func (c *conversion) convertTable(lines []string) string {
+	var rows [][]string
+	var header []bool
+	var caption string
+	var row []string
+	rowIsHeader := false
+	endRow := func() {
+		if len(row) > 0 {
+			rows = append(rows, row)
+			header = append(header, rowIsHeader)
+		}
+		row, rowIsHeader = nil, false
+	}
+
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case i == 0 || strings.HasPrefix(trimmed, "|}"):
+			if i > 0 && i < len(lines)-1 {
+				c.issue("nested table flattened")
+			}
+		case strings.HasPrefix(trimmed, "{|"):
+			c.issue("nested table flattened")
+		case strings.HasPrefix(trimmed, "|+"):
+			caption = c.convertInline(cellContent(c, trimmed[2:]))
+		case strings.HasPrefix(trimmed, "|-"):
+			endRow()
+		case strings.HasPrefix(trimmed, "!"):
+			rowIsHeader = len(row) == 0 || rowIsHeader
+			for _, cell := range splitCells(trimmed[1:], "!!", "||") {
+				row = append(row, c.convertInline(cellContent(c, cell)))
+			}
+		case strings.HasPrefix(trimmed, "|"):
+			for _, cell := range splitCells(trimmed[1:], "||") {
+				row = append(row, c.convertInline(cellContent(c, cell)))
+			}
+		case trimmed != "" && len(row) > 0:
+			// Continuation of the previous cell.
+			row[len(row)-1] += "<br>" + c.convertInline(trimmed)
+		}
+	}
+	endRow()
+
+	if len(rows) == 0 {
+		return ""
+	}
+	columns := 0
+	for _, r := range rows {
+		columns = max(columns, len(r))
+	}
+
+	var sb strings.Builder
+	if caption != "" {
+		sb.WriteString("**" + caption + "**\n\n")
+	}
+	writeRow := func(cells []string) {
+		sb.WriteString("|")
+		for i := 0; i < columns; i++ {
+			cell := ""
+			if i < len(cells) {
+				cell = strings.ReplaceAll(cells[i], "|", `\|`)
+			}
+			sb.WriteString(" " + cell + " |")
+		}
+		sb.WriteString("\n")
+	}
+
+	body := rows
+	if header[0] {
+		writeRow(rows[0])
+		body = rows[1:]
+	} else {
+		writeRow(make([]string, columns))
+	}
+	sb.WriteString("|" + strings.Repeat(" --- |", columns) + "\n")
+	for _, r := range body {
+		writeRow(r)
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// splitCells splits a table line on any of the separators, ignoring ones
+// inside [[links]].
+func splitCells(s string, separators ...string) []string {
+	var cells []string
+	depth := 0
+	last := 0
+	for i := 0; i < len(s); i++ {
+		switch {
+		case strings.HasPrefix(s[i:], "[["):
+			depth++
+			i++
+			continue
+		case strings.HasPrefix(s[i:], "]]"):
+			depth--
+			i++
+			continue
+		}
+		if depth > 0 {
+			continue
+		}
+		for _, sep := range separators {
+			if strings.HasPrefix(s[i:], sep) {
+				cells = append(cells, s[last:i])
+				last = i + len(sep)
+				i += len(sep) - 1
+				break
+			}
+		}
+	}
+	return append(cells, s[last:])
+}
+
+// cellContent drops a cell's attributes ("style=... | text"), reporting
+// spans, which GFM tables cannot express.
+func cellContent(c *conversion, cell string) string {
+	depth := 0
+	for i := 0; i < len(cell); i++ {
+		switch {
+		case strings.HasPrefix(cell[i:], "[["):
+			depth++
+			i++
+		case strings.HasPrefix(cell[i:], "]]"):
+			depth--
+			i++
+		case cell[i] == '|' && depth == 0:
+			attrs := strings.ToLower(cell[:i])
+			if strings.Contains(attrs, "colspan") || strings.Contains(attrs, "rowspan") {
+				c.issue("table cell span dropped")
+			}
+			return strings.TrimSpace(cell[i+1:])
+		}
+	}
+	return strings.TrimSpace(cell)
+}
+
+func (c *conversion) convertInline(s string) string {
+	s = boldItalicPattern.ReplaceAllString(s, "***$1***")
+	s = boldPattern.ReplaceAllString(s, "**$1**")
+	s = italicPattern.ReplaceAllString(s, "*$1*")
+
+	s = extLinkPattern.ReplaceAllStringFunc(s, func(m string) string {
+		parts := extLinkPattern.FindStringSubmatch(m)
+		if parts[2] == "" {
+			return "<" + parts[1] + ">"
+		}
+		return c.hold("[" + strings.TrimSpace(parts[2]) + "](" + parts[1] + ")")
+	})
+
+	return linkPattern.ReplaceAllStringFunc(s, func(m string) string {
+		parts := linkPattern.FindStringSubmatch(m)
+		target := strings.TrimPrefix(strings.TrimSpace(parts[1]), ":")
+		text := parts[2]
+		if text == "" {
+			text = target
+		}
+		text += parts[3]
+
+		page, section, _ := strings.Cut(target, "#")
+		href := ""
+		if page != "" {
+			if href = c.LinkTarget(page); href == "" {
+				c.issue("link to missing page %q kept as text", page)
+				return text
+			}
+		}
+		if section != "" {
+			href += "#" + strings.ToLower(strings.ReplaceAll(strings.TrimSpace(section), " ", "-"))
+		}
+		return c.hold("[" + text + "](" + href + ")")
+	})
+}
+
+var blankLines = regexp.MustCompile(`\n{3,}`)
+
+func collapseBlankLines(s string) string {
+	return blankLines.ReplaceAllString(s, "\n\n")
+}
@@ -0,0 +1,176 @@
+package mediawiki
+
+import (
+	"reflect"
+	"testing"
+)
+
+var testConverter = &Converter{
+	LinkTarget: func(title string) string {
+		switch title {
+		case "Octagon":
+			return "/articles/octagon"
+		case "Garnet Hostel":
+			return "/articles/garnet-hostel"
+		}
+		return ""
+	},
+}
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		name       string
+		wikitext   string
+		markdown   string
+		categories []string
+		issues     []string
+	}{
+		{
+			name:     "headings",
+			wikitext: "== History ==\ntext\n=== Early ''days'' ===",
+			markdown: "## History\n\ntext\n\n### Early *days*\n",
+		},
+		{
+			name:     "unbalanced heading uses the shorter side",
+			wikitext: "=== Title ==",
+			markdown: "## Title\n",
+		},
+		{
+			name:     "bold and italic",
+			wikitext: "'''bold''', ''italic'' and '''''both'''''",
+			markdown: "**bold**, *italic* and ***both***\n",
+		},
+		{
+			name:     "internal links",
+			wikitext: "See [[Octagon]], [[Garnet Hostel|the hostel]] and [[octagon]]s.",
+			markdown: "See [Octagon](/articles/octagon), [the hostel](/articles/garnet-hostel) and octagons.\n",
+			issues:   []string{`link to missing page "octagon" kept as text`},
+		},
+		{
+			name:     "link trail and section",
+			wikitext: "[[Octagon#Floor Plan|floor]]s and [[#Notes]]",
+			markdown: "[floors](/articles/octagon#floor-plan) and [#Notes](#notes)\n",
+		},
+		{
+			name:     "missing pages keep their text",
+			wikitext: "[[Nowhere|somewhere]] and [[Nowhere]]",
+			markdown: "somewhere and Nowhere\n",
+			issues:   []string{`link to missing page "Nowhere" kept as text (x2)`},
+		},
+		{
+			name:     "external links",
+			wikitext: "[https://nitt.edu NIT Trichy] and [https://example.org]",
+			markdown: "[NIT Trichy](https://nitt.edu) and <https://example.org>\n",
+		},
+		{
+			name:     "lists",
+			wikitext: "* one\n** nested\n# first\n#* mixed",
+			markdown: "- one\n  - nested\n1. first\n   - mixed\n",
+		},
+		{
+			name:     "definitions and indents",
+			wikitext: "; Term : definition\n; Alone\n:: indented",
+			markdown: "**Term**: definition\n**Alone**\n  > > indented\n",
+		},
+		{
+			name:     "horizontal rule",
+			wikitext: "above\n----\nbelow",
+			markdown: "above\n\n---\n\nbelow\n",
+		},
+		{
+			name:     "preformatted lines",
+			wikitext: "text\n code line\n  more\nafter",
+			markdown: "text\n\n```\ncode line\n more\n```\n\nafter\n",
+		},
+		{
+			name:     "source and pre blocks are left alone",
+			wikitext: "<syntaxhighlight lang=\"go\">\nx := '''y'''\n</syntaxhighlight>\n<pre>[[Octagon]]</pre>",
+			markdown: "```go\nx := '''y'''\n```\n\n```\n[[Octagon]]\n```\n",
+		},
+		{
+			name:     "nowiki is escaped",
+			wikitext: "<nowiki>[[Octagon]] and *stars*</nowiki>",
+			markdown: "\\[\\[Octagon\\]\\] and \\*stars\\*\n",
+		},
+		{
+			name:     "nowiki inside a link",
+			wikitext: "[[Octagon|<nowiki>*</nowiki>]]",
+			markdown: "[\\*](/articles/octagon)\n",
+		},
+		{
+			name:     "comments and magic words",
+			wikitext: "a<!-- hidden\n-->b __NOTOC__",
+			markdown: "ab\n",
+		},
+		{
+			name:     "templates",
+			wikitext: "{{Infobox|name={{PAGENAME}}}}Text{{#if:x|y}} {{cite web|url=x}}",
+			markdown: "Text\n",
+			issues:   []string{"template {{#if}} removed", "template {{Infobox}} removed", "template {{cite web}} removed"},
+		},
+		{
+			name:     "unclosed template is kept",
+			wikitext: "a {{broken",
+			markdown: "a {{broken\n",
+		},
+		{
+			name:     "references",
+			wikitext: "Fact.<ref name=\"a\">Source</ref> Again.<ref name=\"a\"/> More.<ref>x</ref>\n== Notes ==\n<references/>\n<references>\n<ref name=\"b\">y</ref>\n</references>",
+			markdown: "Fact. Again. More.\n\n## Notes\n",
+			issues:   []string{"footnote <ref> removed (x4)"},
+		},
+		{
+			name:       "categories and files",
+			wikitext:   "[[File:Map.png|thumb|The map]]Text\n[[Category:Hostels]]\n[[category: Campus Life|sort key]]",
+			markdown:   "Text\n",
+			categories: []string{"Hostels", "Campus Life"},
+			issues:     []string{"file Map.png not imported"},
+		},
+		{
+			name:     "table with header and caption",
+			wikitext: "{|\n|+ Hostels\n! Name !! Rooms\n|-\n| [[Garnet Hostel|Garnet]] || 200\n|-\n| Agate\n| 150\n|}",
+			markdown: "**Hostels**\n\n| Name | Rooms |\n| --- | --- |\n| [Garnet](/articles/garnet-hostel) | 200 |\n| Agate | 150 |\n",
+		},
+		{
+			name:     "table without header",
+			wikitext: "{| class=\"wikitable\"\n| a || b\n|}",
+			markdown: "|  |  |\n| --- | --- |\n| a | b |\n",
+		},
+		{
+			name:     "table cell attributes and spans",
+			wikitext: "{|\n! colspan=\"2\" | Both\n|-\n| style=\"color:red\" | a | b\n| c\n|}",
+			markdown: "| Both |  |\n| --- | --- |\n| a \\| b | c |\n",
+			issues:   []string{"table cell span dropped"},
+		},
+		{
+			name:     "nested tables are flattened",
+			wikitext: "{|\n| outer\n{|\n| inner\n|}\n|}",
+			markdown: "|  |  |\n| --- | --- |\n| outer | inner |\n",
+			issues:   []string{"nested table flattened (x2)"},
+		},
+		{
+			name:     "NUL bytes cannot forge placeholders",
+			wikitext: "a\x000\x00b \x0099\x00 [[Octagon]]",
+			markdown: "a0b 99 [Octagon](/articles/octagon)\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := testConverter.Convert(tt.wikitext)
+			if got.Markdown != tt.markdown {
+				t.Errorf("markdown:\ngot  %q\nwant %q", got.Markdown, tt.markdown)
+			}
+			if !reflect.DeepEqual(got.Categories, tt.categories) {
+				t.Errorf("categories = %q, want %q", got.Categories, tt.categories)
+			}
+			issues := tt.issues
+			if issues == nil {
+				issues = []string{}
+			}
+			if !reflect.DeepEqual(got.Issues, issues) {
+				t.Errorf("issues = %q, want %q", got.Issues, issues)
+			}
+		})
+	}
+}
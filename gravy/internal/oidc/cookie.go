@@ -0,0 +1,72 @@
+package oidc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// cookieName is the cookie holding the browser's half of a pending login.
+// Begin sets it and Complete requires it, so a state can only be completed
+// in the browser that started the login: an attacker cannot sign a victim
+// in to the attacker's account by sending them a callback link.
+const cookieName = "oidc_login"
+
+var errNoCookies = errors.New("OpenID Connect login needs a browser request")
+
+type exchangeCtxKey struct{}
+
+type exchange struct {
+	w      http.ResponseWriter
+	r      *http.Request
+	secure bool
+}
+
+// Middleware lets Begin and Complete read and set the login cookie. With
+// secure set the cookie is Secure and SameSite=None, so it also reaches an
+// API served from another site than the frontend; the browser must send
+// both calls with credentials included.
+func Middleware(secure bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), exchangeCtxKey{}, &exchange{w: w, r: r, secure: secure})
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+func exchangeFromContext(ctx context.Context) (*exchange, error) {
+	ex, ok := ctx.Value(exchangeCtxKey{}).(*exchange)
+	if !ok {
+		return nil, errNoCookies
+	}
+	return ex, nil
+}
+
+func (ex *exchange) cookie() string {
+	c, err := ex.r.Cookie(cookieName)
+	if err != nil {
+		return ""
+	}
+	return c.Value
+}
+
+// setCookie sets the login cookie, or clears it if value is empty.
+func (ex *exchange) setCookie(value string) {
+	c := &http.Cookie{
+		Name:     cookieName,
+		Value:    value,
+		Path:     "/",
+		MaxAge:   int(loginTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   ex.secure,
+		SameSite: http.SameSiteLaxMode,
+	}
+	if ex.secure {
+		c.SameSite = http.SameSiteNoneMode
+	}
+	if value == "" {
+		c.MaxAge = -1
+	}
+	http.SetCookie(ex.w, c)
+}
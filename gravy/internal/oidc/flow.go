@@ -0,0 +1,150 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// loginTTL is how long a user has to finish signing in at the provider.
+const loginTTL = 10 * time.Minute
+
+var ErrInvalidState = errors.New("login expired or was already completed, please try again")
+
+// Service runs the authorization code flow with PKCE. The code verifier and
+// nonce never leave the server: the browser only carries the state and the
+// login cookie it is bound to.
+type Service struct {
+	provider *Provider
+	repo     Repository
+}
+
+func NewService(provider *Provider, repo Repository) *Service {
+	return &Service{provider: provider, repo: repo}
+}
+
+func (s *Service) Config() Config {
+	return s.provider.Config()
+}
+
+// Begin starts a login and returns the provider URL to send the user to,
+// and the state the provider will hand back. It sets the login cookie the
+// state is bound to.
+func (s *Service) Begin(ctx context.Context) (string, string, error) {
+	ex, err := exchangeFromContext(ctx)
+	if err != nil {
+		return "", "", err
+	}
+	state, err := randomToken()
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := randomToken()
+	if err != nil {
+		return "", "", err
+	}
+	verifier, err := randomToken()
+	if err != nil {
+		return "", "", err
+	}
+	browser, err := randomToken()
+	if err != nil {
+		return "", "", err
+	}
+
+	challenge := sha256.Sum256([]byte(verifier))
+	authURL, err := s.provider.AuthCodeURL(ctx, state, nonce, base64.RawURLEncoding.EncodeToString(challenge[:]))
+	if err != nil {
+		return "", "", err
+	}
+
+	now := time.Now()
+	err = s.repo.SavePending(ctx, PendingLogin{
+		StateHash:    hashState(state),
+		BrowserHash:  hashState(browser),
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		CreatedAt:    now,
+		ExpiresAt:    now.Add(loginTTL),
+	})
+	if err != nil {
+		return "", "", err
+	}
+	ex.setCookie(browser)
+	return authURL, state, nil
+}
+
+// Complete redeems the code the provider returned for a login started with
+// Begin in the same browser and returns the verified claims.
+func (s *Service) Complete(ctx context.Context, code, state string) (*Claims, error) {
+	ex, err := exchangeFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	browser := ex.cookie()
+	if code == "" || state == "" || browser == "" {
+		return nil, ErrInvalidState
+	}
+	login, err := s.repo.TakePending(ctx, hashState(state), hashState(browser))
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrInvalidState
+	}
+	if err != nil {
+		return nil, err
+	}
+	ex.setCookie("")
+	return s.provider.Exchange(ctx, code, login.CodeVerifier, login.Nonce)
+}
+
+// Identity returns the identity the claims belong to, or nil if it has not
+// been linked to a user yet.
+func (s *Service) Identity(ctx context.Context, claims *Claims) (*Identity, error) {
+	identity, err := s.repo.GetIdentity(ctx, claims.Issuer, claims.Subject)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.TouchIdentity(ctx, identity.ID, claims.Email); err != nil {
+		return nil, err
+	}
+	return identity, nil
+}
+
+// Link links the claims' identity to a user.
+func (s *Service) Link(ctx context.Context, userID string, claims *Claims) error {
+	now := time.Now()
+	return s.repo.LinkIdentity(ctx, &Identity{
+		UserID:      userID,
+		Issuer:      claims.Issuer,
+		Subject:     claims.Subject,
+		Email:       claims.Email,
+		CreatedAt:   now,
+		LastLoginAt: now,
+	})
+}
+
+// Identities returns the identities linked to a user.
+func (s *Service) Identities(ctx context.Context, userID string) ([]*Identity, error) {
+	return s.repo.ListIdentities(ctx, userID)
+}
+
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashState(state string) string {
+	sum := sha256.Sum256([]byte(state))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,138 @@
+package oidc
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// PendingLogin is a login that was started but not yet completed. It is
+// stored under the hash of its state so a leaked database dump cannot be
+// used to complete logins in progress. BrowserHash is the hash of the login
+// cookie set in the browser that started it.
+type PendingLogin struct {
+	StateHash    string    `bson:"_id"`
+	BrowserHash  string    `bson:"browserHash"`
+	Nonce        string    `bson:"nonce"`
+	CodeVerifier string    `bson:"codeVerifier"`
+	CreatedAt    time.Time `bson:"createdAt"`
+	ExpiresAt    time.Time `bson:"expiresAt"`
+}
+
+// Identity links an account at the OpenID Connect provider to a user.
+type Identity struct {
+	ID          string    `bson:"_id,omitempty"`
+	UserID      string    `bson:"userId"`
+	Issuer      string    `bson:"issuer"`
+	Subject     string    `bson:"subject"`
+	Email       string    `bson:"email"`
+	CreatedAt   time.Time `bson:"createdAt"`
+	LastLoginAt time.Time `bson:"lastLoginAt"`
+}
+
+type Repository interface {
+	SavePending(ctx context.Context, login PendingLogin) error
+	TakePending(ctx context.Context, stateHash, browserHash string) (*PendingLogin, error)
+	GetIdentity(ctx context.Context, issuer, subject string) (*Identity, error)
+	LinkIdentity(ctx context.Context, identity *Identity) error
+	TouchIdentity(ctx context.Context, id, email string) error
+	ListIdentities(ctx context.Context, userID string) ([]*Identity, error)
+	EnsureIndexes(ctx context.Context) error
+}
+
+type repository struct {
+	pending    *mongo.Collection
+	identities *mongo.Collection
+}
+
+func NewRepository(db *mongo.Database) Repository {
+	return &repository{
+		pending:    db.Collection("oidc_logins"),
+		identities: db.Collection("identities"),
+	}
+}
+
+func (r *repository) SavePending(ctx context.Context, login PendingLogin) error {
+	_, err := r.pending.InsertOne(ctx, login)
+	return err
+}
+
+// TakePending returns a pending login and deletes it, so each state can
+// only be completed once. It returns mongo.ErrNoDocuments if there is no
+// unexpired login for the state started in the browser. A state presented
+// with another browser's cookie is left for its own browser to complete.
+func (r *repository) TakePending(ctx context.Context, stateHash, browserHash string) (*PendingLogin, error) {
+	var login PendingLogin
+	filter := bson.M{"_id": stateHash, "browserHash": browserHash, "expiresAt": bson.M{"$gt": time.Now()}}
+	if err := r.pending.FindOneAndDelete(ctx, filter).Decode(&login); err != nil {
+		return nil, err
+	}
+	return &login, nil
+}
+
+func (r *repository) GetIdentity(ctx context.Context, issuer, subject string) (*Identity, error) {
+	var identity Identity
+	err := r.identities.FindOne(ctx, bson.M{"issuer": issuer, "subject": subject}).Decode(&identity)
+	if err != nil {
+		return nil, err
+	}
+	return &identity, nil
+}
+
+func (r *repository) LinkIdentity(ctx context.Context, identity *Identity) error {
+	res, err := r.identities.InsertOne(ctx, identity)
+	if err != nil {
+		return err
+	}
+	if oid, ok := res.InsertedID.(bson.ObjectID); ok {
+		identity.ID = oid.Hex()
+	}
+	return nil
+}
+
+// TouchIdentity records a login, along with the address the provider now
+// reports.
+func (r *repository) TouchIdentity(ctx context.Context, id, email string) error {
+	oid, err := bson.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	_, err = r.identities.UpdateOne(ctx, bson.M{"_id": oid}, bson.M{"$set": bson.M{
+		"email":       email,
+		"lastLoginAt": time.Now(),
+	}})
+	return err
+}
+
+func (r *repository) ListIdentities(ctx context.Context, userID string) ([]*Identity, error) {
+	cursor, err := r.identities.Find(ctx, bson.M{"userId": userID}, options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	var list []*Identity
+	if err := cursor.All(ctx, &list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (r *repository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.identities.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "issuer", Value: 1}, {Key: "subject", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{Keys: bson.D{{Key: "userId", Value: 1}}},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = r.pending.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	return err
+}
@@ -0,0 +1,108 @@
+package preview
+
+import (
+	"encoding/json"
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/pranava-mohan/wikinitt/gravy/internal/auth"
+)
+
+var pageTemplate = template.Must(template.New("preview").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<link rel="canonical" href="{{.URL}}">
+<meta name="description" content="{{.Description}}">
+<meta property="og:site_name" content="WikiNITT">
+<meta property="og:type" content="{{if eq .Type "ARTICLE"}}article{{else}}website{{end}}">
+<meta property="og:title" content="{{.Title}}">
+<meta property="og:description" content="{{.Description}}">
+<meta property="og:url" content="{{.URL}}">
+{{if .Image}}<meta property="og:image" content="{{.Image}}">
+<meta name="twitter:card" content="summary_large_image">
+<meta name="twitter:image" content="{{.Image}}">
+{{else}}<meta name="twitter:card" content="summary">
+{{end}}<meta name="twitter:title" content="{{.Title}}">
+<meta name="twitter:description" content="{{.Description}}">
+<meta http-equiv="refresh" content="0; url={{.URL}}">
+</head>
+<body><a href="{{.URL}}">{{.Title}}</a></body>
+</html>
+`))
+
+type previewJSON struct {
+	Type        Type   `json:"type"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Image       string `json:"image,omitempty"`
+	URL         string `json:"url"`
+}
+
+// Handler serves /og/wiki/{slug} and /og/post/{id}. Crawlers get an HTML
+// page of Open Graph tags that redirects people to the canonical URL;
+// requests that accept application/json get the preview as JSON. It expects
+// auth.Middleware to have run, so members can preview private posts.
+func (s *Service) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		viewerID := ""
+		if user := auth.ForContext(r.Context()); user != nil {
+			viewerID = user.ID
+		}
+
+		var p *Preview
+		var err error
+		switch kind, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/og/"), "/"); {
+		case key == "" || strings.Contains(key, "/"):
+			err = ErrNotFound
+		case kind == "wiki":
+			p, err = s.Article(r.Context(), key)
+		case kind == "post":
+			p, err = s.Post(r.Context(), key, viewerID)
+		default:
+			err = ErrNotFound
+		}
+		if errors.Is(err, ErrNotFound) {
+			http.NotFound(w, r)
+			return
+		}
+		if err != nil {
+			log.Printf("Failed to build preview for %s: %v", r.URL.Path, err)
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		if p.Public {
+			w.Header().Set("Cache-Control", "public, max-age=300")
+		} else {
+			w.Header().Set("Cache-Control", "private, no-store")
+		}
+		w.Header().Set("Vary", "Accept, Authorization")
+
+		if strings.Contains(r.Header.Get("Accept"), "application/json") {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(previewJSON{
+				Type:        p.Type,
+				Title:       p.Title,
+				Description: p.Description,
+				Image:       p.Image,
+				URL:         p.URL,
+			})
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := pageTemplate.Execute(w, p); err != nil {
+			log.Printf("Failed to render preview for %s: %v", r.URL.Path, err)
+		}
+	})
+}
@@ -0,0 +1,214 @@
+// Package preview builds the title, description, image and canonical URL
+// that chat apps and search engines show for links to wiki content.
+package preview
+
+import (
+	"context"
+	"errors"
+	"html"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/pranava-mohan/wikinitt/gravy/internal/articles"
+	"github.com/pranava-mohan/wikinitt/gravy/internal/community"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// ErrNotFound is returned for content that does not exist or that the
+// viewer may not see. The two are not distinguished, so previews do not
+// reveal that private content exists.
+var ErrNotFound = errors.New("preview not found")
+
+type Type string
+
+const (
+	TypeArticle Type = "ARTICLE"
+	TypePost    Type = "POST"
+	TypeGroup   Type = "GROUP"
+)
+
+type Preview struct {
+	Type        Type
+	Title       string
+	Description string
+	Image       string
+	URL         string
+	// Public is false for previews of private group content, which must
+	// not be cached by shared caches.
+	Public bool
+}
+
+type Service struct {
+	articles    articles.Repository
+	community   community.Repository
+	frontendURL string
+}
+
+// NewService creates a Service. Canonical URLs point at FRONTEND_URL.
+func NewService(articleRepo articles.Repository, communityRepo community.Repository) *Service {
+	frontendURL := os.Getenv("FRONTEND_URL")
+	if frontendURL == "" {
+		frontendURL = "https://wikinitt.netlify.app"
+	}
+	return &Service{
+		articles:    articleRepo,
+		community:   communityRepo,
+		frontendURL: strings.TrimRight(frontendURL, "/"),
+	}
+}
+
+// Article previews a published article. Old slugs of renamed or merged
+// articles preview the article they redirect to.
+func (s *Service) Article(ctx context.Context, slug string) (*Preview, error) {
+	article, err := s.articles.GetBySlug(ctx, slug)
+	if err == mongo.ErrNoDocuments {
+		article, err = s.articles.ResolveRedirect(ctx, slug)
+	}
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !article.IsPublished() {
+		return nil, ErrNotFound
+	}
+
+	rendered := articles.Render(article.Content)
+	image := article.Thumbnail
+	if image == "" {
+		image = firstImage(rendered.HTML)
+	}
+	return &Preview{
+		Type:        TypeArticle,
+		Title:       article.Title,
+		Description: rendered.Excerpt,
+		Image:       image,
+		URL:         s.frontendURL + "/articles/" + article.Slug,
+		Public:      true,
+	}, nil
+}
+
+// Post previews a community post. Posts in private groups are only
+// previewed for members; viewerID is "" for anonymous requests.
+func (s *Service) Post(ctx context.Context, id string, viewerID string) (*Preview, error) {
+	if _, err := bson.ObjectIDFromHex(id); err != nil {
+		return nil, ErrNotFound
+	}
+	post, err := s.community.GetPost(ctx, id)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	group, err := s.community.GetGroupByID(ctx, post.GroupID)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	if err := s.checkVisible(ctx, group, viewerID); err != nil {
+		return nil, err
+	}
+
+	rendered := articles.Render(post.Content)
+	image := firstImage(rendered.HTML)
+	if image == "" {
+		image = group.Icon
+	}
+	return &Preview{
+		Type:        TypePost,
+		Title:       post.Title,
+		Description: rendered.Excerpt,
+		Image:       image,
+		URL:         s.frontendURL + "/c/" + group.Slug + "/posts/" + post.ID,
+		Public:      group.Type == community.GroupTypePublic,
+	}, nil
+}
+
+// Group previews a group, with the same visibility rules as Post.
+func (s *Service) Group(ctx context.Context, slug string, viewerID string) (*Preview, error) {
+	group, err := s.community.GetGroup(ctx, slug)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkVisible(ctx, group, viewerID); err != nil {
+		return nil, err
+	}
+	return &Preview{
+		Type:        TypeGroup,
+		Title:       group.Name,
+		Description: group.Description,
+		Image:       group.Icon,
+		URL:         s.frontendURL + "/c/" + group.Slug,
+		Public:      group.Type == community.GroupTypePublic,
+	}, nil
+}
+
+func (s *Service) checkVisible(ctx context.Context, group *community.Group, viewerID string) error {
+	if group.Type != community.GroupTypePrivate {
+		return nil
+	}
+	if viewerID == "" {
+		return ErrNotFound
+	}
+	isMember, err := s.community.IsMember(ctx, group.ID, viewerID)
+	if err != nil {
+		return err
+	}
+	if !isMember {
+		return ErrNotFound
+	}
+	return nil
+}
+
+var (
+	articlePath = regexp.MustCompile(`^/(?:articles|wiki)/([^/]+)/?$`)
+	postPath    = regexp.MustCompile(`^/c/[^/]+/posts/([0-9a-fA-F]{24})/?$`)
+	groupPath   = regexp.MustCompile(`^/c/([^/]+)/?$`)
+)
+
+// ForURL previews a link to the frontend, given as an absolute URL or a
+// path. Links to other sites are not fetched and return ErrNotFound.
+func (s *Service) ForURL(ctx context.Context, rawURL string, viewerID string) (*Preview, error) {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	if u.Host != "" {
+		frontend, err := url.Parse(s.frontendURL)
+		if err != nil || !strings.EqualFold(u.Host, frontend.Host) {
+			return nil, ErrNotFound
+		}
+	}
+
+	switch {
+	case articlePath.MatchString(u.Path):
+		return s.Article(ctx, articlePath.FindStringSubmatch(u.Path)[1])
+	case postPath.MatchString(u.Path):
+		return s.Post(ctx, postPath.FindStringSubmatch(u.Path)[1], viewerID)
+	case groupPath.MatchString(u.Path):
+		slug := groupPath.FindStringSubmatch(u.Path)[1]
+		// /c/all and /c/create are pages, not groups.
+		if slug == "all" || slug == "create" {
+			return nil, ErrNotFound
+		}
+		return s.Group(ctx, slug, viewerID)
+	}
+	return nil, ErrNotFound
+}
+
+var imagePattern = regexp.MustCompile(`<img[^>]+src="([^"]+)"`)
+
+// firstImage returns the source of the first image in rendered HTML.
+func firstImage(rendered string) string {
+	if m := imagePattern.FindStringSubmatch(rendered); m != nil {
+		return html.UnescapeString(m[1])
+	}
+	return ""
+}
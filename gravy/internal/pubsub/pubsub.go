@@ -0,0 +1,72 @@
+package pubsub
+
+import (
+	"context"
+	"log"
+	"sync"
+)
+
+// subscriberBuffer is how many undelivered payloads a slow subscriber may
+// hold before new ones are dropped for it.
+const subscriberBuffer = 32
+
+// Broker fans out payloads published on a topic to every live subscriber of
+// that topic. Subscriptions end when the context passed to Subscribe is done,
+// at which point the returned channel is closed.
+type Broker interface {
+	Publish(ctx context.Context, topic string, payload []byte) error
+	Subscribe(ctx context.Context, topic string) (<-chan []byte, error)
+}
+
+// MemoryBroker delivers payloads within a single process. It is enough when
+// only one server replica is running.
+type MemoryBroker struct {
+	mu     sync.RWMutex
+	topics map[string]map[chan []byte]struct{}
+}
+
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{
+		topics: make(map[string]map[chan []byte]struct{}),
+	}
+}
+
+func (b *MemoryBroker) Publish(ctx context.Context, topic string, payload []byte) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for ch := range b.topics[topic] {
+		select {
+		case ch <- payload:
+		default:
+			log.Printf("pubsub: dropping message on %s for slow subscriber", topic)
+		}
+	}
+	return nil
+}
+
+func (b *MemoryBroker) Subscribe(ctx context.Context, topic string) (<-chan []byte, error) {
+	ch := make(chan []byte, subscriberBuffer)
+
+	b.mu.Lock()
+	if b.topics[topic] == nil {
+		b.topics[topic] = make(map[chan []byte]struct{})
+	}
+	b.topics[topic][ch] = struct{}{}
+	b.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+
+		b.mu.Lock()
+		delete(b.topics[topic], ch)
+		if len(b.topics[topic]) == 0 {
+			delete(b.topics, topic)
+		}
+		b.mu.Unlock()
+
+		close(ch)
+	}()
+
+	return ch, nil
+}
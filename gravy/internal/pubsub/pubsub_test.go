@@ -0,0 +1,113 @@
+package pubsub
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func receive(t *testing.T, ch <-chan []byte) (string, bool) {
+	t.Helper()
+	select {
+	case payload, ok := <-ch:
+		return string(payload), ok
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for a payload")
+		return "", false
+	}
+}
+
+func TestMemoryBrokerFanOut(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	b := NewMemoryBroker()
+
+	first, _ := b.Subscribe(ctx, "channel:1")
+	second, _ := b.Subscribe(ctx, "channel:1")
+	other, _ := b.Subscribe(ctx, "channel:2")
+
+	if err := b.Publish(ctx, "channel:1", []byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Publish(ctx, "channel:1", []byte("again")); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, ch := range map[string]<-chan []byte{"first": first, "second": second} {
+		for _, want := range []string{"hello", "again"} {
+			if got, _ := receive(t, ch); got != want {
+				t.Errorf("%s subscriber got %q, want %q", name, got, want)
+			}
+		}
+	}
+	select {
+	case payload := <-other:
+		t.Errorf("subscriber of another topic got %q", payload)
+	default:
+	}
+}
+
+func TestMemoryBrokerUnsubscribesOnCancel(t *testing.T) {
+	b := NewMemoryBroker()
+	ctx, cancel := context.WithCancel(context.Background())
+	ch, _ := b.Subscribe(ctx, "channel:1")
+	stay, _ := b.Subscribe(context.Background(), "channel:1")
+
+	cancel()
+	if _, ok := receive(t, ch); ok {
+		t.Fatal("channel still open after its context was cancelled")
+	}
+
+	b.mu.RLock()
+	n := len(b.topics["channel:1"])
+	b.mu.RUnlock()
+	if n != 1 {
+		t.Errorf("topic has %d subscribers, want 1", n)
+	}
+
+	// Publishing after the cancel must neither panic on the closed channel
+	// nor stop the remaining subscriber from receiving.
+	if err := b.Publish(context.Background(), "channel:1", []byte("after")); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := receive(t, stay); got != "after" {
+		t.Errorf("remaining subscriber got %q, want %q", got, "after")
+	}
+}
+
+func TestMemoryBrokerRemovesEmptyTopics(t *testing.T) {
+	b := NewMemoryBroker()
+	ctx, cancel := context.WithCancel(context.Background())
+	ch, _ := b.Subscribe(ctx, "channel:1")
+	cancel()
+	receive(t, ch)
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if _, ok := b.topics["channel:1"]; ok {
+		t.Error("topic without subscribers was kept")
+	}
+}
+
+func TestMemoryBrokerSlowSubscriber(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	b := NewMemoryBroker()
+	slow, _ := b.Subscribe(ctx, "channel:1")
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < subscriberBuffer+10; i++ {
+			b.Publish(ctx, "channel:1", []byte("x"))
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Publish blocked on a subscriber that does not read")
+	}
+	if len(slow) != subscriberBuffer {
+		t.Errorf("slow subscriber holds %d payloads, want %d", len(slow), subscriberBuffer)
+	}
+}
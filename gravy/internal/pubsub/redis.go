@@ -0,0 +1,65 @@
+package pubsub
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisBroker relays payloads through Redis PUBLISH/SUBSCRIBE so that
+// subscribers connected to any server replica receive them.
+type RedisBroker struct {
+	rdb *redis.Client
+}
+
+func NewRedisBroker(addr string, password string) *RedisBroker {
+	rdb := redis.NewClient(&redis.Options{
+		Addr:     addr,
+		Password: password,
+		DB:       0,
+	})
+
+	return &RedisBroker{rdb: rdb}
+}
+
+func (b *RedisBroker) Publish(ctx context.Context, topic string, payload []byte) error {
+	return b.rdb.Publish(ctx, topic, payload).Err()
+}
+
+func (b *RedisBroker) Subscribe(ctx context.Context, topic string) (<-chan []byte, error) {
+	ps := b.rdb.Subscribe(ctx, topic)
+
+	// Wait for the subscription to be confirmed so messages published right
+	// after Subscribe returns are not missed.
+	if _, err := ps.Receive(ctx); err != nil {
+		ps.Close()
+		return nil, fmt.Errorf("failed to subscribe to %s: %w", topic, err)
+	}
+
+	out := make(chan []byte, subscriberBuffer)
+	go func() {
+		defer close(out)
+		defer ps.Close()
+
+		msgs := ps.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-msgs:
+				if !ok {
+					return
+				}
+				select {
+				case out <- []byte(msg.Payload):
+				default:
+					log.Printf("pubsub: dropping message on %s for slow subscriber", topic)
+				}
+			}
+		}
+	}()
+
+	return out, nil
+}
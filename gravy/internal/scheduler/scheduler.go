@@ -0,0 +1,139 @@
+package scheduler
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/pranava-mohan/wikinitt/gravy/internal/articles"
+	"github.com/pranava-mohan/wikinitt/gravy/internal/backlinks"
+	"github.com/pranava-mohan/wikinitt/gravy/internal/lease"
+	"github.com/pranava-mohan/wikinitt/gravy/internal/rag"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const batchSize = 50
+
+// locker is the part of *lease.Lease the scheduler uses.
+type locker interface {
+	Acquire(ctx context.Context) (bool, error)
+	Release(ctx context.Context) error
+}
+
+// backlinkQueue is the part of *backlinks.Runner the scheduler uses.
+type backlinkQueue interface {
+	Enqueue(ctx context.Context, a *articles.Article) (*backlinks.Job, error)
+}
+
+// ArticleScheduler publishes SCHEDULED articles once their publishAt passes
+// and archives published articles once their expireAt passes. Every replica
+// may run one; the lease makes sure only one of them does work per tick.
+type ArticleScheduler struct {
+	repo      articles.Repository
+	backlinks backlinkQueue
+	ragClient rag.Client
+	lease     locker
+	interval  time.Duration
+}
+
+func NewArticleScheduler(repo articles.Repository, backlinkRunner *backlinks.Runner, ragClient rag.Client, l *lease.Lease, interval time.Duration) *ArticleScheduler {
+	return &ArticleScheduler{
+		repo:      repo,
+		backlinks: backlinkRunner,
+		ragClient: ragClient,
+		lease:     l,
+		interval:  interval,
+	}
+}
+
+// Run ticks until ctx is cancelled.
+func (s *ArticleScheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.tick(ctx)
+
+		select {
+		case <-ctx.Done():
+			_ = s.lease.Release(context.Background())
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *ArticleScheduler) tick(ctx context.Context) {
+	held, err := s.lease.Acquire(ctx)
+	if err != nil {
+		log.Printf("scheduler: failed to acquire lease: %v", err)
+		return
+	}
+	if !held {
+		return
+	}
+
+	now := time.Now()
+	s.publishDue(ctx, now)
+	s.expireDue(ctx, now)
+}
+
+func (s *ArticleScheduler) publishDue(ctx context.Context, now time.Time) {
+	due, err := s.repo.ListScheduledDue(ctx, now, batchSize)
+	if err != nil {
+		log.Printf("scheduler: failed to list scheduled articles: %v", err)
+		return
+	}
+
+	for _, a := range due {
+		fields := bson.M{}
+		if a.PublishedAt == nil {
+			fields["publishedAt"] = now
+		}
+		published, err := s.repo.Transition(ctx, a.ID, []articles.Status{articles.StatusScheduled}, articles.StatusPublished, fields)
+		if err != nil {
+			// ErrInvalidTransition means an admin changed it in the meantime.
+			if err != articles.ErrInvalidTransition {
+				log.Printf("scheduler: failed to publish article %s: %v", a.ID, err)
+			}
+			continue
+		}
+
+		log.Printf("scheduler: published article %s", published.ID)
+		if _, err := s.backlinks.Enqueue(ctx, published); err != nil {
+			log.Printf("scheduler: failed to enqueue backlink job for article %s: %v", published.ID, err)
+		}
+		s.pushEvent(ctx, rag.ArticleToEvent(rag.EventTypeCreate, published))
+	}
+}
+
+func (s *ArticleScheduler) expireDue(ctx context.Context, now time.Time) {
+	due, err := s.repo.ListExpiredDue(ctx, now, batchSize)
+	if err != nil {
+		log.Printf("scheduler: failed to list expired articles: %v", err)
+		return
+	}
+
+	for _, a := range due {
+		// Clear the expiry so a later restore does not archive it again.
+		archived, err := s.repo.Transition(ctx, a.ID, []articles.Status{articles.StatusPublished}, articles.StatusArchived, bson.M{"expireAt": nil})
+		if err != nil {
+			if err != articles.ErrInvalidTransition {
+				log.Printf("scheduler: failed to archive article %s: %v", a.ID, err)
+			}
+			continue
+		}
+
+		log.Printf("scheduler: archived expired article %s", archived.ID)
+		s.pushEvent(ctx, rag.RagEvent{Type: rag.EventTypeDelete, ArticleID: archived.ID})
+	}
+}
+
+func (s *ArticleScheduler) pushEvent(ctx context.Context, event rag.RagEvent) {
+	if s.ragClient == nil {
+		return
+	}
+	if err := s.ragClient.PushEvent(ctx, event); err != nil {
+		log.Printf("scheduler: failed to push RAG %s event for article %s: %v", event.Type, event.ArticleID, err)
+	}
+}
@@ -0,0 +1,268 @@
+package scheduler
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pranava-mohan/wikinitt/gravy/internal/articles"
+	"github.com/pranava-mohan/wikinitt/gravy/internal/backlinks"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// memoryRepo holds articles in memory and implements what the scheduler
+// calls with the same filters as the Mongo repository.
+type memoryRepo struct {
+	articles.Repository
+	mu       sync.Mutex
+	articles map[string]*articles.Article
+}
+
+func newMemoryRepo(list ...*articles.Article) *memoryRepo {
+	r := &memoryRepo{articles: make(map[string]*articles.Article)}
+	for _, a := range list {
+		r.articles[a.ID] = a
+	}
+	return r
+}
+
+func (r *memoryRepo) ListScheduledDue(_ context.Context, now time.Time, limit int) ([]*articles.Article, error) {
+	return r.list(limit, func(a *articles.Article) bool {
+		return a.Status == articles.StatusScheduled && (a.PublishAt == nil || !a.PublishAt.After(now))
+	}), nil
+}
+
+func (r *memoryRepo) ListExpiredDue(_ context.Context, now time.Time, limit int) ([]*articles.Article, error) {
+	return r.list(limit, func(a *articles.Article) bool {
+		return a.IsPublished() && a.ExpireAt != nil && !a.ExpireAt.After(now)
+	}), nil
+}
+
+func (r *memoryRepo) list(limit int, match func(*articles.Article) bool) []*articles.Article {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var result []*articles.Article
+	for _, a := range r.articles {
+		if match(a) && len(result) < limit {
+			copied := *a
+			result = append(result, &copied)
+		}
+	}
+	return result
+}
+
+func (r *memoryRepo) Transition(_ context.Context, id string, from []articles.Status, to articles.Status, fields bson.M) (*articles.Article, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	a, ok := r.articles[id]
+	if !ok {
+		return nil, articles.ErrInvalidTransition
+	}
+	allowed := false
+	for _, s := range from {
+		allowed = allowed || a.CurrentStatus() == s
+	}
+	if !allowed {
+		return nil, articles.ErrInvalidTransition
+	}
+	a.Status = to
+	for k, v := range fields {
+		switch k {
+		case "publishedAt":
+			t := v.(time.Time)
+			a.PublishedAt = &t
+		case "expireAt":
+			if v == nil {
+				a.ExpireAt = nil
+			}
+		}
+	}
+	copied := *a
+	return &copied, nil
+}
+
+func (r *memoryRepo) get(id string) articles.Article {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return *r.articles[id]
+}
+
+type recordingQueue struct {
+	mu  sync.Mutex
+	ids []string
+}
+
+func (q *recordingQueue) Enqueue(_ context.Context, a *articles.Article) (*backlinks.Job, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.ids = append(q.ids, a.ID)
+	return &backlinks.Job{TargetID: a.ID}, nil
+}
+
+// leaseStore is the shared lease document; memoryLease is one replica's
+// handle on it. Like the Mongo lease, an owner keeps the lease by renewing
+// it and anyone may take it once it has expired.
+type leaseStore struct {
+	mu        sync.Mutex
+	owner     string
+	expiresAt time.Time
+	now       time.Time
+}
+
+type memoryLease struct {
+	store *leaseStore
+	owner string
+	ttl   time.Duration
+}
+
+func (l *memoryLease) Acquire(context.Context) (bool, error) {
+	l.store.mu.Lock()
+	defer l.store.mu.Unlock()
+	if l.store.owner != l.owner && l.store.now.Before(l.store.expiresAt) {
+		return false, nil
+	}
+	l.store.owner = l.owner
+	l.store.expiresAt = l.store.now.Add(l.ttl)
+	return true, nil
+}
+
+func (l *memoryLease) Release(context.Context) error {
+	l.store.mu.Lock()
+	defer l.store.mu.Unlock()
+	if l.store.owner == l.owner {
+		l.store.owner = ""
+		l.store.expiresAt = time.Time{}
+	}
+	return nil
+}
+
+func newTestScheduler(repo *memoryRepo, queue *recordingQueue, l locker) *ArticleScheduler {
+	return &ArticleScheduler{repo: repo, backlinks: queue, lease: l, interval: time.Minute}
+}
+
+func ptr(t time.Time) *time.Time { return &t }
+
+func TestPublishDue(t *testing.T) {
+	now := time.Now()
+	publishedAt := now.Add(-48 * time.Hour)
+	repo := newMemoryRepo(
+		&articles.Article{ID: "due", Status: articles.StatusScheduled, PublishAt: ptr(now.Add(-time.Minute))},
+		&articles.Article{ID: "no-date", Status: articles.StatusScheduled},
+		&articles.Article{ID: "republished", Status: articles.StatusScheduled, PublishAt: ptr(now.Add(-time.Minute)), PublishedAt: &publishedAt},
+		&articles.Article{ID: "future", Status: articles.StatusScheduled, PublishAt: ptr(now.Add(time.Hour))},
+		&articles.Article{ID: "draft", Status: articles.StatusDraft, PublishAt: ptr(now.Add(-time.Minute))},
+	)
+	queue := &recordingQueue{}
+	store := &leaseStore{now: now}
+	newTestScheduler(repo, queue, &memoryLease{store: store, owner: "a", ttl: time.Minute}).tick(context.Background())
+
+	for id, want := range map[string]articles.Status{
+		"due":         articles.StatusPublished,
+		"no-date":     articles.StatusPublished,
+		"republished": articles.StatusPublished,
+		"future":      articles.StatusScheduled,
+		"draft":       articles.StatusDraft,
+	} {
+		if got := repo.get(id).Status; got != want {
+			t.Errorf("%s: status = %s, want %s", id, got, want)
+		}
+	}
+	if a := repo.get("due"); a.PublishedAt == nil {
+		t.Error("published article has no publishedAt")
+	}
+	if a := repo.get("republished"); !a.PublishedAt.Equal(publishedAt) {
+		t.Errorf("publishedAt of a republished article changed to %v", a.PublishedAt)
+	}
+	if len(queue.ids) != 3 {
+		t.Errorf("backlink jobs enqueued for %v, want the three published articles", queue.ids)
+	}
+}
+
+func TestExpireDue(t *testing.T) {
+	now := time.Now()
+	repo := newMemoryRepo(
+		&articles.Article{ID: "expired", Status: articles.StatusPublished, ExpireAt: ptr(now.Add(-time.Minute))},
+		&articles.Article{ID: "live", Status: articles.StatusPublished, ExpireAt: ptr(now.Add(time.Hour))},
+		&articles.Article{ID: "no-expiry", Status: articles.StatusPublished},
+		&articles.Article{ID: "draft", Status: articles.StatusDraft, ExpireAt: ptr(now.Add(-time.Minute))},
+	)
+	store := &leaseStore{now: now}
+	s := newTestScheduler(repo, &recordingQueue{}, &memoryLease{store: store, owner: "a", ttl: time.Minute})
+	s.tick(context.Background())
+
+	for id, want := range map[string]articles.Status{
+		"expired":   articles.StatusArchived,
+		"live":      articles.StatusPublished,
+		"no-expiry": articles.StatusPublished,
+		"draft":     articles.StatusDraft,
+	} {
+		if got := repo.get(id).Status; got != want {
+			t.Errorf("%s: status = %s, want %s", id, got, want)
+		}
+	}
+	if a := repo.get("expired"); a.ExpireAt != nil {
+		t.Errorf("archived article kept expireAt %v", a.ExpireAt)
+	}
+
+	// Published again without a new expiry, it must stay published.
+	repo.articles["expired"].Status = articles.StatusPublished
+	s.tick(context.Background())
+	if got := repo.get("expired").Status; got != articles.StatusPublished {
+		t.Errorf("restored article was archived again: status = %s", got)
+	}
+}
+
+func TestLeaseTakeover(t *testing.T) {
+	now := time.Now()
+	store := &leaseStore{now: now}
+	ttl := time.Minute
+	repo := newMemoryRepo()
+	queue := &recordingQueue{}
+	a := newTestScheduler(repo, queue, &memoryLease{store: store, owner: "a", ttl: ttl})
+	b := newTestScheduler(repo, queue, &memoryLease{store: store, owner: "b", ttl: ttl})
+
+	schedule := func(id string) {
+		repo.mu.Lock()
+		repo.articles[id] = &articles.Article{ID: id, Status: articles.StatusScheduled}
+		repo.mu.Unlock()
+	}
+
+	// a holds the lease, so b does nothing.
+	a.tick(context.Background())
+	schedule("1")
+	b.tick(context.Background())
+	if got := repo.get("1").Status; got != articles.StatusScheduled {
+		t.Fatalf("replica without the lease published: status = %s", got)
+	}
+
+	// a stops renewing. Before the lease expires b still waits...
+	store.now = now.Add(ttl / 2)
+	b.tick(context.Background())
+	if got := repo.get("1").Status; got != articles.StatusScheduled {
+		t.Fatalf("replica took an unexpired lease: status = %s", got)
+	}
+
+	// ...and once it has expired b takes over.
+	store.now = now.Add(ttl + time.Second)
+	b.tick(context.Background())
+	if got := repo.get("1").Status; got != articles.StatusPublished {
+		t.Fatalf("replica did not take over an expired lease: status = %s", got)
+	}
+
+	// a comes back and finds the lease taken.
+	schedule("2")
+	a.tick(context.Background())
+	if got := repo.get("2").Status; got != articles.StatusScheduled {
+		t.Errorf("old owner published after losing the lease: status = %s", got)
+	}
+
+	// Releasing hands the lease over without waiting for it to expire.
+	if err := b.lease.Release(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	a.tick(context.Background())
+	if got := repo.get("2").Status; got != articles.StatusPublished {
+		t.Errorf("lease was not free after release: status = %s", got)
+	}
+}
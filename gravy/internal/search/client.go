@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"strings"
 
 	"github.com/meilisearch/meilisearch-go"
 )
@@ -37,6 +38,22 @@ func (c *Client) CreateIndexes() error {
 		log.Printf("Error creating community index (might exist): %v", err)
 	}
 
+	articleAttrs := []interface{}{"tags", "category"}
+	task, err := c.client.Index("articles").UpdateFilterableAttributes(&articleAttrs)
+	if err != nil {
+		return fmt.Errorf("failed to update filterable attributes for articles: %w", err)
+	}
+	log.Printf("Update article filterable attributes task: %v", task.TaskUID)
+
+	task, err = c.client.Index("articles").UpdateFaceting(&meilisearch.Faceting{
+		MaxValuesPerFacet: 100,
+		SortFacetValuesBy: map[string]meilisearch.SortFacetType{"tags": meilisearch.SortFacetTypeCount},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update faceting for articles: %w", err)
+	}
+	log.Printf("Update article faceting task: %v", task.TaskUID)
+
 	filterableAttributes := []string{"group_type", "group_id", "type", "postId"}
 
 	attrs := make([]interface{}, len(filterableAttributes))
@@ -44,7 +61,7 @@ func (c *Client) CreateIndexes() error {
 		attrs[i] = v
 	}
 
-	task, err := c.client.Index("community").UpdateFilterableAttributes(&attrs)
+	task, err = c.client.Index("community").UpdateFilterableAttributes(&attrs)
 	if err != nil {
 		return fmt.Errorf("failed to update filterable attributes for community: %w", err)
 	}
@@ -169,10 +186,13 @@ func (c *Client) DeleteCommunityDataByPostID(ctx context.Context, postID string)
 	return nil
 }
 
-func (c *Client) SearchArticles(ctx context.Context, query string, limit, offset int) ([]string, error) {
+// SearchArticles returns the IDs of articles matching query. If tags is not
+// empty, only articles carrying all of them match.
+func (c *Client) SearchArticles(ctx context.Context, query string, tags []string, limit, offset int) ([]string, error) {
 	searchRes, err := c.client.Index("articles").Search(query, &meilisearch.SearchRequest{
 		Limit:  int64(limit),
 		Offset: int64(offset),
+		Filter: tagFilter(tags),
 	})
 	if err != nil {
 		return nil, fmt.Errorf("search articles failed: %w", err)
@@ -247,3 +267,63 @@ func (c *Client) SearchCommunity(ctx context.Context, query string, limit, offse
 	}
 	return hits, nil
 }
+
+// ArticleTagFacets counts the tags on articles matching query, narrowed by
+// tags like SearchArticles.
+func (c *Client) ArticleTagFacets(ctx context.Context, query string, tags []string) (map[string]int64, error) {
+	searchRes, err := c.client.Index("articles").Search(query, &meilisearch.SearchRequest{
+		// Only the facet distribution is used; a zero limit would be
+		// dropped and fall back to the default of 20.
+		Limit:  1,
+		Filter: tagFilter(tags),
+		Facets: []string{"tags"},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("article tag facets failed: %w", err)
+	}
+
+	var distribution map[string]map[string]int64
+	if len(searchRes.FacetDistribution) > 0 {
+		if err := json.Unmarshal(searchRes.FacetDistribution, &distribution); err != nil {
+			return nil, fmt.Errorf("failed to decode tag facets: %w", err)
+		}
+	}
+	return distribution["tags"], nil
+}
+
+func tagFilter(tags []string) interface{} {
+	if len(tags) == 0 {
+		return nil
+	}
+	clauses := make([]string, len(tags))
+	for i, t := range tags {
+		clauses[i] = fmt.Sprintf("tags = %q", t)
+	}
+	return strings.Join(clauses, " AND ")
+}
+
+// SearchSimilarArticles returns the IDs of articles matching as many of
+// terms as possible, for finding articles similar to one described by its
+// key terms. Rare terms weigh more than common ones.
+func (c *Client) SearchSimilarArticles(ctx context.Context, terms []string, limit int) ([]string, error) {
+	searchRes, err := c.client.Index("articles").Search(strings.Join(terms, " "), &meilisearch.SearchRequest{
+		Limit:                int64(limit),
+		MatchingStrategy:     meilisearch.Frequency,
+		AttributesToRetrieve: []string{"id"},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("similar articles search failed: %w", err)
+	}
+
+	ids := make([]string, 0, len(searchRes.Hits))
+	for _, hit := range searchRes.Hits {
+		var hitMap map[string]interface{}
+		b, _ := json.Marshal(hit)
+		_ = json.Unmarshal(b, &hitMap)
+
+		if id, ok := hitMap["id"].(string); ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
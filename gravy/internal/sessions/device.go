@@ -0,0 +1,52 @@
+package sessions
+
+import "strings"
+
+// Device gives a short description of a user agent, such as "Firefox on
+// Linux", for listing sessions. It is a rough guess meant for people, not
+// for decisions.
+func Device(userAgent string) string {
+	if userAgent == "" {
+		return "Unknown device"
+	}
+	browser := match(userAgent, [][2]string{
+		{"Edg/", "Edge"},
+		{"OPR/", "Opera"},
+		{"Firefox/", "Firefox"},
+		{"Chrome/", "Chrome"},
+		{"CriOS/", "Chrome"},
+		{"Safari/", "Safari"},
+		{"curl/", "curl"},
+		{"okhttp", "Android app"},
+		{"Dart/", "Mobile app"},
+	})
+	os := match(userAgent, [][2]string{
+		{"Android", "Android"},
+		{"iPhone", "iOS"},
+		{"iPad", "iPadOS"},
+		{"Windows", "Windows"},
+		{"Mac OS X", "macOS"},
+		{"CrOS", "ChromeOS"},
+		{"Linux", "Linux"},
+	})
+
+	switch {
+	case browser != "" && os != "":
+		return browser + " on " + os
+	case browser != "":
+		return browser
+	case os != "":
+		return os
+	}
+	return "Unknown device"
+}
+
+// match returns the name paired with the first marker found in s.
+func match(s string, markers [][2]string) string {
+	for _, m := range markers {
+		if strings.Contains(s, m[0]) {
+			return m[1]
+		}
+	}
+	return ""
+}
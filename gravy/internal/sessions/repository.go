@@ -0,0 +1,236 @@
+package sessions
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// ErrNotFound is returned when a session does not exist, has expired or has
+// been revoked.
+var ErrNotFound = errors.New("session not found")
+
+// retainExpired is how long expired and revoked sessions are kept before the
+// TTL index removes them, so recent sign-ins can still be looked into.
+const retainExpired = 7 * 24 * time.Hour
+
+// Session is one signed-in device. Only hashes of its refresh tokens are
+// stored: RefreshHash is the token that can be used next, and PreviousHash
+// the one it replaced, kept to notice a stolen token being replayed.
+type Session struct {
+	ID           string     `bson:"_id,omitempty"`
+	UserID       string     `bson:"userId"`
+	RefreshHash  string     `bson:"refreshHash"`
+	PreviousHash string     `bson:"previousHash,omitempty"`
+	UserAgent    string     `bson:"userAgent"`
+	IP           string     `bson:"ip"`
+	CreatedAt    time.Time  `bson:"createdAt"`
+	LastSeenAt   time.Time  `bson:"lastSeenAt"`
+	ExpiresAt    time.Time  `bson:"expiresAt"`
+	RevokedAt    *time.Time `bson:"revokedAt,omitempty"`
+	RevokeReason string     `bson:"revokeReason,omitempty"`
+}
+
+// Active reports whether the session can still be used.
+func (s *Session) Active(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
+
+// Client describes the device a request came from.
+type Client struct {
+	UserAgent string
+	IP        string
+}
+
+type Repository interface {
+	Create(ctx context.Context, session *Session) error
+	Get(ctx context.Context, id string) (*Session, error)
+	Rotate(ctx context.Context, id, oldHash, newHash string, client Client, expiresAt time.Time) (*Session, error)
+	Touch(ctx context.Context, id string, client Client) error
+	ListActive(ctx context.Context, userID string) ([]*Session, error)
+	Revoke(ctx context.Context, userID, id, reason string) (bool, error)
+	RevokeAll(ctx context.Context, userID, exceptID, reason string) ([]string, error)
+	EnsureIndexes(ctx context.Context) error
+}
+
+type repository struct {
+	coll *mongo.Collection
+}
+
+func NewRepository(db *mongo.Database) Repository {
+	return &repository{
+		coll: db.Collection("sessions"),
+	}
+}
+
+func (r *repository) Create(ctx context.Context, session *Session) error {
+	res, err := r.coll.InsertOne(ctx, session)
+	if err != nil {
+		return err
+	}
+	if oid, ok := res.InsertedID.(bson.ObjectID); ok {
+		session.ID = oid.Hex()
+	}
+	return nil
+}
+
+func (r *repository) Get(ctx context.Context, id string) (*Session, error) {
+	oid, err := bson.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	var session Session
+	err = r.coll.FindOne(ctx, bson.M{"_id": oid}).Decode(&session)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// Rotate replaces the session's refresh token hash, provided oldHash is
+// still the current one, so of two requests racing with the same token
+// only one succeeds. It returns ErrNotFound otherwise.
+func (r *repository) Rotate(ctx context.Context, id, oldHash, newHash string, client Client, expiresAt time.Time) (*Session, error) {
+	oid, err := bson.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	now := time.Now()
+	filter := bson.M{
+		"_id":         oid,
+		"refreshHash": oldHash,
+		"revokedAt":   bson.M{"$exists": false},
+		"expiresAt":   bson.M{"$gt": now},
+	}
+	update := bson.M{"$set": bson.M{
+		"refreshHash":  newHash,
+		"previousHash": oldHash,
+		"userAgent":    client.UserAgent,
+		"ip":           client.IP,
+		"lastSeenAt":   now,
+		"expiresAt":    expiresAt,
+	}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var session Session
+	err = r.coll.FindOneAndUpdate(ctx, filter, update, opts).Decode(&session)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// Touch records that the session was just used.
+func (r *repository) Touch(ctx context.Context, id string, client Client) error {
+	oid, err := bson.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	_, err = r.coll.UpdateOne(ctx, bson.M{"_id": oid}, bson.M{"$set": bson.M{
+		"lastSeenAt": time.Now(),
+		"userAgent":  client.UserAgent,
+		"ip":         client.IP,
+	}})
+	return err
+}
+
+// ListActive returns the user's usable sessions, most recently used first.
+func (r *repository) ListActive(ctx context.Context, userID string) ([]*Session, error) {
+	filter := bson.M{
+		"userId":    userID,
+		"revokedAt": bson.M{"$exists": false},
+		"expiresAt": bson.M{"$gt": time.Now()},
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "lastSeenAt", Value: -1}})
+
+	cursor, err := r.coll.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	var list []*Session
+	if err := cursor.All(ctx, &list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// Revoke revokes one of the user's sessions. It reports whether an active
+// session was revoked.
+func (r *repository) Revoke(ctx context.Context, userID, id, reason string) (bool, error) {
+	oid, err := bson.ObjectIDFromHex(id)
+	if err != nil {
+		return false, nil
+	}
+	res, err := r.coll.UpdateOne(ctx,
+		bson.M{"_id": oid, "userId": userID, "revokedAt": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"revokedAt": time.Now(), "revokeReason": reason}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount > 0, nil
+}
+
+// RevokeAll revokes every active session of the user but exceptID, if set,
+// and returns their IDs.
+func (r *repository) RevokeAll(ctx context.Context, userID, exceptID, reason string) ([]string, error) {
+	filter := bson.M{"userId": userID, "revokedAt": bson.M{"$exists": false}}
+	if exceptID != "" {
+		except, err := bson.ObjectIDFromHex(exceptID)
+		if err != nil {
+			return nil, ErrNotFound
+		}
+		filter["_id"] = bson.M{"$ne": except}
+	}
+	cursor, err := r.coll.Find(ctx, filter, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	var found []struct {
+		ID bson.ObjectID `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &found); err != nil {
+		return nil, err
+	}
+	if len(found) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]string, len(found))
+	oids := make([]bson.ObjectID, len(found))
+	for i, f := range found {
+		ids[i] = f.ID.Hex()
+		oids[i] = f.ID
+	}
+	// Sessions created after the Find are left alone; callers that must
+	// lock a user out (bans) also mark the user so no new session starts.
+	_, err = r.coll.UpdateMany(ctx,
+		bson.M{"_id": bson.M{"$in": oids}, "revokedAt": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"revokedAt": time.Now(), "revokeReason": reason}},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+func (r *repository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.coll.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "lastSeenAt", Value: -1}}},
+		{
+			Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(int32(retainExpired.Seconds())),
+		},
+	})
+	return err
+}
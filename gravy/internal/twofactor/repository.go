@@ -0,0 +1,217 @@
+package twofactor
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// Settings is a user's second factor. They live apart from the user so the
+// secret never travels with the user document.
+type Settings struct {
+	UserID string `bson:"_id"`
+	Secret string `bson:"secret,omitempty"`
+	// PendingSecret is the secret of an enrollment that has not been
+	// confirmed with a code yet.
+	PendingSecret string     `bson:"pendingSecret,omitempty"`
+	Enabled       bool       `bson:"enabled"`
+	EnabledAt     *time.Time `bson:"enabledAt,omitempty"`
+	// LastStep is the time step of the last accepted code, so a code can
+	// not be used twice.
+	LastStep int64 `bson:"lastStep"`
+	// RecoveryCodes are SHA-256 hashes of the unused recovery codes.
+	RecoveryCodes []string `bson:"recoveryCodes"`
+	// Attempts counts the codes tried by the signed-in user, who has no
+	// challenge to count them on, since AttemptsResetAt was last passed.
+	Attempts        int        `bson:"attempts"`
+	AttemptsResetAt *time.Time `bson:"attemptsResetAt,omitempty"`
+}
+
+// Challenge is a login that passed the password (or provider) step and
+// waits for the second factor. It is stored under the hash of its token.
+type Challenge struct {
+	Hash      string    `bson:"_id"`
+	UserID    string    `bson:"userId"`
+	Attempts  int       `bson:"attempts"`
+	CreatedAt time.Time `bson:"createdAt"`
+	ExpiresAt time.Time `bson:"expiresAt"`
+}
+
+type Repository interface {
+	Get(ctx context.Context, userID string) (*Settings, error)
+	SetPending(ctx context.Context, userID, secret string) error
+	Enable(ctx context.Context, userID, secret string, step int64, recoveryHashes []string) (bool, error)
+	UseStep(ctx context.Context, userID string, step int64) (bool, error)
+	UseRecoveryCode(ctx context.Context, userID, hash string) (bool, error)
+	SetRecoveryCodes(ctx context.Context, userID string, hashes []string) error
+	Delete(ctx context.Context, userID string) error
+	CreateChallenge(ctx context.Context, challenge Challenge) error
+	AttemptChallenge(ctx context.Context, hash string, maxAttempts int) (*Challenge, error)
+	TakeChallenge(ctx context.Context, hash string) (bool, error)
+	AttemptVerify(ctx context.Context, userID string, maxAttempts int, window time.Duration) (bool, error)
+	EnsureIndexes(ctx context.Context) error
+}
+
+type repository struct {
+	settings   *mongo.Collection
+	challenges *mongo.Collection
+}
+
+func NewRepository(db *mongo.Database) Repository {
+	return &repository{
+		settings:   db.Collection("two_factor"),
+		challenges: db.Collection("login_challenges"),
+	}
+}
+
+// Get returns mongo.ErrNoDocuments if the user never started enrolling.
+func (r *repository) Get(ctx context.Context, userID string) (*Settings, error) {
+	var settings Settings
+	if err := r.settings.FindOne(ctx, bson.M{"_id": userID}).Decode(&settings); err != nil {
+		return nil, err
+	}
+	return &settings, nil
+}
+
+func (r *repository) SetPending(ctx context.Context, userID, secret string) error {
+	_, err := r.settings.UpdateOne(ctx,
+		bson.M{"_id": userID},
+		bson.M{"$set": bson.M{"pendingSecret": secret}},
+		options.UpdateOne().SetUpsert(true),
+	)
+	return err
+}
+
+// Enable turns on the pending secret. It reports false if the pending
+// secret changed in the meantime, e.g. because enrollment was restarted in
+// another tab.
+func (r *repository) Enable(ctx context.Context, userID, secret string, step int64, recoveryHashes []string) (bool, error) {
+	res, err := r.settings.UpdateOne(ctx,
+		bson.M{"_id": userID, "pendingSecret": secret},
+		bson.M{
+			"$set": bson.M{
+				"secret":        secret,
+				"enabled":       true,
+				"enabledAt":     time.Now(),
+				"lastStep":      step,
+				"recoveryCodes": recoveryHashes,
+			},
+			"$unset": bson.M{"pendingSecret": ""},
+		},
+	)
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount == 1, nil
+}
+
+// UseStep records that the code for step was used. It reports false if a
+// code for that step or a later one was already used.
+func (r *repository) UseStep(ctx context.Context, userID string, step int64) (bool, error) {
+	res, err := r.settings.UpdateOne(ctx,
+		bson.M{"_id": userID, "enabled": true, "lastStep": bson.M{"$lt": step}},
+		bson.M{"$set": bson.M{"lastStep": step}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount == 1, nil
+}
+
+// UseRecoveryCode removes a recovery code, reporting false if the user has
+// no such unused code.
+func (r *repository) UseRecoveryCode(ctx context.Context, userID, hash string) (bool, error) {
+	res, err := r.settings.UpdateOne(ctx,
+		bson.M{"_id": userID, "enabled": true, "recoveryCodes": hash},
+		bson.M{"$pull": bson.M{"recoveryCodes": hash}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount == 1, nil
+}
+
+func (r *repository) SetRecoveryCodes(ctx context.Context, userID string, hashes []string) error {
+	_, err := r.settings.UpdateOne(ctx,
+		bson.M{"_id": userID, "enabled": true},
+		bson.M{"$set": bson.M{"recoveryCodes": hashes}},
+	)
+	return err
+}
+
+func (r *repository) Delete(ctx context.Context, userID string) error {
+	if _, err := r.settings.DeleteOne(ctx, bson.M{"_id": userID}); err != nil {
+		return err
+	}
+	_, err := r.challenges.DeleteMany(ctx, bson.M{"userId": userID})
+	return err
+}
+
+func (r *repository) CreateChallenge(ctx context.Context, challenge Challenge) error {
+	_, err := r.challenges.InsertOne(ctx, challenge)
+	return err
+}
+
+// AttemptChallenge counts an attempt at a challenge and returns it. It
+// returns mongo.ErrNoDocuments if the challenge does not exist, expired or
+// ran out of attempts.
+func (r *repository) AttemptChallenge(ctx context.Context, hash string, maxAttempts int) (*Challenge, error) {
+	var challenge Challenge
+	err := r.challenges.FindOneAndUpdate(ctx,
+		bson.M{"_id": hash, "expiresAt": bson.M{"$gt": time.Now()}, "attempts": bson.M{"$lt": maxAttempts}},
+		bson.M{"$inc": bson.M{"attempts": 1}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&challenge)
+	if err != nil {
+		return nil, err
+	}
+	return &challenge, nil
+}
+
+// TakeChallenge deletes a challenge once it is passed, reporting false if
+// it was already taken.
+func (r *repository) TakeChallenge(ctx context.Context, hash string) (bool, error) {
+	res, err := r.challenges.DeleteOne(ctx, bson.M{"_id": hash})
+	if err != nil {
+		return false, err
+	}
+	return res.DeletedCount == 1, nil
+}
+
+// AttemptVerify counts an attempt by a signed-in user to enter a code. It
+// reports false once maxAttempts were made within window; the count starts
+// over with the first attempt after the window has passed.
+func (r *repository) AttemptVerify(ctx context.Context, userID string, maxAttempts int, window time.Duration) (bool, error) {
+	now := time.Now()
+	inWindow := bson.M{"$gt": bson.A{"$attemptsResetAt", now}}
+	var settings Settings
+	err := r.settings.FindOneAndUpdate(ctx,
+		bson.M{"_id": userID, "enabled": true},
+		bson.A{bson.M{"$set": bson.M{
+			"attempts":        bson.M{"$cond": bson.A{inWindow, bson.M{"$add": bson.A{"$attempts", 1}}, 1}},
+			"attemptsResetAt": bson.M{"$cond": bson.A{inWindow, "$attemptsResetAt", now.Add(window)}},
+		}}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&settings)
+	if err == mongo.ErrNoDocuments {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return settings.Attempts <= maxAttempts, nil
+}
+
+func (r *repository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.challenges.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "userId", Value: 1}}},
+		{
+			Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	})
+	return err
+}
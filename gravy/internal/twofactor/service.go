@@ -0,0 +1,282 @@
+package twofactor
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+const (
+	// ChallengeTTL is how long a user has to enter the second factor after
+	// the first one was accepted.
+	ChallengeTTL = 5 * time.Minute
+	// maxAttempts is how many codes can be tried against one challenge
+	// before the user has to sign in again.
+	maxAttempts       = 5
+	recoveryCodeCount = 10
+)
+
+var (
+	ErrAlreadyEnabled   = errors.New("two-factor authentication is already enabled")
+	ErrNotEnabled       = errors.New("two-factor authentication is not enabled")
+	ErrNoEnrollment     = errors.New("start setting up two-factor authentication first")
+	ErrInvalidCode      = errors.New("invalid or already used code")
+	ErrInvalidChallenge = errors.New("the sign-in attempt expired, please sign in again")
+	ErrTooManyAttempts  = errors.New("too many attempts, please try again in a few minutes")
+)
+
+// Enrollment is a secret waiting to be confirmed with a code.
+type Enrollment struct {
+	Secret string
+	// URI is the otpauth:// URI to show as a QR code.
+	URI string
+}
+
+type Status struct {
+	Enabled                bool
+	EnabledAt              *time.Time
+	RecoveryCodesRemaining int
+}
+
+// Service manages TOTP second factors and the challenges logins wait on
+// while the second factor is entered.
+type Service struct {
+	repo   Repository
+	issuer string
+}
+
+// NewService creates a Service. issuer is the name authenticator apps show
+// next to the account.
+func NewService(repo Repository, issuer string) *Service {
+	return &Service{repo: repo, issuer: issuer}
+}
+
+// BeginEnrollment creates a new secret for the user. It only takes effect
+// once ConfirmEnrollment is called with a code from it, so abandoning
+// enrollment changes nothing.
+func (s *Service) BeginEnrollment(ctx context.Context, userID, account string) (*Enrollment, error) {
+	settings, err := s.settings(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if settings != nil && settings.Enabled {
+		return nil, ErrAlreadyEnabled
+	}
+
+	secret, err := newSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.SetPending(ctx, userID, secret); err != nil {
+		return nil, err
+	}
+	return &Enrollment{Secret: secret, URI: otpauthURI(s.issuer, account, secret)}, nil
+}
+
+// ConfirmEnrollment enables two-factor authentication if code is valid for
+// the pending secret, and returns the recovery codes. They are only shown
+// this once.
+func (s *Service) ConfirmEnrollment(ctx context.Context, userID, code string) ([]string, error) {
+	settings, err := s.settings(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if settings != nil && settings.Enabled {
+		return nil, ErrAlreadyEnabled
+	}
+	if settings == nil || settings.PendingSecret == "" {
+		return nil, ErrNoEnrollment
+	}
+
+	step, ok := matchStep(settings.PendingSecret, normalizeCode(code), time.Now())
+	if !ok {
+		return nil, ErrInvalidCode
+	}
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	enabled, err := s.repo.Enable(ctx, userID, settings.PendingSecret, step, hashes)
+	if err != nil {
+		return nil, err
+	}
+	if !enabled {
+		return nil, ErrNoEnrollment
+	}
+	return codes, nil
+}
+
+// Verify checks a code from the authenticator app or a recovery code. Each
+// code works only once.
+func (s *Service) Verify(ctx context.Context, userID, code string) error {
+	settings, err := s.settings(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if settings == nil || !settings.Enabled {
+		return ErrNotEnabled
+	}
+
+	code = normalizeCode(code)
+	var ok bool
+	if isTOTPCode(code) {
+		step, valid := matchStep(settings.Secret, code, time.Now())
+		if !valid {
+			return ErrInvalidCode
+		}
+		ok, err = s.repo.UseStep(ctx, userID, step)
+	} else {
+		ok, err = s.repo.UseRecoveryCode(ctx, userID, hashCode(code))
+	}
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrInvalidCode
+	}
+	return nil
+}
+
+// VerifySignedIn is Verify for a user who is already signed in, e.g. to
+// turn the second factor off. Like a login challenge it allows maxAttempts
+// codes, here per ChallengeTTL, so the code cannot be guessed by retrying.
+func (s *Service) VerifySignedIn(ctx context.Context, userID, code string) error {
+	allowed, err := s.repo.AttemptVerify(ctx, userID, maxAttempts, ChallengeTTL)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		settings, err := s.settings(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if settings == nil || !settings.Enabled {
+			return ErrNotEnabled
+		}
+		return ErrTooManyAttempts
+	}
+	return s.Verify(ctx, userID, code)
+}
+
+// Disable removes the user's second factor. Callers check a code first.
+func (s *Service) Disable(ctx context.Context, userID string) error {
+	return s.repo.Delete(ctx, userID)
+}
+
+// RegenerateRecoveryCodes replaces the user's recovery codes.
+func (s *Service) RegenerateRecoveryCodes(ctx context.Context, userID string) ([]string, error) {
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.SetRecoveryCodes(ctx, userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+func (s *Service) Status(ctx context.Context, userID string) (*Status, error) {
+	settings, err := s.settings(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if settings == nil || !settings.Enabled {
+		return &Status{}, nil
+	}
+	return &Status{
+		Enabled:                true,
+		EnabledAt:              settings.EnabledAt,
+		RecoveryCodesRemaining: len(settings.RecoveryCodes),
+	}, nil
+}
+
+// StartChallenge returns a token that stands for a login waiting on the
+// second factor.
+func (s *Service) StartChallenge(ctx context.Context, userID string) (string, time.Time, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", time.Time{}, err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+
+	now := time.Now()
+	challenge := Challenge{
+		Hash:      hashCode(token),
+		UserID:    userID,
+		CreatedAt: now,
+		ExpiresAt: now.Add(ChallengeTTL),
+	}
+	if err := s.repo.CreateChallenge(ctx, challenge); err != nil {
+		return "", time.Time{}, err
+	}
+	return token, challenge.ExpiresAt, nil
+}
+
+// CompleteChallenge checks the second factor for a challenge and returns
+// the ID of the user who may now be signed in.
+func (s *Service) CompleteChallenge(ctx context.Context, token, code string) (string, error) {
+	hash := hashCode(token)
+	challenge, err := s.repo.AttemptChallenge(ctx, hash, maxAttempts)
+	if err == mongo.ErrNoDocuments {
+		return "", ErrInvalidChallenge
+	}
+	if err != nil {
+		return "", err
+	}
+	if err := s.Verify(ctx, challenge.UserID, code); err != nil {
+		return "", err
+	}
+	taken, err := s.repo.TakeChallenge(ctx, hash)
+	if err != nil {
+		return "", err
+	}
+	if !taken {
+		return "", ErrInvalidChallenge
+	}
+	return challenge.UserID, nil
+}
+
+func (s *Service) settings(ctx context.Context, userID string) (*Settings, error) {
+	settings, err := s.repo.Get(ctx, userID)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	return settings, err
+}
+
+// newRecoveryCodes returns recovery codes formatted as "xxxxx-xxxxx" and
+// their hashes. Each holds 50 bits, enough that hashing them without a salt
+// is safe.
+func newRecoveryCodes() ([]string, []string, error) {
+	const alphabet = "abcdefghijklmnopqrstuvwxyz234567"
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		b := make([]byte, 10)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		var sb strings.Builder
+		for j, c := range b {
+			if j == 5 {
+				sb.WriteByte('-')
+			}
+			sb.WriteByte(alphabet[c%32])
+		}
+		codes[i] = sb.String()
+		hashes[i] = hashCode(normalizeCode(codes[i]))
+	}
+	return codes, hashes, nil
+}
+
+func hashCode(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
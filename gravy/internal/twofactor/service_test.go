@@ -0,0 +1,70 @@
+package twofactor
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// memoryRepo keeps one user's settings in memory, enough for Verify and
+// VerifySignedIn.
+type memoryRepo struct {
+	Repository
+	settings *Settings
+}
+
+func (r *memoryRepo) Get(context.Context, string) (*Settings, error) {
+	s := *r.settings
+	return &s, nil
+}
+
+func (r *memoryRepo) UseStep(_ context.Context, _ string, step int64) (bool, error) {
+	if step <= r.settings.LastStep {
+		return false, nil
+	}
+	r.settings.LastStep = step
+	return true, nil
+}
+
+func (r *memoryRepo) UseRecoveryCode(context.Context, string, string) (bool, error) {
+	return false, nil
+}
+
+func (r *memoryRepo) AttemptVerify(_ context.Context, _ string, maxAttempts int, window time.Duration) (bool, error) {
+	now := time.Now()
+	if r.settings.AttemptsResetAt == nil || !r.settings.AttemptsResetAt.After(now) {
+		resetAt := now.Add(window)
+		r.settings.Attempts = 0
+		r.settings.AttemptsResetAt = &resetAt
+	}
+	r.settings.Attempts++
+	return r.settings.Attempts <= maxAttempts, nil
+}
+
+func TestVerifySignedInLimitsAttempts(t *testing.T) {
+	repo := &memoryRepo{settings: &Settings{UserID: "u1", Secret: rfcSecret, Enabled: true}}
+	s := NewService(repo, "WikiNITT")
+	ctx := context.Background()
+
+	for i := 0; i < maxAttempts; i++ {
+		if err := s.VerifySignedIn(ctx, "u1", "000000"); !errors.Is(err, ErrInvalidCode) {
+			t.Fatalf("attempt %d: err = %v, want ErrInvalidCode", i+1, err)
+		}
+	}
+
+	valid, err := code(rfcSecret, time.Now().Unix()/30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.VerifySignedIn(ctx, "u1", valid); !errors.Is(err, ErrTooManyAttempts) {
+		t.Fatalf("valid code after the limit: err = %v, want ErrTooManyAttempts", err)
+	}
+
+	// Once the window has passed the count starts over.
+	past := time.Now().Add(-time.Second)
+	repo.settings.AttemptsResetAt = &past
+	if err := s.VerifySignedIn(ctx, "u1", valid); err != nil {
+		t.Fatalf("valid code after the window: %v", err)
+	}
+}
@@ -0,0 +1,139 @@
+package twofactor
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA1 key of the RFC 6238 test vectors.
+var rfcSecret = secretEncoding.EncodeToString([]byte("12345678901234567890"))
+
+func TestCode(t *testing.T) {
+	// RFC 6238 appendix B, SHA1 rows, cut to the last six digits.
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, tt := range tests {
+		got, err := code(rfcSecret, tt.unix/30)
+		if err != nil {
+			t.Fatalf("code at %d: %v", tt.unix, err)
+		}
+		if got != tt.want {
+			t.Errorf("code at %d = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+
+	if _, err := code("not base32!", 1); err == nil {
+		t.Error("code with an invalid secret succeeded")
+	}
+}
+
+func TestMatchStep(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	step := now.Unix() / 30
+	codeAt := func(s int64) string {
+		c, err := code(rfcSecret, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	tests := []struct {
+		name     string
+		input    string
+		wantStep int64
+		wantOK   bool
+	}{
+		{"current step", "005924", step, true},
+		{"previous step", codeAt(step - 1), step - 1, true},
+		{"next step", codeAt(step + 1), step + 1, true},
+		{"two steps late", codeAt(step - 2), 0, false},
+		{"two steps early", codeAt(step + 2), 0, false},
+		{"wrong code", "000000", 0, false},
+		{"eight digits", "89005924", 0, false},
+		{"too short", "05924", 0, false},
+		{"not digits", "00592a", 0, false},
+		{"empty", "", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := matchStep(rfcSecret, tt.input, now)
+			if got != tt.wantStep || ok != tt.wantOK {
+				t.Errorf("matchStep(%q) = %d, %v; want %d, %v", tt.input, got, ok, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestNormalizeCode(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"123 456", "123456"},
+		{" 123-456 ", "123456"},
+		{"ABCDE-FGHIJ", "abcdefghij"},
+		{"abcde fghij", "abcdefghij"},
+	}
+	for _, tt := range tests {
+		if got := normalizeCode(tt.input); got != tt.want {
+			t.Errorf("normalizeCode(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestOtpauthURI(t *testing.T) {
+	u, err := url.Parse(otpauthURI("WikiNITT", "student@nitt.edu", rfcSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Scheme != "otpauth" || u.Host != "totp" || u.Path != "/WikiNITT:student@nitt.edu" {
+		t.Errorf("URI = %s", u)
+	}
+	q := u.Query()
+	want := map[string]string{
+		"secret":    rfcSecret,
+		"issuer":    "WikiNITT",
+		"algorithm": "SHA1",
+		"digits":    "6",
+		"period":    "30",
+	}
+	for k, v := range want {
+		if q.Get(k) != v {
+			t.Errorf("%s = %q, want %q", k, q.Get(k), v)
+		}
+	}
+}
+
+func TestNewRecoveryCodes(t *testing.T) {
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(codes) != recoveryCodeCount || len(hashes) != recoveryCodeCount {
+		t.Fatalf("got %d codes and %d hashes, want %d", len(codes), len(hashes), recoveryCodeCount)
+	}
+	seen := make(map[string]bool)
+	for i, c := range codes {
+		if len(c) != 11 || c[5] != '-' || isTOTPCode(normalizeCode(c)) {
+			t.Errorf("code %q is not formatted as xxxxx-xxxxx", c)
+		}
+		if hashes[i] != hashCode(normalizeCode(c)) {
+			t.Errorf("hash of %q does not match", c)
+		}
+		if seen[c] {
+			t.Errorf("code %q repeats", c)
+		}
+		seen[c] = true
+	}
+}
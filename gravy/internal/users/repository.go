@@ -2,6 +2,8 @@ package users
 
 import (
 	"context"
+	"log"
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/v2/bson"
@@ -19,12 +21,17 @@ type User struct {
 	Avatar      string `bson:"avatar"`
 	PhoneNumber string `bson:"phoneNumber"`
 
-	OAuthID       string    `bson:"oauthId"`
+	OAuthID       string    `bson:"oauthId,omitempty"`
 	PasswordHash  string    `bson:"passwordHash"`
+	EmailVerified bool      `bson:"emailVerified"`
 	SetupComplete bool      `bson:"setupComplete"`
 	IsAdmin       bool      `bson:"isAdmin"`
 	IsBanned      bool      `bson:"isBanned"`
 	CreatedAt     time.Time `bson:"createdAt"`
+
+	// TwoFactorEnabled mirrors whether the user has a confirmed second
+	// factor; the factor itself is kept by the twofactor package.
+	TwoFactorEnabled bool `bson:"twoFactorEnabled"`
 }
 
 type PublicUser struct {
@@ -48,6 +55,7 @@ type Repository interface {
 	CompleteSetup(ctx context.Context, id, username, displayName string) error
 	Update(ctx context.Context, id string, updates map[string]interface{}) (*User, error)
 	EnsureIndexes(ctx context.Context) error
+	BackfillEmails(ctx context.Context) (int, error)
 }
 
 type repository struct {
@@ -190,3 +198,55 @@ func (r *repository) EnsureIndexes(ctx context.Context) error {
 	_, err := r.coll.Indexes().CreateMany(ctx, indices)
 	return err
 }
+
+// BackfillEmails brings users saved before addresses were normalized and
+// verified in line with the rest. Users without an emailVerified field
+// predate password sign-ups: their address came from the sign-in provider
+// or from create_admin, so it counts as verified. Addresses are lowercased
+// so lookups by normalized address find them, unless another user already
+// has the lowercased address. It returns how many users changed.
+func (r *repository) BackfillEmails(ctx context.Context) (int, error) {
+	res, err := r.coll.UpdateMany(ctx,
+		bson.M{"emailVerified": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"emailVerified": true}},
+	)
+	if err != nil {
+		return 0, err
+	}
+	count := int(res.ModifiedCount)
+
+	// Users without a provider account must not share an empty oauthId, or
+	// the sparse unique index rejects the second one.
+	if _, err := r.coll.UpdateMany(ctx, bson.M{"oauthId": ""}, bson.M{"$unset": bson.M{"oauthId": ""}}); err != nil {
+		return count, err
+	}
+
+	opts := options.Find().SetProjection(bson.M{"_id": 1, "email": 1})
+	cursor, err := r.coll.Find(ctx, bson.M{"email": bson.M{"$regex": `[A-Z]|^\s|\s$`}}, opts)
+	if err != nil {
+		return count, err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var u User
+		if err := cursor.Decode(&u); err != nil {
+			return count, err
+		}
+		oid, err := bson.ObjectIDFromHex(u.ID)
+		if err != nil {
+			return count, err
+		}
+		email := strings.ToLower(strings.TrimSpace(u.Email))
+		_, err = r.coll.UpdateOne(ctx, bson.M{"_id": oid}, bson.M{"$set": bson.M{"email": email}})
+		if mongo.IsDuplicateKeyError(err) {
+			log.Printf("Kept the email address of user %s: %s belongs to another user", u.ID, email)
+			continue
+		}
+		if err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, cursor.Err()
+}
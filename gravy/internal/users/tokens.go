@@ -0,0 +1,143 @@
+package users
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+type TokenPurpose string
+
+const (
+	TokenPasswordReset TokenPurpose = "PASSWORD_RESET"
+	TokenVerifyEmail   TokenPurpose = "VERIFY_EMAIL"
+)
+
+// tokenResendInterval is how long a user has to wait before another token
+// for the same purpose is sent, so the endpoints can't be used to flood an
+// inbox.
+const tokenResendInterval = time.Minute
+
+var (
+	ErrTokenInvalid        = errors.New("this link is invalid or has expired")
+	ErrTokenRecentlyIssued = errors.New("an email was sent less than a minute ago, please wait before asking again")
+)
+
+// Token is a single-use token emailed to a user. Only its hash is stored;
+// the token itself exists only in the email.
+type Token struct {
+	Hash    string       `bson:"_id"`
+	UserID  string       `bson:"userId"`
+	Purpose TokenPurpose `bson:"purpose"`
+	// Email is the address the token was sent to.
+	Email     string    `bson:"email"`
+	CreatedAt time.Time `bson:"createdAt"`
+	ExpiresAt time.Time `bson:"expiresAt"`
+}
+
+type TokenRepository interface {
+	Issue(ctx context.Context, userID, email string, purpose TokenPurpose, ttl time.Duration) (string, error)
+	Peek(ctx context.Context, raw string, purpose TokenPurpose) (*Token, error)
+	Consume(ctx context.Context, raw string, purpose TokenPurpose) (*Token, error)
+	EnsureIndexes(ctx context.Context) error
+}
+
+type tokenRepository struct {
+	coll *mongo.Collection
+}
+
+func NewTokenRepository(db *mongo.Database) TokenRepository {
+	return &tokenRepository{
+		coll: db.Collection("user_tokens"),
+	}
+}
+
+// Issue creates a token and invalidates earlier ones for the same purpose,
+// so only the most recent email works. It returns ErrTokenRecentlyIssued if
+// the last one was issued moments ago.
+func (r *tokenRepository) Issue(ctx context.Context, userID, email string, purpose TokenPurpose, ttl time.Duration) (string, error) {
+	now := time.Now()
+	recent := bson.M{"userId": userID, "purpose": purpose, "createdAt": bson.M{"$gt": now.Add(-tokenResendInterval)}}
+	if err := r.coll.FindOne(ctx, recent).Err(); err == nil {
+		return "", ErrTokenRecentlyIssued
+	} else if err != mongo.ErrNoDocuments {
+		return "", err
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	raw := base64.RawURLEncoding.EncodeToString(b)
+
+	if _, err := r.coll.DeleteMany(ctx, bson.M{"userId": userID, "purpose": purpose}); err != nil {
+		return "", err
+	}
+	_, err := r.coll.InsertOne(ctx, Token{
+		Hash:      hashToken(raw),
+		UserID:    userID,
+		Purpose:   purpose,
+		Email:     email,
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	})
+	if err != nil {
+		return "", err
+	}
+	return raw, nil
+}
+
+// Peek returns a valid token without using it up.
+func (r *tokenRepository) Peek(ctx context.Context, raw string, purpose TokenPurpose) (*Token, error) {
+	var token Token
+	err := r.coll.FindOne(ctx, validToken(raw, purpose)).Decode(&token)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrTokenInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// Consume returns a valid token and deletes it, so it works only once even
+// if it is used twice at the same time.
+func (r *tokenRepository) Consume(ctx context.Context, raw string, purpose TokenPurpose) (*Token, error) {
+	var token Token
+	err := r.coll.FindOneAndDelete(ctx, validToken(raw, purpose)).Decode(&token)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrTokenInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func validToken(raw string, purpose TokenPurpose) bson.M {
+	return bson.M{"_id": hashToken(raw), "purpose": purpose, "expiresAt": bson.M{"$gt": time.Now()}}
+}
+
+func hashToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+func (r *tokenRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.coll.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "purpose", Value: 1}}},
+		{
+			Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	})
+	return err
+}
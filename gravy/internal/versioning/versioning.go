@@ -0,0 +1,31 @@
+package versioning
+
+import (
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Field is the document field holding the version counter. It starts at 1
+// and is incremented by every edit.
+const Field = "version"
+
+// ConflictError reports that a document changed since the caller read it.
+type ConflictError struct {
+	Resource       string
+	CurrentVersion int
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("%s was modified by someone else (current version %d)", e.Resource, e.CurrentVersion)
+}
+
+// Matches is the filter value for Field that accepts documents at expected.
+// Documents written before versioning have no version field and count as
+// version 0.
+func Matches(expected int) interface{} {
+	if expected == 0 {
+		return bson.M{"$in": bson.A{0, nil}}
+	}
+	return expected
+}
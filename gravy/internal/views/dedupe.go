@@ -0,0 +1,77 @@
+package views
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Deduper remembers which viewers have recently seen an article, so that
+// reloading a page does not count as another view.
+type Deduper interface {
+	// FirstView reports whether key has not been seen within window, and
+	// marks it as seen.
+	FirstView(ctx context.Context, key string, window time.Duration) (bool, error)
+}
+
+// MemoryDeduper keeps seen keys in process. It is enough when only one
+// server replica is running.
+type MemoryDeduper struct {
+	mu   sync.Mutex
+	seen map[string]time.Time
+}
+
+func NewMemoryDeduper() *MemoryDeduper {
+	d := &MemoryDeduper{seen: make(map[string]time.Time)}
+	go d.cleanupExpired()
+	return d
+}
+
+func (d *MemoryDeduper) FirstView(ctx context.Context, key string, window time.Duration) (bool, error) {
+	now := time.Now()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if expires, ok := d.seen[key]; ok && now.Before(expires) {
+		return false, nil
+	}
+	d.seen[key] = now.Add(window)
+	return true, nil
+}
+
+func (d *MemoryDeduper) cleanupExpired() {
+	for {
+		time.Sleep(time.Minute)
+
+		now := time.Now()
+		d.mu.Lock()
+		for key, expires := range d.seen {
+			if now.After(expires) {
+				delete(d.seen, key)
+			}
+		}
+		d.mu.Unlock()
+	}
+}
+
+// RedisDeduper shares seen keys between replicas.
+type RedisDeduper struct {
+	rdb *redis.Client
+}
+
+func NewRedisDeduper(addr string, password string) *RedisDeduper {
+	rdb := redis.NewClient(&redis.Options{
+		Addr:     addr,
+		Password: password,
+		DB:       0,
+	})
+
+	return &RedisDeduper{rdb: rdb}
+}
+
+func (d *RedisDeduper) FirstView(ctx context.Context, key string, window time.Duration) (bool, error) {
+	return d.rdb.SetNX(ctx, "views:seen:"+key, 1, window).Result()
+}
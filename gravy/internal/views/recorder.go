@@ -0,0 +1,229 @@
+package views
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"maps"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const (
+	queueSize     = 1024
+	flushInterval = 10 * time.Second
+)
+
+type view struct {
+	articleID string
+	viewer    string
+}
+
+// Recorder counts article views off the request path. Views are queued,
+// de-duplicated per viewer within the window and written in batches, so
+// recording a view never waits on the database.
+type Recorder struct {
+	repo   Repository
+	dedupe Deduper
+	window time.Duration
+	queue  chan view
+
+	// failed is the batch whose write last failed, retried as it is before
+	// anything newer is written. Only Run touches it.
+	failed *batch
+}
+
+// batch is one flush's worth of counts. Its ID lets the repository tell a
+// retry of the batch from new views.
+type batch struct {
+	id     string
+	at     time.Time
+	counts map[string]int64
+}
+
+func NewRecorder(repo Repository, dedupe Deduper, window time.Duration) *Recorder {
+	return &Recorder{
+		repo:   repo,
+		dedupe: dedupe,
+		window: window,
+		queue:  make(chan view, queueSize),
+	}
+}
+
+// Record queues a view of articleID by viewer, as returned by ViewerKey. If
+// the queue is full the view is dropped rather than slowing the caller down.
+func (r *Recorder) Record(articleID string, viewer string) {
+	if viewer == "" {
+		return
+	}
+	select {
+	case r.queue <- view{articleID: articleID, viewer: viewer}:
+	default:
+		log.Printf("views: queue full, dropping view of article %s", articleID)
+	}
+}
+
+// Run processes queued views until ctx is cancelled.
+func (r *Recorder) Run(ctx context.Context) {
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	pending := make(map[string]int64)
+	for {
+		select {
+		case <-ctx.Done():
+			r.flush(context.Background(), pending)
+			return
+		case v := <-r.queue:
+			first, err := r.dedupe.FirstView(ctx, v.articleID+":"+v.viewer, r.window)
+			if err != nil {
+				log.Printf("views: failed to de-duplicate view: %v", err)
+				continue
+			}
+			if first {
+				pending[v.articleID]++
+			}
+		case <-ticker.C:
+			r.flush(ctx, pending)
+		}
+	}
+}
+
+// flush writes the batch that failed last time, if any, and then the views
+// counted since. A failed batch keeps its ID and time, so retrying it only
+// adds the counts the failed write did not get to.
+func (r *Recorder) flush(ctx context.Context, pending map[string]int64) {
+	if r.failed != nil {
+		if err := r.write(ctx, r.failed); err != nil {
+			return
+		}
+		r.failed = nil
+	}
+	if len(pending) == 0 {
+		return
+	}
+	b := &batch{id: bson.NewObjectID().Hex(), at: time.Now(), counts: maps.Clone(pending)}
+	clear(pending)
+	if err := r.write(ctx, b); err != nil {
+		r.failed = b
+	}
+}
+
+func (r *Recorder) write(ctx context.Context, b *batch) error {
+	err := r.repo.Add(ctx, b.id, b.at, b.counts)
+	if err != nil {
+		log.Printf("views: failed to record %d articles' views: %v", len(b.counts), err)
+	}
+	return err
+}
+
+// ViewerKey identifies a viewer for de-duplication: the user ID when signed
+// in, otherwise a hash of the client IP so raw addresses are not stored.
+func ViewerKey(userID string, ip string) string {
+	if userID != "" {
+		return "u:" + userID
+	}
+	if ip == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(ip))
+	return "ip:" + hex.EncodeToString(sum[:12])
+}
+
+var ipCtxKey = &contextKey{"client-ip"}
+
+type contextKey struct {
+	name string
+}
+
+// Middleware makes the client IP available to resolvers through
+// IPFromContext. X-Forwarded-For and X-Real-IP are only believed when the
+// request comes from one of trustedProxies; anyone else could set them to
+// make each request look like a new visitor.
+func Middleware(trustedProxies []netip.Prefix) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), ipCtxKey, clientIP(r, trustedProxies))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+func IPFromContext(ctx context.Context) string {
+	ip, _ := ctx.Value(ipCtxKey).(string)
+	return ip
+}
+
+// ParseTrustedProxies parses a comma-separated list of IP addresses and
+// CIDR ranges, as given in TRUSTED_PROXIES.
+func ParseTrustedProxies(s string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, field := range strings.Split(s, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		if strings.Contains(field, "/") {
+			prefix, err := netip.ParsePrefix(field)
+			if err != nil {
+				return nil, fmt.Errorf("trusted proxy %q: %w", field, err)
+			}
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(field)
+		if err != nil {
+			return nil, fmt.Errorf("trusted proxy %q: %w", field, err)
+		}
+		addr = addr.Unmap()
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return prefixes, nil
+}
+
+func trusted(ip string, proxies []netip.Prefix) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, p := range proxies {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+func clientIP(r *http.Request, proxies []netip.Prefix) string {
+	remote := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(remote); err == nil {
+		remote = host
+	}
+	if !trusted(remote, proxies) {
+		return remote
+	}
+
+	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+		// Each proxy appends the address it received the request from, so
+		// the client is the last entry not added by one of our proxies.
+		hops := strings.Split(xff, ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(hops[i])
+			if hop != "" && (i == 0 || !trusted(hop, proxies)) {
+				return hop
+			}
+		}
+	}
+	if xri := strings.TrimSpace(r.Header.Get("X-Real-IP")); xri != "" {
+		return xri
+	}
+	return remote
+}
@@ -0,0 +1,163 @@
+package views
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+func TestClientIP(t *testing.T) {
+	proxies, err := ParseTrustedProxies("10.0.0.0/8, 127.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		xff        string
+		xri        string
+		want       string
+	}{
+		{"direct", "203.0.113.7:5000", "", "", "203.0.113.7"},
+		{"spoofed headers from an untrusted peer", "203.0.113.7:5000", "198.51.100.1", "198.51.100.2", "203.0.113.7"},
+		{"trusted proxy", "10.1.2.3:5000", "198.51.100.1", "", "198.51.100.1"},
+		{"spoofed first hop behind a trusted proxy", "10.1.2.3:5000", "1.2.3.4, 198.51.100.1", "", "198.51.100.1"},
+		{"chain of trusted proxies", "127.0.0.1:5000", "198.51.100.1, 10.0.0.5", "", "198.51.100.1"},
+		{"only trusted hops", "10.1.2.3:5000", "10.0.0.9", "", "10.0.0.9"},
+		{"X-Real-IP from a trusted proxy", "127.0.0.1:5000", "", "198.51.100.2", "198.51.100.2"},
+		{"trusted proxy without headers", "127.0.0.1:5000", "", "", "127.0.0.1"},
+		{"IPv6 peer", "[2001:db8::1]:5000", "198.51.100.1", "", "2001:db8::1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/query", nil)
+			r.RemoteAddr = tt.remoteAddr
+			if tt.xff != "" {
+				r.Header.Set("X-Forwarded-For", tt.xff)
+			}
+			if tt.xri != "" {
+				r.Header.Set("X-Real-IP", tt.xri)
+			}
+			if got := clientIP(r, proxies); got != tt.want {
+				t.Errorf("clientIP = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTrustedProxies(t *testing.T) {
+	if proxies, err := ParseTrustedProxies(""); err != nil || len(proxies) != 0 {
+		t.Errorf("empty list = %v, %v", proxies, err)
+	}
+	if _, err := ParseTrustedProxies("10.0.0.0/8,not-an-ip"); err == nil {
+		t.Error("expected an error for an invalid address")
+	}
+}
+
+// stagedRepo mimics the repository's three writes. Each stage remembers the
+// batches it applied, as the documents do, and failAt makes a stage fail
+// once after applying half its counts.
+type stagedRepo struct {
+	stages  [3]map[string]int64
+	applied [3]map[string]bool
+	failAt  int
+	batches []string
+}
+
+func newStagedRepo(failAt int) *stagedRepo {
+	r := &stagedRepo{failAt: failAt}
+	for i := range r.stages {
+		r.stages[i] = make(map[string]int64)
+		r.applied[i] = make(map[string]bool)
+	}
+	return r
+}
+
+func (r *stagedRepo) Add(_ context.Context, batchID string, _ time.Time, counts map[string]int64) error {
+	r.batches = append(r.batches, batchID)
+	for stage := range r.stages {
+		written := 0
+		for id, n := range counts {
+			key := batchID + ":" + id
+			if r.applied[stage][key] {
+				continue
+			}
+			if stage == r.failAt && written == len(counts)/2 {
+				r.failAt = -1
+				return errors.New("write failed")
+			}
+			r.stages[stage][id] += n
+			r.applied[stage][key] = true
+			written++
+		}
+	}
+	return nil
+}
+
+func (r *stagedRepo) BackfillArticleCounts(context.Context) (int, error) { return 0, nil }
+
+func (r *stagedRepo) Trending(context.Context, time.Time, int) ([]Trend, error) { return nil, nil }
+
+func (r *stagedRepo) EnsureIndexes(context.Context) error { return nil }
+
+func TestFlushRetriesFailedBatch(t *testing.T) {
+	for failAt := range 3 {
+		t.Run(fmt.Sprintf("failure in stage %d", failAt+1), func(t *testing.T) {
+			repo := newStagedRepo(failAt)
+			rec := NewRecorder(repo, nil, time.Minute)
+
+			pending := map[string]int64{"a": 3, "b": 2, "c": 1, "d": 4}
+			rec.flush(context.Background(), pending)
+			if rec.failed == nil {
+				t.Fatal("failed batch was not kept")
+			}
+			if len(pending) != 0 {
+				t.Errorf("pending still holds %v after being batched", pending)
+			}
+
+			// Views counted while the batch waits go into a batch of their own.
+			pending["a"] = 5
+			rec.flush(context.Background(), pending)
+			if rec.failed != nil {
+				t.Fatal("failed batch was kept after a successful retry")
+			}
+			if len(repo.batches) != 3 || repo.batches[0] != repo.batches[1] || repo.batches[1] == repo.batches[2] {
+				t.Errorf("batches written = %v, want the failed one twice and then a new one", repo.batches)
+			}
+
+			want := map[string]int64{"a": 8, "b": 2, "c": 1, "d": 4}
+			for stage, got := range repo.stages {
+				if !maps.Equal(got, want) {
+					t.Errorf("stage %d counted %v, want %v", stage+1, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestAddOnce(t *testing.T) {
+	model := addOnce(bson.M{"articleId": "a"}, "batches", "b1", bson.M{"$inc": bson.M{"count": int64(2)}}).(*mongo.UpdateOneModel)
+	filter := model.Filter.(bson.M)
+	if filter["articleId"] != "a" {
+		t.Errorf("filter lost the document key: %v", filter)
+	}
+	if guard, _ := filter["batches"].(bson.M); guard["$ne"] != "b1" {
+		t.Errorf("filter does not skip documents that have the batch: %v", filter)
+	}
+	update := model.Update.(bson.M)
+	push, _ := update["$push"].(bson.M)
+	if each, _ := push["batches"].(bson.M); each["$slice"] != -batchMemory {
+		t.Errorf("update does not record the batch: %v", update)
+	}
+	if model.Upsert != nil && *model.Upsert {
+		t.Error("guarded update must not upsert")
+	}
+}
@@ -0,0 +1,218 @@
+package views
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// Day is one article's view count for one UTC day.
+type Day struct {
+	ID        string    `bson:"_id,omitempty"`
+	ArticleID string    `bson:"articleId"`
+	Day       time.Time `bson:"day"`
+	Count     int64     `bson:"count"`
+}
+
+// Total is an article's all-time view count.
+type Total struct {
+	ID        string    `bson:"_id,omitempty"`
+	ArticleID string    `bson:"articleId"`
+	Count     int64     `bson:"count"`
+	UpdatedAt time.Time `bson:"updatedAt"`
+}
+
+// Trend is an article's view count over a trending window.
+type Trend struct {
+	ArticleID string `bson:"_id"`
+	Views     int64  `bson:"views"`
+}
+
+type Repository interface {
+	// Add adds counts, keyed by article ID, to the rollup for the UTC day
+	// containing at and to the all-time totals. The totals are copied onto
+	// the articles too, so listing articles needs no extra lookups.
+	// batchID identifies the counts: adding the same batch again, after a
+	// failure part of the way through, only adds what was not added yet.
+	Add(ctx context.Context, batchID string, at time.Time, counts map[string]int64) error
+	BackfillArticleCounts(ctx context.Context) (int, error)
+	Trending(ctx context.Context, since time.Time, limit int) ([]Trend, error)
+	EnsureIndexes(ctx context.Context) error
+}
+
+// batchMemory is how many recent batch IDs each document remembers. A
+// failed batch is retried on the next flush, long before it is forgotten.
+const batchMemory = 50
+
+type repository struct {
+	days     *mongo.Collection
+	totals   *mongo.Collection
+	articles *mongo.Collection
+}
+
+func NewRepository(db *mongo.Database) Repository {
+	return &repository{
+		days:     db.Collection("article_view_days"),
+		totals:   db.Collection("article_view_totals"),
+		articles: db.Collection("articles"),
+	}
+}
+
+func StartOfDay(t time.Time) time.Time {
+	return t.UTC().Truncate(24 * time.Hour)
+}
+
+func (r *repository) Add(ctx context.Context, batchID string, at time.Time, counts map[string]int64) error {
+	if len(counts) == 0 {
+		return nil
+	}
+	day := StartOfDay(at)
+	now := time.Now()
+
+	var dayDocs, dayWrites, totalDocs, totalWrites, articleWrites []mongo.WriteModel
+	for articleID, count := range counts {
+		dayFilter := bson.M{"articleId": articleID, "day": day}
+		dayDocs = append(dayDocs, createMissing(dayFilter))
+		dayWrites = append(dayWrites, addOnce(dayFilter, "batches", batchID,
+			bson.M{"$inc": bson.M{"count": count}}))
+
+		totalFilter := bson.M{"articleId": articleID}
+		totalDocs = append(totalDocs, createMissing(totalFilter))
+		totalWrites = append(totalWrites, addOnce(totalFilter, "batches", batchID,
+			bson.M{"$inc": bson.M{"count": count}, "$set": bson.M{"updatedAt": now}}))
+
+		if idObj, err := bson.ObjectIDFromHex(articleID); err == nil {
+			articleWrites = append(articleWrites, addOnce(bson.M{"_id": idObj}, "viewBatches", batchID,
+				bson.M{"$inc": bson.M{"viewCount": count}}))
+		}
+	}
+
+	// The counters are created first and only then incremented, so the
+	// increments need no upsert: an upsert that finds the batch already
+	// applied would try to insert a second document for the same key.
+	opts := options.BulkWrite().SetOrdered(false)
+	if _, err := r.days.BulkWrite(ctx, dayDocs, opts); err != nil && !onlyDuplicateKeys(err) {
+		return err
+	}
+	if _, err := r.days.BulkWrite(ctx, dayWrites, opts); err != nil {
+		return err
+	}
+	if _, err := r.totals.BulkWrite(ctx, totalDocs, opts); err != nil && !onlyDuplicateKeys(err) {
+		return err
+	}
+	if _, err := r.totals.BulkWrite(ctx, totalWrites, opts); err != nil {
+		return err
+	}
+	if len(articleWrites) == 0 {
+		return nil
+	}
+	_, err := r.articles.BulkWrite(ctx, articleWrites, opts)
+	return err
+}
+
+// createMissing creates a zero counter for filter unless one exists.
+func createMissing(filter bson.M) mongo.WriteModel {
+	return mongo.NewUpdateOneModel().
+		SetFilter(filter).
+		SetUpdate(bson.M{"$setOnInsert": bson.M{"count": int64(0)}}).
+		SetUpsert(true)
+}
+
+// addOnce applies update to the document matching filter unless the batch
+// is already listed in its field of recent batches, and lists it.
+func addOnce(filter bson.M, field, batchID string, update bson.M) mongo.WriteModel {
+	guarded := bson.M{field: bson.M{"$ne": batchID}}
+	for k, v := range filter {
+		guarded[k] = v
+	}
+	update["$push"] = bson.M{field: bson.M{"$each": bson.A{batchID}, "$slice": -batchMemory}}
+	return mongo.NewUpdateOneModel().SetFilter(guarded).SetUpdate(update)
+}
+
+// onlyDuplicateKeys reports whether a bulk write failed only because other
+// writers created some of the documents first.
+func onlyDuplicateKeys(err error) bool {
+	var bwe mongo.BulkWriteException
+	if !errors.As(err, &bwe) || bwe.WriteConcernError != nil || len(bwe.WriteErrors) == 0 {
+		return false
+	}
+	for _, we := range bwe.WriteErrors {
+		if !we.HasErrorCode(11000) {
+			return false
+		}
+	}
+	return true
+}
+
+// BackfillArticleCounts copies the all-time totals onto articles that were
+// viewed before the counts were kept on the article.
+func (r *repository) BackfillArticleCounts(ctx context.Context) (int, error) {
+	cursor, err := r.totals.Find(ctx, bson.M{})
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	count := 0
+	for cursor.Next(ctx) {
+		var total Total
+		if err := cursor.Decode(&total); err != nil {
+			return count, err
+		}
+		idObj, err := bson.ObjectIDFromHex(total.ArticleID)
+		if err != nil {
+			continue
+		}
+		res, err := r.articles.UpdateOne(ctx,
+			bson.M{"_id": idObj, "viewCount": bson.M{"$exists": false}},
+			bson.M{"$set": bson.M{"viewCount": total.Count}},
+		)
+		if err != nil {
+			return count, err
+		}
+		count += int(res.ModifiedCount)
+	}
+	return count, cursor.Err()
+}
+
+// Trending returns the most viewed articles in the daily rollups since
+// since, most viewed first.
+func (r *repository) Trending(ctx context.Context, since time.Time, limit int) ([]Trend, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"day": bson.M{"$gte": StartOfDay(since)}}}},
+		{{Key: "$group", Value: bson.M{"_id": "$articleId", "views": bson.M{"$sum": "$count"}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "views", Value: -1}, {Key: "_id", Value: 1}}}},
+		{{Key: "$limit", Value: limit}},
+	}
+	cursor, err := r.days.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	var trends []Trend
+	if err := cursor.All(ctx, &trends); err != nil {
+		return nil, err
+	}
+	return trends, nil
+}
+
+func (r *repository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.days.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "articleId", Value: 1}, {Key: "day", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{Keys: bson.D{{Key: "day", Value: 1}}},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = r.totals.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "articleId", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
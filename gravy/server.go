@@ -6,27 +6,45 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/99designs/gqlgen/graphql"
 	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/extension"
+	"github.com/99designs/gqlgen/graphql/handler/lru"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
 	"github.com/99designs/gqlgen/graphql/playground"
+	"github.com/gorilla/websocket"
 	"github.com/joho/godotenv"
 	"github.com/pranava-mohan/wikinitt/gravy/graph"
 	"github.com/pranava-mohan/wikinitt/gravy/graph/model"
 	"github.com/pranava-mohan/wikinitt/gravy/internal/articles"
 	"github.com/pranava-mohan/wikinitt/gravy/internal/auth"
+	"github.com/pranava-mohan/wikinitt/gravy/internal/backlinks"
 	"github.com/pranava-mohan/wikinitt/gravy/internal/categories"
 	"github.com/pranava-mohan/wikinitt/gravy/internal/community"
 	"github.com/pranava-mohan/wikinitt/gravy/internal/db"
+	"github.com/pranava-mohan/wikinitt/gravy/internal/export"
+	"github.com/pranava-mohan/wikinitt/gravy/internal/feeds"
+	"github.com/pranava-mohan/wikinitt/gravy/internal/lease"
+	"github.com/pranava-mohan/wikinitt/gravy/internal/mailer"
 	"github.com/pranava-mohan/wikinitt/gravy/internal/maplocation"
+	"github.com/pranava-mohan/wikinitt/gravy/internal/oidc"
+	"github.com/pranava-mohan/wikinitt/gravy/internal/preview"
+	"github.com/pranava-mohan/wikinitt/gravy/internal/pubsub"
 	"github.com/pranava-mohan/wikinitt/gravy/internal/rag"
 	"github.com/pranava-mohan/wikinitt/gravy/internal/ratelimit"
+	"github.com/pranava-mohan/wikinitt/gravy/internal/scheduler"
 	"github.com/pranava-mohan/wikinitt/gravy/internal/search"
+	"github.com/pranava-mohan/wikinitt/gravy/internal/sessions"
+	"github.com/pranava-mohan/wikinitt/gravy/internal/twofactor"
 	"github.com/pranava-mohan/wikinitt/gravy/internal/uploader"
 	"github.com/pranava-mohan/wikinitt/gravy/internal/users"
+	"github.com/pranava-mohan/wikinitt/gravy/internal/views"
 	"github.com/rs/cors"
+	"github.com/vektah/gqlparser/v2/ast"
 	"golang.org/x/time/rate"
 )
 
@@ -65,21 +83,68 @@ func main() {
 	searchClient := search.NewClient(meiliHost, meiliKey)
 
 	userRepo := users.NewRepository(database)
+	userTokenRepo := users.NewTokenRepository(database)
+	sessionRepo := sessions.NewRepository(database)
+	twoFactorRepo := twofactor.NewRepository(database)
 	articleRepo := articles.NewRepository(database, searchClient)
 	categoryRepo := categories.NewRepository(database)
 	communityRepo := community.NewRepository(database, searchClient)
 	mapLocationRepo := maplocation.NewRepository(database)
+	backlinkJobRepo := backlinks.NewRepository(database)
+	viewRepo := views.NewRepository(database)
+	exportJobRepo := export.NewRepository(database)
 
 	ctx := context.Background()
+	if n, err := userRepo.BackfillEmails(ctx); err != nil {
+		log.Printf("Failed to backfill user email addresses: %v", err)
+	} else if n > 0 {
+		log.Printf("Backfilled email addresses for %d users", n)
+	}
 	if err := userRepo.EnsureIndexes(ctx); err != nil {
 		log.Printf("Failed to create user indexes: %v", err)
 	}
+	if err := userTokenRepo.EnsureIndexes(ctx); err != nil {
+		log.Printf("Failed to create user token indexes: %v", err)
+	}
+	if err := sessionRepo.EnsureIndexes(ctx); err != nil {
+		log.Printf("Failed to create session indexes: %v", err)
+	}
+	if err := twoFactorRepo.EnsureIndexes(ctx); err != nil {
+		log.Printf("Failed to create two-factor indexes: %v", err)
+	}
 	if err := articleRepo.EnsureIndexes(ctx); err != nil {
 		log.Printf("Failed to create article indexes: %v", err)
 	}
+	if n, err := articleRepo.BackfillExcerpts(ctx); err != nil {
+		log.Printf("Failed to backfill article excerpts: %v", err)
+	} else if n > 0 {
+		log.Printf("Backfilled excerpts for %d articles", n)
+	}
+	if n, err := articleRepo.BackfillLinks(ctx); err != nil {
+		log.Printf("Failed to backfill article links: %v", err)
+	} else if n > 0 {
+		log.Printf("Backfilled links for %d articles", n)
+	}
 	if err := communityRepo.EnsureIndexes(ctx); err != nil {
 		log.Printf("Failed to create community indexes: %v", err)
 	}
+	if err := categoryRepo.EnsureIndexes(ctx); err != nil {
+		log.Printf("Failed to create category indexes: %v", err)
+	}
+	if err := backlinkJobRepo.EnsureIndexes(ctx); err != nil {
+		log.Printf("Failed to create backlink job indexes: %v", err)
+	}
+	if err := viewRepo.EnsureIndexes(ctx); err != nil {
+		log.Printf("Failed to create view indexes: %v", err)
+	}
+	if n, err := viewRepo.BackfillArticleCounts(ctx); err != nil {
+		log.Printf("Failed to backfill article view counts: %v", err)
+	} else if n > 0 {
+		log.Printf("Backfilled view counts for %d articles", n)
+	}
+	if err := exportJobRepo.EnsureIndexes(ctx); err != nil {
+		log.Printf("Failed to create export job indexes: %v", err)
+	}
 
 	cldName := os.Getenv("CLOUDINARY_CLOUD_NAME")
 	cldKey := os.Getenv("CLOUDINARY_API_KEY")
@@ -94,15 +159,59 @@ func main() {
 		log.Fatalf("Failed to create Cloudinary uploader: %v", err)
 	}
 
+	isProduction := strings.ToLower(os.Getenv("GO_ENV")) == "production"
+
+	var mailService mailer.Mailer
+	if smtpHost := os.Getenv("SMTP_HOST"); smtpHost != "" {
+		smtpPort := 587
+		if p := os.Getenv("SMTP_PORT"); p != "" {
+			smtpPort, err = strconv.Atoi(p)
+			if err != nil {
+				log.Fatalf("Invalid SMTP_PORT %q", p)
+			}
+		}
+		mailService, err = mailer.NewSMTPMailer(mailer.SMTPConfig{
+			Host:     smtpHost,
+			Port:     smtpPort,
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     os.Getenv("MAIL_FROM"),
+		})
+		if err != nil {
+			log.Fatalf("Failed to create SMTP mailer: %v", err)
+		}
+		log.Printf("Sending email through %s:%d", smtpHost, smtpPort)
+	} else if mailDir := os.Getenv("MAIL_DIR"); mailDir != "" {
+		mailService, err = mailer.NewFileSink(mailDir)
+		if err != nil {
+			log.Fatalf("Failed to create mail directory: %v", err)
+		}
+		log.Printf("SMTP_HOST not set, emails are written to %s", mailDir)
+	} else if isProduction {
+		// The log sink would put password reset links in the server logs.
+		log.Fatal("SMTP_HOST is required in production")
+	} else {
+		log.Println("SMTP_HOST not set, emails are written to the log")
+		mailService = mailer.NewLogSink()
+	}
+
 	redisHost := os.Getenv("REDIS_HOST")
 	redisPort := os.Getenv("REDIS_PORT")
 	var ragClient rag.Client
+	var broker pubsub.Broker
+	var viewDeduper views.Deduper
 	if redisHost != "" && redisPort != "" {
 		redisAddr := fmt.Sprintf("%s:%s", redisHost, redisPort)
 		ragClient = rag.NewRedisClient(redisAddr, "")
 		log.Printf("Initialized Redis RAG client at %s", redisAddr)
+		broker = pubsub.NewRedisBroker(redisAddr, "")
+		log.Printf("Initialized Redis pub/sub broker at %s", redisAddr)
+		viewDeduper = views.NewRedisDeduper(redisAddr, "")
 	} else {
 		log.Println("REDIS_HOST or REDIS_PORT not set, RAG sync disabled")
+		broker = pubsub.NewMemoryBroker()
+		log.Println("Using in-process pub/sub broker")
+		viewDeduper = views.NewMemoryDeduper()
 	}
 
 	go func() {
@@ -126,16 +235,7 @@ func main() {
 
 			docs := make([]interface{}, len(unindexedArticles))
 			for i, a := range unindexedArticles {
-				docs[i] = map[string]interface{}{
-					"id":        a.ID,
-					"title":     a.Title,
-					"content":   a.Content,
-					"slug":      a.Slug,
-					"category":  a.Category,
-					"thumbnail": a.Thumbnail,
-					"authorID":  a.AuthorID,
-					"createdAt": a.CreatedAt.Unix(),
-				}
+				docs[i] = articles.SearchDocument(a)
 			}
 			if err := searchClient.IndexArticles(ctx, docs); err != nil {
 				log.Printf("Failed to index articles batch: %v", err)
@@ -328,16 +428,83 @@ func main() {
 		log.Println("Meilisearch indexing reference complete.")
 	}()
 
+	backlinkRunner := backlinks.NewRunner(backlinkJobRepo, articleRepo, 4)
+	go backlinkRunner.Run(context.Background())
+
+	articleScheduler := scheduler.NewArticleScheduler(
+		articleRepo,
+		backlinkRunner,
+		ragClient,
+		lease.New(database, "article-scheduler", 2*time.Minute),
+		30*time.Second,
+	)
+	go articleScheduler.Run(context.Background())
+
+	keySet, err := auth.Keys()
+	if err != nil {
+		log.Fatalf("Failed to load JWT keys: %v", err)
+	}
+	sessionStore := auth.NewSessions(sessionRepo)
+
+	totpIssuer := os.Getenv("TOTP_ISSUER")
+	if totpIssuer == "" {
+		totpIssuer = "WikiNITT"
+	}
+	twoFactorService := twofactor.NewService(twoFactorRepo, totpIssuer)
+	requireAdmin2FA := os.Getenv("REQUIRE_ADMIN_2FA") == "true"
+
+	var oidcService *oidc.Service
+	oidcConfig, oidcEnabled := oidc.ConfigFromEnv()
+	if oidcEnabled {
+		if err := oidcConfig.Validate(); err != nil {
+			log.Fatal(err)
+		}
+		oidcRepo := oidc.NewRepository(database)
+		if err := oidcRepo.EnsureIndexes(ctx); err != nil {
+			log.Printf("Failed to create OIDC indexes: %v", err)
+		}
+		oidcService = oidc.NewService(oidc.NewProvider(oidcConfig), oidcRepo)
+	} else {
+		log.Println("OIDC_ISSUER not set, OpenID Connect login disabled")
+	}
+
+	previewService := preview.NewService(articleRepo, communityRepo)
+
+	exportRunner := export.NewRunner(exportJobRepo, export.NewBuilder(articleRepo, categoryRepo))
+	go exportRunner.Run(context.Background())
+
+	viewRecorder := views.NewRecorder(viewRepo, viewDeduper, 30*time.Minute)
+	go viewRecorder.Run(context.Background())
+	trustedProxies, err := views.ParseTrustedProxies(os.Getenv("TRUSTED_PROXIES"))
+	if err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
+
 	c := graph.Config{
 		Resolvers: &graph.Resolver{
 			UserRepo:        userRepo,
+			UserTokenRepo:   userTokenRepo,
+			Mailer:          mailService,
+			Sessions:        sessionStore,
+			OIDC:            oidcService,
+			SignUps:         oidcConfig,
+			TwoFactor:       twoFactorService,
+			RequireAdmin2FA: requireAdmin2FA,
 			ArticleRepo:     articleRepo,
+			BacklinkJobRepo: backlinkJobRepo,
+			BacklinkRunner:  backlinkRunner,
 			CategoryRepo:    categoryRepo,
 			CommunityRepo:   communityRepo,
 			MapLocationRepo: mapLocationRepo,
 			Uploader:        uploaderService,
 			SearchClient:    searchClient,
 			RagClient:       ragClient,
+			Broker:          broker,
+			ViewRepo:        viewRepo,
+			ViewRecorder:    viewRecorder,
+			Previews:        previewService,
+			ExportRepo:      exportJobRepo,
+			ExportRunner:    exportRunner,
 		},
 	}
 	c.Directives.Auth = func(ctx context.Context, obj interface{}, next graphql.Resolver, requires *model.Role) (interface{}, error) {
@@ -346,17 +513,18 @@ func main() {
 			return nil, fmt.Errorf("access denied: not authenticated")
 		}
 
-		if requires != nil && *requires == model.RoleAdmin && !user.IsAdmin {
-			return nil, fmt.Errorf("access denied: admins only")
+		if requires != nil && *requires == model.RoleAdmin {
+			if !user.IsAdmin {
+				return nil, fmt.Errorf("access denied: admins only")
+			}
+			if requireAdmin2FA && !user.TwoFactorEnabled {
+				return nil, fmt.Errorf("access denied: enable two-factor authentication to use admin features")
+			}
 		}
 
 		return next(ctx)
 	}
 
-	srv := handler.NewDefaultServer(graph.NewExecutableSchema(c))
-
-	isProduction := strings.ToLower(os.Getenv("GO_ENV")) == "production"
-
 	var allowedOrigins []string
 	if isProduction {
 		allowedOrigins = []string{"https://wikinitt.netlify.app"}
@@ -366,6 +534,44 @@ func main() {
 		log.Println("Running in DEVELOPMENT mode")
 	}
 
+	srv := handler.New(graph.NewExecutableSchema(c))
+	srv.SetErrorPresenter(graph.ErrorPresenter)
+
+	srv.AddTransport(transport.Websocket{
+		KeepAlivePingInterval: 10 * time.Second,
+		InitFunc:              auth.WebsocketInitFunc(userRepo, sessionStore),
+		CloseFunc:             auth.WebsocketCloseFunc,
+		Upgrader: websocket.Upgrader{
+			CheckOrigin: func(r *http.Request) bool {
+				origin := r.Header.Get("Origin")
+				if origin == "" {
+					return true
+				}
+				for _, allowed := range allowedOrigins {
+					if origin == allowed {
+						return true
+					}
+				}
+				return false
+			},
+		},
+	})
+	srv.AddTransport(transport.SSE{KeepAlivePingInterval: 10 * time.Second})
+	srv.AddTransport(transport.Options{})
+	srv.AddTransport(transport.GET{})
+	srv.AddTransport(transport.POST{})
+	srv.AddTransport(transport.MultipartForm{})
+
+	srv.SetQueryCache(lru.New[*ast.QueryDocument](1000))
+	srv.AroundResponses(func(ctx context.Context, next graphql.ResponseHandler) *graphql.Response {
+		return next(graph.WithRenderCache(ctx))
+	})
+
+	srv.Use(extension.Introspection{})
+	srv.Use(extension.AutomaticPersistedQuery{
+		Cache: lru.New[string](100),
+	})
+
 	corsMiddleware := cors.New(cors.Options{
 		AllowedOrigins:   allowedOrigins,
 		AllowedMethods:   []string{http.MethodGet, http.MethodPost, http.MethodOptions},
@@ -386,7 +592,16 @@ func main() {
 		log.Printf("GraphQL playground available at http://localhost:%s/", port)
 	}
 
-	mux.Handle("/query", auth.Middleware(userRepo)(srv))
+	mux.Handle("/query", views.Middleware(trustedProxies)(auth.Middleware(userRepo, sessionStore)(oidc.Middleware(isProduction)(srv))))
+
+	feedHandler := feeds.NewHandler(articleRepo, categoryRepo, communityRepo, userRepo)
+	mux.HandleFunc("/sitemap.xml", feedHandler.Sitemap)
+	mux.HandleFunc("/feeds/articles.atom", feedHandler.ArticlesFeed)
+	mux.HandleFunc("/feeds/groups/", feedHandler.GroupFeed)
+
+	mux.Handle("/og/", auth.Middleware(userRepo, sessionStore)(previewService.Handler()))
+	mux.Handle("/exports/", export.DownloadHandler(exportJobRepo))
+	mux.Handle("/.well-known/jwks.json", keySet.JWKSHandler())
 
 	var finalHandler http.Handler = mux
 
@@ -396,7 +611,7 @@ func main() {
 		rateLimiter := ratelimit.NewIPRateLimiter(rate.Limit(10), 20)
 		finalHandler = ratelimit.Middleware(rateLimiter)(finalHandler)
 
-		finalHandler = http.TimeoutHandler(finalHandler, 30*time.Second, `{"errors":[{"message":"Request timeout"}]}`)
+		finalHandler = withTimeout(finalHandler, 30*time.Second)
 
 		finalHandler = recoveryMiddleware(finalHandler)
 	}
@@ -418,3 +633,19 @@ func recoveryMiddleware(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
+
+// withTimeout applies http.TimeoutHandler to regular requests only. Websocket
+// upgrades and SSE streams are long-lived and need the underlying
+// http.Hijacker / http.Flusher, which TimeoutHandler does not expose.
+func withTimeout(next http.Handler, timeout time.Duration) http.Handler {
+	timeoutHandler := http.TimeoutHandler(next, timeout, `{"errors":[{"message":"Request timeout"}]}`)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		isWebsocket := strings.EqualFold(r.Header.Get("Upgrade"), "websocket")
+		isEventStream := strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+		if isWebsocket || isEventStream {
+			next.ServeHTTP(w, r)
+			return
+		}
+		timeoutHandler.ServeHTTP(w, r)
+	})
+}
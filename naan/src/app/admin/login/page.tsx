@@ -7,6 +7,8 @@ import { signIn, useSession } from "next-auth/react";
 export default function AdminLogin() {
   const [email, setEmail] = useState("");
   const [password, setPassword] = useState("");
+  const [code, setCode] = useState("");
+  const [needsCode, setNeedsCode] = useState(false);
   const [error, setError] = useState("");
   const router = useRouter();
   const { data: session } = useSession();
@@ -26,11 +28,15 @@ export default function AdminLogin() {
       const result = await signIn("credentials", {
         email,
         password,
+        code,
         redirect: false,
       });
 
-      if (result?.error) {
-        setError("Invalid credentials");
+      if (result?.code === "two_factor_required") {
+        setNeedsCode(true);
+        setError("Enter the code from your authenticator app");
+      } else if (result?.error) {
+        setError(needsCode ? "Invalid credentials or code" : "Invalid credentials");
       } else {
         router.push("/admin/users");
       }
@@ -70,6 +76,22 @@ export default function AdminLogin() {
               required
             />
           </div>
+          {needsCode && (
+            <div>
+              <label className="block text-sm font-medium text-gray-700">
+                Two-factor code
+              </label>
+              <input
+                type="text"
+                inputMode="numeric"
+                autoComplete="one-time-code"
+                value={code}
+                onChange={(e) => setCode(e.target.value)}
+                className="mt-1 block w-full rounded-md border border-gray-300 p-2 shadow-sm focus:border-blue-500 focus:outline-none focus:ring-1 focus:ring-blue-500"
+                required
+              />
+            </div>
+          )}
           <button
             type="submit"
             className="w-full rounded-md bg-blue-600 px-4 py-2 text-white hover:bg-blue-700 focus:outline-none focus:ring-2 focus:ring-blue-500 focus:ring-offset-2"
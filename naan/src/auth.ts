@@ -1,11 +1,54 @@
-import NextAuth, { User } from "next-auth";
+import NextAuth, { CredentialsSignin, User } from "next-auth";
+import { JWT } from "next-auth/jwt";
 import Credentials from "next-auth/providers/credentials";
 import Google from "next-auth/providers/google";
 import { GraphQLClient } from "graphql-request";
 import { gql } from "@/gql";
 import { ADMIN_LOGIN_MUTATION } from "@/gql/admin";
+import {
+  AuthPayload,
+  COMPLETE_TWO_FACTOR_LOGIN_MUTATION,
+  LoginResult,
+  REFRESH_TOKEN_MUTATION,
+  SIGN_IN_MUTATION,
+} from "@/gql/auth";
 import { GetCurrentUserQuery } from "@/gql/graphql";
 
+// Access tokens are refreshed this long before they expire, so requests
+// made with the session's token do not race its expiry.
+const REFRESH_MARGIN_MS = 60 * 1000;
+
+class TwoFactorRequired extends CredentialsSignin {
+  code = "two_factor_required";
+}
+
+function applyTokens(target: JWT | User, payload: AuthPayload) {
+  target.backendToken = payload.accessToken;
+  target.refreshToken = payload.refreshToken;
+  target.backendTokenExpiresAt = Date.parse(payload.accessTokenExpiresAt);
+}
+
+async function refreshBackendToken(token: JWT): Promise<JWT> {
+  const client = new GraphQLClient(process.env.NEXT_PUBLIC_GRAPHQL_API_URL!);
+  try {
+    const data = await client.request<{ refreshToken: AuthPayload }>(
+      REFRESH_TOKEN_MUTATION,
+      { token: token.refreshToken },
+    );
+    applyTokens(token, data.refreshToken);
+    delete token.error;
+  } catch (error) {
+    // The session was revoked or the refresh token already used; the user
+    // has to sign in again.
+    console.error("Token refresh failed:", error);
+    delete token.backendToken;
+    delete token.refreshToken;
+    delete token.backendTokenExpiresAt;
+    token.error = "RefreshTokenError";
+  }
+  return token;
+}
+
 export const { handlers, signIn, signOut, auth } = NextAuth({
   providers: [
     Credentials({
@@ -13,6 +56,7 @@ export const { handlers, signIn, signOut, auth } = NextAuth({
       credentials: {
         email: { label: "Email", type: "email" },
         password: { label: "Password", type: "password" },
+        code: { label: "Two-factor code", type: "text" },
       },
       async authorize(credentials) {
         if (!credentials?.email || !credentials?.password) return null;
@@ -22,7 +66,7 @@ export const { handlers, signIn, signOut, auth } = NextAuth({
         );
 
         try {
-          const data = await client.request<{ login: string }>(
+          const data = await client.request<{ login: LoginResult }>(
             ADMIN_LOGIN_MUTATION,
             {
               input: {
@@ -32,18 +76,32 @@ export const { handlers, signIn, signOut, auth } = NextAuth({
             },
           );
 
-          if (data.login) {
-            return {
+          let payload = data.login.auth;
+          if (!payload && data.login.challenge) {
+            if (!credentials.code) throw new TwoFactorRequired();
+            const completed = await client.request<{
+              completeTwoFactorLogin: AuthPayload;
+            }>(COMPLETE_TWO_FACTOR_LOGIN_MUTATION, {
+              challenge: data.login.challenge.token,
+              code: credentials.code,
+            });
+            payload = completed.completeTwoFactorLogin;
+          }
+
+          if (payload) {
+            const user: User = {
               id: "admin",
               email: credentials.email as string,
               name: "Admin",
-              backendToken: data.login,
               isAdmin: true,
               setupComplete: true,
             };
+            applyTokens(user, payload);
+            return user;
           }
           return null;
         } catch (error) {
+          if (error instanceof CredentialsSignin) throw error;
           console.error("Admin Login Failed:", error);
           return null;
         }
@@ -75,23 +133,21 @@ export const { handlers, signIn, signOut, auth } = NextAuth({
       if (user) {
         if (account?.provider === "credentials") {
           token.backendToken = (user as User).backendToken;
+          token.refreshToken = (user as User).refreshToken;
+          token.backendTokenExpiresAt = (user as User).backendTokenExpiresAt;
           token.isAdmin = true;
           token.setupComplete = true;
           return token;
         }
 
-        const mutation = gql(`
-        mutation SignIn($input: NewUser!) {
-          signIn(input: $input)
-        }
-          `);
-
         const graphQLClient = new GraphQLClient(
           process.env.NEXT_PUBLIC_GRAPHQL_API_URL!,
         );
 
         try {
-          const response = await graphQLClient.request(mutation, {
+          const response = await graphQLClient.request<{
+            signIn: AuthPayload;
+          }>(SIGN_IN_MUTATION, {
             input: {
               id: String(profile!.sub || user.id),
               name: user.name!,
@@ -101,7 +157,7 @@ export const { handlers, signIn, signOut, auth } = NextAuth({
               machineToken: process.env.MACHINE_TOKEN!,
             },
           });
-          token.backendToken = response.signIn;
+          applyTokens(token, response.signIn);
 
           const userQuery = gql(`
             query GetCurrentUser {
@@ -143,6 +199,14 @@ export const { handlers, signIn, signOut, auth } = NextAuth({
         }
       }
 
+      if (
+        token.refreshToken &&
+        token.backendTokenExpiresAt &&
+        Date.now() > token.backendTokenExpiresAt - REFRESH_MARGIN_MS
+      ) {
+        token = await refreshBackendToken(token);
+      }
+
       if (token.backendToken && !token.id) {
         const userQuery = gql(`
             query GetCurrentUser {
@@ -187,6 +251,9 @@ export const { handlers, signIn, signOut, auth } = NextAuth({
       if (token.backendToken) {
         session.backendToken = token.backendToken as string;
       }
+      if (token.error) {
+        session.error = token.error;
+      }
       if (token.isAdmin) {
         session.user.isAdmin = true;
       }
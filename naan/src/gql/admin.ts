@@ -2,7 +2,17 @@ import { gql } from "graphql-request";
 
 export const ADMIN_LOGIN_MUTATION = gql`
   mutation AdminLogin($input: LoginInput!) {
-    login(input: $input)
+    login(input: $input) {
+      auth {
+        accessToken
+        accessTokenExpiresAt
+        refreshToken
+      }
+      challenge {
+        token
+        expiresAt
+      }
+    }
   }
 `;
 
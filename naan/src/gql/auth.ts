@@ -0,0 +1,47 @@
+import { gql } from "graphql-request";
+
+// Tokens issued when a backend session starts or is refreshed. The access
+// token is short-lived; the refresh token is traded for a new pair with
+// REFRESH_TOKEN_MUTATION and only works once.
+export type AuthPayload = {
+  accessToken: string;
+  accessTokenExpiresAt: string;
+  refreshToken: string;
+};
+
+// Logins of users with two-factor authentication return a challenge
+// instead of tokens, completed with COMPLETE_TWO_FACTOR_LOGIN_MUTATION.
+export type LoginResult = {
+  auth: AuthPayload | null;
+  challenge: { token: string; expiresAt: string } | null;
+};
+
+export const SIGN_IN_MUTATION = gql`
+  mutation SignIn($input: NewUser!) {
+    signIn(input: $input) {
+      accessToken
+      accessTokenExpiresAt
+      refreshToken
+    }
+  }
+`;
+
+export const REFRESH_TOKEN_MUTATION = gql`
+  mutation RefreshToken($token: String!) {
+    refreshToken(token: $token) {
+      accessToken
+      accessTokenExpiresAt
+      refreshToken
+    }
+  }
+`;
+
+export const COMPLETE_TWO_FACTOR_LOGIN_MUTATION = gql`
+  mutation CompleteTwoFactorLogin($challenge: String!, $code: String!) {
+    completeTwoFactorLogin(challenge: $challenge, code: $code) {
+      accessToken
+      accessTokenExpiresAt
+      refreshToken
+    }
+  }
+`;
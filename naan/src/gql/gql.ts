@@ -14,7 +14,6 @@ import { TypedDocumentNode as DocumentNode } from '@graphql-typed-document-node/
  * Learn more about it here: https://the-guild.dev/graphql/codegen/plugins/presets/preset-client#reducing-bundle-size
  */
 type Documents = {
-    "\n        mutation SignIn($input: NewUser!) {\n          signIn(input: $input)\n        }\n          ": typeof types.SignInDocument,
     "\n            query GetCurrentUser {\n              me {\n                id\n                username\n                displayName\n                setupComplete\n                isAdmin\n              }\n            }\n          ": typeof types.GetCurrentUserDocument,
     "\n  mutation CompleteSetup($input: CompleteSetupInput!) {\n    completeSetup(input: $input)\n  }\n": typeof types.CompleteSetupDocument,
     "\n  query CheckUsername($username: String!) {\n    checkUsername(username: $username)\n  }\n": typeof types.CheckUsernameDocument,
@@ -61,7 +60,6 @@ type Documents = {
     "\n  mutation UploadAvatar($file: Upload!) {\n    uploadAvatar(file: $file)\n  }\n": typeof types.UploadAvatarDocument,
 };
 const documents: Documents = {
-    "\n        mutation SignIn($input: NewUser!) {\n          signIn(input: $input)\n        }\n          ": types.SignInDocument,
     "\n            query GetCurrentUser {\n              me {\n                id\n                username\n                displayName\n                setupComplete\n                isAdmin\n              }\n            }\n          ": types.GetCurrentUserDocument,
     "\n  mutation CompleteSetup($input: CompleteSetupInput!) {\n    completeSetup(input: $input)\n  }\n": types.CompleteSetupDocument,
     "\n  query CheckUsername($username: String!) {\n    checkUsername(username: $username)\n  }\n": types.CheckUsernameDocument,
@@ -122,10 +120,6 @@ const documents: Documents = {
  */
 export function gql(source: string): unknown;
 
-/**
- * The gql function is used to parse GraphQL queries into a document that can be used by GraphQL clients.
- */
-export function gql(source: "\n        mutation SignIn($input: NewUser!) {\n          signIn(input: $input)\n        }\n          "): (typeof documents)["\n        mutation SignIn($input: NewUser!) {\n          signIn(input: $input)\n        }\n          "];
 /**
  * The gql function is used to parse GraphQL queries into a document that can be used by GraphQL clients.
  */
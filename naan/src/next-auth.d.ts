@@ -4,6 +4,9 @@ import { JWT } from "next-auth/jwt";
 declare module "next-auth" {
   interface Session {
     backendToken?: string;
+    // Set when the backend session could not be refreshed and the user
+    // has to sign in again.
+    error?: "RefreshTokenError";
     user: {
       id: string;
       username?: string;
@@ -15,6 +18,8 @@ declare module "next-auth" {
 
   interface User {
     backendToken?: string;
+    refreshToken?: string;
+    backendTokenExpiresAt?: number;
     gender?: string;
     phoneNumber?: string;
     username?: string;
@@ -27,6 +32,10 @@ declare module "next-auth" {
 declare module "next-auth/jwt" {
   interface JWT {
     backendToken?: string;
+    refreshToken?: string;
+    // When backendToken expires, in milliseconds since the epoch.
+    backendTokenExpiresAt?: number;
+    error?: "RefreshTokenError";
     username?: string;
     displayName?: string;
     setupComplete?: boolean;
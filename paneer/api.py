@@ -55,6 +55,22 @@ class ChatRequest(BaseModel):
 
 ENVIRONMENT = os.getenv("ENV", "development")
 JWT_SECRET = os.getenv("JWT_SECRET", "your-secret-key")
+# When set (e.g. http://gravy:8080/.well-known/jwks.json), tokens signed with
+# gravy's asymmetric keys are verified against its published keys, so this
+# service does not need the signing secret. HS256 tokens still use JWT_SECRET.
+JWKS_URL = os.getenv("JWKS_URL")
+jwks_client = jwt.PyJWKClient(JWKS_URL, cache_keys=True, lifespan=300) if JWKS_URL else None
+
+
+def decode_token(token: str) -> dict:
+    header = jwt.get_unverified_header(token)
+    if header.get("alg") == "HS256":
+        return jwt.decode(token, JWT_SECRET, algorithms=["HS256"])
+    if jwks_client is None:
+        raise jwt.InvalidTokenError("JWKS_URL is not configured")
+    signing_key = jwks_client.get_signing_key_from_jwt(token)
+    return jwt.decode(token, signing_key.key, algorithms=["EdDSA", "RS256"])
+
 MONGODB_URI = os.getenv("MONGODB_URI", "mongodb://localhost:27017")
 
 # Database Connection
@@ -86,7 +102,7 @@ class AuthMiddleware(BaseHTTPMiddleware):
                     raise HTTPException(status_code=403, detail="Invalid authentication scheme")
                 
                 # Decode token
-                payload = jwt.decode(token, JWT_SECRET, algorithms=["HS256"])
+                payload = decode_token(token)
                 user_id = payload.get("user_id")
                 
                 if user_id:
@@ -94,7 +110,7 @@ class AuthMiddleware(BaseHTTPMiddleware):
                 else:
                      return JSONResponse(status_code=403, content={"detail": "Invalid token payload"})
                      
-            except (ValueError, jwt.ExpiredSignatureError, jwt.InvalidTokenError) as e:
+            except (ValueError, jwt.ExpiredSignatureError, jwt.InvalidTokenError, jwt.PyJWKClientError) as e:
                 print(f"Auth failed: {e}")
                 return JSONResponse(status_code=403, content={"detail": "Invalid authentication token"})
         